@@ -0,0 +1,14 @@
+// Package openapi embeds the OpenAPI 3 description of the HTTP API and its documentation page.
+package openapi
+
+import _ "embed"
+
+// Spec is the OpenAPI 3 document served at /openapi.json
+//
+//go:embed openapi.json
+var Spec []byte
+
+// DocsPage is the Redoc page served at /docs
+//
+//go:embed docs.html
+var DocsPage []byte
@@ -0,0 +1,642 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.12
+// 	protoc        (unknown)
+// source: weather/v1/weather.proto
+
+package weatherv1
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	durationpb "google.golang.org/protobuf/types/known/durationpb"
+	timestamppb "google.golang.org/protobuf/types/known/timestamppb"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+// Temperature represents the temperature in different scales
+type Temperature struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Celsius       float64                `protobuf:"fixed64,1,opt,name=celsius,proto3" json:"celsius,omitempty"`
+	Fahrenheit    float64                `protobuf:"fixed64,2,opt,name=fahrenheit,proto3" json:"fahrenheit,omitempty"`
+	Kelvin        float64                `protobuf:"fixed64,3,opt,name=kelvin,proto3" json:"kelvin,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Temperature) Reset() {
+	*x = Temperature{}
+	mi := &file_weather_v1_weather_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Temperature) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Temperature) ProtoMessage() {}
+
+func (x *Temperature) ProtoReflect() protoreflect.Message {
+	mi := &file_weather_v1_weather_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Temperature.ProtoReflect.Descriptor instead.
+func (*Temperature) Descriptor() ([]byte, []int) {
+	return file_weather_v1_weather_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *Temperature) GetCelsius() float64 {
+	if x != nil {
+		return x.Celsius
+	}
+	return 0
+}
+
+func (x *Temperature) GetFahrenheit() float64 {
+	if x != nil {
+		return x.Fahrenheit
+	}
+	return 0
+}
+
+func (x *Temperature) GetKelvin() float64 {
+	if x != nil {
+		return x.Kelvin
+	}
+	return 0
+}
+
+// Error represents a per-item failure using the gRPC status code numbers
+type Error struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Code          int32                  `protobuf:"varint,1,opt,name=code,proto3" json:"code,omitempty"`
+	Message       string                 `protobuf:"bytes,2,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Error) Reset() {
+	*x = Error{}
+	mi := &file_weather_v1_weather_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Error) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Error) ProtoMessage() {}
+
+func (x *Error) ProtoReflect() protoreflect.Message {
+	mi := &file_weather_v1_weather_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Error.ProtoReflect.Descriptor instead.
+func (*Error) Descriptor() ([]byte, []int) {
+	return file_weather_v1_weather_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Error) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *Error) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type GetTemperatureRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Cep           string                 `protobuf:"bytes,1,opt,name=cep,proto3" json:"cep,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTemperatureRequest) Reset() {
+	*x = GetTemperatureRequest{}
+	mi := &file_weather_v1_weather_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTemperatureRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTemperatureRequest) ProtoMessage() {}
+
+func (x *GetTemperatureRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_weather_v1_weather_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTemperatureRequest.ProtoReflect.Descriptor instead.
+func (*GetTemperatureRequest) Descriptor() ([]byte, []int) {
+	return file_weather_v1_weather_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *GetTemperatureRequest) GetCep() string {
+	if x != nil {
+		return x.Cep
+	}
+	return ""
+}
+
+type GetTemperatureResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Cep           string                 `protobuf:"bytes,1,opt,name=cep,proto3" json:"cep,omitempty"`
+	Temperature   *Temperature           `protobuf:"bytes,2,opt,name=temperature,proto3" json:"temperature,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *GetTemperatureResponse) Reset() {
+	*x = GetTemperatureResponse{}
+	mi := &file_weather_v1_weather_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *GetTemperatureResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*GetTemperatureResponse) ProtoMessage() {}
+
+func (x *GetTemperatureResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_weather_v1_weather_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use GetTemperatureResponse.ProtoReflect.Descriptor instead.
+func (*GetTemperatureResponse) Descriptor() ([]byte, []int) {
+	return file_weather_v1_weather_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *GetTemperatureResponse) GetCep() string {
+	if x != nil {
+		return x.Cep
+	}
+	return ""
+}
+
+func (x *GetTemperatureResponse) GetTemperature() *Temperature {
+	if x != nil {
+		return x.Temperature
+	}
+	return nil
+}
+
+type BatchGetTemperatureRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Ceps          []string               `protobuf:"bytes,1,rep,name=ceps,proto3" json:"ceps,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchGetTemperatureRequest) Reset() {
+	*x = BatchGetTemperatureRequest{}
+	mi := &file_weather_v1_weather_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchGetTemperatureRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchGetTemperatureRequest) ProtoMessage() {}
+
+func (x *BatchGetTemperatureRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_weather_v1_weather_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchGetTemperatureRequest.ProtoReflect.Descriptor instead.
+func (*BatchGetTemperatureRequest) Descriptor() ([]byte, []int) {
+	return file_weather_v1_weather_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *BatchGetTemperatureRequest) GetCeps() []string {
+	if x != nil {
+		return x.Ceps
+	}
+	return nil
+}
+
+type BatchGetTemperatureResult struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Cep   string                 `protobuf:"bytes,1,opt,name=cep,proto3" json:"cep,omitempty"`
+	// Types that are valid to be assigned to Result:
+	//
+	//	*BatchGetTemperatureResult_Temperature
+	//	*BatchGetTemperatureResult_Error
+	Result        isBatchGetTemperatureResult_Result `protobuf_oneof:"result"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchGetTemperatureResult) Reset() {
+	*x = BatchGetTemperatureResult{}
+	mi := &file_weather_v1_weather_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchGetTemperatureResult) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchGetTemperatureResult) ProtoMessage() {}
+
+func (x *BatchGetTemperatureResult) ProtoReflect() protoreflect.Message {
+	mi := &file_weather_v1_weather_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchGetTemperatureResult.ProtoReflect.Descriptor instead.
+func (*BatchGetTemperatureResult) Descriptor() ([]byte, []int) {
+	return file_weather_v1_weather_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *BatchGetTemperatureResult) GetCep() string {
+	if x != nil {
+		return x.Cep
+	}
+	return ""
+}
+
+func (x *BatchGetTemperatureResult) GetResult() isBatchGetTemperatureResult_Result {
+	if x != nil {
+		return x.Result
+	}
+	return nil
+}
+
+func (x *BatchGetTemperatureResult) GetTemperature() *Temperature {
+	if x != nil {
+		if x, ok := x.Result.(*BatchGetTemperatureResult_Temperature); ok {
+			return x.Temperature
+		}
+	}
+	return nil
+}
+
+func (x *BatchGetTemperatureResult) GetError() *Error {
+	if x != nil {
+		if x, ok := x.Result.(*BatchGetTemperatureResult_Error); ok {
+			return x.Error
+		}
+	}
+	return nil
+}
+
+type isBatchGetTemperatureResult_Result interface {
+	isBatchGetTemperatureResult_Result()
+}
+
+type BatchGetTemperatureResult_Temperature struct {
+	Temperature *Temperature `protobuf:"bytes,2,opt,name=temperature,proto3,oneof"`
+}
+
+type BatchGetTemperatureResult_Error struct {
+	Error *Error `protobuf:"bytes,3,opt,name=error,proto3,oneof"`
+}
+
+func (*BatchGetTemperatureResult_Temperature) isBatchGetTemperatureResult_Result() {}
+
+func (*BatchGetTemperatureResult_Error) isBatchGetTemperatureResult_Result() {}
+
+type BatchGetTemperatureResponse struct {
+	state         protoimpl.MessageState       `protogen:"open.v1"`
+	Results       []*BatchGetTemperatureResult `protobuf:"bytes,1,rep,name=results,proto3" json:"results,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BatchGetTemperatureResponse) Reset() {
+	*x = BatchGetTemperatureResponse{}
+	mi := &file_weather_v1_weather_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BatchGetTemperatureResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BatchGetTemperatureResponse) ProtoMessage() {}
+
+func (x *BatchGetTemperatureResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_weather_v1_weather_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BatchGetTemperatureResponse.ProtoReflect.Descriptor instead.
+func (*BatchGetTemperatureResponse) Descriptor() ([]byte, []int) {
+	return file_weather_v1_weather_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *BatchGetTemperatureResponse) GetResults() []*BatchGetTemperatureResult {
+	if x != nil {
+		return x.Results
+	}
+	return nil
+}
+
+type WatchTemperatureRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Cep   string                 `protobuf:"bytes,1,opt,name=cep,proto3" json:"cep,omitempty"`
+	// Interval between updates. Defaults to one minute and cannot be lower than ten seconds.
+	Interval      *durationpb.Duration `protobuf:"bytes,2,opt,name=interval,proto3" json:"interval,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchTemperatureRequest) Reset() {
+	*x = WatchTemperatureRequest{}
+	mi := &file_weather_v1_weather_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchTemperatureRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchTemperatureRequest) ProtoMessage() {}
+
+func (x *WatchTemperatureRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_weather_v1_weather_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchTemperatureRequest.ProtoReflect.Descriptor instead.
+func (*WatchTemperatureRequest) Descriptor() ([]byte, []int) {
+	return file_weather_v1_weather_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *WatchTemperatureRequest) GetCep() string {
+	if x != nil {
+		return x.Cep
+	}
+	return ""
+}
+
+func (x *WatchTemperatureRequest) GetInterval() *durationpb.Duration {
+	if x != nil {
+		return x.Interval
+	}
+	return nil
+}
+
+type WatchTemperatureResponse struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Cep           string                 `protobuf:"bytes,1,opt,name=cep,proto3" json:"cep,omitempty"`
+	Temperature   *Temperature           `protobuf:"bytes,2,opt,name=temperature,proto3" json:"temperature,omitempty"`
+	ObservedAt    *timestamppb.Timestamp `protobuf:"bytes,3,opt,name=observed_at,json=observedAt,proto3" json:"observed_at,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *WatchTemperatureResponse) Reset() {
+	*x = WatchTemperatureResponse{}
+	mi := &file_weather_v1_weather_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *WatchTemperatureResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*WatchTemperatureResponse) ProtoMessage() {}
+
+func (x *WatchTemperatureResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_weather_v1_weather_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use WatchTemperatureResponse.ProtoReflect.Descriptor instead.
+func (*WatchTemperatureResponse) Descriptor() ([]byte, []int) {
+	return file_weather_v1_weather_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *WatchTemperatureResponse) GetCep() string {
+	if x != nil {
+		return x.Cep
+	}
+	return ""
+}
+
+func (x *WatchTemperatureResponse) GetTemperature() *Temperature {
+	if x != nil {
+		return x.Temperature
+	}
+	return nil
+}
+
+func (x *WatchTemperatureResponse) GetObservedAt() *timestamppb.Timestamp {
+	if x != nil {
+		return x.ObservedAt
+	}
+	return nil
+}
+
+var File_weather_v1_weather_proto protoreflect.FileDescriptor
+
+const file_weather_v1_weather_proto_rawDesc = "" +
+	"\n" +
+	"\x18weather/v1/weather.proto\x12\n" +
+	"weather.v1\x1a\x1egoogle/protobuf/duration.proto\x1a\x1fgoogle/protobuf/timestamp.proto\"_\n" +
+	"\vTemperature\x12\x18\n" +
+	"\acelsius\x18\x01 \x01(\x01R\acelsius\x12\x1e\n" +
+	"\n" +
+	"fahrenheit\x18\x02 \x01(\x01R\n" +
+	"fahrenheit\x12\x16\n" +
+	"\x06kelvin\x18\x03 \x01(\x01R\x06kelvin\"5\n" +
+	"\x05Error\x12\x12\n" +
+	"\x04code\x18\x01 \x01(\x05R\x04code\x12\x18\n" +
+	"\amessage\x18\x02 \x01(\tR\amessage\")\n" +
+	"\x15GetTemperatureRequest\x12\x10\n" +
+	"\x03cep\x18\x01 \x01(\tR\x03cep\"e\n" +
+	"\x16GetTemperatureResponse\x12\x10\n" +
+	"\x03cep\x18\x01 \x01(\tR\x03cep\x129\n" +
+	"\vtemperature\x18\x02 \x01(\v2\x17.weather.v1.TemperatureR\vtemperature\"0\n" +
+	"\x1aBatchGetTemperatureRequest\x12\x12\n" +
+	"\x04ceps\x18\x01 \x03(\tR\x04ceps\"\x9f\x01\n" +
+	"\x19BatchGetTemperatureResult\x12\x10\n" +
+	"\x03cep\x18\x01 \x01(\tR\x03cep\x12;\n" +
+	"\vtemperature\x18\x02 \x01(\v2\x17.weather.v1.TemperatureH\x00R\vtemperature\x12)\n" +
+	"\x05error\x18\x03 \x01(\v2\x11.weather.v1.ErrorH\x00R\x05errorB\b\n" +
+	"\x06result\"^\n" +
+	"\x1bBatchGetTemperatureResponse\x12?\n" +
+	"\aresults\x18\x01 \x03(\v2%.weather.v1.BatchGetTemperatureResultR\aresults\"b\n" +
+	"\x17WatchTemperatureRequest\x12\x10\n" +
+	"\x03cep\x18\x01 \x01(\tR\x03cep\x125\n" +
+	"\binterval\x18\x02 \x01(\v2\x19.google.protobuf.DurationR\binterval\"\xa4\x01\n" +
+	"\x18WatchTemperatureResponse\x12\x10\n" +
+	"\x03cep\x18\x01 \x01(\tR\x03cep\x129\n" +
+	"\vtemperature\x18\x02 \x01(\v2\x17.weather.v1.TemperatureR\vtemperature\x12;\n" +
+	"\vobserved_at\x18\x03 \x01(\v2\x1a.google.protobuf.TimestampR\n" +
+	"observedAt2\xb2\x02\n" +
+	"\x0eWeatherService\x12W\n" +
+	"\x0eGetTemperature\x12!.weather.v1.GetTemperatureRequest\x1a\".weather.v1.GetTemperatureResponse\x12f\n" +
+	"\x13BatchGetTemperature\x12&.weather.v1.BatchGetTemperatureRequest\x1a'.weather.v1.BatchGetTemperatureResponse\x12_\n" +
+	"\x10WatchTemperature\x12#.weather.v1.WatchTemperatureRequest\x1a$.weather.v1.WatchTemperatureResponse0\x01BEZCgithub.com/xavierpms/weather-by-city/api/proto/weather/v1;weatherv1b\x06proto3"
+
+var (
+	file_weather_v1_weather_proto_rawDescOnce sync.Once
+	file_weather_v1_weather_proto_rawDescData []byte
+)
+
+func file_weather_v1_weather_proto_rawDescGZIP() []byte {
+	file_weather_v1_weather_proto_rawDescOnce.Do(func() {
+		file_weather_v1_weather_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_weather_v1_weather_proto_rawDesc), len(file_weather_v1_weather_proto_rawDesc)))
+	})
+	return file_weather_v1_weather_proto_rawDescData
+}
+
+var file_weather_v1_weather_proto_msgTypes = make([]protoimpl.MessageInfo, 9)
+var file_weather_v1_weather_proto_goTypes = []any{
+	(*Temperature)(nil),                 // 0: weather.v1.Temperature
+	(*Error)(nil),                       // 1: weather.v1.Error
+	(*GetTemperatureRequest)(nil),       // 2: weather.v1.GetTemperatureRequest
+	(*GetTemperatureResponse)(nil),      // 3: weather.v1.GetTemperatureResponse
+	(*BatchGetTemperatureRequest)(nil),  // 4: weather.v1.BatchGetTemperatureRequest
+	(*BatchGetTemperatureResult)(nil),   // 5: weather.v1.BatchGetTemperatureResult
+	(*BatchGetTemperatureResponse)(nil), // 6: weather.v1.BatchGetTemperatureResponse
+	(*WatchTemperatureRequest)(nil),     // 7: weather.v1.WatchTemperatureRequest
+	(*WatchTemperatureResponse)(nil),    // 8: weather.v1.WatchTemperatureResponse
+	(*durationpb.Duration)(nil),         // 9: google.protobuf.Duration
+	(*timestamppb.Timestamp)(nil),       // 10: google.protobuf.Timestamp
+}
+var file_weather_v1_weather_proto_depIdxs = []int32{
+	0,  // 0: weather.v1.GetTemperatureResponse.temperature:type_name -> weather.v1.Temperature
+	0,  // 1: weather.v1.BatchGetTemperatureResult.temperature:type_name -> weather.v1.Temperature
+	1,  // 2: weather.v1.BatchGetTemperatureResult.error:type_name -> weather.v1.Error
+	5,  // 3: weather.v1.BatchGetTemperatureResponse.results:type_name -> weather.v1.BatchGetTemperatureResult
+	9,  // 4: weather.v1.WatchTemperatureRequest.interval:type_name -> google.protobuf.Duration
+	0,  // 5: weather.v1.WatchTemperatureResponse.temperature:type_name -> weather.v1.Temperature
+	10, // 6: weather.v1.WatchTemperatureResponse.observed_at:type_name -> google.protobuf.Timestamp
+	2,  // 7: weather.v1.WeatherService.GetTemperature:input_type -> weather.v1.GetTemperatureRequest
+	4,  // 8: weather.v1.WeatherService.BatchGetTemperature:input_type -> weather.v1.BatchGetTemperatureRequest
+	7,  // 9: weather.v1.WeatherService.WatchTemperature:input_type -> weather.v1.WatchTemperatureRequest
+	3,  // 10: weather.v1.WeatherService.GetTemperature:output_type -> weather.v1.GetTemperatureResponse
+	6,  // 11: weather.v1.WeatherService.BatchGetTemperature:output_type -> weather.v1.BatchGetTemperatureResponse
+	8,  // 12: weather.v1.WeatherService.WatchTemperature:output_type -> weather.v1.WatchTemperatureResponse
+	10, // [10:13] is the sub-list for method output_type
+	7,  // [7:10] is the sub-list for method input_type
+	7,  // [7:7] is the sub-list for extension type_name
+	7,  // [7:7] is the sub-list for extension extendee
+	0,  // [0:7] is the sub-list for field type_name
+}
+
+func init() { file_weather_v1_weather_proto_init() }
+func file_weather_v1_weather_proto_init() {
+	if File_weather_v1_weather_proto != nil {
+		return
+	}
+	file_weather_v1_weather_proto_msgTypes[5].OneofWrappers = []any{
+		(*BatchGetTemperatureResult_Temperature)(nil),
+		(*BatchGetTemperatureResult_Error)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_weather_v1_weather_proto_rawDesc), len(file_weather_v1_weather_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   9,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_weather_v1_weather_proto_goTypes,
+		DependencyIndexes: file_weather_v1_weather_proto_depIdxs,
+		MessageInfos:      file_weather_v1_weather_proto_msgTypes,
+	}.Build()
+	File_weather_v1_weather_proto = out.File
+	file_weather_v1_weather_proto_goTypes = nil
+	file_weather_v1_weather_proto_depIdxs = nil
+}
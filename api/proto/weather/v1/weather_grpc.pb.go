@@ -0,0 +1,213 @@
+// Code generated by protoc-gen-go-grpc. DO NOT EDIT.
+// versions:
+// - protoc-gen-go-grpc v1.6.2
+// - protoc             (unknown)
+// source: weather/v1/weather.proto
+
+package weatherv1
+
+import (
+	context "context"
+	grpc "google.golang.org/grpc"
+	codes "google.golang.org/grpc/codes"
+	status "google.golang.org/grpc/status"
+)
+
+// This is a compile-time assertion to ensure that this generated file
+// is compatible with the grpc package it is being compiled against.
+// Requires gRPC-Go v1.64.0 or later.
+const _ = grpc.SupportPackageIsVersion9
+
+const (
+	WeatherService_GetTemperature_FullMethodName      = "/weather.v1.WeatherService/GetTemperature"
+	WeatherService_BatchGetTemperature_FullMethodName = "/weather.v1.WeatherService/BatchGetTemperature"
+	WeatherService_WatchTemperature_FullMethodName    = "/weather.v1.WeatherService/WatchTemperature"
+)
+
+// WeatherServiceClient is the client API for WeatherService service.
+//
+// For semantics around ctx use and closing/ending streaming RPCs, please refer to https://pkg.go.dev/google.golang.org/grpc/?tab=doc#ClientConn.NewStream.
+//
+// WeatherService exposes the temperature lookup by CEP
+type WeatherServiceClient interface {
+	// GetTemperature returns the current temperature for a CEP
+	GetTemperature(ctx context.Context, in *GetTemperatureRequest, opts ...grpc.CallOption) (*GetTemperatureResponse, error)
+	// BatchGetTemperature returns the current temperature for several CEPs,
+	// reporting failures per CEP instead of failing the whole call
+	BatchGetTemperature(ctx context.Context, in *BatchGetTemperatureRequest, opts ...grpc.CallOption) (*BatchGetTemperatureResponse, error)
+	// WatchTemperature streams the temperature for a CEP at a fixed interval
+	WatchTemperature(ctx context.Context, in *WatchTemperatureRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WatchTemperatureResponse], error)
+}
+
+type weatherServiceClient struct {
+	cc grpc.ClientConnInterface
+}
+
+func NewWeatherServiceClient(cc grpc.ClientConnInterface) WeatherServiceClient {
+	return &weatherServiceClient{cc}
+}
+
+func (c *weatherServiceClient) GetTemperature(ctx context.Context, in *GetTemperatureRequest, opts ...grpc.CallOption) (*GetTemperatureResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(GetTemperatureResponse)
+	err := c.cc.Invoke(ctx, WeatherService_GetTemperature_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *weatherServiceClient) BatchGetTemperature(ctx context.Context, in *BatchGetTemperatureRequest, opts ...grpc.CallOption) (*BatchGetTemperatureResponse, error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	out := new(BatchGetTemperatureResponse)
+	err := c.cc.Invoke(ctx, WeatherService_BatchGetTemperature_FullMethodName, in, out, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	return out, nil
+}
+
+func (c *weatherServiceClient) WatchTemperature(ctx context.Context, in *WatchTemperatureRequest, opts ...grpc.CallOption) (grpc.ServerStreamingClient[WatchTemperatureResponse], error) {
+	cOpts := append([]grpc.CallOption{grpc.StaticMethod()}, opts...)
+	stream, err := c.cc.NewStream(ctx, &WeatherService_ServiceDesc.Streams[0], WeatherService_WatchTemperature_FullMethodName, cOpts...)
+	if err != nil {
+		return nil, err
+	}
+	x := &grpc.GenericClientStream[WatchTemperatureRequest, WatchTemperatureResponse]{ClientStream: stream}
+	if err := x.ClientStream.SendMsg(in); err != nil {
+		return nil, err
+	}
+	if err := x.ClientStream.CloseSend(); err != nil {
+		return nil, err
+	}
+	return x, nil
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type WeatherService_WatchTemperatureClient = grpc.ServerStreamingClient[WatchTemperatureResponse]
+
+// WeatherServiceServer is the server API for WeatherService service.
+// All implementations must embed UnimplementedWeatherServiceServer
+// for forward compatibility.
+//
+// WeatherService exposes the temperature lookup by CEP
+type WeatherServiceServer interface {
+	// GetTemperature returns the current temperature for a CEP
+	GetTemperature(context.Context, *GetTemperatureRequest) (*GetTemperatureResponse, error)
+	// BatchGetTemperature returns the current temperature for several CEPs,
+	// reporting failures per CEP instead of failing the whole call
+	BatchGetTemperature(context.Context, *BatchGetTemperatureRequest) (*BatchGetTemperatureResponse, error)
+	// WatchTemperature streams the temperature for a CEP at a fixed interval
+	WatchTemperature(*WatchTemperatureRequest, grpc.ServerStreamingServer[WatchTemperatureResponse]) error
+	mustEmbedUnimplementedWeatherServiceServer()
+}
+
+// UnimplementedWeatherServiceServer must be embedded to have
+// forward compatible implementations.
+//
+// NOTE: this should be embedded by value instead of pointer to avoid a nil
+// pointer dereference when methods are called.
+type UnimplementedWeatherServiceServer struct{}
+
+func (UnimplementedWeatherServiceServer) GetTemperature(context.Context, *GetTemperatureRequest) (*GetTemperatureResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method GetTemperature not implemented")
+}
+func (UnimplementedWeatherServiceServer) BatchGetTemperature(context.Context, *BatchGetTemperatureRequest) (*BatchGetTemperatureResponse, error) {
+	return nil, status.Error(codes.Unimplemented, "method BatchGetTemperature not implemented")
+}
+func (UnimplementedWeatherServiceServer) WatchTemperature(*WatchTemperatureRequest, grpc.ServerStreamingServer[WatchTemperatureResponse]) error {
+	return status.Error(codes.Unimplemented, "method WatchTemperature not implemented")
+}
+func (UnimplementedWeatherServiceServer) mustEmbedUnimplementedWeatherServiceServer() {}
+func (UnimplementedWeatherServiceServer) testEmbeddedByValue()                        {}
+
+// UnsafeWeatherServiceServer may be embedded to opt out of forward compatibility for this service.
+// Use of this interface is not recommended, as added methods to WeatherServiceServer will
+// result in compilation errors.
+type UnsafeWeatherServiceServer interface {
+	mustEmbedUnimplementedWeatherServiceServer()
+}
+
+func RegisterWeatherServiceServer(s grpc.ServiceRegistrar, srv WeatherServiceServer) {
+	// If the following call panics, it indicates UnimplementedWeatherServiceServer was
+	// embedded by pointer and is nil.  This will cause panics if an
+	// unimplemented method is ever invoked, so we test this at initialization
+	// time to prevent it from happening at runtime later due to I/O.
+	if t, ok := srv.(interface{ testEmbeddedByValue() }); ok {
+		t.testEmbeddedByValue()
+	}
+	s.RegisterService(&WeatherService_ServiceDesc, srv)
+}
+
+func _WeatherService_GetTemperature_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(GetTemperatureRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WeatherServiceServer).GetTemperature(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WeatherService_GetTemperature_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WeatherServiceServer).GetTemperature(ctx, req.(*GetTemperatureRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WeatherService_BatchGetTemperature_Handler(srv interface{}, ctx context.Context, dec func(interface{}) error, interceptor grpc.UnaryServerInterceptor) (interface{}, error) {
+	in := new(BatchGetTemperatureRequest)
+	if err := dec(in); err != nil {
+		return nil, err
+	}
+	if interceptor == nil {
+		return srv.(WeatherServiceServer).BatchGetTemperature(ctx, in)
+	}
+	info := &grpc.UnaryServerInfo{
+		Server:     srv,
+		FullMethod: WeatherService_BatchGetTemperature_FullMethodName,
+	}
+	handler := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return srv.(WeatherServiceServer).BatchGetTemperature(ctx, req.(*BatchGetTemperatureRequest))
+	}
+	return interceptor(ctx, in, info, handler)
+}
+
+func _WeatherService_WatchTemperature_Handler(srv interface{}, stream grpc.ServerStream) error {
+	m := new(WatchTemperatureRequest)
+	if err := stream.RecvMsg(m); err != nil {
+		return err
+	}
+	return srv.(WeatherServiceServer).WatchTemperature(m, &grpc.GenericServerStream[WatchTemperatureRequest, WatchTemperatureResponse]{ServerStream: stream})
+}
+
+// This type alias is provided for backwards compatibility with existing code that references the prior non-generic stream type by name.
+type WeatherService_WatchTemperatureServer = grpc.ServerStreamingServer[WatchTemperatureResponse]
+
+// WeatherService_ServiceDesc is the grpc.ServiceDesc for WeatherService service.
+// It's only intended for direct use with grpc.RegisterService,
+// and not to be introspected or modified (even as a copy)
+var WeatherService_ServiceDesc = grpc.ServiceDesc{
+	ServiceName: "weather.v1.WeatherService",
+	HandlerType: (*WeatherServiceServer)(nil),
+	Methods: []grpc.MethodDesc{
+		{
+			MethodName: "GetTemperature",
+			Handler:    _WeatherService_GetTemperature_Handler,
+		},
+		{
+			MethodName: "BatchGetTemperature",
+			Handler:    _WeatherService_BatchGetTemperature_Handler,
+		},
+	},
+	Streams: []grpc.StreamDesc{
+		{
+			StreamName:    "WatchTemperature",
+			Handler:       _WeatherService_WatchTemperature_Handler,
+			ServerStreams: true,
+		},
+	},
+	Metadata: "weather/v1/weather.proto",
+}
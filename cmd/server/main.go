@@ -1,63 +1,618 @@
 package main
 
 import (
-	"log"
+	"context"
+	"fmt"
+	"log/slog"
+	"net"
 	"net/http"
+	"os"
+	"os/signal"
+	"sync"
+	"syscall"
+	"time"
 
 	"github.com/go-chi/chi"
 	"github.com/go-chi/chi/middleware"
+	"github.com/redis/go-redis/v9"
+	"github.com/xavierpms/weather-by-city/api/openapi"
 	"github.com/xavierpms/weather-by-city/internal/config"
+	"github.com/xavierpms/weather-by-city/internal/infra/auth"
+	"github.com/xavierpms/weather-by-city/internal/infra/breaker"
+	"github.com/xavierpms/weather-by-city/internal/infra/graphqlapi"
+	"github.com/xavierpms/weather-by-city/internal/infra/grpcserver"
+	"github.com/xavierpms/weather-by-city/internal/infra/health"
+	"github.com/xavierpms/weather-by-city/internal/infra/keypool"
+	"github.com/xavierpms/weather-by-city/internal/infra/logging"
+	"github.com/xavierpms/weather-by-city/internal/infra/metrics"
+	"github.com/xavierpms/weather-by-city/internal/infra/quota"
+	"github.com/xavierpms/weather-by-city/internal/infra/ratelimit"
+	"github.com/xavierpms/weather-by-city/internal/infra/reload"
 	"github.com/xavierpms/weather-by-city/internal/infra/repository"
+	"github.com/xavierpms/weather-by-city/internal/infra/retry"
+	"github.com/xavierpms/weather-by-city/internal/infra/scheduler"
+	"github.com/xavierpms/weather-by-city/internal/infra/tracing"
 	"github.com/xavierpms/weather-by-city/internal/infra/validator"
+	"github.com/xavierpms/weather-by-city/internal/infra/webhook"
+	"github.com/xavierpms/weather-by-city/internal/infra/webserver"
 	"github.com/xavierpms/weather-by-city/internal/infra/webserver/handlers"
+	"github.com/xavierpms/weather-by-city/internal/infra/webserver/middlewares"
+	"github.com/xavierpms/weather-by-city/internal/infra/webserver/problem"
+	"github.com/xavierpms/weather-by-city/internal/infra/webserver/render"
 	"github.com/xavierpms/weather-by-city/internal/usecase"
 )
 
 func main() {
-	// Load the configurations from the .env file
-	cfg, err := config.LoadConfig()
+	// Load the configurations from the defaults, the config file, the environment (with the .env
+	// file and the secret files) and the flags
+	opts, err := config.ParseArgs(os.Args[1:])
 	if err != nil {
-		log.Fatalf("failed to load config: %v", err)
+		fatal("invalid command line", err)
+	}
+	cfg, err := config.Load(opts)
+	if err != nil {
+		fatal("failed to load config", err)
+	}
+	if opts.Check {
+		checkConfig(cfg)
+	}
+	if err := cfg.Validate(); err != nil {
+		fatal("invalid config", err)
 	}
 
-	log.Printf(
-		"Config loaded: port=%q weather_api_key_set=%t weather_api_url=%q via_cep_url=%q",
-		cfg.Port,
-		cfg.WeatherAPIKey != "",
-		cfg.WeatherAPIURL,
-		cfg.ViaCEPURL,
-	)
-	if cfg.WeatherAPIKey == "" {
-		log.Printf("WARNING: WEATHER_API_KEY is empty")
+	// Configure the structured logger, also used by the standard log package. The level may
+	// change on a config reload.
+	logLevel := new(slog.LevelVar)
+	level, err := logging.ParseLevel(cfg.LogLevel)
+	if err != nil {
+		fatal("failed to configure logging", err)
 	}
-	if cfg.WeatherAPIURL == "" {
-		log.Printf("WARNING: WEATHER_API_URL is empty")
+	logLevel.Set(level)
+	logger, err := logging.NewWithLevel(os.Stdout, logLevel, cfg.LogFormat)
+	if err != nil {
+		fatal("failed to configure logging", err)
 	}
-	if cfg.ViaCEPURL == "" {
-		log.Printf("WARNING: VIA_CEP_URL is empty")
+	slog.SetDefault(logger)
+
+	// Cancelled on SIGTERM (sent by Cloud Run and Kubernetes) or SIGINT to start the shutdown
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+
+	// Configure the tracer provider
+	shutdownTracing, err := tracing.Setup(ctx, tracing.Options{
+		Exporter:     cfg.TracingExporter,
+		OTLPEndpoint: cfg.TracingOTLPEndpoint,
+		OTLPInsecure: cfg.TracingOTLPInsecure,
+		SampleRatio:  cfg.TracingSampleRatio,
+	})
+	if err != nil {
+		fatal("failed to configure tracing", err)
 	}
-	if cfg.Port == "" {
-		log.Printf("WARNING: PORT is empty")
+
+	slog.Info("Config loaded",
+		"port", cfg.Port,
+		"weather_api_keys", len(cfg.WeatherAPIKeyPool()),
+		"weather_api_key_strategy", cfg.WeatherAPIKeyStrategy,
+		"weather_api_url", cfg.WeatherAPIURL,
+		"via_cep_url", cfg.ViaCEPURL,
+		"log_level", cfg.LogLevel,
+		"log_format", cfg.LogFormat,
+		"tracing_exporter", cfg.TracingExporter,
+		"secrets_dir", cfg.SecretsDir,
+	)
+	for _, setting := range cfg.Settings() {
+		slog.Debug("Setting", "name", setting.Name, "value", setting.Value, "source", setting.Source)
 	}
 
 	// Initialize the router
 	router := chi.NewRouter()
-	router.Use(middleware.Logger)
+	problems := problem.NewWriter(cfg.ErrorCompatMode)
+	appMetrics := metrics.New()
+	router.Use(tracing.Middleware)
+	router.Use(middleware.RequestID)
+	router.Use(appMetrics.Middleware)
+	router.Use(middlewares.RequestLogger)
 	router.Use(middleware.Recoverer)
 
+	// Validate the traffic against the OpenAPI document
+	if cfg.OpenAPIValidation == config.OpenAPIValidationRequests || cfg.OpenAPIValidation == config.OpenAPIValidationAll {
+		openAPIValidator, err := middlewares.NewOpenAPIValidator(openapi.Spec, cfg.OpenAPIValidation == config.OpenAPIValidationAll, problems)
+		if err != nil {
+			fatal("failed to load OpenAPI document", err)
+		}
+		router.Use(openAPIValidator.Handler)
+		slog.Info("OpenAPI validation enabled", "mode", cfg.OpenAPIValidation)
+	}
+
 	// Inject dependencies
+	// Each retry attempt is traced and counted as an upstream request
+	retryPolicy := retry.Policy{
+		MaxAttempts: cfg.UpstreamRetryMaxAttempts,
+		BaseDelay:   cfg.UpstreamRetryBaseDelay,
+		MaxDelay:    cfg.UpstreamRetryMaxDelay,
+		// Every upstream call gets a deadline, whichever path it comes from
+		Timeout:        cfg.UpstreamTimeout,
+		AttemptTimeout: cfg.UpstreamAttemptTimeout,
+	}
+	viaCEPClient := &http.Client{Transport: retry.Transport(appMetrics.Transport(metrics.UpstreamViaCEP, tracing.Transport(nil)), retryPolicy)}
+
+	// Each WeatherAPI call, retries included, is spaced by the outbound rate limit and counted against the plan limits.
+	// An attempt waits for its turn no longer than it may take to run.
+	weatherAPIRate, err := parseWeatherAPIRate(cfg)
+	if err != nil {
+		fatal("invalid WEATHER_API_RATE_LIMIT", err)
+	}
+	weatherAPIBudget, err := quota.NewBudget(metrics.UpstreamWeatherAPI, weatherAPILimits(cfg),
+		repository.NewCallCountRepository(cfg.WeatherAPIUsageFile))
+	if err != nil {
+		fatal("failed to load the WeatherAPI usage", err)
+	}
+	appMetrics.RegisterQuota(weatherAPIBudget)
+	weatherAPIQuota := quota.Transport(appMetrics.Transport(metrics.UpstreamWeatherAPI, tracing.Transport(nil)), weatherAPIBudget, weatherAPIRate, cfg.UpstreamAttemptTimeout, appMetrics)
+	// The key pool adds a WeatherAPI key to each call and moves to the next key when one is refused
+	weatherAPIKeys := keypool.New(metrics.UpstreamWeatherAPI, cfg.WeatherAPIKeyPool(), keypool.Strategy(cfg.WeatherAPIKeyStrategy))
+	weatherAPIClient := &http.Client{Transport: retry.Transport(weatherAPIKeys.Transport(weatherAPIQuota), retryPolicy)}
+
+	// One circuit breaker per upstream, shared by every repository calling it
+	breakerSettings := breaker.Settings{
+		FailureThreshold: cfg.BreakerFailureThreshold,
+		OpenDuration:     cfg.BreakerOpenDuration,
+		HalfOpenProbes:   cfg.BreakerHalfOpenProbes,
+		OnStateChange: func(name string, from, to breaker.State) {
+			slog.Warn("Circuit breaker state changed", "upstream", name, "from", from.String(), "to", to.String())
+			appMetrics.ObserveBreakerTransition(name, from, to)
+		},
+	}
+	viaCEPBreaker := breaker.New(metrics.UpstreamViaCEP, breakerSettings)
+	weatherAPIBreaker := breaker.New(metrics.UpstreamWeatherAPI, breakerSettings)
+	breakers := []*breaker.Breaker{viaCEPBreaker, weatherAPIBreaker}
+	for _, b := range breakers {
+		appMetrics.ObserveBreakerState(b.Name(), b.State())
+	}
+
+	// Stale cache entries are served while a breaker is open
+	var cacheStaleFor time.Duration
+	if cfg.BreakerServeStale {
+		cacheStaleFor = cfg.CacheStaleMaxAge
+	}
+
 	cepValidator := validator.NewCEPValidator()
-	cepRepository := repository.NewCEPRepository(cfg.ViaCEPURL)
-	tempRepository := repository.NewTemperatureRepository(cfg.WeatherAPIURL, cfg.WeatherAPIKey)
-	getTempUseCase := usecase.NewGetTemperatureByCEP(cepRepository, tempRepository, cepValidator)
-	temperatureHandler := handlers.NewTemperatureHandler(getTempUseCase)
+	cepRepository := repository.NewCachedCEPRepository(
+		repository.NewBreakerCEPRepository(
+			tracing.NewTracedCEPRepository(repository.NewCEPRepository(cfg.ViaCEPURL, viaCEPClient), metrics.UpstreamViaCEP),
+			viaCEPBreaker),
+		cfg.CacheCEPTTL, cfg.CacheCEPNotFoundTTL, cacheStaleFor, cfg.CacheMaxEntries, cfg.CacheCEPNotFoundMaxEntries, appMetrics)
+	// The Open-Meteo fallback is always in the chain so a config reload can turn it on or off
+	openMeteoClient := &http.Client{Transport: retry.Transport(appMetrics.Transport(metrics.UpstreamOpenMeteo, tracing.Transport(nil)), retryPolicy)}
+	temperatureProvider := repository.NewFallbackTemperatureRepository(
+		repository.NewBreakerTemperatureRepository(
+			tracing.NewTracedTemperatureRepository(repository.NewTemperatureRepository(cfg.WeatherAPIURL, weatherAPIClient), metrics.UpstreamWeatherAPI),
+			weatherAPIBreaker),
+		tracing.NewTracedTemperatureRepository(
+			repository.NewOpenMeteoTemperatureRepository(cfg.OpenMeteoGeocodingURL, cfg.OpenMeteoForecastURL, openMeteoClient), metrics.UpstreamOpenMeteo),
+		cfg.WeatherAPIFallback == config.WeatherAPIFallbackOpenMeteo)
+	if cfg.WeatherAPIFallback == config.WeatherAPIFallbackOpenMeteo {
+		slog.Info("Open-Meteo answers once the WeatherAPI hard limit is reached")
+	}
+	// Near the soft limit of the WeatherAPI budget, stale temperatures are served instead of calling it
+	tempRepository := repository.NewCachedTemperatureRepository(temperatureProvider,
+		cfg.CacheTemperatureTTL, cacheStaleFor, cfg.CacheMaxEntries, appMetrics,
+		func() bool { return weatherAPIBudget.Level() >= quota.LevelSoft })
+	getTempUseCase := metrics.NewInstrumentedTemperatureUseCase(
+		tracing.NewTracedTemperatureUseCase(usecase.NewGetTemperatureByCEP(cepRepository, tempRepository, cepValidator)), appMetrics)
+	getTempsUseCase := usecase.NewGetTemperaturesByCEPs(getTempUseCase, cfg.BatchMaxSize, cfg.BatchConcurrency)
+	temperatureHandler := handlers.NewTemperatureHandler(getTempUseCase, getTempsUseCase, problems, render.NewDefaultRegistry())
+
+	forecastRepository := repository.NewBreakerForecastRepository(
+		tracing.NewTracedForecastRepository(
+			repository.NewForecastRepository(cfg.WeatherForecastAPIURL, weatherAPIClient), metrics.UpstreamWeatherAPI),
+		weatherAPIBreaker)
+	graphqlService, err := graphqlapi.NewService(cepRepository, tempRepository, forecastRepository, cepValidator,
+		cfg.BatchMaxSize, cfg.BatchConcurrency)
+	if err != nil {
+		fatal("failed to build GraphQL schema", err)
+	}
+	graphqlHandler := handlers.NewGraphQLHandler(graphqlService, problems)
+	docsHandler := handlers.NewDocsHandler(openapi.Spec, openapi.DocsPage)
+
+	// Probe the dependencies without the instrumented clients, so probes do not count as traffic
+	probeClient := &http.Client{Timeout: cfg.HealthCheckTimeout}
+	readinessChecker := health.NewChecker(cfg.HealthCheckTTL, cfg.HealthCheckTimeout)
+	readinessChecker.Add("config", func(ctx context.Context) error { return cfg.Validate() })
+	// An unreachable upstream or an open breaker degrades the report without taking the instance out
+	// of rotation, since every instance shares the same upstreams and stale data may still be served
+	readinessChecker.AddNonCritical(metrics.UpstreamViaCEP, health.HTTPCheck(probeClient, cfg.ViaCEPURL+"/01001000/json/"))
+	readinessChecker.AddNonCritical(metrics.UpstreamWeatherAPI, health.HTTPCheck(probeClient, cfg.WeatherAPIURL))
+	for _, b := range breakers {
+		readinessChecker.AddNonCritical(b.Name()+"_breaker", func(ctx context.Context) error {
+			if state := b.State(); state != breaker.StateClosed {
+				return fmt.Errorf("circuit breaker is %s", state)
+			}
+			return nil
+		})
+	}
+
+	// Limit the public routes per client, sharing the buckets through Redis when configured. The
+	// gRPC guard applies the same credentials, scopes and limits to the RPCs.
+	var grpcGuard grpcserver.GuardOptions
+	rateLimit := func(next http.Handler) http.Handler { return next }
+	var rateLimiter *middlewares.RateLimiter
+	var shutdownRateLimit webserver.ShutdownHook = func(ctx context.Context) error { return nil }
+	if cfg.RateLimitEnabled {
+		rateLimitOptions, err := parseRateLimitOptions(cfg)
+		if err != nil {
+			fatal("invalid rate limit", err)
+		}
+
+		var store ratelimit.Store = ratelimit.NewMemoryStore()
+		if cfg.RateLimitBackend == config.RateLimitBackendRedis {
+			redisOptions, err := redis.ParseURL(cfg.RateLimitRedisURL)
+			if err != nil {
+				fatal("invalid RATE_LIMIT_REDIS_URL", err)
+			}
+			// Without it the client ignores the deadline of RATE_LIMIT_REDIS_TIMEOUT
+			redisOptions.ContextTimeoutEnabled = true
+			redisClient := redis.NewClient(redisOptions)
+			// While Redis is down the breaker lets the requests through at once, instead of each one
+			// waiting for the call to time out
+			store = ratelimit.NewBreakerStore(ratelimit.NewRedisStore(redisClient, "weather:ratelimit:", cfg.RateLimitRedisTimeout),
+				breaker.New("ratelimit_redis", breakerSettings))
+			// The limiter lets requests through while Redis is down, so it does not take the instance out
+			readinessChecker.AddNonCritical("ratelimit_redis", func(ctx context.Context) error {
+				return redisClient.Ping(ctx).Err()
+			})
+			shutdownRateLimit = func(ctx context.Context) error { return redisClient.Close() }
+		}
+
+		rateLimiter = middlewares.NewRateLimiter(store, rateLimitOptions, problems, appMetrics)
+		rateLimit = rateLimiter.Handler
+		grpcGuard.RateLimiter = rateLimiter
+		slog.Info("Rate limiting enabled", "backend", cfg.RateLimitBackend, "default", cfg.RateLimitDefault, "routes", cfg.RateLimitRoutes)
+	}
+	healthHandler := handlers.NewHealthHandler(readinessChecker)
+
+	// Authenticate the consumers by API key, counting their usage against the daily quotas
+	apiKeyRepository, err := repository.NewAPIKeyRepository(cfg.APIKeysFile)
+	if err != nil {
+		fatal("failed to load API keys", err)
+	}
+	usageRepository, err := repository.NewUsageRepository(cfg.APIKeysUsageFile)
+	if err != nil {
+		fatal("failed to load API key usage", err)
+	}
+	manageAPIKeys := usecase.NewManageAPIKeys(apiKeyRepository, usageRepository, cfg.APIKeysDefaultDailyQuota)
+	apiKeyHandler := handlers.NewAPIKeyHandler(manageAPIKeys, problems)
+	upstreamKeyHandler := handlers.NewUpstreamKeyHandler(weatherAPIKeys)
+
+	// Reload the config on SIGHUP, on changes of the config file and on demand, swapping the
+	// settings that can change while serving. Every setting is parsed before any is swapped, so a
+	// refused config changes nothing.
+	configReloader := reload.NewReloader(cfg, cfg.ConfigFile,
+		func() (*config.Config, error) { return config.Load(opts) },
+		func(next *config.Config) error {
+			level, err := logging.ParseLevel(next.LogLevel)
+			if err != nil {
+				return err
+			}
+			rate, err := parseWeatherAPIRate(next)
+			if err != nil {
+				return fmt.Errorf("invalid WEATHER_API_RATE_LIMIT: %w", err)
+			}
+			var rateLimitOptions middlewares.RateLimitOptions
+			if rateLimiter != nil {
+				if rateLimitOptions, err = parseRateLimitOptions(next); err != nil {
+					return err
+				}
+			}
+
+			logLevel.Set(level)
+			tempRepository.SetTTL(next.CacheTemperatureTTL)
+			cepRepository.SetTTL(next.CacheCEPTTL)
+			cepRepository.SetNotFoundTTL(next.CacheCEPNotFoundTTL)
+			weatherAPIBudget.SetLimits(weatherAPILimits(next))
+			weatherAPIQuota.SetRate(rate)
+			weatherAPIKeys.SetKeys(next.WeatherAPIKeyPool())
+			weatherAPIKeys.SetStrategy(keypool.Strategy(next.WeatherAPIKeyStrategy))
+			temperatureProvider.SetEnabled(next.WeatherAPIFallback == config.WeatherAPIFallbackOpenMeteo)
+			if rateLimiter != nil {
+				rateLimiter.SetOptions(rateLimitOptions)
+			}
+			return nil
+		},
+		reloadableSettings)
+	go configReloader.Run(ctx, cfg.ConfigWatchInterval)
+	configHandler := handlers.NewConfigHandler(configReloader, problems)
+	authenticate := func(next http.Handler) http.Handler { return next }
+	authorize := func(next http.Handler) http.Handler { return next }
+	if cfg.APIKeysMode != config.APIKeysModeDisabled {
+		apiKeyAuth := middlewares.NewAPIKeyAuth(manageAPIKeys, cfg.APIKeysMode == config.APIKeysModeRequired, problems)
+		authenticate = apiKeyAuth.Authenticate
+		authorize = apiKeyAuth.Authorize
+		grpcGuard.APIKeys = manageAPIKeys
+		grpcGuard.APIKeysRequired = cfg.APIKeysMode == config.APIKeysModeRequired
+		slog.Info("API key authentication enabled", "mode", cfg.APIKeysMode)
+	}
+
+	// Verify the bearer JWTs and enforce the scopes of the admin and configured routes
+	authenticateToken := func(next http.Handler) http.Handler { return next }
+	authorizeToken := func(next http.Handler) http.Handler { return next }
+	var adminAuth []func(http.Handler) http.Handler
+	if cfg.JWTEnabled() {
+		verifier, err := newJWTVerifier(cfg)
+		if err != nil {
+			fatal("failed to configure JWT verification", err)
+		}
+		routeScopes, err := auth.ParseScopeMap(cfg.JWTRouteScopes)
+		if err != nil {
+			fatal("invalid JWT_ROUTE_SCOPES", err)
+		}
+		jwtAuth := middlewares.NewJWTAuth(verifier, routeScopes, problems)
+		authenticateToken = jwtAuth.Authenticate
+		authorizeToken = jwtAuth.Authorize
+		adminAuth = []func(http.Handler) http.Handler{jwtAuth.Authenticate, jwtAuth.RequireScope(auth.ScopeAdmin)}
+		grpcGuard.Verifier = verifier
+		grpcGuard.Scopes = routeScopes
+		if cfg.AdminToken != "" {
+			slog.Warn("ADMIN_TOKEN is ignored, admin endpoints require a JWT with the admin scope")
+		}
+		slog.Info("JWT authentication enabled", "route_scopes", cfg.JWTRouteScopes)
+	} else if cfg.AdminToken != "" {
+		adminAuth = []func(http.Handler) http.Handler{middlewares.AdminToken(cfg.AdminToken, problems)}
+	} else {
+		slog.Warn("ADMIN_TOKEN is empty and JWT is not configured, admin endpoints are disabled")
+	}
 
-	// Define the routes
-	router.Get("/{cep}", temperatureHandler.GetTemperatureByCEP)
+	alertRuleRepository := repository.NewAlertRuleRepository()
+	webhookDispatcher := webhook.NewDispatcher(cfg.AlertWebhookSecret, cfg.AlertWebhookMaxAttempts, cfg.AlertWebhookBackoff, cfg.AlertMaxDeadLetters)
+	manageAlertRules := usecase.NewManageAlertRules(alertRuleRepository, cepValidator, cfg.AlertMaxRulesPerOwner)
+	evaluateAlertRules := usecase.NewEvaluateAlertRules(alertRuleRepository, getTempUseCase, webhookDispatcher)
+	alertHandler := handlers.NewAlertHandler(manageAlertRules, webhookDispatcher, problems)
+
+	// Start the alert evaluation and the periodic flush of the API key usage
+	schedules := []*scheduler.Scheduler{
+		scheduler.NewScheduler("api-key-usage", cfg.APIKeysUsageFlushInterval, usageRepository.Flush),
+		scheduler.NewScheduler("weatherapi-usage", cfg.WeatherAPIUsageFlushInterval, weatherAPIBudget.Flush),
+	}
+	if cfg.AlertsEnabled {
+		schedules = append(schedules, scheduler.NewScheduler("alerts", cfg.AlertEvaluationInterval, evaluateAlertRules.Evaluate))
+	} else {
+		slog.Info("Alerts disabled")
+	}
+	var schedulers sync.WaitGroup
+	for _, s := range schedules {
+		schedulers.Go(func() { s.Run(ctx) })
+	}
+	schedulerDone := make(chan struct{})
+	go func() {
+		schedulers.Wait()
+		close(schedulerDone)
+	}()
+
+	// Define the routes, rate limiting the public API but not the probes, metrics and docs
+	router.Get("/healthz", healthHandler.GetLiveness)
+	router.Get("/readyz", healthHandler.GetReadiness)
+	router.Handle("/metrics", appMetrics.Handler())
+	router.Get("/openapi.json", docsHandler.GetOpenAPI)
+	router.Get("/docs", docsHandler.GetDocs)
+	if adminAuth != nil {
+		router.Route("/admin", func(r chi.Router) {
+			r.Use(adminAuth...)
+			r.Post("/api-keys", apiKeyHandler.CreateKey)
+			r.Get("/api-keys", apiKeyHandler.ListKeys)
+			r.Delete("/api-keys/{id}", apiKeyHandler.RevokeKey)
+			r.Get("/weatherapi-keys", upstreamKeyHandler.ListWeatherAPIKeys)
+			r.Get("/config", configHandler.GetConfig)
+			r.Post("/config/reload", configHandler.ReloadConfig)
+		})
+	}
+	router.Group(func(r chi.Router) {
+		r.Use(authenticateToken, authenticate, rateLimit)
+		// The usage report does not count against the quota
+		r.Get("/v1/usage", apiKeyHandler.GetUsage)
+		r.Group(func(r chi.Router) {
+			// Check the scopes first so rejected requests do not count against the quota. The routes
+			// are registered flat, as a subrouter would hide their pattern behind /v1/alerts/* from
+			// the scopes, the allowed routes and the rate limits.
+			r.Use(authorizeToken, authorize)
+			if cfg.AlertsEnabled {
+				r.Post("/v1/alerts/rules", alertHandler.CreateRule)
+				r.Get("/v1/alerts/rules", alertHandler.ListRules)
+				r.Delete("/v1/alerts/rules/{id}", alertHandler.DeleteRule)
+				r.Get("/v1/alerts/dead-letters", alertHandler.ListDeadLetters)
+				r.Post("/v1/alerts/dead-letters/{id}/replay", alertHandler.ReplayDeadLetter)
+			}
+			r.Post("/v1/temperatures/batch", temperatureHandler.GetTemperaturesByCEPs)
+			r.Get("/v1/graphql", graphqlHandler.Query)
+			r.Post("/v1/graphql", graphqlHandler.Query)
+			r.Get("/{cep}", temperatureHandler.GetTemperatureByCEP)
+		})
+	})
+
+	// Configure the gRPC server
+	var handler http.Handler = router
+	protocols := new(http.Protocols)
+	protocols.SetHTTP1(true)
+	timeouts := webserver.Timeouts{
+		ReadHeader: cfg.HTTPReadHeaderTimeout,
+		Read:       cfg.HTTPReadTimeout,
+		Write:      cfg.HTTPWriteTimeout,
+		Idle:       cfg.HTTPIdleTimeout,
+	}
+
+	grpcServer := grpcserver.NewServer(grpcserver.NewWeatherServer(getTempUseCase, getTempsUseCase),
+		grpcserver.NewGuard(grpcGuard).ServerOptions()...)
+	switch cfg.GRPCMode {
+	case config.GRPCModeMultiplex:
+		// gRPC needs HTTP/2, which is served without TLS behind Cloud Run and load balancers
+		protocols.SetUnencryptedHTTP2(true)
+		handler = grpcserver.MultiplexHandler(grpcServer, router)
+		// The write timeout would cut the gRPC streams served on the same port
+		timeouts.Write = 0
+		slog.Info("Serving gRPC on the HTTP port", "port", cfg.Port)
+
+	case config.GRPCModeSeparate:
+		listener, err := net.Listen("tcp", ":"+cfg.GRPCPort)
+		if err != nil {
+			fatal("failed to listen on gRPC port", err)
+		}
+		go func() {
+			slog.Info("Starting gRPC server", "port", cfg.GRPCPort)
+			if err := grpcServer.Serve(listener); err != nil {
+				fatal("gRPC server error", err)
+			}
+		}()
+
+	default:
+		slog.Info("gRPC server disabled")
+	}
 
 	// Start the server
-	log.Printf("Starting server on port %s", cfg.Port)
-	if err := http.ListenAndServe(":"+cfg.Port, router); err != nil {
-		log.Fatalf("server error: %v", err)
+	server := webserver.NewServer(":"+cfg.Port, handler, protocols, timeouts)
+	listener, err := net.Listen("tcp", server.Addr)
+	if err != nil {
+		fatal("failed to listen on HTTP port", err)
+	}
+	slog.Info("Starting server", "port", cfg.Port)
+
+	// Serve until a signal arrives, then drain the requests while stopping gRPC, whose watch streams
+	// would otherwise hold the drain in multiplex mode, drain the background workers and flush the
+	// usage counts and the telemetry
+	err = webserver.Serve(ctx, server, listener, webserver.Shutdown{
+		Timeout: cfg.ShutdownTimeout,
+		Stop: []webserver.ShutdownHook{
+			func(ctx context.Context) error {
+				return grpcserver.Shutdown(ctx, grpcServer)
+			},
+		},
+		Drain: []webserver.ShutdownHook{
+			func(ctx context.Context) error {
+				select {
+				case <-schedulerDone:
+					return nil
+				case <-ctx.Done():
+					return ctx.Err()
+				}
+			},
+			webhookDispatcher.Shutdown,
+		},
+		FlushTimeout: cfg.ShutdownFlushTimeout,
+		Flush: []webserver.ShutdownHook{
+			usageRepository.Flush,
+			weatherAPIBudget.Flush,
+			shutdownRateLimit,
+			shutdownTracing,
+		},
+	})
+	if err != nil {
+		fatal("shutdown error", err)
+	}
+	slog.Info("Server stopped")
+}
+
+// newJWTVerifier creates the JWT verifier from the HS256 secret and the JWKS file or URL of the config
+func newJWTVerifier(cfg *config.Config) (*auth.Verifier, error) {
+	roleScopes, err := auth.ParseScopeMap(cfg.JWTRoleScopes)
+	if err != nil {
+		return nil, err
+	}
+	options := auth.VerifierOptions{
+		HS256Secret: []byte(cfg.JWTHS256Secret),
+		Issuer:      cfg.JWTIssuer,
+		Audience:    cfg.JWTAudience,
+		Leeway:      cfg.JWTLeeway,
+		RolesClaim:  cfg.JWTRolesClaim,
+		RoleScopes:  roleScopes,
+	}
+
+	switch {
+	case cfg.JWTJWKSFile != "":
+		options.KeySet, err = auth.NewFileKeySet(cfg.JWTJWKSFile)
+		if err != nil {
+			return nil, err
+		}
+	case cfg.JWTJWKSURL != "":
+		client := &http.Client{Timeout: 10 * time.Second, Transport: tracing.Transport(nil)}
+		options.KeySet = auth.NewURLKeySet(client, cfg.JWTJWKSURL, cfg.JWTJWKSRefreshInterval)
+		// A provider that is down at startup is retried when the first token arrives
+		if err := options.KeySet.Refresh(context.Background()); err != nil {
+			slog.Warn("JWKS unavailable at startup", "url", cfg.JWTJWKSURL, "err", err)
+		}
 	}
+
+	return auth.NewVerifier(options)
+}
+
+// reloadableSettings are applied by a config reload, the other settings need a restart
+var reloadableSettings = []string{
+	"LOG_LEVEL",
+	"CACHE_TEMPERATURE_TTL",
+	"CACHE_CEP_TTL",
+	"CACHE_CEP_NOT_FOUND_TTL",
+	"RATE_LIMIT_DEFAULT",
+	"RATE_LIMIT_ROUTES",
+	"RATE_LIMIT_TRUSTED_HOPS",
+	"WEATHER_API_RATE_LIMIT",
+	"WEATHER_API_DAILY_SOFT_LIMIT",
+	"WEATHER_API_DAILY_HARD_LIMIT",
+	"WEATHER_API_MONTHLY_SOFT_LIMIT",
+	"WEATHER_API_MONTHLY_HARD_LIMIT",
+	"WEATHER_API_FALLBACK",
+	"WEATHER_API_KEY",
+	"WEATHER_API_KEYS",
+	"WEATHER_API_KEY_STRATEGY",
+}
+
+// parseWeatherAPIRate reads the outbound WeatherAPI rate, zero when it is not limited
+func parseWeatherAPIRate(cfg *config.Config) (ratelimit.Limit, error) {
+	if cfg.WeatherAPIRateLimit == "" {
+		return ratelimit.Limit{}, nil
+	}
+	return ratelimit.ParseLimit(cfg.WeatherAPIRateLimit)
+}
+
+// weatherAPILimits returns the plan limits of the WeatherAPI budget
+func weatherAPILimits(cfg *config.Config) quota.Limits {
+	return quota.Limits{
+		DailySoft:   cfg.WeatherAPIDailySoftLimit,
+		DailyHard:   cfg.WeatherAPIDailyHardLimit,
+		MonthlySoft: cfg.WeatherAPIMonthlySoftLimit,
+		MonthlyHard: cfg.WeatherAPIMonthlyHardLimit,
+	}
+}
+
+// parseRateLimitOptions reads the limits of the public routes
+func parseRateLimitOptions(cfg *config.Config) (middlewares.RateLimitOptions, error) {
+	defaultLimit, err := ratelimit.ParseLimit(cfg.RateLimitDefault)
+	if err != nil {
+		return middlewares.RateLimitOptions{}, fmt.Errorf("invalid RATE_LIMIT_DEFAULT: %w", err)
+	}
+	routeLimits, err := ratelimit.ParseRouteLimits(cfg.RateLimitRoutes)
+	if err != nil {
+		return middlewares.RateLimitOptions{}, fmt.Errorf("invalid RATE_LIMIT_ROUTES: %w", err)
+	}
+
+	return middlewares.RateLimitOptions{
+		Default:     defaultLimit,
+		Routes:      routeLimits,
+		TrustedHops: cfg.RateLimitTrustedHops,
+	}, nil
+}
+
+// checkConfig prints the redacted config and exits non-zero when a setting is invalid
+func checkConfig(cfg *config.Config) {
+	if err := cfg.Dump(os.Stdout); err != nil {
+		fatal("failed to print the config", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		fmt.Fprintf(os.Stderr, "invalid config:\n%v\n", err)
+		os.Exit(1)
+	}
+	fmt.Fprintln(os.Stderr, "config is valid")
+	os.Exit(0)
+}
+
+// fatal logs the error and exits
+func fatal(msg string, err error) {
+	slog.Error(msg, "err", err)
+	os.Exit(1)
 }
@@ -1,11 +1,19 @@
 package config
 
 import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/url"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/joho/godotenv"
+	"github.com/xavierpms/weather-by-city/internal/infra/auth"
+	"github.com/xavierpms/weather-by-city/internal/infra/ratelimit"
 )
 
 type Config struct {
@@ -13,31 +21,574 @@ type Config struct {
 	WeatherAPIKey string
 	WeatherAPIURL string
 	ViaCEPURL     string
+
+	WeatherForecastAPIURL string
+
+	AlertsEnabled           bool
+	AlertEvaluationInterval time.Duration
+	AlertWebhookSecret      string
+	AlertWebhookMaxAttempts int
+	AlertWebhookBackoff     time.Duration
+	AlertMaxRulesPerOwner   int
+	AlertMaxDeadLetters     int
+
+	GRPCMode string
+	GRPCPort string
+
+	BatchMaxSize     int
+	BatchConcurrency int
+
+	OpenAPIValidation string
+
+	ErrorCompatMode bool
+
+	CacheTemperatureTTL        time.Duration
+	CacheCEPTTL                time.Duration
+	CacheMaxEntries            int
+	CacheStaleMaxAge           time.Duration
+	CacheCEPNotFoundTTL        time.Duration
+	CacheCEPNotFoundMaxEntries int
+
+	LogLevel  string
+	LogFormat string
+
+	TracingExporter     string
+	TracingOTLPEndpoint string
+	TracingOTLPInsecure bool
+	TracingSampleRatio  float64
+
+	HealthCheckTTL     time.Duration
+	HealthCheckTimeout time.Duration
+
+	HTTPReadHeaderTimeout time.Duration
+	HTTPReadTimeout       time.Duration
+	HTTPWriteTimeout      time.Duration
+	HTTPIdleTimeout       time.Duration
+	ShutdownTimeout       time.Duration
+	ShutdownFlushTimeout  time.Duration
+
+	BreakerFailureThreshold int
+	BreakerOpenDuration     time.Duration
+	BreakerHalfOpenProbes   int
+	BreakerServeStale       bool
+
+	UpstreamRetryMaxAttempts int
+	UpstreamRetryBaseDelay   time.Duration
+	UpstreamRetryMaxDelay    time.Duration
+	UpstreamTimeout          time.Duration
+	UpstreamAttemptTimeout   time.Duration
+
+	RateLimitEnabled      bool
+	RateLimitDefault      string
+	RateLimitRoutes       string
+	RateLimitBackend      string
+	RateLimitRedisURL     string
+	RateLimitRedisTimeout time.Duration
+	RateLimitTrustedHops  int
+
+	APIKeysMode               string
+	APIKeysFile               string
+	APIKeysUsageFile          string
+	APIKeysDefaultDailyQuota  int
+	APIKeysUsageFlushInterval time.Duration
+	AdminToken                string
+
+	JWTHS256Secret         string
+	JWTJWKSFile            string
+	JWTJWKSURL             string
+	JWTJWKSRefreshInterval time.Duration
+	JWTIssuer              string
+	JWTAudience            string
+	JWTLeeway              time.Duration
+	JWTRolesClaim          string
+	JWTRoleScopes          string
+	JWTRouteScopes         string
+
+	WeatherAPIRateLimit          string
+	WeatherAPIDailySoftLimit     int
+	WeatherAPIDailyHardLimit     int
+	WeatherAPIMonthlySoftLimit   int
+	WeatherAPIMonthlyHardLimit   int
+	WeatherAPIUsageFile          string
+	WeatherAPIUsageFlushInterval time.Duration
+	WeatherAPIFallback           string
+	OpenMeteoGeocodingURL        string
+	OpenMeteoForecastURL         string
+
+	WeatherAPIKeys        string
+	WeatherAPIKeyStrategy string
+
+	SecretsDir string
+
+	// ConfigFile is the config file the settings were read from, empty when there is none
+	ConfigFile          string
+	ConfigWatchInterval time.Duration
+
+	// settings are the resolved settings, kept for the config dump
+	settings map[string]Setting
 }
 
 const (
 	defaultPort          = "8080"
-	// The correct place to storage the API key would be in a secret manager or environment variable, but for the sake of this exercise, we will use a default value.
-	defaultWeatherAPIKey  = "d37a29f8938b4c07adb173527262402"
 	defaultWeatherAPIURL = "https://api.weatherapi.com/v1/current.json"
 	defaultViaCEPURL     = "https://viacep.com.br/ws"
+
+	defaultWeatherForecastAPIURL = "https://api.weatherapi.com/v1/forecast.json"
+
+	defaultAlertEvaluationInterval = time.Minute
+	defaultAlertWebhookMaxAttempts = 5
+	defaultAlertWebhookBackoff     = 2 * time.Second
+	defaultAlertMaxRulesPerOwner   = 100
+	defaultAlertMaxDeadLetters     = 1000
+
+	// GRPCModeSeparate serves gRPC on GRPC_PORT, GRPCModeMultiplex serves it on PORT alongside HTTP
+	GRPCModeSeparate  = "separate"
+	GRPCModeMultiplex = "multiplex"
+	GRPCModeDisabled  = "disabled"
+	defaultGRPCPort   = "50051"
+
+	defaultBatchMaxSize     = 50
+	defaultBatchConcurrency = 8
+
+	// OpenAPIValidationRequests rejects requests that do not match the spec, OpenAPIValidationAll also checks responses
+	OpenAPIValidationOff      = "off"
+	OpenAPIValidationRequests = "requests"
+	OpenAPIValidationAll      = "all"
+
+	defaultCacheTemperatureTTL = 5 * time.Minute
+	defaultCacheCEPTTL         = 24 * time.Hour
+	defaultCacheMaxEntries     = 10000
+	defaultCacheStaleMaxAge    = time.Hour
+
+	defaultCacheCEPNotFoundTTL        = 10 * time.Minute
+	defaultCacheCEPNotFoundMaxEntries = 1000
+
+	defaultLogLevel  = "info"
+	defaultLogFormat = "json"
+
+	defaultTracingExporter    = "none"
+	defaultTracingSampleRatio = 1.0
+
+	defaultHealthCheckTTL     = 30 * time.Second
+	defaultHealthCheckTimeout = 3 * time.Second
+
+	defaultHTTPReadHeaderTimeout = 5 * time.Second
+	defaultHTTPReadTimeout       = 10 * time.Second
+	defaultHTTPWriteTimeout      = 30 * time.Second
+	defaultHTTPIdleTimeout       = 2 * time.Minute
+	// Cloud Run sends SIGKILL 10 seconds after SIGTERM, the drain and the flush must fit together
+	defaultShutdownTimeout      = 7 * time.Second
+	defaultShutdownFlushTimeout = 2 * time.Second
+
+	defaultBreakerFailureThreshold = 5
+	defaultBreakerOpenDuration     = 30 * time.Second
+	defaultBreakerHalfOpenProbes   = 1
+
+	defaultUpstreamRetryMaxAttempts = 3
+	defaultUpstreamRetryBaseDelay   = 100 * time.Millisecond
+	defaultUpstreamRetryMaxDelay    = 2 * time.Second
+	defaultUpstreamTimeout          = 10 * time.Second
+	defaultUpstreamAttemptTimeout   = 4 * time.Second
+
+	// RateLimitBackendMemory keeps the buckets per instance, RateLimitBackendRedis shares them between instances
+	RateLimitBackendMemory   = "memory"
+	RateLimitBackendRedis    = "redis"
+	defaultRateLimitDefault  = "60/1m"
+	defaultRateLimitRedisURL = "redis://localhost:6379/0"
+	// defaultRateLimitRedisTimeout bounds the delay an unreachable Redis adds to each request
+	defaultRateLimitRedisTimeout = 50 * time.Millisecond
+
+	// APIKeysModeOptional serves anonymous requests but validates the keys that are sent, APIKeysModeRequired rejects anonymous requests
+	APIKeysModeDisabled              = "disabled"
+	APIKeysModeOptional              = "optional"
+	APIKeysModeRequired              = "required"
+	defaultAPIKeysFile               = "data/api_keys.json"
+	defaultAPIKeysUsageFile          = "data/api_key_usage.json"
+	defaultAPIKeysDefaultDailyQuota  = 1000
+	defaultAPIKeysUsageFlushInterval = time.Minute
+
+	defaultJWTJWKSRefreshInterval = time.Hour
+	defaultJWTLeeway              = 30 * time.Second
+	defaultJWTRolesClaim          = "roles"
+	defaultJWTRouteScopes         = "/v1/temperatures/batch=batch:write,/weather.v1.WeatherService/BatchGetTemperature=batch:write"
+	// WeatherAPIFallbackOpenMeteo answers with Open-Meteo once the WeatherAPI hard limit is reached,
+	// WeatherAPIFallbackNone reports the exceeded quota
+	WeatherAPIFallbackNone              = "none"
+	WeatherAPIFallbackOpenMeteo         = "open-meteo"
+	defaultWeatherAPIUsageFile          = "data/weatherapi_usage.json"
+	defaultWeatherAPIUsageFlushInterval = time.Minute
+	defaultOpenMeteoGeocodingURL        = "https://geocoding-api.open-meteo.com/v1/search"
+	defaultOpenMeteoForecastURL         = "https://api.open-meteo.com/v1/forecast"
+
+	// WeatherAPIKeyStrategyRoundRobin spreads the calls over the WeatherAPI keys,
+	// WeatherAPIKeyStrategyPriority uses them in the configured order
+	WeatherAPIKeyStrategyRoundRobin = "round-robin"
+	WeatherAPIKeyStrategyPriority   = "priority"
+
+	defaultConfigWatchInterval = 5 * time.Second
+
+	// minJWTHS256SecretLength is the size of the SHA-256 output, the minimum key size for HS256 (RFC 7518)
+	minJWTHS256SecretLength = 32
 )
 
-// LoadConfig loads the environment variables and returns a Config struct
-func LoadConfig() (*Config, error) {
+// LoadConfig loads the settings from the environment, with the secrets looked up in NAME or
+// NAME_FILE, then in the SECRETS_DIR directory, then in the given providers
+func LoadConfig(providers ...SecretProvider) (*Config, error) {
+	return Load(Options{SecretProviders: providers})
+}
+
+// Load resolves each setting from the flags, the environment (including the .env file), the
+// config file and the defaults, in this order of precedence. Values that cannot be parsed, secrets
+// outside of the secret providers and unknown settings are reported together.
+func Load(opts Options) (*Config, error) {
 	loadDotEnv()
 
-	weatherAPIKey := strings.TrimSpace(getEnv("WEATHER_API_KEY", ""))
-	if weatherAPIKey == "" {
-		weatherAPIKey = defaultWeatherAPIKey
+	fileSource := SourceFlag
+	if opts.File == "" {
+		opts.File, fileSource = strings.TrimSpace(os.Getenv("CONFIG_FILE")), SourceEnv
+	}
+	s, err := newSettingReader(opts.File, opts.Flags)
+	if err != nil {
+		return nil, err
+	}
+	if opts.File != "" {
+		s.settings["CONFIG_FILE"] = Setting{Name: "CONFIG_FILE", Value: opts.File, Source: fileSource}
+	}
+
+	secretsDir := s.get("SECRETS_DIR", "")
+	s.secrets = &secretReader{providers: []SecretProvider{EnvSecretProvider{}}}
+	if secretsDir != "" {
+		s.secrets.providers = append(s.secrets.providers, DirSecretProvider{Dir: secretsDir})
+	}
+	s.secrets.providers = append(s.secrets.providers, opts.SecretProviders...)
+
+	weatherAPIKey := s.getSecret("WEATHER_API_KEY", "")
+	weatherAPIKeys := s.getSecret("WEATHER_API_KEYS", "")
+	// A key that failed to load is already reported
+	if weatherAPIKey == "" && weatherAPIKeys == "" && len(s.secrets.errs) == 0 {
+		s.errs = append(s.errs, errors.New("WEATHER_API_KEY or WEATHER_API_KEYS is required, set it directly, with a _FILE variable or in SECRETS_DIR"))
 	}
 
-	return &Config{
-		Port:          getEnv("PORT", defaultPort),
+	cfg := &Config{
+		Port:          s.get("PORT", defaultPort),
 		WeatherAPIKey: weatherAPIKey,
-		WeatherAPIURL: getEnv("WEATHER_API_URL", defaultWeatherAPIURL),
-		ViaCEPURL:     getEnv("VIA_CEP_URL", defaultViaCEPURL),
-	}, nil
+		WeatherAPIURL: s.get("WEATHER_API_URL", defaultWeatherAPIURL),
+		ViaCEPURL:     s.get("VIA_CEP_URL", defaultViaCEPURL),
+
+		WeatherForecastAPIURL: s.get("WEATHER_FORECAST_API_URL", defaultWeatherForecastAPIURL),
+
+		AlertsEnabled:           s.getBool("ALERTS_ENABLED", false),
+		AlertEvaluationInterval: s.getDuration("ALERT_EVALUATION_INTERVAL", defaultAlertEvaluationInterval),
+		AlertWebhookSecret:      s.getSecret("ALERT_WEBHOOK_SECRET", ""),
+		AlertWebhookMaxAttempts: s.getInt("ALERT_WEBHOOK_MAX_ATTEMPTS", defaultAlertWebhookMaxAttempts),
+		AlertWebhookBackoff:     s.getOptionalDuration("ALERT_WEBHOOK_BACKOFF", defaultAlertWebhookBackoff),
+		AlertMaxRulesPerOwner:   s.getInt("ALERT_MAX_RULES_PER_OWNER", defaultAlertMaxRulesPerOwner),
+		AlertMaxDeadLetters:     s.getInt("ALERT_MAX_DEAD_LETTERS", defaultAlertMaxDeadLetters),
+
+		GRPCMode: strings.ToLower(s.get("GRPC_MODE", GRPCModeSeparate)),
+		GRPCPort: s.get("GRPC_PORT", defaultGRPCPort),
+
+		BatchMaxSize:     s.getInt("BATCH_MAX_SIZE", defaultBatchMaxSize),
+		BatchConcurrency: s.getInt("BATCH_CONCURRENCY", defaultBatchConcurrency),
+
+		OpenAPIValidation: strings.ToLower(s.get("OPENAPI_VALIDATION", OpenAPIValidationOff)),
+
+		ErrorCompatMode: s.getBool("ERROR_COMPAT_MODE", true),
+
+		CacheTemperatureTTL: s.getDuration("CACHE_TEMPERATURE_TTL", defaultCacheTemperatureTTL),
+		CacheCEPTTL:         s.getDuration("CACHE_CEP_TTL", defaultCacheCEPTTL),
+		CacheMaxEntries:     s.getInt("CACHE_MAX_ENTRIES", defaultCacheMaxEntries),
+		CacheStaleMaxAge:    s.getOptionalDuration("CACHE_STALE_MAX_AGE", defaultCacheStaleMaxAge),
+
+		CacheCEPNotFoundTTL:        s.getDuration("CACHE_CEP_NOT_FOUND_TTL", defaultCacheCEPNotFoundTTL),
+		CacheCEPNotFoundMaxEntries: s.getInt("CACHE_CEP_NOT_FOUND_MAX_ENTRIES", defaultCacheCEPNotFoundMaxEntries),
+
+		LogLevel:  strings.ToLower(s.get("LOG_LEVEL", defaultLogLevel)),
+		LogFormat: strings.ToLower(s.get("LOG_FORMAT", defaultLogFormat)),
+
+		TracingExporter:     strings.ToLower(s.get("TRACING_EXPORTER", defaultTracingExporter)),
+		TracingOTLPEndpoint: s.get("TRACING_OTLP_ENDPOINT", ""),
+		TracingOTLPInsecure: s.getBool("TRACING_OTLP_INSECURE", false),
+		TracingSampleRatio:  s.getFloat("TRACING_SAMPLE_RATIO", defaultTracingSampleRatio),
+
+		HealthCheckTTL:     s.getDuration("HEALTH_CHECK_TTL", defaultHealthCheckTTL),
+		HealthCheckTimeout: s.getDuration("HEALTH_CHECK_TIMEOUT", defaultHealthCheckTimeout),
+
+		HTTPReadHeaderTimeout: s.getDuration("HTTP_READ_HEADER_TIMEOUT", defaultHTTPReadHeaderTimeout),
+		HTTPReadTimeout:       s.getDuration("HTTP_READ_TIMEOUT", defaultHTTPReadTimeout),
+		HTTPWriteTimeout:      s.getDuration("HTTP_WRITE_TIMEOUT", defaultHTTPWriteTimeout),
+		HTTPIdleTimeout:       s.getDuration("HTTP_IDLE_TIMEOUT", defaultHTTPIdleTimeout),
+		ShutdownTimeout:       s.getDuration("SHUTDOWN_TIMEOUT", defaultShutdownTimeout),
+		ShutdownFlushTimeout:  s.getDuration("SHUTDOWN_FLUSH_TIMEOUT", defaultShutdownFlushTimeout),
+
+		BreakerFailureThreshold: s.getInt("BREAKER_FAILURE_THRESHOLD", defaultBreakerFailureThreshold),
+		BreakerOpenDuration:     s.getDuration("BREAKER_OPEN_DURATION", defaultBreakerOpenDuration),
+		BreakerHalfOpenProbes:   s.getInt("BREAKER_HALF_OPEN_PROBES", defaultBreakerHalfOpenProbes),
+		BreakerServeStale:       s.getBool("BREAKER_SERVE_STALE", true),
+
+		UpstreamRetryMaxAttempts: s.getInt("UPSTREAM_RETRY_MAX_ATTEMPTS", defaultUpstreamRetryMaxAttempts),
+		UpstreamRetryBaseDelay:   s.getOptionalDuration("UPSTREAM_RETRY_BASE_DELAY", defaultUpstreamRetryBaseDelay),
+		UpstreamRetryMaxDelay:    s.getDuration("UPSTREAM_RETRY_MAX_DELAY", defaultUpstreamRetryMaxDelay),
+		UpstreamTimeout:          s.getDuration("UPSTREAM_TIMEOUT", defaultUpstreamTimeout),
+		UpstreamAttemptTimeout:   s.getDuration("UPSTREAM_ATTEMPT_TIMEOUT", defaultUpstreamAttemptTimeout),
+
+		RateLimitEnabled:      s.getBool("RATE_LIMIT_ENABLED", true),
+		RateLimitDefault:      s.get("RATE_LIMIT_DEFAULT", defaultRateLimitDefault),
+		RateLimitRoutes:       s.get("RATE_LIMIT_ROUTES", ""),
+		RateLimitBackend:      strings.ToLower(s.get("RATE_LIMIT_BACKEND", RateLimitBackendMemory)),
+		RateLimitRedisURL:     s.getSecret("RATE_LIMIT_REDIS_URL", defaultRateLimitRedisURL),
+		RateLimitRedisTimeout: s.getDuration("RATE_LIMIT_REDIS_TIMEOUT", defaultRateLimitRedisTimeout),
+		RateLimitTrustedHops:  s.getInt("RATE_LIMIT_TRUSTED_HOPS", 0),
+
+		APIKeysMode:               strings.ToLower(s.get("API_KEYS_MODE", APIKeysModeDisabled)),
+		APIKeysFile:               s.get("API_KEYS_FILE", defaultAPIKeysFile),
+		APIKeysUsageFile:          s.get("API_KEYS_USAGE_FILE", defaultAPIKeysUsageFile),
+		APIKeysDefaultDailyQuota:  s.getInt("API_KEYS_DEFAULT_DAILY_QUOTA", defaultAPIKeysDefaultDailyQuota),
+		APIKeysUsageFlushInterval: s.getDuration("API_KEYS_USAGE_FLUSH_INTERVAL", defaultAPIKeysUsageFlushInterval),
+		AdminToken:                s.getSecret("ADMIN_TOKEN", ""),
+
+		JWTHS256Secret:         s.getSecret("JWT_HS256_SECRET", ""),
+		JWTJWKSFile:            s.get("JWT_JWKS_FILE", ""),
+		JWTJWKSURL:             s.get("JWT_JWKS_URL", ""),
+		JWTJWKSRefreshInterval: s.getDuration("JWT_JWKS_REFRESH_INTERVAL", defaultJWTJWKSRefreshInterval),
+		JWTIssuer:              s.get("JWT_ISSUER", ""),
+		JWTAudience:            s.get("JWT_AUDIENCE", ""),
+		JWTLeeway:              s.getOptionalDuration("JWT_LEEWAY", defaultJWTLeeway),
+		JWTRolesClaim:          s.get("JWT_ROLES_CLAIM", defaultJWTRolesClaim),
+		JWTRoleScopes:          s.get("JWT_ROLE_SCOPES", ""),
+		JWTRouteScopes:         s.get("JWT_ROUTE_SCOPES", defaultJWTRouteScopes),
+
+		WeatherAPIRateLimit:          s.get("WEATHER_API_RATE_LIMIT", ""),
+		WeatherAPIDailySoftLimit:     s.getInt("WEATHER_API_DAILY_SOFT_LIMIT", 0),
+		WeatherAPIDailyHardLimit:     s.getInt("WEATHER_API_DAILY_HARD_LIMIT", 0),
+		WeatherAPIMonthlySoftLimit:   s.getInt("WEATHER_API_MONTHLY_SOFT_LIMIT", 0),
+		WeatherAPIMonthlyHardLimit:   s.getInt("WEATHER_API_MONTHLY_HARD_LIMIT", 0),
+		WeatherAPIUsageFile:          s.get("WEATHER_API_USAGE_FILE", defaultWeatherAPIUsageFile),
+		WeatherAPIUsageFlushInterval: s.getDuration("WEATHER_API_USAGE_FLUSH_INTERVAL", defaultWeatherAPIUsageFlushInterval),
+		WeatherAPIFallback:           strings.ToLower(s.get("WEATHER_API_FALLBACK", WeatherAPIFallbackNone)),
+		OpenMeteoGeocodingURL:        s.get("OPEN_METEO_GEOCODING_URL", defaultOpenMeteoGeocodingURL),
+		OpenMeteoForecastURL:         s.get("OPEN_METEO_FORECAST_URL", defaultOpenMeteoForecastURL),
+
+		WeatherAPIKeys:        weatherAPIKeys,
+		WeatherAPIKeyStrategy: strings.ToLower(s.get("WEATHER_API_KEY_STRATEGY", WeatherAPIKeyStrategyRoundRobin)),
+
+		SecretsDir: secretsDir,
+
+		ConfigFile:          opts.File,
+		ConfigWatchInterval: s.getDuration("CONFIG_WATCH_INTERVAL", defaultConfigWatchInterval),
+	}
+	cfg.settings = s.settings
+
+	s.errs = append(s.errs, s.secrets.errs...)
+	s.errs = append(s.errs, s.unknown()...)
+	if err := errors.Join(s.errs...); err != nil {
+		return nil, err
+	}
+
+	return cfg, nil
+}
+
+// WeatherAPIKeyPool returns the WeatherAPI keys, from WEATHER_API_KEYS or else WEATHER_API_KEY
+func (c *Config) WeatherAPIKeyPool() []string {
+	var keys []string
+	for _, key := range strings.Split(c.WeatherAPIKeys, ",") {
+		if key = strings.TrimSpace(key); key != "" {
+			keys = append(keys, key)
+		}
+	}
+	if len(keys) == 0 && c.WeatherAPIKey != "" {
+		keys = append(keys, c.WeatherAPIKey)
+	}
+	return keys
+}
+
+// JWTEnabled reports whether JWTs are verified, which needs an HS256 secret or a JWKS
+func (c *Config) JWTEnabled() bool {
+	return c.JWTHS256Secret != "" || c.JWTJWKSFile != "" || c.JWTJWKSURL != ""
+}
+
+// Validate reports every setting that prevents the service from working
+func (c *Config) Validate() error {
+	var errs []error
+
+	if c.Port == "" {
+		errs = append(errs, errors.New("PORT is empty"))
+	}
+	keys := c.WeatherAPIKeyPool()
+	if len(keys) == 0 {
+		errs = append(errs, errors.New("WEATHER_API_KEY is empty"))
+	}
+	seenKeys := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if seenKeys[key] {
+			errs = append(errs, errors.New("WEATHER_API_KEYS has duplicate keys"))
+			break
+		}
+		seenKeys[key] = true
+	}
+	switch c.WeatherAPIKeyStrategy {
+	case WeatherAPIKeyStrategyRoundRobin, WeatherAPIKeyStrategyPriority:
+	default:
+		errs = append(errs, fmt.Errorf("WEATHER_API_KEY_STRATEGY is invalid: %q", c.WeatherAPIKeyStrategy))
+	}
+	for _, setting := range []struct{ name, value string }{
+		{"WEATHER_API_URL", c.WeatherAPIURL},
+		{"WEATHER_FORECAST_API_URL", c.WeatherForecastAPIURL},
+		{"VIA_CEP_URL", c.ViaCEPURL},
+	} {
+		if u, err := url.Parse(setting.value); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("%s is not an absolute URL: %q", setting.name, setting.value))
+		}
+	}
+	switch c.GRPCMode {
+	case GRPCModeSeparate, GRPCModeMultiplex, GRPCModeDisabled:
+	default:
+		errs = append(errs, fmt.Errorf("GRPC_MODE is invalid: %q", c.GRPCMode))
+	}
+	switch c.OpenAPIValidation {
+	case OpenAPIValidationOff, OpenAPIValidationRequests, OpenAPIValidationAll:
+	default:
+		errs = append(errs, fmt.Errorf("OPENAPI_VALIDATION is invalid: %q", c.OpenAPIValidation))
+	}
+	for _, setting := range []struct{ name, value string }{
+		{"PORT", c.Port},
+		{"GRPC_PORT", c.GRPCPort},
+	} {
+		if port, err := strconv.Atoi(setting.value); err != nil || port < 1 || port > 65535 {
+			errs = append(errs, fmt.Errorf("%s must be a port between 1 and 65535: %q", setting.name, setting.value))
+		}
+	}
+	var logLevel slog.Level
+	if err := logLevel.UnmarshalText([]byte(c.LogLevel)); err != nil {
+		errs = append(errs, fmt.Errorf("LOG_LEVEL is invalid: %q", c.LogLevel))
+	}
+	switch c.LogFormat {
+	case "json", "text":
+	default:
+		errs = append(errs, fmt.Errorf("LOG_FORMAT is invalid: %q", c.LogFormat))
+	}
+	switch c.TracingExporter {
+	case "none", "stdout", "otlp":
+	default:
+		errs = append(errs, fmt.Errorf("TRACING_EXPORTER is invalid: %q", c.TracingExporter))
+	}
+	for _, setting := range []struct {
+		name       string
+		value, min int
+	}{
+		{"ALERT_WEBHOOK_MAX_ATTEMPTS", c.AlertWebhookMaxAttempts, 1},
+		{"ALERT_MAX_RULES_PER_OWNER", c.AlertMaxRulesPerOwner, 1},
+		{"ALERT_MAX_DEAD_LETTERS", c.AlertMaxDeadLetters, 1},
+		{"BATCH_MAX_SIZE", c.BatchMaxSize, 1},
+		{"BATCH_CONCURRENCY", c.BatchConcurrency, 1},
+		{"CACHE_MAX_ENTRIES", c.CacheMaxEntries, 0},
+		{"CACHE_CEP_NOT_FOUND_MAX_ENTRIES", c.CacheCEPNotFoundMaxEntries, 1},
+		{"RATE_LIMIT_TRUSTED_HOPS", c.RateLimitTrustedHops, 0},
+	} {
+		if setting.value < setting.min {
+			errs = append(errs, fmt.Errorf("%s must be at least %d: %d", setting.name, setting.min, setting.value))
+		}
+	}
+	if c.AlertsEnabled && c.AlertWebhookSecret == "" {
+		errs = append(errs, errors.New("ALERT_WEBHOOK_SECRET is required when ALERTS_ENABLED is true, the webhooks would be signed with an empty key"))
+	}
+	if c.UpstreamRetryBaseDelay > c.UpstreamRetryMaxDelay {
+		errs = append(errs, fmt.Errorf("UPSTREAM_RETRY_BASE_DELAY must not exceed UPSTREAM_RETRY_MAX_DELAY: %s > %s",
+			c.UpstreamRetryBaseDelay, c.UpstreamRetryMaxDelay))
+	}
+	if c.UpstreamAttemptTimeout > c.UpstreamTimeout {
+		errs = append(errs, fmt.Errorf("UPSTREAM_ATTEMPT_TIMEOUT must not exceed UPSTREAM_TIMEOUT: %s > %s",
+			c.UpstreamAttemptTimeout, c.UpstreamTimeout))
+	}
+	if c.HealthCheckTimeout > c.HealthCheckTTL {
+		errs = append(errs, fmt.Errorf("HEALTH_CHECK_TIMEOUT must not exceed HEALTH_CHECK_TTL: %s > %s",
+			c.HealthCheckTimeout, c.HealthCheckTTL))
+	}
+	if c.TracingSampleRatio < 0 || c.TracingSampleRatio > 1 {
+		errs = append(errs, fmt.Errorf("TRACING_SAMPLE_RATIO must be between 0 and 1: %v", c.TracingSampleRatio))
+	}
+	if c.BreakerFailureThreshold < 1 {
+		errs = append(errs, fmt.Errorf("BREAKER_FAILURE_THRESHOLD must be at least 1: %d", c.BreakerFailureThreshold))
+	}
+	if c.BreakerHalfOpenProbes < 1 {
+		errs = append(errs, fmt.Errorf("BREAKER_HALF_OPEN_PROBES must be at least 1: %d", c.BreakerHalfOpenProbes))
+	}
+	if c.UpstreamRetryMaxAttempts < 1 {
+		errs = append(errs, fmt.Errorf("UPSTREAM_RETRY_MAX_ATTEMPTS must be at least 1: %d", c.UpstreamRetryMaxAttempts))
+	}
+	switch c.RateLimitBackend {
+	case RateLimitBackendMemory, RateLimitBackendRedis:
+	default:
+		errs = append(errs, fmt.Errorf("RATE_LIMIT_BACKEND is invalid: %q", c.RateLimitBackend))
+	}
+	if _, err := ratelimit.ParseLimit(c.RateLimitDefault); err != nil {
+		errs = append(errs, fmt.Errorf("RATE_LIMIT_DEFAULT is invalid: %w", err))
+	}
+	if _, err := ratelimit.ParseRouteLimits(c.RateLimitRoutes); err != nil {
+		errs = append(errs, fmt.Errorf("RATE_LIMIT_ROUTES is invalid: %w", err))
+	}
+	if c.WeatherAPIRateLimit != "" {
+		if _, err := ratelimit.ParseLimit(c.WeatherAPIRateLimit); err != nil {
+			errs = append(errs, fmt.Errorf("WEATHER_API_RATE_LIMIT is invalid: %w", err))
+		}
+	}
+	for _, setting := range []struct{ name, value string }{
+		{"JWT_ROUTE_SCOPES", c.JWTRouteScopes},
+		{"JWT_ROLE_SCOPES", c.JWTRoleScopes},
+	} {
+		if _, err := auth.ParseScopeMap(setting.value); err != nil {
+			errs = append(errs, fmt.Errorf("%s is invalid: %w", setting.name, err))
+		}
+	}
+	switch c.APIKeysMode {
+	case APIKeysModeDisabled, APIKeysModeOptional, APIKeysModeRequired:
+	default:
+		errs = append(errs, fmt.Errorf("API_KEYS_MODE is invalid: %q", c.APIKeysMode))
+	}
+	if c.APIKeysDefaultDailyQuota < 0 {
+		errs = append(errs, fmt.Errorf("API_KEYS_DEFAULT_DAILY_QUOTA must not be negative: %d", c.APIKeysDefaultDailyQuota))
+	}
+	if c.JWTHS256Secret != "" && len(c.JWTHS256Secret) < minJWTHS256SecretLength {
+		errs = append(errs, fmt.Errorf("JWT_HS256_SECRET must have at least %d bytes", minJWTHS256SecretLength))
+	}
+	if c.JWTJWKSFile != "" && c.JWTJWKSURL != "" {
+		errs = append(errs, errors.New("JWT_JWKS_FILE and JWT_JWKS_URL are mutually exclusive"))
+	}
+	if c.JWTJWKSURL != "" {
+		if u, err := url.Parse(c.JWTJWKSURL); err != nil || u.Scheme == "" || u.Host == "" {
+			errs = append(errs, fmt.Errorf("JWT_JWKS_URL is not an absolute URL: %q", c.JWTJWKSURL))
+		}
+	}
+	for _, window := range []struct {
+		name       string
+		soft, hard int
+	}{
+		{"DAILY", c.WeatherAPIDailySoftLimit, c.WeatherAPIDailyHardLimit},
+		{"MONTHLY", c.WeatherAPIMonthlySoftLimit, c.WeatherAPIMonthlyHardLimit},
+	} {
+		if window.soft < 0 || window.hard < 0 {
+			errs = append(errs, fmt.Errorf("WEATHER_API_%s limits must not be negative", window.name))
+		} else if window.soft > 0 && window.hard > 0 && window.soft > window.hard {
+			errs = append(errs, fmt.Errorf("WEATHER_API_%s_SOFT_LIMIT must not exceed WEATHER_API_%[1]s_HARD_LIMIT: %d > %d",
+				window.name, window.soft, window.hard))
+		}
+	}
+	switch c.WeatherAPIFallback {
+	case WeatherAPIFallbackNone:
+	case WeatherAPIFallbackOpenMeteo:
+		for _, setting := range []struct{ name, value string }{
+			{"OPEN_METEO_GEOCODING_URL", c.OpenMeteoGeocodingURL},
+			{"OPEN_METEO_FORECAST_URL", c.OpenMeteoForecastURL},
+		} {
+			if u, err := url.Parse(setting.value); err != nil || u.Scheme == "" || u.Host == "" {
+				errs = append(errs, fmt.Errorf("%s is not an absolute URL: %q", setting.name, setting.value))
+			}
+		}
+	default:
+		errs = append(errs, fmt.Errorf("WEATHER_API_FALLBACK is invalid: %q", c.WeatherAPIFallback))
+	}
+
+	return errors.Join(errs...)
 }
 
 func loadDotEnv() {
@@ -61,15 +612,3 @@ func loadDotEnv() {
 		wd = parent
 	}
 }
-
-func getEnv(key, defaultVal string) string {
-	if value, exists := os.LookupEnv(key); exists {
-		trimmedValue := strings.TrimSpace(value)
-		if trimmedValue != "" {
-			return trimmedValue
-		}
-
-		return defaultVal
-	}
-	return defaultVal
-}
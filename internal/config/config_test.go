@@ -3,9 +3,18 @@ package config
 import (
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
+// TestMain gives the tests a WeatherAPI key, which Load requires, unless the environment has one
+func TestMain(m *testing.M) {
+	if os.Getenv("WEATHER_API_KEY") == "" && os.Getenv("WEATHER_API_KEYS") == "" {
+		_ = os.Setenv("WEATHER_API_KEY", "test_key")
+	}
+	os.Exit(m.Run())
+}
+
 func TestLoadDotEnvFromParentDirectory(t *testing.T) {
 	originalWeatherAPIKey, hadWeatherAPIKey := os.LookupEnv("WEATHER_API_KEY")
 	originalPort, hadPort := os.LookupEnv("PORT")
@@ -66,29 +75,55 @@ func TestLoadDotEnvFromParentDirectory(t *testing.T) {
 	}
 }
 
-func TestLoadConfigUsesDefaultWeatherAPIKeyWhenMissingOrEmpty(t *testing.T) {
-	t.Setenv("WEATHER_API_KEY", "")
+func TestLoadConfigRequiresWeatherAPIKey(t *testing.T) {
+	clearWeatherAPIKeys(t)
+
+	_, err := LoadConfig()
+	if err == nil || !strings.Contains(err.Error(), "WEATHER_API_KEY or WEATHER_API_KEYS is required") {
+		t.Fatalf("expected missing key error, got %v", err)
+	}
+}
+
+func TestLoadConfigPrefersWeatherAPIKeyEnv(t *testing.T) {
+	t.Setenv("WEATHER_API_KEY", "env_secret_key")
 
 	cfg, err := LoadConfig()
 	if err != nil {
 		t.Fatalf("LoadConfig returned an error: %v", err)
 	}
 
-	if cfg.WeatherAPIKey != defaultWeatherAPIKey {
-		t.Fatalf("expected default WeatherAPIKey %q, got %q", defaultWeatherAPIKey, cfg.WeatherAPIKey)
+	if cfg.WeatherAPIKey != "env_secret_key" {
+		t.Fatalf("expected WeatherAPIKey loaded from env var, got %q", cfg.WeatherAPIKey)
 	}
 }
 
-func TestLoadConfigPrefersWeatherAPIKeyEnv(t *testing.T) {
-	t.Setenv("WEATHER_API_KEY", "env_secret_key")
+func TestWeatherAPIKeyPoolPrefersKeyList(t *testing.T) {
+	t.Setenv("WEATHER_API_KEY", "single_key")
+	t.Setenv("WEATHER_API_KEYS", " first_key, ,second_key ")
 
 	cfg, err := LoadConfig()
 	if err != nil {
 		t.Fatalf("LoadConfig returned an error: %v", err)
 	}
 
-	if cfg.WeatherAPIKey != "env_secret_key" {
-		t.Fatalf("expected WeatherAPIKey loaded from env var, got %q", cfg.WeatherAPIKey)
+	keys := cfg.WeatherAPIKeyPool()
+	if len(keys) != 2 || keys[0] != "first_key" || keys[1] != "second_key" {
+		t.Fatalf("expected the keys of WEATHER_API_KEYS in order, got %q", keys)
+	}
+}
+
+func TestValidateReportsDuplicateWeatherAPIKeys(t *testing.T) {
+	t.Setenv("WEATHER_API_KEYS", "first_key,second_key,first_key")
+	t.Setenv("WEATHER_API_KEY_STRATEGY", "random")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned an error: %v", err)
+	}
+
+	expected := "WEATHER_API_KEYS has duplicate keys\nWEATHER_API_KEY_STRATEGY is invalid: \"random\""
+	if err := cfg.Validate(); err == nil || err.Error() != expected {
+		t.Fatalf("expected %q, got %v", expected, err)
 	}
 }
 
@@ -114,3 +149,106 @@ func TestLoadConfigUsesDefaultsWhenVarsAreMissingOrEmpty(t *testing.T) {
 		t.Fatalf("expected default ViaCEP URL %q, got %q", defaultViaCEPURL, cfg.ViaCEPURL)
 	}
 }
+
+func TestValidateAcceptsDefaults(t *testing.T) {
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned an error: %v", err)
+	}
+
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected default config to be valid, got %v", err)
+	}
+}
+
+func TestValidateReportsEveryInvalidSetting(t *testing.T) {
+	t.Setenv("VIA_CEP_URL", "viacep.com.br")
+	t.Setenv("GRPC_MODE", "both")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned an error: %v", err)
+	}
+
+	err = cfg.Validate()
+	if err == nil {
+		t.Fatalf("expected validation error")
+	}
+
+	expected := "VIA_CEP_URL is not an absolute URL: \"viacep.com.br\"\nGRPC_MODE is invalid: \"both\""
+	if err.Error() != expected {
+		t.Fatalf("expected %q, got %q", expected, err.Error())
+	}
+}
+
+func TestLoadConfigReadsTheNotFoundCEPCache(t *testing.T) {
+	t.Setenv("CACHE_CEP_NOT_FOUND_TTL", "")
+	t.Setenv("CACHE_CEP_NOT_FOUND_MAX_ENTRIES", "0")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned an error: %v", err)
+	}
+
+	if cfg.CacheCEPNotFoundTTL != defaultCacheCEPNotFoundTTL {
+		t.Fatalf("expected default not found TTL %v, got %v", defaultCacheCEPNotFoundTTL, cfg.CacheCEPNotFoundTTL)
+	}
+
+	expected := "CACHE_CEP_NOT_FOUND_MAX_ENTRIES must be at least 1: 0"
+	if err := cfg.Validate(); err == nil || err.Error() != expected {
+		t.Fatalf("expected %q, got %v", expected, err)
+	}
+}
+
+func TestValidateReportsUnparsableLimitsAndScopes(t *testing.T) {
+	testCases := []struct {
+		name     string
+		value    string
+		expected string
+	}{
+		{"RATE_LIMIT_DEFAULT", "garbage", `RATE_LIMIT_DEFAULT is invalid: rate limit "garbage" must be written as requests/period`},
+		{"RATE_LIMIT_ROUTES", "/{cep}", `RATE_LIMIT_ROUTES is invalid: route rate limit "/{cep}" must be written as route=requests/period`},
+		{"WEATHER_API_RATE_LIMIT", "nope", `WEATHER_API_RATE_LIMIT is invalid: rate limit "nope" must be written as requests/period`},
+		{"JWT_ROUTE_SCOPES", "???", `JWT_ROUTE_SCOPES is invalid: scope mapping "???" must be written as name=scope`},
+		{"JWT_ROLE_SCOPES", "operator=", `JWT_ROLE_SCOPES is invalid: scope mapping "operator=" must be written as name=scope`},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			t.Setenv(tc.name, tc.value)
+
+			cfg, err := LoadConfig()
+			if err != nil {
+				t.Fatalf("LoadConfig returned an error: %v", err)
+			}
+
+			if err := cfg.Validate(); err == nil || err.Error() != tc.expected {
+				t.Fatalf("expected %q, got %v", tc.expected, err)
+			}
+		})
+	}
+}
+
+func TestValidateRequiresTheWebhookSecretWhenAlertsAreEnabled(t *testing.T) {
+	t.Setenv("ALERTS_ENABLED", "true")
+	t.Setenv("ALERT_WEBHOOK_SECRET", "")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned an error: %v", err)
+	}
+
+	expected := "ALERT_WEBHOOK_SECRET is required when ALERTS_ENABLED is true, the webhooks would be signed with an empty key"
+	if err := cfg.Validate(); err == nil || err.Error() != expected {
+		t.Fatalf("expected %q, got %v", expected, err)
+	}
+
+	t.Setenv("ALERT_WEBHOOK_SECRET", "webhook-secret")
+	cfg, err = LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned an error: %v", err)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("expected config with a webhook secret to be valid, got %v", err)
+	}
+}
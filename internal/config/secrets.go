@@ -0,0 +1,92 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// SecretProvider looks up a secret by the name of its setting, such as WEATHER_API_KEY.
+// It reports false when it does not hold the secret.
+type SecretProvider interface {
+	LookupSecret(name string) (string, bool, error)
+}
+
+// EnvSecretProvider reads the secret from the NAME variable or from the file named by NAME_FILE,
+// as mounted by Docker and Kubernetes secrets
+type EnvSecretProvider struct{}
+
+// LookupSecret reads NAME or NAME_FILE, which are mutually exclusive
+func (EnvSecretProvider) LookupSecret(name string) (string, bool, error) {
+	value := strings.TrimSpace(os.Getenv(name))
+	path := strings.TrimSpace(os.Getenv(name + "_FILE"))
+	switch {
+	case value != "" && path != "":
+		return "", false, fmt.Errorf("%s and %[1]s_FILE are mutually exclusive", name)
+	case path != "":
+		secret, err := readSecretFile(path)
+		if err != nil {
+			return "", false, fmt.Errorf("failed to read %s_FILE: %w", name, err)
+		}
+		return secret, secret != "", nil
+	default:
+		return value, value != "", nil
+	}
+}
+
+// DirSecretProvider reads the secrets from a mounted directory, with one file per secret named
+// after the setting in upper or lower case (WEATHER_API_KEY or weather_api_key)
+type DirSecretProvider struct {
+	Dir string
+}
+
+// LookupSecret reads the file of the secret, if the directory has one
+func (p DirSecretProvider) LookupSecret(name string) (string, bool, error) {
+	for _, file := range []string{name, strings.ToLower(name)} {
+		secret, err := readSecretFile(filepath.Join(p.Dir, file))
+		if errors.Is(err, fs.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return "", false, fmt.Errorf("failed to read the %s secret: %w", name, err)
+		}
+		return secret, secret != "", nil
+	}
+
+	return "", false, nil
+}
+
+// readSecretFile reads a secret, dropping the trailing newline editors and tools add
+func readSecretFile(path string) (string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+
+	return strings.TrimSpace(string(data)), nil
+}
+
+// secretReader looks up each secret in the providers, in order, and collects the failures
+type secretReader struct {
+	providers []SecretProvider
+	errs      []error
+}
+
+// get returns the first value found for the secret
+func (s *secretReader) get(name string) (string, bool) {
+	for _, provider := range s.providers {
+		value, ok, err := provider.LookupSecret(name)
+		if err != nil {
+			s.errs = append(s.errs, err)
+			return "", false
+		}
+		if ok {
+			return value, true
+		}
+	}
+
+	return "", false
+}
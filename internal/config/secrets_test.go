@@ -0,0 +1,120 @@
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+type staticSecretProvider map[string]string
+
+func (p staticSecretProvider) LookupSecret(name string) (string, bool, error) {
+	value, ok := p[name]
+	return value, ok, nil
+}
+
+// clearWeatherAPIKeys unsets the key settings the environment may carry
+func clearWeatherAPIKeys(t *testing.T) {
+	for _, name := range []string{"WEATHER_API_KEY", "WEATHER_API_KEY_FILE", "WEATHER_API_KEYS", "WEATHER_API_KEYS_FILE", "SECRETS_DIR"} {
+		t.Setenv(name, "")
+	}
+}
+
+func writeSecret(t *testing.T, dir, name, value string) string {
+	path := filepath.Join(dir, name)
+	if err := os.WriteFile(path, []byte(value), 0o600); err != nil {
+		t.Fatalf("failed to write secret file: %v", err)
+	}
+	return path
+}
+
+func TestLoadConfigReadsSecretFromFileVar(t *testing.T) {
+	clearWeatherAPIKeys(t)
+	t.Setenv("WEATHER_API_KEY_FILE", writeSecret(t, t.TempDir(), "weather_api_key", "file_secret_key\n"))
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned an error: %v", err)
+	}
+
+	if cfg.WeatherAPIKey != "file_secret_key" {
+		t.Fatalf("expected WeatherAPIKey loaded from WEATHER_API_KEY_FILE, got %q", cfg.WeatherAPIKey)
+	}
+}
+
+func TestLoadConfigRejectsSecretVarAndFileVar(t *testing.T) {
+	clearWeatherAPIKeys(t)
+	t.Setenv("WEATHER_API_KEY", "env_secret_key")
+	t.Setenv("WEATHER_API_KEY_FILE", writeSecret(t, t.TempDir(), "weather_api_key", "file_secret_key"))
+
+	_, err := LoadConfig()
+	if err == nil || err.Error() != "WEATHER_API_KEY and WEATHER_API_KEY_FILE are mutually exclusive" {
+		t.Fatalf("expected mutually exclusive error, got %v", err)
+	}
+}
+
+func TestLoadConfigReportsMissingSecretFile(t *testing.T) {
+	clearWeatherAPIKeys(t)
+	t.Setenv("WEATHER_API_KEY_FILE", filepath.Join(t.TempDir(), "missing"))
+
+	_, err := LoadConfig()
+	if err == nil || !strings.HasPrefix(err.Error(), "failed to read WEATHER_API_KEY_FILE") {
+		t.Fatalf("expected read error, got %v", err)
+	}
+}
+
+func TestLoadConfigReadsSecretsDirectory(t *testing.T) {
+	clearWeatherAPIKeys(t)
+	t.Setenv("ADMIN_TOKEN", "")
+	dir := t.TempDir()
+	writeSecret(t, dir, "weather_api_key", "dir_secret_key\n")
+	writeSecret(t, dir, "ADMIN_TOKEN", "dir_admin_token")
+	t.Setenv("SECRETS_DIR", dir)
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned an error: %v", err)
+	}
+
+	if cfg.WeatherAPIKey != "dir_secret_key" {
+		t.Fatalf("expected WeatherAPIKey loaded from SECRETS_DIR, got %q", cfg.WeatherAPIKey)
+	}
+	if cfg.AdminToken != "dir_admin_token" {
+		t.Fatalf("expected AdminToken loaded from SECRETS_DIR, got %q", cfg.AdminToken)
+	}
+}
+
+func TestLoadConfigPrefersEnvOverProviders(t *testing.T) {
+	clearWeatherAPIKeys(t)
+	t.Setenv("WEATHER_API_KEY", "env_secret_key")
+	t.Setenv("JWT_HS256_SECRET", "")
+
+	cfg, err := LoadConfig(staticSecretProvider{
+		"WEATHER_API_KEY":  "provider_secret_key",
+		"JWT_HS256_SECRET": "provider_jwt_secret",
+	})
+	if err != nil {
+		t.Fatalf("LoadConfig returned an error: %v", err)
+	}
+
+	if cfg.WeatherAPIKey != "env_secret_key" {
+		t.Fatalf("expected WeatherAPIKey loaded from env var, got %q", cfg.WeatherAPIKey)
+	}
+	if cfg.JWTHS256Secret != "provider_jwt_secret" {
+		t.Fatalf("expected JWTHS256Secret loaded from the provider, got %q", cfg.JWTHS256Secret)
+	}
+}
+
+func TestLoadConfigAcceptsKeyListWithoutKey(t *testing.T) {
+	clearWeatherAPIKeys(t)
+	t.Setenv("WEATHER_API_KEYS", "first_key,second_key")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned an error: %v", err)
+	}
+	if len(cfg.WeatherAPIKeyPool()) != 2 {
+		t.Fatalf("expected the configured keys, got %q", cfg.WeatherAPIKeyPool())
+	}
+}
@@ -0,0 +1,329 @@
+package config
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/BurntSushi/toml"
+	"go.yaml.in/yaml/v3"
+)
+
+// Sources of the setting values. A flag overrides the environment, which overrides the config
+// file, which overrides the default.
+const (
+	SourceDefault = "default"
+	SourceFile    = "file"
+	SourceEnv     = "env"
+	SourceFlag    = "flag"
+	SourceSecret  = "secret"
+)
+
+// redacted replaces the secret values in the config dump
+const redacted = "REDACTED"
+
+// Setting is a resolved setting, as shown by the config dump
+type Setting struct {
+	Name   string `json:"name"`
+	Value  string `json:"value"`
+	Source string `json:"source"`
+	Secret bool   `json:"secret"`
+}
+
+// Options tell where LoadConfig finds the settings besides the environment
+type Options struct {
+	// File is a YAML (.yaml, .yml) or TOML (.toml) config file, CONFIG_FILE when empty
+	File string
+	// Flags are the settings given on the command line, by setting name
+	Flags map[string]string
+	// Check asks for the config to be validated and dumped instead of serving
+	Check bool
+	// SecretProviders are looked up after the environment and SECRETS_DIR
+	SecretProviders []SecretProvider
+}
+
+// ParseArgs reads the command line: --config <file>, --config-check and --<setting> <value>,
+// where the setting is named like its environment variable in lower case with dashes, such as
+// --weather-api-url. Values may also be given as --<setting>=<value>.
+func ParseArgs(args []string) (Options, error) {
+	opts := Options{Flags: make(map[string]string)}
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		name, value, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+		if !strings.HasPrefix(arg, "-") || name == "" {
+			return Options{}, fmt.Errorf("unexpected argument %q", arg)
+		}
+
+		if name == "config-check" {
+			if hasValue {
+				return Options{}, errors.New("--config-check takes no value")
+			}
+			opts.Check = true
+			continue
+		}
+		if !hasValue {
+			if i+1 == len(args) {
+				return Options{}, fmt.Errorf("flag --%s needs a value", name)
+			}
+			i++
+			value = args[i]
+		}
+		if name == "config" {
+			opts.File = value
+			continue
+		}
+		opts.Flags[strings.ToUpper(strings.ReplaceAll(name, "-", "_"))] = value
+	}
+
+	return opts, nil
+}
+
+// settingReader resolves each setting from the flags, the environment, the config file and the
+// default, in this order, and collects the invalid values
+type settingReader struct {
+	file     map[string]string
+	flags    map[string]string
+	secrets  *secretReader
+	settings map[string]Setting
+	errs     []error
+}
+
+// newSettingReader reads the config file, whose keys are the setting names in any case. Nested
+// tables are joined with underscores, so cache.temperature_ttl sets CACHE_TEMPERATURE_TTL.
+func newSettingReader(file string, flags map[string]string) (*settingReader, error) {
+	s := &settingReader{
+		file:     make(map[string]string),
+		flags:    make(map[string]string),
+		settings: make(map[string]Setting),
+	}
+	for name, value := range flags {
+		s.flags[strings.ToUpper(name)] = value
+	}
+	if file == "" {
+		return s, nil
+	}
+
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the config file: %w", err)
+	}
+	values := map[string]any{}
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &values)
+	case ".toml":
+		err = toml.Unmarshal(data, &values)
+	default:
+		return nil, fmt.Errorf("the config file must be .yaml, .yml or .toml: %q", file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse the config file %s: %w", file, err)
+	}
+	flatten(s.file, "", values)
+
+	return s, nil
+}
+
+// flatten stores the scalar values by upper case name, joining lists with commas
+func flatten(into map[string]string, prefix string, values map[string]any) {
+	for key, value := range values {
+		name := strings.ToUpper(strings.ReplaceAll(key, "-", "_"))
+		if prefix != "" {
+			name = prefix + "_" + name
+		}
+		switch v := value.(type) {
+		case map[string]any:
+			flatten(into, name, v)
+		case []any:
+			items := make([]string, 0, len(v))
+			for _, item := range v {
+				items = append(items, fmt.Sprint(item))
+			}
+			into[name] = strings.Join(items, ",")
+		case nil:
+		default:
+			into[name] = fmt.Sprint(v)
+		}
+	}
+}
+
+// lookup returns the value of the setting with its source, empty values counting as unset
+func (s *settingReader) lookup(name string) (string, string) {
+	if value := strings.TrimSpace(s.flags[name]); value != "" {
+		return value, SourceFlag
+	}
+	if value := strings.TrimSpace(os.Getenv(name)); value != "" {
+		return value, SourceEnv
+	}
+	if value := strings.TrimSpace(s.file[name]); value != "" {
+		return value, SourceFile
+	}
+	return "", SourceDefault
+}
+
+// get returns the setting, or defaultVal when it is unset
+func (s *settingReader) get(name, defaultVal string) string {
+	value, source := s.lookup(name)
+	if source == SourceDefault {
+		value = defaultVal
+	}
+	s.settings[name] = Setting{Name: name, Value: value, Source: source}
+	return value
+}
+
+func (s *settingReader) getInt(name string, defaultVal int) int {
+	raw := s.get(name, strconv.Itoa(defaultVal))
+	value, err := strconv.Atoi(raw)
+	if err != nil {
+		s.errs = append(s.errs, fmt.Errorf("%s must be an integer: %q", name, raw))
+		return defaultVal
+	}
+	return value
+}
+
+func (s *settingReader) getDuration(name string, defaultVal time.Duration) time.Duration {
+	value, raw, ok := s.parseDuration(name, defaultVal)
+	if ok && value <= 0 {
+		s.errs = append(s.errs, fmt.Errorf("%s must be positive: %q", name, raw))
+		return defaultVal
+	}
+	return value
+}
+
+// getOptionalDuration reads a duration that may be zero, for the settings where zero turns a
+// wait or a tolerance off
+func (s *settingReader) getOptionalDuration(name string, defaultVal time.Duration) time.Duration {
+	value, raw, ok := s.parseDuration(name, defaultVal)
+	if ok && value < 0 {
+		s.errs = append(s.errs, fmt.Errorf("%s must not be negative: %q", name, raw))
+		return defaultVal
+	}
+	return value
+}
+
+// parseDuration reads a duration, returning it with the raw value and whether it parsed
+func (s *settingReader) parseDuration(name string, defaultVal time.Duration) (time.Duration, string, bool) {
+	raw := s.get(name, defaultVal.String())
+	value, err := time.ParseDuration(raw)
+	if err != nil {
+		s.errs = append(s.errs, fmt.Errorf("%s must be a duration such as 30s or 5m: %q", name, raw))
+		return defaultVal, raw, false
+	}
+	return value, raw, true
+}
+
+func (s *settingReader) getFloat(name string, defaultVal float64) float64 {
+	raw := s.get(name, strconv.FormatFloat(defaultVal, 'g', -1, 64))
+	value, err := strconv.ParseFloat(raw, 64)
+	if err != nil {
+		s.errs = append(s.errs, fmt.Errorf("%s must be a number: %q", name, raw))
+		return defaultVal
+	}
+	return value
+}
+
+func (s *settingReader) getBool(name string, defaultVal bool) bool {
+	raw := s.get(name, strconv.FormatBool(defaultVal))
+	value, err := strconv.ParseBool(raw)
+	if err != nil {
+		s.errs = append(s.errs, fmt.Errorf("%s must be true or false: %q", name, raw))
+		return defaultVal
+	}
+	return value
+}
+
+// getSecret returns the secret from the secret providers. Secrets are kept out of the config file
+// and the command line, where they would be committed or shown in the process list.
+func (s *settingReader) getSecret(name, defaultVal string) string {
+	if _, inFile := s.file[name]; inFile {
+		s.errs = append(s.errs, fmt.Errorf("%s is a secret and cannot be set in the config file, use %[1]s, %[1]s_FILE or SECRETS_DIR", name))
+	}
+	if _, inFlags := s.flags[name]; inFlags {
+		s.errs = append(s.errs, fmt.Errorf("%s is a secret and cannot be set by a flag, use %[1]s, %[1]s_FILE or SECRETS_DIR", name))
+	}
+
+	value, found := s.secrets.get(name)
+	source := SourceSecret
+	if !found {
+		value, source = defaultVal, SourceDefault
+	}
+	s.settings[name] = Setting{Name: name, Value: value, Source: source, Secret: true}
+	return value
+}
+
+// unknown reports the file and flag settings that no field reads, most likely typos
+func (s *settingReader) unknown() []error {
+	var errs []error
+	for _, layer := range []struct {
+		source string
+		values map[string]string
+	}{{SourceFile, s.file}, {SourceFlag, s.flags}} {
+		names := make([]string, 0, len(layer.values))
+		for name := range layer.values {
+			if _, known := s.settings[name]; !known {
+				names = append(names, name)
+			}
+		}
+		slices.Sort(names)
+		for _, name := range names {
+			if layer.source == SourceFile {
+				errs = append(errs, fmt.Errorf("unknown setting %s in the config file", name))
+			} else {
+				errs = append(errs, fmt.Errorf("unknown flag --%s", strings.ToLower(strings.ReplaceAll(name, "_", "-"))))
+			}
+		}
+	}
+
+	return errs
+}
+
+// Settings returns every resolved setting, sorted by name, with the secrets redacted
+func (c *Config) Settings() []Setting {
+	settings := make([]Setting, 0, len(c.settings))
+	for _, setting := range c.settings {
+		if setting.Secret && setting.Value != "" {
+			setting.Value = redacted
+		}
+		settings = append(settings, setting)
+	}
+	sort.Slice(settings, func(i, j int) bool { return settings[i].Name < settings[j].Name })
+
+	return settings
+}
+
+// Changed returns the names of the settings whose value differs in the other config, sorted.
+// Secrets are compared by their actual value, so a rotated secret counts as changed.
+func (c *Config) Changed(other *Config) []string {
+	var names []string
+	for name, setting := range c.settings {
+		if otherSetting, ok := other.settings[name]; !ok || otherSetting.Value != setting.Value {
+			names = append(names, name)
+		}
+	}
+	for name := range other.settings {
+		if _, ok := c.settings[name]; !ok {
+			names = append(names, name)
+		}
+	}
+	slices.Sort(names)
+
+	return names
+}
+
+// Dump writes every setting with its source, with the secrets redacted
+func (c *Config) Dump(w io.Writer) error {
+	for _, setting := range c.Settings() {
+		if _, err := fmt.Fprintf(w, "%s=%s\t# %s\n", setting.Name, setting.Value, setting.Source); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,220 @@
+package config
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfigFile(t *testing.T, name, content string) string {
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	return path
+}
+
+func TestParseArgs(t *testing.T) {
+	opts, err := ParseArgs([]string{"--config", "app.yaml", "--port=9090", "--cache-temperature-ttl", "10m", "--config-check"})
+	if err != nil {
+		t.Fatalf("ParseArgs returned an error: %v", err)
+	}
+
+	if opts.File != "app.yaml" || !opts.Check {
+		t.Fatalf("expected the config file and the check mode, got %+v", opts)
+	}
+	if opts.Flags["PORT"] != "9090" || opts.Flags["CACHE_TEMPERATURE_TTL"] != "10m" {
+		t.Fatalf("expected the settings by name, got %v", opts.Flags)
+	}
+
+	if _, err := ParseArgs([]string{"--port"}); err == nil || err.Error() != "flag --port needs a value" {
+		t.Fatalf("expected missing value error, got %v", err)
+	}
+}
+
+func TestLoadAppliesPrecedence(t *testing.T) {
+	t.Setenv("PORT", "")
+	t.Setenv("CACHE_TEMPERATURE_TTL", "")
+	t.Setenv("CACHE_CEP_TTL", "2h")
+	t.Setenv("BATCH_MAX_SIZE", "20")
+	file := writeConfigFile(t, "config.yaml", `
+port: 7070
+batch_max_size: 10
+cache:
+  temperature_ttl: 10m
+  cep_ttl: 1h
+rate_limit_routes:
+  - /v1/temperatures/batch=10/1m
+  - /v1/graphql=30/1m
+`)
+
+	cfg, err := Load(Options{File: file, Flags: map[string]string{"BATCH_MAX_SIZE": "30"}})
+	if err != nil {
+		t.Fatalf("Load returned an error: %v", err)
+	}
+
+	if cfg.Port != "7070" || cfg.CacheTemperatureTTL != 10*time.Minute {
+		t.Fatalf("expected the file to override the defaults, got %q and %v", cfg.Port, cfg.CacheTemperatureTTL)
+	}
+	if cfg.CacheCEPTTL != 2*time.Hour {
+		t.Fatalf("expected the env to override the file, got %v", cfg.CacheCEPTTL)
+	}
+	if cfg.BatchMaxSize != 30 {
+		t.Fatalf("expected the flag to override the env, got %d", cfg.BatchMaxSize)
+	}
+	if cfg.RateLimitRoutes != "/v1/temperatures/batch=10/1m,/v1/graphql=30/1m" {
+		t.Fatalf("expected the list joined with commas, got %q", cfg.RateLimitRoutes)
+	}
+}
+
+func TestLoadReadsTOML(t *testing.T) {
+	t.Setenv("BREAKER_FAILURE_THRESHOLD", "")
+	file := writeConfigFile(t, "config.toml", "[breaker]\nfailure_threshold = 9\nopen_duration = \"1m\"\n")
+
+	cfg, err := Load(Options{File: file})
+	if err != nil {
+		t.Fatalf("Load returned an error: %v", err)
+	}
+
+	if cfg.BreakerFailureThreshold != 9 || cfg.BreakerOpenDuration != time.Minute {
+		t.Fatalf("expected the TOML settings, got %d and %v", cfg.BreakerFailureThreshold, cfg.BreakerOpenDuration)
+	}
+}
+
+func TestLoadReportsInvalidSettings(t *testing.T) {
+	t.Setenv("BATCH_MAX_SIZE", "many")
+	t.Setenv("HEALTH_CHECK_TTL", "-1s")
+	file := writeConfigFile(t, "config.yaml", "admin_token: secret\ncache_ttl: 1m\n")
+
+	_, err := Load(Options{File: file, Flags: map[string]string{"BREAKER_SERVE_STALE": "maybe", "NO_SUCH_SETTING": "1"}})
+	if err == nil {
+		t.Fatalf("expected load error")
+	}
+
+	for _, expected := range []string{
+		`BATCH_MAX_SIZE must be an integer: "many"`,
+		`HEALTH_CHECK_TTL must be positive: "-1s"`,
+		`BREAKER_SERVE_STALE must be true or false: "maybe"`,
+		"ADMIN_TOKEN is a secret and cannot be set in the config file",
+		"unknown setting CACHE_TTL in the config file",
+		"unknown flag --no-such-setting",
+	} {
+		if !strings.Contains(err.Error(), expected) {
+			t.Fatalf("expected %q in %q", expected, err.Error())
+		}
+	}
+}
+
+func TestLoadAcceptsZeroForOptionalDurations(t *testing.T) {
+	t.Setenv("JWT_LEEWAY", "0s")
+	t.Setenv("CACHE_STALE_MAX_AGE", "0")
+	t.Setenv("ALERT_WEBHOOK_BACKOFF", "0s")
+	t.Setenv("UPSTREAM_RETRY_BASE_DELAY", "0s")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned an error: %v", err)
+	}
+
+	if cfg.JWTLeeway != 0 || cfg.CacheStaleMaxAge != 0 || cfg.AlertWebhookBackoff != 0 || cfg.UpstreamRetryBaseDelay != 0 {
+		t.Fatalf("expected zero durations, got %v, %v, %v and %v",
+			cfg.JWTLeeway, cfg.CacheStaleMaxAge, cfg.AlertWebhookBackoff, cfg.UpstreamRetryBaseDelay)
+	}
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("Validate returned an error: %v", err)
+	}
+}
+
+func TestLoadRejectsNegativeOptionalDurations(t *testing.T) {
+	t.Setenv("JWT_LEEWAY", "-5s")
+
+	_, err := LoadConfig()
+
+	expected := `JWT_LEEWAY must not be negative: "-5s"`
+	if err == nil || !strings.Contains(err.Error(), expected) {
+		t.Fatalf("expected %q, got %v", expected, err)
+	}
+}
+
+func TestLoadRejectsUnsupportedConfigFile(t *testing.T) {
+	file := writeConfigFile(t, "config.json", "{}")
+
+	if _, err := Load(Options{File: file}); err == nil || !strings.Contains(err.Error(), "must be .yaml, .yml or .toml") {
+		t.Fatalf("expected unsupported format error, got %v", err)
+	}
+}
+
+func TestDumpRedactsSecrets(t *testing.T) {
+	t.Setenv("WEATHER_API_KEY", "env_secret_key")
+	t.Setenv("PORT", "9999")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned an error: %v", err)
+	}
+	var dump bytes.Buffer
+	if err := cfg.Dump(&dump); err != nil {
+		t.Fatalf("Dump returned an error: %v", err)
+	}
+
+	if strings.Contains(dump.String(), "env_secret_key") {
+		t.Fatalf("expected the secret to be redacted, got %q", dump.String())
+	}
+	for _, expected := range []string{"WEATHER_API_KEY=REDACTED\t# secret\n", "PORT=9999\t# env\n", "BATCH_MAX_SIZE=50\t# default\n"} {
+		if !strings.Contains(dump.String(), expected) {
+			t.Fatalf("expected %q in the dump, got %q", expected, dump.String())
+		}
+	}
+}
+
+func TestValidateReportsOutOfRangeSettings(t *testing.T) {
+	t.Setenv("GRPC_PORT", "70000")
+	t.Setenv("LOG_LEVEL", "verbose")
+	t.Setenv("BATCH_CONCURRENCY", "0")
+	t.Setenv("UPSTREAM_RETRY_BASE_DELAY", "5s")
+	t.Setenv("UPSTREAM_RETRY_MAX_DELAY", "1s")
+
+	cfg, err := LoadConfig()
+	if err != nil {
+		t.Fatalf("LoadConfig returned an error: %v", err)
+	}
+
+	expected := "GRPC_PORT must be a port between 1 and 65535: \"70000\"\n" +
+		"LOG_LEVEL is invalid: \"verbose\"\n" +
+		"BATCH_CONCURRENCY must be at least 1: 0\n" +
+		"UPSTREAM_RETRY_BASE_DELAY must not exceed UPSTREAM_RETRY_MAX_DELAY: 5s > 1s"
+	if err := cfg.Validate(); err == nil || err.Error() != expected {
+		t.Fatalf("expected %q, got %v", expected, err)
+	}
+}
+
+func TestChangedComparesSecretValues(t *testing.T) {
+	clearWeatherAPIKeys(t)
+	t.Setenv("WEATHER_API_KEY", "first_key")
+	t.Setenv("CACHE_CEP_TTL", "")
+	file := writeConfigFile(t, "config.yaml", "cache_cep_ttl: 1h\n")
+
+	before, err := Load(Options{File: file})
+	if err != nil {
+		t.Fatalf("Load returned an error: %v", err)
+	}
+	t.Setenv("WEATHER_API_KEY", "second_key")
+	if err := os.WriteFile(file, []byte("cache_cep_ttl: 2h\n"), 0o644); err != nil {
+		t.Fatalf("failed to write config file: %v", err)
+	}
+	after, err := Load(Options{File: file})
+	if err != nil {
+		t.Fatalf("Load returned an error: %v", err)
+	}
+
+	changed := before.Changed(after)
+	if strings.Join(changed, ",") != "CACHE_CEP_TTL,WEATHER_API_KEY" {
+		t.Fatalf("expected the TTL and the rotated key, got %v", changed)
+	}
+	if len(after.Changed(after)) != 0 {
+		t.Fatalf("expected no change against itself, got %v", after.Changed(after))
+	}
+}
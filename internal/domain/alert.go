@@ -0,0 +1,96 @@
+package domain
+
+import (
+	"errors"
+	"net/netip"
+	"time"
+)
+
+var (
+	ErrAlertRuleNotFound   = errors.New("Alert rule not found")
+	ErrInvalidAlertRule    = errors.New("Invalid alert rule")
+	ErrAlertRuleLimit      = errors.New("Alert rule limit reached")
+	ErrDeadLetterNotFound  = errors.New("Dead letter not found")
+	ErrDeadLetterReplaying = errors.New("Dead letter replay in progress")
+	ErrWebhookDeliveryFail = errors.New("Webhook delivery failed")
+)
+
+// sharedAddressSpace is the carrier-grade NAT range of RFC 6598, internal to the provider network
+var sharedAddressSpace = netip.MustParsePrefix("100.64.0.0/10")
+
+// IsPublicWebhookAddr reports whether webhooks may be delivered to the address. Loopback, private,
+// link-local (such as the 169.254.169.254 metadata endpoint), shared, multicast and unspecified
+// addresses are refused, so a rule cannot reach the internal network.
+func IsPublicWebhookAddr(addr netip.Addr) bool {
+	addr = addr.Unmap()
+	return addr.IsGlobalUnicast() && !addr.IsPrivate() && !sharedAddressSpace.Contains(addr)
+}
+
+// AlertState represents the position of a temperature relative to the thresholds of a rule
+type AlertState string
+
+const (
+	AlertStateNormal AlertState = "normal"
+	AlertStateAbove  AlertState = "above"
+	AlertStateBelow  AlertState = "below"
+)
+
+// AlertRule represents a threshold subscription for a CEP
+type AlertRule struct {
+	ID         string    `json:"id"`
+	CEP        string    `json:"cep"`
+	Above      *float64  `json:"above,omitempty"`
+	Below      *float64  `json:"below,omitempty"`
+	Hysteresis float64   `json:"hysteresis"`
+	WebhookURL string    `json:"webhook_url"`
+	CreatedAt  time.Time `json:"created_at"`
+	// Owner identifies who registered the rule, the API key or the token subject
+	Owner string `json:"-"`
+}
+
+// AlertEvent represents a threshold crossing detected for a rule
+type AlertEvent struct {
+	RuleID      string      `json:"rule_id"`
+	CEP         string      `json:"cep"`
+	State       AlertState  `json:"state"`
+	Previous    AlertState  `json:"previous_state"`
+	Threshold   *float64    `json:"threshold,omitempty"`
+	Temperature Temperature `json:"temperature"`
+	OccurredAt  time.Time   `json:"occurred_at"`
+}
+
+// WebhookDelivery represents an outbound webhook call and its delivery attempts
+type WebhookDelivery struct {
+	ID         string     `json:"id"`
+	RuleID     string     `json:"rule_id"`
+	URL        string     `json:"url"`
+	Event      AlertEvent `json:"event"`
+	Attempts   int        `json:"attempts"`
+	LastError  string     `json:"last_error,omitempty"`
+	FailedAt   time.Time  `json:"failed_at,omitempty"`
+	ReplayedAt *time.Time `json:"replayed_at,omitempty"`
+}
+
+// AlertRuleRepository defines the contract for storing alert rules
+type AlertRuleRepository interface {
+	Create(rule *AlertRule) error
+	Get(id string) (*AlertRule, error)
+	List() ([]*AlertRule, error)
+	// CountByOwner returns the number of rules registered by the owner
+	CountByOwner(owner string) (int, error)
+	Delete(id string) error
+}
+
+// WebhookDispatcher defines the contract for delivering alert events and managing failed deliveries
+type WebhookDispatcher interface {
+	Dispatch(rule *AlertRule, event *AlertEvent) error
+	DeadLetters() []*WebhookDelivery
+	Replay(id string) error
+}
+
+// AlertRuleUseCase defines the contract for managing alert rules
+type AlertRuleUseCase interface {
+	CreateRule(rule *AlertRule) (*AlertRule, error)
+	ListRules() ([]*AlertRule, error)
+	DeleteRule(id string) error
+}
@@ -0,0 +1,75 @@
+package domain
+
+import (
+	"errors"
+	"time"
+)
+
+var (
+	ErrAPIKeyNotFound    = errors.New("API key not found")
+	ErrInvalidAPIKey     = errors.New("Invalid API key")
+	ErrInvalidAPIKeySpec = errors.New("Invalid API key definition")
+	ErrRouteNotAllowed   = errors.New("Route not allowed for the API key")
+	ErrQuotaExceeded     = errors.New("Daily quota exceeded")
+)
+
+// APIKey represents a consumer of the API. Only the hash of the secret is stored.
+type APIKey struct {
+	ID            string     `json:"id"`
+	Name          string     `json:"name"`
+	Prefix        string     `json:"prefix"`
+	Hash          string     `json:"-"`
+	DailyQuota    int        `json:"daily_quota"`
+	AllowedRoutes []string   `json:"allowed_routes,omitempty"`
+	CreatedAt     time.Time  `json:"created_at"`
+	RevokedAt     *time.Time `json:"revoked_at,omitempty"`
+}
+
+// CreatedAPIKey is returned once when a key is created, carrying the secret in clear
+type CreatedAPIKey struct {
+	APIKey
+	Secret string `json:"secret"`
+}
+
+// DailyUsage represents the requests of an API key on one day, by route
+type DailyUsage struct {
+	Day    string         `json:"day"`
+	Total  int            `json:"total"`
+	Routes map[string]int `json:"routes"`
+}
+
+// UsageReport represents the consumption of an API key
+type UsageReport struct {
+	KeyID      string       `json:"key_id"`
+	Name       string       `json:"name"`
+	DailyQuota int          `json:"daily_quota"`
+	Days       []DailyUsage `json:"days"`
+}
+
+// APIKeyRepository defines the contract for storing API keys
+type APIKeyRepository interface {
+	Create(key *APIKey) error
+	GetByHash(hash string) (*APIKey, error)
+	List() ([]*APIKey, error)
+	Revoke(id string, at time.Time) error
+}
+
+// UsageRepository defines the contract for counting the requests of each API key
+type UsageRepository interface {
+	// Increment counts a request of the key on the day and route
+	Increment(keyID, day, route string) error
+	// Total returns the requests of the key on the day
+	Total(keyID, day string) (int, error)
+	// List returns the usage of the key, most recent day first
+	List(keyID string) ([]DailyUsage, error)
+}
+
+// APIKeyUseCase defines the contract for managing API keys and enforcing their limits
+type APIKeyUseCase interface {
+	CreateKey(key *APIKey) (*CreatedAPIKey, error)
+	ListKeys() ([]*APIKey, error)
+	RevokeKey(id string) error
+	Authenticate(secret string) (*APIKey, error)
+	Authorize(key *APIKey, route string) error
+	Usage(key *APIKey) (*UsageReport, error)
+}
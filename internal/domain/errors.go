@@ -1,9 +1,58 @@
 package domain
 
-import "errors"
+import (
+	"errors"
+	"fmt"
+)
 
 var (
 	ErrInvalidCEPFormat    = errors.New("Invalid CEP format")
 	ErrCEPNotFound         = errors.New("CEP not found")
 	ErrTemperatureNotFound = errors.New("Temperature data not found")
+	ErrForecastNotFound    = errors.New("Forecast data not found")
+	ErrInvalidForecastDays = errors.New("Invalid forecast days")
+	ErrUpstreamUnavailable = errors.New("Upstream service unavailable")
+	// ErrUpstreamQuotaExceeded is reported along with ErrUpstreamUnavailable when the call budget of an upstream is spent
+	ErrUpstreamQuotaExceeded = errors.New("Upstream quota exceeded")
+)
+
+var (
+	ErrEmptyBatch    = errors.New("Batch has no CEPs")
+	ErrBatchTooLarge = errors.New("Batch has too many CEPs")
+)
+
+// CEPFormatReason tells why a CEP was refused
+type CEPFormatReason string
+
+const (
+	CEPFormatEmpty            CEPFormatReason = "empty"
+	CEPFormatInvalidCharacter CEPFormatReason = "invalid_character"
+	CEPFormatWrongLength      CEPFormatReason = "wrong_length"
+	CEPFormatOutOfRange       CEPFormatReason = "out_of_range"
 )
+
+// CEPFormatError reports why a CEP was refused. It matches ErrInvalidCEPFormat.
+type CEPFormatError struct {
+	Reason CEPFormatReason
+	// Character is the first character that is neither a digit nor an accepted separator
+	Character rune
+	// Digits is the number of digits found
+	Digits int
+}
+
+func (e *CEPFormatError) Error() string {
+	switch e.Reason {
+	case CEPFormatInvalidCharacter:
+		return fmt.Sprintf("%s: invalid character %q", ErrInvalidCEPFormat, e.Character)
+	case CEPFormatWrongLength:
+		return fmt.Sprintf("%s: %d digits instead of 8", ErrInvalidCEPFormat, e.Digits)
+	case CEPFormatOutOfRange:
+		return fmt.Sprintf("%s: outside the Correios ranges", ErrInvalidCEPFormat)
+	default:
+		return fmt.Sprintf("%s: empty", ErrInvalidCEPFormat)
+	}
+}
+
+func (e *CEPFormatError) Unwrap() error {
+	return ErrInvalidCEPFormat
+}
@@ -1,36 +1,80 @@
 package domain
 
+import (
+	"context"
+	"time"
+)
+
 // Temperature represents the temperature in different scales
 type Temperature struct {
 	Celsius    float64 `json:"temp_C"`
 	Fahrenheit float64 `json:"temp_F"`
 	Kelvin     float64 `json:"temp_K"`
+
+	// ExpiresAt is when the reading stops being fresh, zero when it is not cached
+	ExpiresAt time.Time `json:"-"`
 }
 
 // CEPData represents the data returned by the ViaCEP API
 type CEPData struct {
-	CEP     string
-	City    string
-	Region  string
-	RawData map[string]interface{}
+	CEP          string
+	Street       string
+	Neighborhood string
+	City         string
+	Region       string
+	IBGE         string
+	DDD          string
+	RawData      map[string]interface{}
+}
+
+// ForecastDay represents the forecast for a single day
+type ForecastDay struct {
+	Date          string
+	MinCelsius    float64
+	MaxCelsius    float64
+	AvgCelsius    float64
+	MinFahrenheit float64
+	MaxFahrenheit float64
+	AvgFahrenheit float64
+	Condition     string
 }
 
 // TemperatureRepository defines the contract for fetching temperature data
 type TemperatureRepository interface {
-	GetTemperatureByCityName(cityName string) (*Temperature, error)
+	GetTemperatureByCityName(ctx context.Context, cityName string) (*Temperature, error)
+}
+
+// ForecastRepository defines the contract for fetching forecast data
+type ForecastRepository interface {
+	GetForecastByCityName(ctx context.Context, cityName string, days int) ([]ForecastDay, error)
 }
 
 // CEPRepository defines the contract for fetching CEP data
 type CEPRepository interface {
-	GetCEPData(cep string) (*CEPData, error)
+	GetCEPData(ctx context.Context, cep string) (*CEPData, error)
 }
 
 // TemperatureUseCase defines the contract for the use case related to temperature retrieval
 type TemperatureUseCase interface {
-	GetTemperatureByCEP(cep string) (*Temperature, error)
+	GetTemperatureByCEP(ctx context.Context, cep string) (*Temperature, error)
 }
 
 // CEPValidator defines the contract for validating CEP
 type CEPValidator interface {
-	ValidateCEPFormat(cep string) bool
+	// NormalizeCEP returns the CEP as 8 digits, or a *CEPFormatError telling why it is invalid
+	NormalizeCEP(cep string) (string, error)
+	// InferUF returns the UF of the Correios range holding the CEP, given as 8 digits
+	InferUF(cep string) (string, bool)
+}
+
+// TemperatureResult represents the outcome of a temperature lookup for one CEP in a batch
+type TemperatureResult struct {
+	CEP         string
+	Temperature *Temperature
+	Err         error
+}
+
+// BatchTemperatureUseCase defines the contract for fetching the temperature of several CEPs at once
+type BatchTemperatureUseCase interface {
+	GetTemperaturesByCEPs(ctx context.Context, ceps []string) ([]TemperatureResult, error)
 }
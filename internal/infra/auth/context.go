@@ -0,0 +1,33 @@
+package auth
+
+import (
+	"context"
+
+	"github.com/xavierpms/weather-by-city/internal/domain"
+)
+
+type apiKeyContextKey struct{}
+
+// WithAPIKey returns a copy of the context carrying the authenticated API key
+func WithAPIKey(ctx context.Context, key *domain.APIKey) context.Context {
+	return context.WithValue(ctx, apiKeyContextKey{}, key)
+}
+
+// APIKeyFromContext returns the API key authenticated for the request
+func APIKeyFromContext(ctx context.Context) (*domain.APIKey, bool) {
+	key, ok := ctx.Value(apiKeyContextKey{}).(*domain.APIKey)
+	return key, ok
+}
+
+type claimsContextKey struct{}
+
+// WithClaims returns a copy of the context carrying the claims of the verified token
+func WithClaims(ctx context.Context, claims *Claims) context.Context {
+	return context.WithValue(ctx, claimsContextKey{}, claims)
+}
+
+// ClaimsFromContext returns the claims of the token verified for the request
+func ClaimsFromContext(ctx context.Context) (*Claims, bool) {
+	claims, ok := ctx.Value(claimsContextKey{}).(*Claims)
+	return claims, ok
+}
@@ -0,0 +1,171 @@
+package auth
+
+import (
+	"context"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"math/big"
+	"net/http"
+	"os"
+	"sync"
+	"time"
+)
+
+// ErrUnknownKey is returned when the JWKS has no key with the requested ID
+var ErrUnknownKey = errors.New("Unknown signing key")
+
+// jwksMinRefreshInterval bounds how often the JWKS is fetched, so forged key IDs or an unavailable
+// identity provider do not turn every request into a fetch
+const jwksMinRefreshInterval = time.Minute
+
+// jwk is an entry of a JSON Web Key Set. Only RSA signing keys are used.
+type jwk struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n"`
+	E   string `json:"e"`
+}
+
+// KeySet holds the RSA public keys of a JWKS, loaded from a file or from a URL refreshed periodically
+type KeySet struct {
+	load    func(ctx context.Context) ([]byte, error)
+	refresh time.Duration
+	now     func() time.Time
+
+	mu        sync.Mutex
+	keys      map[string]*rsa.PublicKey
+	loadedAt  time.Time
+	attemptAt time.Time
+}
+
+// NewFileKeySet loads the JWKS from a local file. The file is only read once.
+func NewFileKeySet(path string) (*KeySet, error) {
+	s := &KeySet{
+		load: func(ctx context.Context) ([]byte, error) { return os.ReadFile(path) },
+		now:  time.Now,
+	}
+	if err := s.Refresh(context.Background()); err != nil {
+		return nil, err
+	}
+
+	return s, nil
+}
+
+// NewURLKeySet fetches the JWKS from the URL when first needed and again every refresh interval,
+// or sooner when a token names an unknown key, as happens after the identity provider rotates its keys
+func NewURLKeySet(client *http.Client, url string, refresh time.Duration) *KeySet {
+	return &KeySet{
+		load: func(ctx context.Context) ([]byte, error) {
+			req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+			if err != nil {
+				return nil, err
+			}
+			resp, err := client.Do(req)
+			if err != nil {
+				return nil, err
+			}
+			defer resp.Body.Close()
+
+			if resp.StatusCode != http.StatusOK {
+				return nil, fmt.Errorf("unexpected status %d fetching the JWKS", resp.StatusCode)
+			}
+			return io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+		},
+		refresh: refresh,
+		now:     time.Now,
+	}
+}
+
+// Key returns the public key with the ID. An empty ID matches the only key of a single-key set.
+func (s *KeySet) Key(ctx context.Context, kid string) (*rsa.PublicKey, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	key, found := s.lookup(kid)
+	due := s.refresh > 0 && (!found || now.Sub(s.loadedAt) >= s.refresh)
+	if due && (s.attemptAt.IsZero() || now.Sub(s.attemptAt) >= jwksMinRefreshInterval) {
+		if err := s.refreshLocked(ctx); err != nil && s.keys == nil {
+			return nil, err
+		}
+		key, found = s.lookup(kid)
+	}
+	if !found {
+		return nil, fmt.Errorf("%w %q", ErrUnknownKey, kid)
+	}
+
+	return key, nil
+}
+
+// Refresh loads the key set again, keeping the previous keys when it fails
+func (s *KeySet) Refresh(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.refreshLocked(ctx)
+}
+
+func (s *KeySet) refreshLocked(ctx context.Context) error {
+	s.attemptAt = s.now()
+
+	data, err := s.load(ctx)
+	if err != nil {
+		return fmt.Errorf("failed to load the JWKS: %w", err)
+	}
+	keys, err := parseJWKS(data)
+	if err != nil {
+		return err
+	}
+
+	s.keys = keys
+	s.loadedAt = s.attemptAt
+	return nil
+}
+
+func (s *KeySet) lookup(kid string) (*rsa.PublicKey, bool) {
+	if kid == "" && len(s.keys) == 1 {
+		for _, key := range s.keys {
+			return key, true
+		}
+	}
+	key, ok := s.keys[kid]
+	return key, ok
+}
+
+// parseJWKS returns the RSA signing keys of the set by key ID
+func parseJWKS(data []byte) (map[string]*rsa.PublicKey, error) {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	if err := json.Unmarshal(data, &set); err != nil {
+		return nil, fmt.Errorf("invalid JWKS: %w", err)
+	}
+
+	keys := make(map[string]*rsa.PublicKey)
+	for _, k := range set.Keys {
+		if k.Kty != "RSA" || (k.Use != "" && k.Use != "sig") || (k.Alg != "" && k.Alg != "RS256") {
+			continue
+		}
+
+		n, errN := base64.RawURLEncoding.DecodeString(k.N)
+		e, errE := base64.RawURLEncoding.DecodeString(k.E)
+		if errN != nil || errE != nil || len(e) == 0 || len(e) > 4 {
+			return nil, fmt.Errorf("invalid JWKS: malformed RSA key %q", k.Kid)
+		}
+		keys[k.Kid] = &rsa.PublicKey{
+			N: new(big.Int).SetBytes(n),
+			E: int(new(big.Int).SetBytes(e).Int64()),
+		}
+	}
+	if len(keys) == 0 {
+		return nil, errors.New("invalid JWKS: no RSA signing key")
+	}
+
+	return keys, nil
+}
@@ -0,0 +1,167 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+// Scopes enforced by the service
+const (
+	ScopeWeatherRead = "weather:read"
+	ScopeBatchWrite  = "batch:write"
+	ScopeAdmin       = "admin"
+)
+
+// ErrInvalidToken is returned for tokens that are malformed, badly signed, expired or not meant for the service
+var ErrInvalidToken = errors.New("Invalid token")
+
+// Claims is the identity carried by a verified token
+type Claims struct {
+	Subject string
+	Scopes  []string
+}
+
+// HasScope reports whether the claims grant the scope
+func (c *Claims) HasScope(scope string) bool {
+	return slices.Contains(c.Scopes, scope)
+}
+
+// VerifierOptions configures the accepted tokens. At least one of HS256Secret and KeySet must be set.
+type VerifierOptions struct {
+	// HS256Secret verifies the tokens signed with HS256
+	HS256Secret []byte
+	// KeySet verifies the tokens signed with RS256
+	KeySet *KeySet
+	// Issuer and Audience, when set, must match the iss and aud claims
+	Issuer   string
+	Audience string
+	// Leeway tolerates clock skew when checking exp, nbf and iat
+	Leeway time.Duration
+	// RolesClaim names the claim listing the roles of the subject, mapped to scopes by RoleScopes
+	RolesClaim string
+	RoleScopes map[string][]string
+}
+
+// Verifier validates JWTs and maps their claims to scopes
+type Verifier struct {
+	options VerifierOptions
+	parser  *jwt.Parser
+}
+
+// NewVerifier creates a verifier. Only the algorithms with a configured key are accepted, so a token
+// cannot pick a weaker algorithm than the one the service expects.
+func NewVerifier(options VerifierOptions) (*Verifier, error) {
+	var methods []string
+	if len(options.HS256Secret) > 0 {
+		methods = append(methods, jwt.SigningMethodHS256.Alg())
+	}
+	if options.KeySet != nil {
+		methods = append(methods, jwt.SigningMethodRS256.Alg())
+	}
+	if len(methods) == 0 {
+		return nil, errors.New("JWT verification needs an HS256 secret or a JWKS")
+	}
+
+	parserOptions := []jwt.ParserOption{
+		jwt.WithValidMethods(methods),
+		jwt.WithLeeway(options.Leeway),
+		jwt.WithExpirationRequired(),
+		jwt.WithIssuedAt(),
+	}
+	if options.Issuer != "" {
+		parserOptions = append(parserOptions, jwt.WithIssuer(options.Issuer))
+	}
+	if options.Audience != "" {
+		parserOptions = append(parserOptions, jwt.WithAudience(options.Audience))
+	}
+
+	return &Verifier{
+		options: options,
+		parser:  jwt.NewParser(parserOptions...),
+	}, nil
+}
+
+// Verify validates the token and returns its claims
+func (v *Verifier) Verify(ctx context.Context, token string) (*Claims, error) {
+	claims := jwt.MapClaims{}
+	_, err := v.parser.ParseWithClaims(token, claims, func(t *jwt.Token) (any, error) {
+		switch t.Method.Alg() {
+		case jwt.SigningMethodHS256.Alg():
+			return v.options.HS256Secret, nil
+		default:
+			kid, _ := t.Header["kid"].(string)
+			return v.options.KeySet.Key(ctx, kid)
+		}
+	})
+	if err != nil {
+		return nil, fmt.Errorf("%w: %w", ErrInvalidToken, err)
+	}
+
+	subject, _ := claims.GetSubject()
+	return &Claims{Subject: subject, Scopes: v.scopes(claims)}, nil
+}
+
+// scopes collects the scopes of the OAuth 2.0 scope claim, of the scp claim used by some identity
+// providers, and of the roles mapped by RoleScopes
+func (v *Verifier) scopes(claims jwt.MapClaims) []string {
+	var scopes []string
+	add := func(values ...string) {
+		for _, s := range values {
+			if s != "" && !slices.Contains(scopes, s) {
+				scopes = append(scopes, s)
+			}
+		}
+	}
+
+	add(claimStrings(claims["scope"])...)
+	add(claimStrings(claims["scp"])...)
+	if v.options.RolesClaim != "" {
+		for _, role := range claimStrings(claims[v.options.RolesClaim]) {
+			add(v.options.RoleScopes[role]...)
+		}
+	}
+
+	return scopes
+}
+
+// claimStrings reads a claim holding a space-separated string or an array of strings
+func claimStrings(value any) []string {
+	switch v := value.(type) {
+	case string:
+		return strings.Fields(v)
+	case []any:
+		values := make([]string, 0, len(v))
+		for _, item := range v {
+			if s, ok := item.(string); ok {
+				values = append(values, s)
+			}
+		}
+		return values
+	}
+	return nil
+}
+
+// ParseScopeMap parses a comma-separated list of name=scopes, with the scopes separated by spaces,
+// such as /v1/temperatures/batch=batch:write,admin=admin batch:write
+func ParseScopeMap(value string) (map[string][]string, error) {
+	scopes := make(map[string][]string)
+	for _, entry := range strings.Split(value, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+
+		name, scopeList, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(name) == "" || len(strings.Fields(scopeList)) == 0 {
+			return nil, fmt.Errorf("scope mapping %q must be written as name=scope", entry)
+		}
+		scopes[strings.TrimSpace(name)] = strings.Fields(scopeList)
+	}
+
+	return scopes, nil
+}
@@ -0,0 +1,233 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/base64"
+	"encoding/json"
+	"math/big"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+var testSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// mintHS256 signs the claims with the test secret, expiring in an hour unless exp is set
+func mintHS256(t *testing.T, claims jwt.MapClaims) string {
+	if _, ok := claims["exp"]; !ok {
+		claims["exp"] = time.Now().Add(time.Hour).Unix()
+	}
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString(testSecret)
+	require.NoError(t, err)
+	return token
+}
+
+// mintRS256 signs the claims with the RSA key, naming it by kid
+func mintRS256(t *testing.T, key *rsa.PrivateKey, kid string, claims jwt.MapClaims) string {
+	claims["exp"] = time.Now().Add(time.Hour).Unix()
+	token := jwt.NewWithClaims(jwt.SigningMethodRS256, claims)
+	token.Header["kid"] = kid
+	signed, err := token.SignedString(key)
+	require.NoError(t, err)
+	return signed
+}
+
+// jwksJSON returns the JWKS publishing the public keys by kid
+func jwksJSON(t *testing.T, keys map[string]*rsa.PrivateKey) []byte {
+	var set struct {
+		Keys []jwk `json:"keys"`
+	}
+	for kid, key := range keys {
+		set.Keys = append(set.Keys, jwk{
+			Kty: "RSA",
+			Kid: kid,
+			Use: "sig",
+			Alg: "RS256",
+			N:   base64.RawURLEncoding.EncodeToString(key.N.Bytes()),
+			E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(key.E)).Bytes()),
+		})
+	}
+	data, err := json.Marshal(set)
+	require.NoError(t, err)
+	return data
+}
+
+func newRSAKey(t *testing.T) *rsa.PrivateKey {
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	return key
+}
+
+// TestVerifierHS256 tests the accepted tokens and the scopes mapped from their claims
+func TestVerifierHS256(t *testing.T) {
+	// Arrange
+	verifier, err := NewVerifier(VerifierOptions{
+		HS256Secret: testSecret,
+		Issuer:      "https://issuer.example.com",
+		Audience:    "weather-by-city",
+		RolesClaim:  "roles",
+		RoleScopes:  map[string][]string{"operator": {ScopeAdmin, ScopeBatchWrite}},
+	})
+	require.NoError(t, err)
+	token := mintHS256(t, jwt.MapClaims{
+		"sub":   "user-1",
+		"iss":   "https://issuer.example.com",
+		"aud":   "weather-by-city",
+		"scope": "weather:read batch:write",
+		"scp":   []any{"weather:read", "custom"},
+		"roles": []any{"operator", "unmapped"},
+	})
+
+	// Act
+	claims, err := verifier.Verify(context.Background(), token)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "user-1", claims.Subject)
+	assert.Equal(t, []string{ScopeWeatherRead, ScopeBatchWrite, "custom", ScopeAdmin}, claims.Scopes)
+	assert.True(t, claims.HasScope(ScopeAdmin))
+}
+
+// TestVerifierRejectsInvalidTokens tests the tokens that must not be accepted
+func TestVerifierRejectsInvalidTokens(t *testing.T) {
+	// Arrange
+	verifier, err := NewVerifier(VerifierOptions{
+		HS256Secret: testSecret,
+		Issuer:      "https://issuer.example.com",
+		Audience:    "weather-by-city",
+		Leeway:      time.Second,
+	})
+	require.NoError(t, err)
+	valid := jwt.MapClaims{"iss": "https://issuer.example.com", "aud": "weather-by-city"}
+	with := func(key string, value any) jwt.MapClaims {
+		claims := jwt.MapClaims{}
+		for k, v := range valid {
+			claims[k] = v
+		}
+		claims[key] = value
+		return claims
+	}
+	otherSecret, err := jwt.NewWithClaims(jwt.SigningMethodHS256, with("exp", time.Now().Add(time.Hour).Unix())).
+		SignedString([]byte("another-secret-another-secret-xx"))
+	require.NoError(t, err)
+	rs256, err := jwt.NewWithClaims(jwt.SigningMethodRS256, with("exp", time.Now().Add(time.Hour).Unix())).
+		SignedString(newRSAKey(t))
+	require.NoError(t, err)
+	unsigned, err := jwt.NewWithClaims(jwt.SigningMethodNone, with("exp", time.Now().Add(time.Hour).Unix())).
+		SignedString(jwt.UnsafeAllowNoneSignatureType)
+	require.NoError(t, err)
+	noExpiry, err := jwt.NewWithClaims(jwt.SigningMethodHS256, valid).SignedString(testSecret)
+	require.NoError(t, err)
+
+	testCases := map[string]string{
+		"expired":         mintHS256(t, with("exp", time.Now().Add(-time.Minute).Unix())),
+		"not yet valid":   mintHS256(t, with("nbf", time.Now().Add(time.Minute).Unix())),
+		"wrong issuer":    mintHS256(t, with("iss", "https://evil.example.com")),
+		"wrong audience":  mintHS256(t, with("aud", "another-service")),
+		"without expiry":  noExpiry,
+		"other secret":    otherSecret,
+		"unexpected alg":  rs256,
+		"alg none":        unsigned,
+		"malformed token": "not.a.jwt",
+	}
+
+	for name, token := range testCases {
+		// Act
+		_, err := verifier.Verify(context.Background(), token)
+
+		// Assert
+		assert.ErrorIs(t, err, ErrInvalidToken, name)
+	}
+}
+
+// TestVerifierRS256WithJWKSFile tests the tokens signed by the keys of a local JWKS
+func TestVerifierRS256WithJWKSFile(t *testing.T) {
+	// Arrange
+	key := newRSAKey(t)
+	path := filepath.Join(t.TempDir(), "jwks.json")
+	require.NoError(t, os.WriteFile(path, jwksJSON(t, map[string]*rsa.PrivateKey{"key-1": key}), 0o600))
+	keySet, err := NewFileKeySet(path)
+	require.NoError(t, err)
+	verifier, err := NewVerifier(VerifierOptions{KeySet: keySet})
+	require.NoError(t, err)
+
+	// Act
+	claims, err := verifier.Verify(context.Background(), mintRS256(t, key, "key-1", jwt.MapClaims{"scope": "admin"}))
+	_, errUnknownKey := verifier.Verify(context.Background(), mintRS256(t, key, "key-2", jwt.MapClaims{}))
+	_, errOtherKey := verifier.Verify(context.Background(), mintRS256(t, newRSAKey(t), "key-1", jwt.MapClaims{}))
+	hs256, _ := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{"exp": time.Now().Add(time.Hour).Unix()}).
+		SignedString(testSecret)
+	_, errHS256 := verifier.Verify(context.Background(), hs256)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, []string{ScopeAdmin}, claims.Scopes)
+	assert.ErrorIs(t, errUnknownKey, ErrUnknownKey)
+	assert.ErrorIs(t, errOtherKey, ErrInvalidToken)
+	assert.ErrorIs(t, errHS256, ErrInvalidToken)
+}
+
+// TestURLKeySetRefreshesOnRotation tests that an unknown key ID fetches the JWKS again, at most once a minute
+func TestURLKeySetRefreshesOnRotation(t *testing.T) {
+	// Arrange
+	oldKey, newKey := newRSAKey(t), newRSAKey(t)
+	published := map[string]*rsa.PrivateKey{"old": oldKey}
+	var fetches atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		fetches.Add(1)
+		_, _ = w.Write(jwksJSON(t, published))
+	}))
+	defer server.Close()
+
+	now := time.Now()
+	keySet := NewURLKeySet(server.Client(), server.URL, time.Hour)
+	keySet.now = func() time.Time { return now }
+	verifier, err := NewVerifier(VerifierOptions{KeySet: keySet})
+	require.NoError(t, err)
+
+	// Act & Assert
+	_, err = verifier.Verify(context.Background(), mintRS256(t, oldKey, "old", jwt.MapClaims{}))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), fetches.Load())
+
+	published = map[string]*rsa.PrivateKey{"old": oldKey, "new": newKey}
+	_, err = verifier.Verify(context.Background(), mintRS256(t, newKey, "new", jwt.MapClaims{}))
+	assert.ErrorIs(t, err, ErrUnknownKey, "the JWKS was fetched less than a minute ago")
+	assert.Equal(t, int32(1), fetches.Load())
+
+	now = now.Add(time.Minute)
+	_, err = verifier.Verify(context.Background(), mintRS256(t, newKey, "new", jwt.MapClaims{}))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), fetches.Load())
+
+	_, err = verifier.Verify(context.Background(), mintRS256(t, oldKey, "old", jwt.MapClaims{}))
+	assert.NoError(t, err)
+	assert.Equal(t, int32(2), fetches.Load())
+}
+
+// TestParseScopeMap tests the parsing of the route and role scope settings
+func TestParseScopeMap(t *testing.T) {
+	// Act
+	scopes, err := ParseScopeMap("/v1/temperatures/batch=batch:write, operator=admin  batch:write,")
+	_, errMissingScope := ParseScopeMap("/v1/graphql=")
+	_, errMissingName := ParseScopeMap("weather:read")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, map[string][]string{
+		"/v1/temperatures/batch": {ScopeBatchWrite},
+		"operator":               {ScopeAdmin, ScopeBatchWrite},
+	}, scopes)
+	assert.Error(t, errMissingScope)
+	assert.Error(t, errMissingName)
+}
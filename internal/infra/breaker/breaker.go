@@ -0,0 +1,176 @@
+package breaker
+
+import (
+	"errors"
+	"sync"
+	"time"
+)
+
+// State is the state of a circuit breaker
+type State int
+
+const (
+	// StateClosed lets every call through
+	StateClosed State = iota
+	// StateHalfOpen lets a single probe through to test the upstream
+	StateHalfOpen
+	// StateOpen rejects every call until the open duration elapses
+	StateOpen
+)
+
+func (s State) String() string {
+	switch s {
+	case StateClosed:
+		return "closed"
+	case StateHalfOpen:
+		return "half_open"
+	default:
+		return "open"
+	}
+}
+
+// ErrOpen is returned when the breaker rejects a call
+var ErrOpen = errors.New("circuit breaker is open")
+
+// Settings configure a circuit breaker
+type Settings struct {
+	// FailureThreshold is the number of consecutive failures that opens the breaker
+	FailureThreshold int
+	// OpenDuration is how long the breaker rejects calls before probing the upstream
+	OpenDuration time.Duration
+	// HalfOpenProbes is the number of consecutive successful probes that closes the breaker
+	HalfOpenProbes int
+	// OnStateChange is called, without the lock held, after every transition
+	OnStateChange func(name string, from, to State)
+}
+
+// Breaker stops calling a failing upstream for a while, then probes it before letting the traffic through again
+type Breaker struct {
+	name     string
+	settings Settings
+	now      func() time.Time
+
+	mu         sync.Mutex
+	state      State
+	generation uint64
+	failures   int
+	successes  int
+	probing    bool
+	openedAt   time.Time
+}
+
+// New creates a closed circuit breaker
+func New(name string, settings Settings) *Breaker {
+	if settings.FailureThreshold < 1 {
+		settings.FailureThreshold = 1
+	}
+	if settings.HalfOpenProbes < 1 {
+		settings.HalfOpenProbes = 1
+	}
+
+	return &Breaker{
+		name:     name,
+		settings: settings,
+		now:      time.Now,
+	}
+}
+
+// Name returns the name of the breaker
+func (b *Breaker) Name() string {
+	return b.name
+}
+
+// State returns the current state, reporting half-open once the open duration has elapsed
+func (b *Breaker) State() State {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.settings.OpenDuration {
+		return StateHalfOpen
+	}
+	return b.state
+}
+
+// Allow reports whether a call may proceed. When it may, the returned function must be
+// called with the outcome of the call.
+func (b *Breaker) Allow() (func(failed bool), error) {
+	b.mu.Lock()
+
+	from := b.state
+	if b.state == StateOpen && b.now().Sub(b.openedAt) >= b.settings.OpenDuration {
+		b.transition(StateHalfOpen)
+	}
+
+	switch {
+	case b.state == StateOpen, b.state == StateHalfOpen && b.probing:
+		b.mu.Unlock()
+		b.notify(from)
+		return nil, ErrOpen
+	case b.state == StateHalfOpen:
+		b.probing = true
+	}
+
+	generation := b.generation
+	b.mu.Unlock()
+	b.notify(from)
+
+	return func(failed bool) { b.record(generation, failed) }, nil
+}
+
+// record updates the breaker with the outcome of a call, ignoring calls started before the last transition
+func (b *Breaker) record(generation uint64, failed bool) {
+	b.mu.Lock()
+
+	from := b.state
+	if generation != b.generation {
+		b.mu.Unlock()
+		return
+	}
+
+	switch b.state {
+	case StateClosed:
+		if !failed {
+			b.failures = 0
+		} else if b.failures++; b.failures >= b.settings.FailureThreshold {
+			b.transition(StateOpen)
+		}
+
+	case StateHalfOpen:
+		b.probing = false
+		if failed {
+			b.transition(StateOpen)
+		} else if b.successes++; b.successes >= b.settings.HalfOpenProbes {
+			b.transition(StateClosed)
+		}
+	}
+
+	b.mu.Unlock()
+	b.notify(from)
+}
+
+// transition moves to the new state and resets the counters, with the lock held
+func (b *Breaker) transition(to State) {
+	b.state = to
+	b.generation++
+	b.failures = 0
+	b.successes = 0
+	b.probing = false
+	if to == StateOpen {
+		b.openedAt = b.now()
+	}
+}
+
+// notify reports the transition from the given state, if any, without the lock held
+func (b *Breaker) notify(from State) {
+	if b.settings.OnStateChange == nil {
+		return
+	}
+
+	b.mu.Lock()
+	to := b.state
+	b.mu.Unlock()
+
+	if to != from {
+		b.settings.OnStateChange(b.name, from, to)
+	}
+}
@@ -0,0 +1,112 @@
+package breaker
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type transition struct {
+	from, to State
+}
+
+// newTestBreaker creates a breaker with a controllable clock that records its transitions
+func newTestBreaker(settings Settings) (*Breaker, *time.Time, *[]transition) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	var transitions []transition
+	settings.OnStateChange = func(name string, from, to State) {
+		transitions = append(transitions, transition{from, to})
+	}
+
+	b := New("upstream", settings)
+	b.now = func() time.Time { return now }
+	return b, &now, &transitions
+}
+
+// call runs a call through the breaker with the given outcome
+func call(t *testing.T, b *Breaker, failed bool) {
+	t.Helper()
+	done, err := b.Allow()
+	require.NoError(t, err)
+	done(failed)
+}
+
+// TestBreakerOpensAfterConsecutiveFailures tests that only consecutive failures open the breaker
+func TestBreakerOpensAfterConsecutiveFailures(t *testing.T) {
+	// Arrange
+	b, _, transitions := newTestBreaker(Settings{FailureThreshold: 3, OpenDuration: time.Minute})
+
+	// Act
+	call(t, b, true)
+	call(t, b, true)
+	call(t, b, false)
+	call(t, b, true)
+	call(t, b, true)
+	assert.Equal(t, StateClosed, b.State())
+	call(t, b, true)
+
+	// Assert
+	assert.Equal(t, StateOpen, b.State())
+	_, err := b.Allow()
+	assert.ErrorIs(t, err, ErrOpen)
+	assert.Equal(t, []transition{{StateClosed, StateOpen}}, *transitions)
+}
+
+// TestBreakerHalfOpenProbes tests that a single probe is let through after the open duration
+func TestBreakerHalfOpenProbes(t *testing.T) {
+	// Arrange
+	b, now, transitions := newTestBreaker(Settings{FailureThreshold: 1, OpenDuration: time.Minute, HalfOpenProbes: 2})
+	call(t, b, true)
+
+	// Act
+	*now = now.Add(time.Minute)
+	assert.Equal(t, StateHalfOpen, b.State())
+	done, err := b.Allow()
+	require.NoError(t, err)
+	_, concurrentErr := b.Allow()
+	done(false)
+	call(t, b, false)
+
+	// Assert
+	assert.ErrorIs(t, concurrentErr, ErrOpen)
+	assert.Equal(t, StateClosed, b.State())
+	assert.Equal(t, []transition{
+		{StateClosed, StateOpen},
+		{StateOpen, StateHalfOpen},
+		{StateHalfOpen, StateClosed},
+	}, *transitions)
+}
+
+// TestBreakerReopensWhenProbeFails tests that a failed probe opens the breaker for another period
+func TestBreakerReopensWhenProbeFails(t *testing.T) {
+	// Arrange
+	b, now, _ := newTestBreaker(Settings{FailureThreshold: 1, OpenDuration: time.Minute})
+	call(t, b, true)
+	*now = now.Add(time.Minute)
+
+	// Act
+	call(t, b, true)
+
+	// Assert
+	assert.Equal(t, StateOpen, b.State())
+	*now = now.Add(time.Minute - time.Second)
+	_, err := b.Allow()
+	assert.ErrorIs(t, err, ErrOpen)
+}
+
+// TestBreakerIgnoresStaleResults tests that calls started before a transition do not affect the new state
+func TestBreakerIgnoresStaleResults(t *testing.T) {
+	// Arrange
+	b, _, _ := newTestBreaker(Settings{FailureThreshold: 1, OpenDuration: time.Minute})
+	slow, err := b.Allow()
+	require.NoError(t, err)
+	call(t, b, true)
+
+	// Act
+	slow(false)
+
+	// Assert
+	assert.Equal(t, StateOpen, b.State())
+}
@@ -0,0 +1,115 @@
+package cache
+
+import (
+	"container/list"
+	"sync"
+	"time"
+)
+
+// Cache is an in-memory cache with a TTL per entry and a bounded size,
+// evicting the least recently used entry when full
+type Cache[K comparable, V any] struct {
+	maxEntries int
+	now        func() time.Time
+
+	mu      sync.Mutex
+	ttl     time.Duration
+	entries map[K]*list.Element
+	order   *list.List
+}
+
+type entry[K comparable, V any] struct {
+	key       K
+	value     V
+	expiresAt time.Time
+}
+
+// New creates a cache. A maxEntries of zero or less means unbounded.
+func New[K comparable, V any](ttl time.Duration, maxEntries int) *Cache[K, V] {
+	return &Cache[K, V]{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		now:        time.Now,
+		entries:    make(map[K]*list.Element),
+		order:      list.New(),
+	}
+}
+
+// SetTTL changes the TTL of the entries set from now on
+func (c *Cache[K, V]) SetTTL(ttl time.Duration) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.ttl = ttl
+}
+
+// Get returns a fresh value and its expiration time
+func (c *Cache[K, V]) Get(key K) (V, time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	element, ok := c.entries[key]
+	if !ok {
+		return zero, time.Time{}, false
+	}
+
+	e := element.Value.(*entry[K, V])
+	if !c.now().Before(e.expiresAt) {
+		return zero, time.Time{}, false
+	}
+
+	c.order.MoveToFront(element)
+	return e.value, e.expiresAt, true
+}
+
+// GetStale returns a value that expired less than maxStale ago, or a fresh one, and its expiration time
+func (c *Cache[K, V]) GetStale(key K, maxStale time.Duration) (V, time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	var zero V
+	element, ok := c.entries[key]
+	if !ok {
+		return zero, time.Time{}, false
+	}
+
+	e := element.Value.(*entry[K, V])
+	if !c.now().Before(e.expiresAt.Add(maxStale)) {
+		return zero, time.Time{}, false
+	}
+
+	return e.value, e.expiresAt, true
+}
+
+// Set stores the value and returns its expiration time
+func (c *Cache[K, V]) Set(key K, value V) time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	expiresAt := c.now().Add(c.ttl)
+	if element, ok := c.entries[key]; ok {
+		e := element.Value.(*entry[K, V])
+		e.value = value
+		e.expiresAt = expiresAt
+		c.order.MoveToFront(element)
+		return expiresAt
+	}
+
+	c.entries[key] = c.order.PushFront(&entry[K, V]{key: key, value: value, expiresAt: expiresAt})
+	if c.maxEntries > 0 && c.order.Len() > c.maxEntries {
+		oldest := c.order.Back()
+		c.order.Remove(oldest)
+		delete(c.entries, oldest.Value.(*entry[K, V]).key)
+	}
+
+	return expiresAt
+}
+
+// Len returns the number of entries, including expired ones not yet evicted
+func (c *Cache[K, V]) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	return c.order.Len()
+}
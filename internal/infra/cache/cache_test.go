@@ -0,0 +1,73 @@
+package cache
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCacheExpiresEntries tests that entries are only returned while fresh
+func TestCacheExpiresEntries(t *testing.T) {
+	// Arrange
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	c := New[string, int](time.Minute, 10)
+	c.now = func() time.Time { return now }
+
+	// Act
+	expiresAt := c.Set("a", 1)
+
+	// Assert
+	value, gotExpiresAt, ok := c.Get("a")
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+	assert.Equal(t, now.Add(time.Minute), expiresAt)
+	assert.Equal(t, expiresAt, gotExpiresAt)
+
+	now = now.Add(time.Minute)
+	_, _, ok = c.Get("a")
+	assert.False(t, ok)
+}
+
+// TestCacheEvictsLeastRecentlyUsed tests the size bound
+func TestCacheEvictsLeastRecentlyUsed(t *testing.T) {
+	// Arrange
+	c := New[string, int](time.Minute, 2)
+	c.Set("a", 1)
+	c.Set("b", 2)
+
+	// Act
+	c.Get("a")
+	c.Set("c", 3)
+
+	// Assert
+	_, _, okA := c.Get("a")
+	_, _, okB := c.Get("b")
+	_, _, okC := c.Get("c")
+	assert.True(t, okA)
+	assert.False(t, okB)
+	assert.True(t, okC)
+	assert.Equal(t, 2, c.Len())
+}
+
+// TestCacheGetStale tests that expired entries are returned within the stale window
+func TestCacheGetStale(t *testing.T) {
+	// Arrange
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	c := New[string, int](time.Minute, 10)
+	c.now = func() time.Time { return now }
+	expiresAt := c.Set("a", 1)
+
+	// Act
+	now = now.Add(90 * time.Second)
+	value, gotExpiresAt, ok := c.GetStale("a", time.Minute)
+
+	// Assert
+	assert.True(t, ok)
+	assert.Equal(t, 1, value)
+	assert.Equal(t, expiresAt, gotExpiresAt)
+
+	now = now.Add(30 * time.Second)
+	_, _, ok = c.GetStale("a", time.Minute)
+	assert.False(t, ok)
+}
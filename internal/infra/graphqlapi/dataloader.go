@@ -0,0 +1,105 @@
+package graphqlapi
+
+import "sync"
+
+// BatchFunc fetches the values for a set of distinct keys, returning one result per key
+type BatchFunc[K comparable, V any] func(keys []K) map[K]Result[V]
+
+// Result holds the value or error loaded for a key
+type Result[V any] struct {
+	Value V
+	Err   error
+}
+
+// Loader collects the keys requested while a query level is being resolved and fetches
+// them in one deduplicated batch when the first value is needed. A loader caches its
+// results and must be scoped to a single request.
+type Loader[K comparable, V any] struct {
+	fetch BatchFunc[K, V]
+
+	mu      sync.Mutex
+	pending []K
+	entries map[K]*loaderEntry[V]
+}
+
+type loaderEntry[V any] struct {
+	done   chan struct{}
+	result Result[V]
+}
+
+// NewLoader creates a new loader
+func NewLoader[K comparable, V any](fetch BatchFunc[K, V]) *Loader[K, V] {
+	return &Loader[K, V]{
+		fetch:   fetch,
+		entries: make(map[K]*loaderEntry[V]),
+	}
+}
+
+// Load queues the key and returns a thunk that resolves it, dispatching the pending batch if needed
+func (l *Loader[K, V]) Load(key K) func() (V, error) {
+	l.mu.Lock()
+	entry, ok := l.entries[key]
+	if !ok {
+		entry = &loaderEntry[V]{done: make(chan struct{})}
+		l.entries[key] = entry
+		l.pending = append(l.pending, key)
+	}
+	l.mu.Unlock()
+
+	return func() (V, error) {
+		l.dispatch()
+		<-entry.done
+		return entry.result.Value, entry.result.Err
+	}
+}
+
+// dispatch fetches every pending key in a single batch
+func (l *Loader[K, V]) dispatch() {
+	l.mu.Lock()
+	keys := l.pending
+	l.pending = nil
+	l.mu.Unlock()
+
+	if len(keys) == 0 {
+		return
+	}
+
+	results := l.fetch(keys)
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	for _, key := range keys {
+		entry := l.entries[key]
+		entry.result = results[key]
+		close(entry.done)
+	}
+}
+
+// parallel runs fn for every key concurrently, holding one of the slots during each call, and
+// collects the results. A nil slots channel does not bound the calls.
+func parallel[K comparable, V any](keys []K, slots chan struct{}, fn func(key K) (V, error)) map[K]Result[V] {
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	results := make(map[K]Result[V], len(keys))
+
+	for _, key := range keys {
+		wg.Add(1)
+		go func(key K) {
+			defer wg.Done()
+
+			if slots != nil {
+				slots <- struct{}{}
+				defer func() { <-slots }()
+			}
+			value, err := fn(key)
+
+			mu.Lock()
+			results[key] = Result[V]{Value: value, Err: err}
+			mu.Unlock()
+		}(key)
+	}
+	wg.Wait()
+
+	return results
+}
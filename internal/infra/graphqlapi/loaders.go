@@ -0,0 +1,158 @@
+package graphqlapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/xavierpms/weather-by-city/internal/domain"
+)
+
+type loadersKey struct{}
+
+// forecastKey identifies a forecast by place (CEP or city) and number of days
+type forecastKey struct {
+	place string
+	days  int
+}
+
+// loaders holds the request-scoped loaders used by the resolvers
+type loaders struct {
+	cep           *Loader[string, *domain.CEPData]
+	temperature   *Loader[string, *domain.Temperature]
+	forecast      *Loader[forecastKey, []domain.ForecastDay]
+	currentByCEP  *Loader[string, *domain.Temperature]
+	forecastByCEP *Loader[forecastKey, []domain.ForecastDay]
+}
+
+// newLoaders creates the loaders for a single request, bound to its context. The upstream calls
+// of the request share the same slots, so they never exceed the configured concurrency, and
+// every CEP goes through the cep loader, which resolves at most maxCEPs distinct CEPs.
+func (s *Service) newLoaders(ctx context.Context) *loaders {
+	l := &loaders{}
+	var slots chan struct{}
+	if s.concurrency > 0 {
+		slots = make(chan struct{}, s.concurrency)
+	}
+	var mu sync.Mutex
+	admitted := 0
+
+	l.cep = NewLoader(func(ceps []string) map[string]Result[*domain.CEPData] {
+		// The loader fetches each CEP once, so counting the fetched keys counts the distinct CEPs
+		mu.Lock()
+		accepted := ceps
+		if s.maxCEPs > 0 {
+			accepted = ceps[:max(min(len(ceps), s.maxCEPs-admitted), 0)]
+		}
+		admitted += len(accepted)
+		mu.Unlock()
+
+		results := parallel(accepted, slots, func(cep string) (*domain.CEPData, error) {
+			cepData, err := s.cepRepository.GetCEPData(ctx, cep)
+			if err != nil && !errors.Is(err, domain.ErrCEPNotFound) && !errors.Is(err, domain.ErrUpstreamUnavailable) {
+				// Like the use case, only the provider saying the CEP does not exist is a miss
+				return nil, fmt.Errorf("%w: %w", domain.ErrUpstreamUnavailable, err)
+			}
+			if err != nil {
+				return nil, err
+			}
+			return cepData, nil
+		})
+		for _, cep := range ceps[len(accepted):] {
+			results[cep] = Result[*domain.CEPData]{Err: &QueryError{
+				Code:    codeTooManyZipcodes,
+				Message: fmt.Sprintf("the query asks for more than %d zipcodes", s.maxCEPs),
+			}}
+		}
+		return results
+	})
+
+	l.temperature = NewLoader(func(cities []string) map[string]Result[*domain.Temperature] {
+		return parallel(cities, slots, func(city string) (*domain.Temperature, error) {
+			temperature, err := s.temperatureRepository.GetTemperatureByCityName(ctx, city)
+			if err != nil {
+				return nil, orUnavailable(err, domain.ErrTemperatureNotFound)
+			}
+			return temperature, nil
+		})
+	})
+
+	l.forecast = NewLoader(func(keys []forecastKey) map[forecastKey]Result[[]domain.ForecastDay] {
+		return parallel(keys, slots, func(key forecastKey) ([]domain.ForecastDay, error) {
+			forecast, err := s.forecastRepository.GetForecastByCityName(ctx, key.place, key.days)
+			if err != nil {
+				return nil, orUnavailable(err, domain.ErrForecastNotFound)
+			}
+			return forecast, nil
+		})
+	})
+
+	// The CEP-keyed loaders resolve every address first, so that the cities of the
+	// whole batch are queued together before the weather lookups are dispatched
+	l.currentByCEP = NewLoader(func(ceps []string) map[string]Result[*domain.Temperature] {
+		cepThunks := make(map[string]func() (*domain.CEPData, error), len(ceps))
+		for _, cep := range ceps {
+			cepThunks[cep] = l.cep.Load(cep)
+		}
+
+		results := make(map[string]Result[*domain.Temperature], len(ceps))
+		temperatureThunks := make(map[string]func() (*domain.Temperature, error), len(ceps))
+		for cep, thunk := range cepThunks {
+			cepData, err := thunk()
+			if err != nil {
+				results[cep] = Result[*domain.Temperature]{Err: err}
+				continue
+			}
+			temperatureThunks[cep] = l.temperature.Load(cepData.City)
+		}
+
+		for cep, thunk := range temperatureThunks {
+			temperature, err := thunk()
+			results[cep] = Result[*domain.Temperature]{Value: temperature, Err: err}
+		}
+
+		return results
+	})
+
+	l.forecastByCEP = NewLoader(func(keys []forecastKey) map[forecastKey]Result[[]domain.ForecastDay] {
+		cepThunks := make(map[forecastKey]func() (*domain.CEPData, error), len(keys))
+		for _, key := range keys {
+			cepThunks[key] = l.cep.Load(key.place)
+		}
+
+		results := make(map[forecastKey]Result[[]domain.ForecastDay], len(keys))
+		forecastThunks := make(map[forecastKey]func() ([]domain.ForecastDay, error), len(keys))
+		for key, thunk := range cepThunks {
+			cepData, err := thunk()
+			if err != nil {
+				results[key] = Result[[]domain.ForecastDay]{Err: err}
+				continue
+			}
+			forecastThunks[key] = l.forecast.Load(forecastKey{place: cepData.City, days: key.days})
+		}
+
+		for key, thunk := range forecastThunks {
+			forecast, err := thunk()
+			results[key] = Result[[]domain.ForecastDay]{Value: forecast, Err: err}
+		}
+
+		return results
+	})
+
+	return l
+}
+
+// orUnavailable keeps the upstream unavailability, which is not a lookup miss, and maps any other error to notFound
+func orUnavailable(err, notFound error) error {
+	if errors.Is(err, domain.ErrUpstreamUnavailable) {
+		return err
+	}
+	return notFound
+}
+
+// loadersFromContext returns the loaders of the current request
+func loadersFromContext(ctx context.Context) *loaders {
+	l, _ := ctx.Value(loadersKey{}).(*loaders)
+	return l
+}
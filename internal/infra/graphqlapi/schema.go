@@ -0,0 +1,346 @@
+package graphqlapi
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/graphql-go/graphql"
+	"github.com/graphql-go/graphql/gqlerrors"
+	"github.com/graphql-go/graphql/language/ast"
+	"github.com/graphql-go/graphql/language/kinds"
+	"github.com/graphql-go/graphql/language/location"
+	"github.com/graphql-go/graphql/language/parser"
+	"github.com/graphql-go/graphql/language/visitor"
+	"github.com/xavierpms/weather-by-city/internal/domain"
+)
+
+const (
+	defaultForecastDays = 3
+	maxForecastDays     = 14
+
+	codeTooManyZipcodes = "TOO_MANY_ZIPCODES"
+)
+
+// Service executes GraphQL queries over the CEP, temperature and forecast repositories
+type Service struct {
+	schema graphql.Schema
+
+	cepRepository         domain.CEPRepository
+	temperatureRepository domain.TemperatureRepository
+	forecastRepository    domain.ForecastRepository
+	cepValidator          domain.CEPValidator
+	maxCEPs               int
+	concurrency           int
+}
+
+// QueryError represents a resolver error with a stable code exposed in the error extensions
+type QueryError struct {
+	Code    string
+	Message string
+	// Reason refines the code, such as wrong_length for INVALID_ZIPCODE
+	Reason string
+}
+
+func (e *QueryError) Error() string {
+	return e.Message
+}
+
+// Extensions implements gqlerrors.ExtendedError
+func (e *QueryError) Extensions() map[string]interface{} {
+	if e.Reason != "" {
+		return map[string]interface{}{"code": e.Code, "reason": e.Reason}
+	}
+	return map[string]interface{}{"code": e.Code}
+}
+
+// NewService creates a new GraphQL service. Like the batch endpoint, a query may ask for at most
+// maxCEPs distinct CEPs and makes at most concurrency upstream calls at a time; zero disables a limit.
+func NewService(
+	cepRepo domain.CEPRepository,
+	tempRepo domain.TemperatureRepository,
+	forecastRepo domain.ForecastRepository,
+	validator domain.CEPValidator,
+	maxCEPs, concurrency int,
+) (*Service, error) {
+	s := &Service{
+		cepRepository:         cepRepo,
+		temperatureRepository: tempRepo,
+		forecastRepository:    forecastRepo,
+		cepValidator:          validator,
+		maxCEPs:               maxCEPs,
+		concurrency:           concurrency,
+	}
+
+	schema, err := s.buildSchema()
+	if err != nil {
+		return nil, err
+	}
+	s.schema = schema
+
+	return s, nil
+}
+
+// Execute runs a query with request-scoped loaders, refusing upfront the queries asking for too
+// many CEPs. The loaders enforce the same cap on the CEPs actually resolved.
+func (s *Service) Execute(ctx context.Context, query string, variables map[string]interface{}, operationName string) *graphql.Result {
+	if count := s.distinctCEPs(query, variables); s.maxCEPs > 0 && count > s.maxCEPs {
+		return &graphql.Result{Errors: []gqlerrors.FormattedError{{
+			Message:    fmt.Sprintf("the query asks for %d zipcodes, at most %d are accepted", count, s.maxCEPs),
+			Locations:  []location.SourceLocation{},
+			Extensions: map[string]interface{}{"code": codeTooManyZipcodes},
+		}}}
+	}
+
+	ctx = context.WithValue(ctx, loadersKey{}, s.newLoaders(ctx))
+
+	return graphql.Do(graphql.Params{
+		Schema:         s.schema,
+		RequestString:  query,
+		VariableValues: variables,
+		OperationName:  operationName,
+		Context:        ctx,
+	})
+}
+
+// distinctCEPs counts the valid distinct CEPs of the cep arguments, given literally or by a variable,
+// supplied or defaulted. Queries that fail to parse count none and are reported by the execution.
+func (s *Service) distinctCEPs(query string, variables map[string]interface{}) int {
+	document, err := parser.Parse(parser.ParseParams{Source: query})
+	if err != nil {
+		return 0
+	}
+
+	values := make(map[string]interface{}, len(variables))
+	for _, definition := range document.Definitions {
+		operation, ok := definition.(*ast.OperationDefinition)
+		if !ok {
+			continue
+		}
+		for _, variable := range operation.VariableDefinitions {
+			if defaultValue, ok := variable.DefaultValue.(*ast.StringValue); ok && variable.Variable != nil {
+				values[variable.Variable.Name.Value] = defaultValue.Value
+			}
+		}
+	}
+	for name, value := range variables {
+		values[name] = value
+	}
+
+	ceps := make(map[string]bool)
+	visitor.Visit(document, &visitor.VisitorOptions{
+		KindFuncMap: map[string]visitor.NamedVisitFuncs{
+			kinds.Argument: {Kind: func(p visitor.VisitFuncParams) (string, interface{}) {
+				argument, ok := p.Node.(*ast.Argument)
+				if !ok || argument.Name == nil || argument.Name.Value != "cep" {
+					return visitor.ActionNoChange, nil
+				}
+
+				var raw string
+				switch value := argument.Value.(type) {
+				case *ast.StringValue:
+					raw = value.Value
+				case *ast.Variable:
+					raw, _ = values[value.Name.Value].(string)
+				}
+				if cep, err := s.cepValidator.NormalizeCEP(raw); err == nil {
+					ceps[cep] = true
+				}
+				return visitor.ActionNoChange, nil
+			}},
+		},
+	}, nil)
+
+	return len(ceps)
+}
+
+func (s *Service) buildSchema() (graphql.Schema, error) {
+	temperatureType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Temperature",
+		Fields: graphql.Fields{
+			"celsius":    &graphql.Field{Type: graphql.NewNonNull(graphql.Float), Resolve: field(func(t *domain.Temperature) interface{} { return t.Celsius })},
+			"fahrenheit": &graphql.Field{Type: graphql.NewNonNull(graphql.Float), Resolve: field(func(t *domain.Temperature) interface{} { return t.Fahrenheit })},
+			"kelvin":     &graphql.Field{Type: graphql.NewNonNull(graphql.Float), Resolve: field(func(t *domain.Temperature) interface{} { return t.Kelvin })},
+		},
+	})
+
+	forecastDayType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "ForecastDay",
+		Fields: graphql.Fields{
+			"date":          &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: field(func(d domain.ForecastDay) interface{} { return d.Date })},
+			"minCelsius":    &graphql.Field{Type: graphql.NewNonNull(graphql.Float), Resolve: field(func(d domain.ForecastDay) interface{} { return d.MinCelsius })},
+			"maxCelsius":    &graphql.Field{Type: graphql.NewNonNull(graphql.Float), Resolve: field(func(d domain.ForecastDay) interface{} { return d.MaxCelsius })},
+			"avgCelsius":    &graphql.Field{Type: graphql.NewNonNull(graphql.Float), Resolve: field(func(d domain.ForecastDay) interface{} { return d.AvgCelsius })},
+			"minFahrenheit": &graphql.Field{Type: graphql.NewNonNull(graphql.Float), Resolve: field(func(d domain.ForecastDay) interface{} { return d.MinFahrenheit })},
+			"maxFahrenheit": &graphql.Field{Type: graphql.NewNonNull(graphql.Float), Resolve: field(func(d domain.ForecastDay) interface{} { return d.MaxFahrenheit })},
+			"avgFahrenheit": &graphql.Field{Type: graphql.NewNonNull(graphql.Float), Resolve: field(func(d domain.ForecastDay) interface{} { return d.AvgFahrenheit })},
+			"condition":     &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: field(func(d domain.ForecastDay) interface{} { return d.Condition })},
+		},
+	})
+
+	daysArgument := &graphql.ArgumentConfig{Type: graphql.Int, DefaultValue: defaultForecastDays}
+	cepArgument := &graphql.ArgumentConfig{Type: graphql.NewNonNull(graphql.String)}
+
+	locationType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Location",
+		Fields: graphql.Fields{
+			"cep":           &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: field(func(c *domain.CEPData) interface{} { return c.CEP })},
+			"street":        &graphql.Field{Type: graphql.String, Resolve: field(func(c *domain.CEPData) interface{} { return c.Street })},
+			"neighborhood":  &graphql.Field{Type: graphql.String, Resolve: field(func(c *domain.CEPData) interface{} { return c.Neighborhood })},
+			"city":          &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: field(func(c *domain.CEPData) interface{} { return c.City })},
+			"state":         &graphql.Field{Type: graphql.NewNonNull(graphql.String), Resolve: field(func(c *domain.CEPData) interface{} { return c.Region })},
+			"inferredState": &graphql.Field{Type: graphql.String, Resolve: field(s.inferredState)},
+			"ibge":          &graphql.Field{Type: graphql.String, Resolve: field(func(c *domain.CEPData) interface{} { return c.IBGE })},
+			"ddd":           &graphql.Field{Type: graphql.String, Resolve: field(func(c *domain.CEPData) interface{} { return c.DDD })},
+			"current": &graphql.Field{
+				Type: temperatureType,
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					cepData := p.Source.(*domain.CEPData)
+					return thunk(loadersFromContext(p.Context).temperature.Load(cepData.City)), nil
+				},
+			},
+			"forecast": &graphql.Field{
+				Type: graphql.NewList(graphql.NewNonNull(forecastDayType)),
+				Args: graphql.FieldConfigArgument{"days": daysArgument},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					days, err := forecastDays(p.Args)
+					if err != nil {
+						return nil, err
+					}
+					cepData := p.Source.(*domain.CEPData)
+					return thunk(loadersFromContext(p.Context).forecast.Load(forecastKey{place: cepData.City, days: days})), nil
+				},
+			},
+		},
+	})
+
+	queryType := graphql.NewObject(graphql.ObjectConfig{
+		Name: "Query",
+		Fields: graphql.Fields{
+			"location": &graphql.Field{
+				Type: locationType,
+				Args: graphql.FieldConfigArgument{"cep": cepArgument},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					cep, err := s.cepArgument(p.Args)
+					if err != nil {
+						return nil, err
+					}
+					return thunk(loadersFromContext(p.Context).cep.Load(cep)), nil
+				},
+			},
+			"current": &graphql.Field{
+				Type: temperatureType,
+				Args: graphql.FieldConfigArgument{"cep": cepArgument},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					cep, err := s.cepArgument(p.Args)
+					if err != nil {
+						return nil, err
+					}
+					return thunk(loadersFromContext(p.Context).currentByCEP.Load(cep)), nil
+				},
+			},
+			"forecast": &graphql.Field{
+				Type: graphql.NewList(graphql.NewNonNull(forecastDayType)),
+				Args: graphql.FieldConfigArgument{"cep": cepArgument, "days": daysArgument},
+				Resolve: func(p graphql.ResolveParams) (interface{}, error) {
+					cep, err := s.cepArgument(p.Args)
+					if err != nil {
+						return nil, err
+					}
+					days, err := forecastDays(p.Args)
+					if err != nil {
+						return nil, err
+					}
+					return thunk(loadersFromContext(p.Context).forecastByCEP.Load(forecastKey{place: cep, days: days})), nil
+				},
+			},
+		},
+	})
+
+	return graphql.NewSchema(graphql.SchemaConfig{Query: queryType})
+}
+
+// cepArgument returns the cep argument normalized to its 8 digits
+func (s *Service) cepArgument(args map[string]interface{}) (string, error) {
+	cep, _ := args["cep"].(string)
+	cep, err := s.cepValidator.NormalizeCEP(cep)
+	if err != nil {
+		return "", toQueryError(err)
+	}
+
+	return cep, nil
+}
+
+// inferredState returns the UF of the Correios range holding the CEP of the location, or nil
+func (s *Service) inferredState(c *domain.CEPData) interface{} {
+	cep, err := s.cepValidator.NormalizeCEP(c.CEP)
+	if err != nil {
+		return nil
+	}
+	if uf, ok := s.cepValidator.InferUF(cep); ok {
+		return uf
+	}
+
+	return nil
+}
+
+// forecastDays returns the validated days argument
+func forecastDays(args map[string]interface{}) (int, error) {
+	days, ok := args["days"].(int)
+	if !ok {
+		days = defaultForecastDays
+	}
+
+	if days < 1 || days > maxForecastDays {
+		return 0, toQueryError(domain.ErrInvalidForecastDays)
+	}
+
+	return days, nil
+}
+
+// thunk adapts a loader thunk to the deferred resolver signature of graphql-go
+func thunk[V any](load func() (V, error)) func() (interface{}, error) {
+	return func() (interface{}, error) {
+		value, err := load()
+		if err != nil {
+			return nil, toQueryError(err)
+		}
+		return value, nil
+	}
+}
+
+// field builds a resolver that reads a value from a typed source
+func field[T any](get func(source T) interface{}) graphql.FieldResolveFn {
+	return func(p graphql.ResolveParams) (interface{}, error) {
+		return get(p.Source.(T)), nil
+	}
+}
+
+// toQueryError maps domain errors to GraphQL errors with stable codes
+func toQueryError(err error) error {
+	var queryErr *QueryError
+	switch {
+	case errors.As(err, &queryErr):
+		return queryErr
+	case errors.Is(err, domain.ErrInvalidCEPFormat):
+		var formatErr *domain.CEPFormatError
+		if errors.As(err, &formatErr) {
+			return &QueryError{Code: "INVALID_ZIPCODE", Message: "invalid zipcode", Reason: string(formatErr.Reason)}
+		}
+		return &QueryError{Code: "INVALID_ZIPCODE", Message: "invalid zipcode"}
+	case errors.Is(err, domain.ErrInvalidForecastDays):
+		return &QueryError{Code: "INVALID_FORECAST_DAYS", Message: "days must be between 1 and 14"}
+	case errors.Is(err, domain.ErrCEPNotFound):
+		return &QueryError{Code: "ZIPCODE_NOT_FOUND", Message: "can not find zipcode"}
+	case errors.Is(err, domain.ErrTemperatureNotFound):
+		return &QueryError{Code: "TEMPERATURE_UNAVAILABLE", Message: "can not fetch temperature"}
+	case errors.Is(err, domain.ErrForecastNotFound):
+		return &QueryError{Code: "FORECAST_UNAVAILABLE", Message: "can not fetch forecast"}
+	case errors.Is(err, domain.ErrUpstreamQuotaExceeded):
+		return &QueryError{Code: "UPSTREAM_QUOTA_EXCEEDED", Message: "weather provider quota exceeded, try again later"}
+	case errors.Is(err, domain.ErrUpstreamUnavailable):
+		return &QueryError{Code: "UPSTREAM_UNAVAILABLE", Message: "upstream service unavailable, try again later"}
+	default:
+		return &QueryError{Code: "INTERNAL", Message: "internal server error"}
+	}
+}
@@ -0,0 +1,265 @@
+package graphqlapi
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xavierpms/weather-by-city/internal/domain"
+	"github.com/xavierpms/weather-by-city/internal/infra/validator"
+)
+
+// countingRepository records the calls to every repository and the most CEP lookups in flight
+type countingRepository struct {
+	mu          sync.Mutex
+	calls       map[string]int
+	inFlight    int
+	maxInFlight int
+	delay       time.Duration
+}
+
+func (r *countingRepository) count(key string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls[key]++
+}
+
+func (r *countingRepository) GetCEPData(ctx context.Context, cep string) (*domain.CEPData, error) {
+	r.count("cep:" + cep)
+	r.mu.Lock()
+	r.inFlight++
+	r.maxInFlight = max(r.maxInFlight, r.inFlight)
+	r.mu.Unlock()
+	time.Sleep(r.delay)
+	r.mu.Lock()
+	r.inFlight--
+	r.mu.Unlock()
+
+	cities := map[string]string{
+		"01001000": "São Paulo",
+		"01310100": "São Paulo",
+		"32450000": "Ibirité",
+	}
+	city, ok := cities[cep]
+	if !ok {
+		return nil, domain.ErrCEPNotFound
+	}
+	return &domain.CEPData{CEP: cep, City: city, Region: "SP"}, nil
+}
+
+func (r *countingRepository) GetTemperatureByCityName(ctx context.Context, city string) (*domain.Temperature, error) {
+	r.count("temperature:" + city)
+	return &domain.Temperature{Celsius: 28.5, Fahrenheit: 83.3, Kelvin: 301.65}, nil
+}
+
+func (r *countingRepository) GetForecastByCityName(ctx context.Context, city string, days int) ([]domain.ForecastDay, error) {
+	r.count("forecast:" + city)
+	forecast := make([]domain.ForecastDay, days)
+	for i := range forecast {
+		forecast[i] = domain.ForecastDay{Date: "2026-10-19", MaxCelsius: 30}
+	}
+	return forecast, nil
+}
+
+func newTestService(t *testing.T) (*Service, *countingRepository) {
+	repo := &countingRepository{calls: make(map[string]int)}
+	service, err := NewService(repo, repo, repo, validator.NewCEPValidator(), 3, 2)
+	require.NoError(t, err)
+	return service, repo
+}
+
+// TestExecuteBatchesAndDeduplicatesLookups tests that nested queries collapse into one call per CEP and city
+func TestExecuteBatchesAndDeduplicatesLookups(t *testing.T) {
+	// Arrange
+	service, repo := newTestService(t)
+	query := `{
+		a: location(cep: "01001000") { city current { celsius } forecast(days: 2) { date } }
+		b: location(cep: "01310100") { city current { celsius } forecast(days: 2) { date } }
+		c: location(cep: "01001000") { street }
+		d: current(cep: "32450000") { kelvin }
+		e: current(cep: "01310100") { fahrenheit }
+		f: forecast(cep: "32450000", days: 2) { maxCelsius }
+	}`
+
+	// Act
+	result := service.Execute(context.Background(), query, nil, "")
+
+	// Assert
+	require.Empty(t, result.Errors)
+	data := result.Data.(map[string]interface{})
+	assert.Equal(t, "São Paulo", data["a"].(map[string]interface{})["city"])
+	assert.Len(t, data["b"].(map[string]interface{})["forecast"], 2)
+	assert.Equal(t, 301.65, data["d"].(map[string]interface{})["kelvin"])
+
+	assert.Equal(t, map[string]int{
+		"cep:01001000":          1,
+		"cep:01310100":          1,
+		"cep:32450000":          1,
+		"temperature:São Paulo": 1,
+		"temperature:Ibirité":   1,
+		"forecast:São Paulo":    1,
+		"forecast:Ibirité":      1,
+	}, repo.calls)
+}
+
+// TestExecuteReportsFieldErrors tests that failures are reported per field with stable messages
+func TestExecuteReportsFieldErrors(t *testing.T) {
+	// Arrange
+	service, _ := newTestService(t)
+	query := `{
+		invalid: location(cep: "3245000") { city }
+		missing: current(cep: "99999999") { celsius }
+		ok: location(cep: "32450-000") { city }
+	}`
+
+	// Act
+	result := service.Execute(context.Background(), query, nil, "")
+
+	// Assert
+	messages := make([]string, 0, len(result.Errors))
+	for _, err := range result.Errors {
+		messages = append(messages, err.Message)
+	}
+	assert.ElementsMatch(t, []string{"invalid zipcode", "can not find zipcode"}, messages)
+	for _, err := range result.Errors {
+		if err.Message == "invalid zipcode" {
+			assert.Equal(t, "wrong_length", err.Extensions["reason"])
+		}
+	}
+
+	data := result.Data.(map[string]interface{})
+	assert.Nil(t, data["invalid"])
+	assert.Nil(t, data["missing"])
+	assert.Equal(t, "Ibirité", data["ok"].(map[string]interface{})["city"])
+}
+
+// TestExecuteRejectsInvalidForecastDays tests the forecast days range
+func TestExecuteRejectsInvalidForecastDays(t *testing.T) {
+	// Arrange
+	service, repo := newTestService(t)
+
+	// Act
+	result := service.Execute(context.Background(), `{ forecast(cep: "32450000", days: 15) { date } }`, nil, "")
+
+	// Assert
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "days must be between 1 and 14", result.Errors[0].Message)
+	assert.Equal(t, "INVALID_FORECAST_DAYS", result.Errors[0].Extensions["code"])
+	assert.Empty(t, repo.calls)
+}
+
+// TestExecuteResolvesTheInferredState tests that the UF of the Correios range is exposed next to the provider state
+func TestExecuteResolvesTheInferredState(t *testing.T) {
+	// Arrange
+	service, _ := newTestService(t)
+	query := `{ location(cep: "32450-000") { state inferredState } }`
+
+	// Act
+	result := service.Execute(context.Background(), query, nil, "")
+
+	// Assert
+	require.Empty(t, result.Errors)
+	location := result.Data.(map[string]interface{})["location"].(map[string]interface{})
+	assert.Equal(t, "SP", location["state"])
+	assert.Equal(t, "MG", location["inferredState"])
+}
+
+// TestExecuteRefusesTooManyZipcodes tests that a query asking for more distinct CEPs than allowed is refused before any lookup
+func TestExecuteRefusesTooManyZipcodes(t *testing.T) {
+	// Arrange
+	service, repo := newTestService(t)
+	query := `query($last: String!) {
+		a: location(cep: "01001000") { city }
+		b: current(cep: "01001-000") { celsius }
+		c: location(cep: "01310100") { city }
+		d: forecast(cep: "32450000") { date }
+		e: location(cep: $last) { city }
+	}`
+
+	// Act
+	result := service.Execute(context.Background(), query, map[string]interface{}{"last": "20040002"}, "")
+
+	// Assert
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "TOO_MANY_ZIPCODES", result.Errors[0].Extensions["code"])
+	assert.Equal(t, "the query asks for 4 zipcodes, at most 3 are accepted", result.Errors[0].Message)
+	assert.Nil(t, result.Data)
+	assert.Empty(t, repo.calls)
+}
+
+// TestExecuteBoundsConcurrentLookups tests that the lookups of a query never exceed the configured concurrency
+func TestExecuteBoundsConcurrentLookups(t *testing.T) {
+	// Arrange
+	repo := &countingRepository{calls: make(map[string]int), delay: 20 * time.Millisecond}
+	service, err := NewService(repo, repo, repo, validator.NewCEPValidator(), 10, 2)
+	require.NoError(t, err)
+	query := "{"
+	for i := range 6 {
+		query += fmt.Sprintf(` c%d: location(cep: "0100100%d") { cep }`, i, i)
+	}
+	query += " }"
+
+	// Act
+	result := service.Execute(context.Background(), query, nil, "")
+
+	// Assert
+	require.NotNil(t, result)
+	assert.Len(t, repo.calls, 6)
+	assert.Equal(t, 2, repo.maxInFlight)
+}
+
+// TestExecuteCountsVariableDefaults tests that the CEPs given as variable defaults count toward the limit
+func TestExecuteCountsVariableDefaults(t *testing.T) {
+	// Arrange
+	service, repo := newTestService(t)
+	query := `query($a: String = "01001000", $b: String = "01310100", $c: String = "32450000", $d: String = "20040002") {
+		a: location(cep: $a) { city }
+		b: location(cep: $b) { city }
+		c: location(cep: $c) { city }
+		d: location(cep: $d) { city }
+	}`
+
+	// Act
+	result := service.Execute(context.Background(), query, nil, "")
+
+	// Assert
+	require.Len(t, result.Errors, 1)
+	assert.Equal(t, "TOO_MANY_ZIPCODES", result.Errors[0].Extensions["code"])
+	assert.Empty(t, repo.calls)
+}
+
+// TestLoadersCapResolvedZipcodes tests that the loaders resolve at most the allowed distinct CEPs, whatever the query
+func TestLoadersCapResolvedZipcodes(t *testing.T) {
+	// Arrange
+	service, repo := newTestService(t)
+	l := service.newLoaders(context.Background())
+	ceps := []string{"01001000", "01310100", "32450000", "20040002"}
+	thunks := make([]func() (*domain.CEPData, error), 0, len(ceps))
+	for _, cep := range ceps {
+		thunks = append(thunks, l.cep.Load(cep))
+	}
+
+	// Act
+	var errs []error
+	for _, thunk := range thunks {
+		_, err := thunk()
+		errs = append(errs, err)
+	}
+	_, again := l.cep.Load("01001000")()
+	_, later := l.cep.Load("70040010")()
+
+	// Assert
+	assert.NoError(t, errs[0])
+	assert.NoError(t, errs[1])
+	assert.NoError(t, errs[2])
+	var queryErr *QueryError
+	require.ErrorAs(t, errs[3], &queryErr)
+	assert.Equal(t, "TOO_MANY_ZIPCODES", queryErr.Code)
+	assert.NoError(t, again)
+	assert.ErrorAs(t, later, &queryErr)
+	assert.Len(t, repo.calls, 3)
+}
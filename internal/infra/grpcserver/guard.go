@@ -0,0 +1,243 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"math"
+	"strings"
+
+	"github.com/xavierpms/weather-by-city/internal/domain"
+	"github.com/xavierpms/weather-by-city/internal/infra/auth"
+	"github.com/xavierpms/weather-by-city/internal/infra/ratelimit"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// apiKeyBearerPrefix marks the bearer tokens that are API keys rather than JWTs
+const apiKeyBearerPrefix = "wk_"
+
+// RateLimiter takes a token of a route for a client, as the rate limiter of the HTTP API does
+type RateLimiter interface {
+	Allow(ctx context.Context, route, remoteAddr string, forwardedFor []string, apiKey string) (ratelimit.Decision, ratelimit.Limit, error)
+}
+
+// GuardOptions configure the checks applied to every RPC. A nil field disables its check.
+type GuardOptions struct {
+	// Verifier verifies the bearer JWT sent in the authorization metadata
+	Verifier *auth.Verifier
+	// Scopes maps full method names, such as /weather.v1.WeatherService/BatchGetTemperature, to the
+	// scopes they require; methods without an entry are served without a token
+	Scopes map[string][]string
+	// APIKeys authenticates the API key sent in the x-api-key metadata or as a bearer token, and
+	// enforces its methods and daily quota
+	APIKeys domain.APIKeyUseCase
+	// APIKeysRequired refuses the calls without an API key
+	APIKeysRequired bool
+	// RateLimiter limits the calls of each client per method
+	RateLimiter RateLimiter
+}
+
+// Guard applies to the RPCs the credentials, scopes, quotas and rate limits of the HTTP API. The
+// full method name takes the place of the route pattern.
+type Guard struct {
+	options GuardOptions
+}
+
+// NewGuard creates a guard with the given checks
+func NewGuard(options GuardOptions) *Guard {
+	return &Guard{options: options}
+}
+
+// ServerOptions returns the interceptors guarding the unary and streaming RPCs
+func (g *Guard) ServerOptions() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(g.unary),
+		grpc.ChainStreamInterceptor(g.stream),
+	}
+}
+
+func (g *Guard) unary(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+	ctx, err := g.check(ctx, info.FullMethod)
+	if err != nil {
+		return nil, err
+	}
+	return handler(ctx, req)
+}
+
+func (g *Guard) stream(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+	ctx, err := g.check(ss.Context(), info.FullMethod)
+	if err != nil {
+		return err
+	}
+	return handler(srv, &guardedStream{ServerStream: ss, ctx: ctx})
+}
+
+// check runs the checks in the order of the HTTP API: the credentials, the rate limit, the scopes
+// and then the API key quota, so rejected calls do not count against it. It returns the context
+// carrying the verified credentials.
+func (g *Guard) check(ctx context.Context, method string) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+
+	if g.options.Verifier != nil {
+		if token := bearerJWT(md); token != "" {
+			claims, err := g.options.Verifier.Verify(ctx, token)
+			if err != nil {
+				slog.InfoContext(ctx, "JWT rejected", "err", err)
+				return ctx, status.Error(codes.Unauthenticated, "the token is invalid or expired")
+			}
+			ctx = auth.WithClaims(ctx, claims)
+		}
+	}
+
+	if g.options.APIKeys != nil {
+		var err error
+		if ctx, err = g.authenticateKey(ctx, md); err != nil {
+			return ctx, err
+		}
+	}
+
+	if err := g.limit(ctx, method, md); err != nil {
+		return ctx, err
+	}
+
+	if g.options.Verifier != nil {
+		if err := g.authorizeToken(ctx, method); err != nil {
+			return ctx, err
+		}
+	}
+
+	if g.options.APIKeys != nil {
+		if err := g.authorizeKey(ctx, method); err != nil {
+			return ctx, err
+		}
+	}
+
+	return ctx, nil
+}
+
+// authenticateKey resolves the API key of the call, if any, and stores it in the context
+func (g *Guard) authenticateKey(ctx context.Context, md metadata.MD) (context.Context, error) {
+	secret := apiKeySecret(md)
+	if secret == "" {
+		if g.options.APIKeysRequired {
+			return ctx, status.Error(codes.Unauthenticated, "an API key is required, in the x-api-key metadata or as a bearer token")
+		}
+		return ctx, nil
+	}
+
+	key, err := g.options.APIKeys.Authenticate(secret)
+	if err != nil {
+		slog.InfoContext(ctx, "API key rejected", "err", err)
+		return ctx, status.Error(codes.Unauthenticated, "the API key is unknown or revoked")
+	}
+	return auth.WithAPIKey(ctx, key), nil
+}
+
+// authorizeKey checks the method and the daily quota of the authenticated key and counts the call
+func (g *Guard) authorizeKey(ctx context.Context, method string) error {
+	key, ok := auth.APIKeyFromContext(ctx)
+	if !ok {
+		return nil
+	}
+
+	err := g.options.APIKeys.Authorize(key, method)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, domain.ErrRouteNotAllowed):
+		return status.Error(codes.PermissionDenied, "the API key is not allowed to call this method")
+	case errors.Is(err, domain.ErrQuotaExceeded):
+		return status.Error(codes.ResourceExhausted, "the daily quota of the API key is exhausted, it resets at midnight UTC")
+	default:
+		slog.ErrorContext(ctx, "API key authorization error", "err", err)
+		return status.Error(codes.Internal, "internal server error")
+	}
+}
+
+// limit takes a token of the method for the client. Store errors let the call through.
+func (g *Guard) limit(ctx context.Context, method string, md metadata.MD) error {
+	if g.options.RateLimiter == nil {
+		return nil
+	}
+
+	var remoteAddr string
+	if p, ok := peer.FromContext(ctx); ok {
+		remoteAddr = p.Addr.String()
+	}
+	decision, _, err := g.options.RateLimiter.Allow(ctx, method, remoteAddr, md.Get("x-forwarded-for"), first(md, "x-api-key"))
+	if err != nil {
+		slog.WarnContext(ctx, "rate limit store error, letting the call through", "err", err)
+		return nil
+	}
+	if !decision.Allowed {
+		return status.Errorf(codes.ResourceExhausted, "the rate limit of the method was exceeded, retry after %ds",
+			max(int(math.Ceil(decision.RetryAfter.Seconds())), 1))
+	}
+	return nil
+}
+
+// authorizeToken checks that the verified token grants every scope the method requires
+func (g *Guard) authorizeToken(ctx context.Context, method string) error {
+	scopes := g.options.Scopes[method]
+	if len(scopes) == 0 {
+		return nil
+	}
+
+	required := strings.Join(scopes, " ")
+	claims, ok := auth.ClaimsFromContext(ctx)
+	if !ok {
+		return status.Error(codes.Unauthenticated, "a bearer token with the scope "+required+" is required")
+	}
+	for _, scope := range scopes {
+		if !claims.HasScope(scope) {
+			return status.Error(codes.PermissionDenied, "the token lacks the scope "+required)
+		}
+	}
+	return nil
+}
+
+// guardedStream carries the context with the verified credentials to the streaming handler
+type guardedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *guardedStream) Context() context.Context {
+	return s.ctx
+}
+
+// bearerJWT returns the bearer token of the call unless it is an API key
+func bearerJWT(md metadata.MD) string {
+	scheme, token, ok := strings.Cut(first(md, "authorization"), " ")
+	token = strings.TrimSpace(token)
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.HasPrefix(token, apiKeyBearerPrefix) {
+		return ""
+	}
+	return token
+}
+
+// apiKeySecret returns the API key of the call, from x-api-key or from an authorization bearer
+// token with the API key prefix
+func apiKeySecret(md metadata.MD) string {
+	if secret := first(md, "x-api-key"); secret != "" {
+		return secret
+	}
+
+	scheme, token, ok := strings.Cut(first(md, "authorization"), " ")
+	if ok && strings.EqualFold(scheme, "Bearer") && strings.HasPrefix(token, apiKeyBearerPrefix) {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
+
+// first returns the first value of the metadata key, or an empty string
+func first(md metadata.MD, key string) string {
+	if values := md.Get(key); len(values) > 0 {
+		return strings.TrimSpace(values[0])
+	}
+	return ""
+}
@@ -0,0 +1,180 @@
+package grpcserver
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	weatherv1 "github.com/xavierpms/weather-by-city/api/proto/weather/v1"
+	"github.com/xavierpms/weather-by-city/internal/domain"
+	"github.com/xavierpms/weather-by-city/internal/infra/auth"
+	"github.com/xavierpms/weather-by-city/internal/infra/ratelimit"
+	"github.com/xavierpms/weather-by-city/internal/infra/repository"
+	"github.com/xavierpms/weather-by-city/internal/infra/webserver/middlewares"
+	"github.com/xavierpms/weather-by-city/internal/infra/webserver/problem"
+	"github.com/xavierpms/weather-by-city/internal/usecase"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+var jwtTestSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// MockRateLimitObserver is a mock of the RateLimitObserver for testing
+type MockRateLimitObserver struct{}
+
+func (m *MockRateLimitObserver) ObserveRateLimit(route string, allowed bool) {}
+
+// withBearer returns a context sending a JWT granting the scope
+func withBearer(t *testing.T, scope string) context.Context {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":   "client",
+		"scope": scope,
+		"exp":   time.Now().Add(time.Minute).Unix(),
+	}).SignedString(jwtTestSecret)
+	require.NoError(t, err)
+	return metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer "+token)
+}
+
+// TestGuardEnforcesMethodScopes tests that the scoped methods require a token granting their scopes
+func TestGuardEnforcesMethodScopes(t *testing.T) {
+	// Arrange
+	verifier, err := auth.NewVerifier(auth.VerifierOptions{HS256Secret: jwtTestSecret})
+	require.NoError(t, err)
+	guard := NewGuard(GuardOptions{
+		Verifier: verifier,
+		Scopes:   map[string][]string{weatherv1.WeatherService_BatchGetTemperature_FullMethodName: {auth.ScopeBatchWrite}},
+	})
+	client := newTestClient(t, &MockTemperatureUseCase{getTemperatureByCEPFunc: temperatureOrError}, guard.ServerOptions()...)
+	batch := func(ctx context.Context) error {
+		_, err := client.BatchGetTemperature(ctx, &weatherv1.BatchGetTemperatureRequest{Ceps: []string{"32450000"}})
+		return err
+	}
+	invalid := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer not-a-jwt")
+
+	// Act
+	anonymous := batch(context.Background())
+	wrongScope := batch(withBearer(t, auth.ScopeWeatherRead))
+	granted := batch(withBearer(t, auth.ScopeBatchWrite))
+	_, rejectedToken := client.GetTemperature(invalid, &weatherv1.GetTemperatureRequest{Cep: "32450000"})
+	_, unscoped := client.GetTemperature(context.Background(), &weatherv1.GetTemperatureRequest{Cep: "32450000"})
+
+	// Assert
+	assert.Equal(t, codes.Unauthenticated, status.Code(anonymous))
+	assert.Equal(t, codes.PermissionDenied, status.Code(wrongScope))
+	assert.NoError(t, granted)
+	assert.Equal(t, codes.Unauthenticated, status.Code(rejectedToken))
+	assert.NoError(t, unscoped)
+}
+
+// TestGuardRejectsCallsOverTheRateLimit tests that unary and streaming calls share the limits of the HTTP API
+func TestGuardRejectsCallsOverTheRateLimit(t *testing.T) {
+	// Arrange
+	limiter := middlewares.NewRateLimiter(ratelimit.NewMemoryStore(), middlewares.RateLimitOptions{
+		Default: ratelimit.Limit{Requests: 1, Period: time.Minute},
+	}, problem.NewWriter(false), &MockRateLimitObserver{})
+	guard := NewGuard(GuardOptions{RateLimiter: limiter})
+	client := newTestClient(t, &MockTemperatureUseCase{getTemperatureByCEPFunc: temperatureOrError}, guard.ServerOptions()...)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watch := func() error {
+		stream, err := client.WatchTemperature(ctx, &weatherv1.WatchTemperatureRequest{Cep: "32450000"})
+		if err != nil {
+			return err
+		}
+		_, err = stream.Recv()
+		return err
+	}
+
+	// Act
+	_, first := client.GetTemperature(ctx, &weatherv1.GetTemperatureRequest{Cep: "32450000"})
+	_, second := client.GetTemperature(ctx, &weatherv1.GetTemperatureRequest{Cep: "32450000"})
+	firstWatch := watch()
+	secondWatch := watch()
+
+	// Assert
+	assert.NoError(t, first)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(second))
+	assert.NoError(t, firstWatch)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(secondWatch))
+}
+
+// TestGuardEnforcesAPIKeysAndQuotas tests that required API keys, their methods and their daily quotas apply to the RPCs
+func TestGuardEnforcesAPIKeysAndQuotas(t *testing.T) {
+	// Arrange
+	keyRepo, err := repository.NewAPIKeyRepository("")
+	require.NoError(t, err)
+	usageRepo, err := repository.NewUsageRepository("")
+	require.NoError(t, err)
+	manageAPIKeys := usecase.NewManageAPIKeys(keyRepo, usageRepo, 0)
+	partner, err := manageAPIKeys.CreateKey(&domain.APIKey{Name: "partner", DailyQuota: 2})
+	require.NoError(t, err)
+	batchOnly, err := manageAPIKeys.CreateKey(&domain.APIKey{Name: "batch-only",
+		AllowedRoutes: []string{weatherv1.WeatherService_BatchGetTemperature_FullMethodName}})
+	require.NoError(t, err)
+	guard := NewGuard(GuardOptions{APIKeys: manageAPIKeys, APIKeysRequired: true})
+	client := newTestClient(t, &MockTemperatureUseCase{getTemperatureByCEPFunc: temperatureOrError}, guard.ServerOptions()...)
+	get := func(kv ...string) error {
+		ctx := metadata.AppendToOutgoingContext(context.Background(), kv...)
+		_, err := client.GetTemperature(ctx, &weatherv1.GetTemperatureRequest{Cep: "32450000"})
+		return err
+	}
+
+	// Act
+	anonymous := get()
+	unknown := get("x-api-key", "wk_unknown")
+	var quota []codes.Code
+	for i := range 3 {
+		if i%2 == 0 {
+			quota = append(quota, status.Code(get("x-api-key", partner.Secret)))
+		} else {
+			quota = append(quota, status.Code(get("authorization", "Bearer "+partner.Secret)))
+		}
+	}
+	notAllowed := get("x-api-key", batchOnly.Secret)
+	report, err := manageAPIKeys.Usage(&partner.APIKey)
+
+	// Assert
+	assert.Equal(t, codes.Unauthenticated, status.Code(anonymous))
+	assert.Equal(t, codes.Unauthenticated, status.Code(unknown))
+	assert.Equal(t, []codes.Code{codes.OK, codes.OK, codes.ResourceExhausted}, quota)
+	assert.Equal(t, codes.PermissionDenied, status.Code(notAllowed))
+	require.NoError(t, err)
+	require.Len(t, report.Days, 1)
+	assert.Equal(t, map[string]int{weatherv1.WeatherService_GetTemperature_FullMethodName: 2}, report.Days[0].Routes)
+}
+
+// TestGuardEnforcesStreamScopes tests that the scopes of a streaming method are checked before the stream starts
+func TestGuardEnforcesStreamScopes(t *testing.T) {
+	// Arrange
+	verifier, err := auth.NewVerifier(auth.VerifierOptions{HS256Secret: jwtTestSecret})
+	require.NoError(t, err)
+	guard := NewGuard(GuardOptions{
+		Verifier: verifier,
+		Scopes:   map[string][]string{weatherv1.WeatherService_WatchTemperature_FullMethodName: {auth.ScopeWeatherRead}},
+	})
+	client := newTestClient(t, &MockTemperatureUseCase{getTemperatureByCEPFunc: temperatureOrError}, guard.ServerOptions()...)
+	watch := func(ctx context.Context) error {
+		ctx, cancel := context.WithCancel(ctx)
+		defer cancel()
+		stream, err := client.WatchTemperature(ctx, &weatherv1.WatchTemperatureRequest{Cep: "32450000"})
+		if err != nil {
+			return err
+		}
+		_, err = stream.Recv()
+		return err
+	}
+
+	// Act
+	anonymous := watch(context.Background())
+	wrongScope := watch(withBearer(t, auth.ScopeBatchWrite))
+	granted := watch(withBearer(t, auth.ScopeWeatherRead))
+
+	// Assert
+	assert.Equal(t, codes.Unauthenticated, status.Code(anonymous))
+	assert.Equal(t, codes.PermissionDenied, status.Code(wrongScope))
+	assert.NoError(t, granted)
+}
@@ -0,0 +1,190 @@
+package grpcserver
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+
+	weatherv1 "github.com/xavierpms/weather-by-city/api/proto/weather/v1"
+	"github.com/xavierpms/weather-by-city/internal/domain"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/timestamppb"
+)
+
+const (
+	defaultWatchInterval = time.Minute
+	minWatchInterval     = 10 * time.Second
+)
+
+// WeatherServer implements weatherv1.WeatherServiceServer on top of the temperature use cases
+type WeatherServer struct {
+	weatherv1.UnimplementedWeatherServiceServer
+
+	useCase      domain.TemperatureUseCase
+	batchUseCase domain.BatchTemperatureUseCase
+}
+
+// NewWeatherServer creates a new gRPC weather server
+func NewWeatherServer(useCase domain.TemperatureUseCase, batchUseCase domain.BatchTemperatureUseCase) *WeatherServer {
+	return &WeatherServer{
+		useCase:      useCase,
+		batchUseCase: batchUseCase,
+	}
+}
+
+// NewServer creates a gRPC server with the weather service registered, such as the interceptors of a Guard
+func NewServer(weatherServer *WeatherServer, options ...grpc.ServerOption) *grpc.Server {
+	server := grpc.NewServer(options...)
+	weatherv1.RegisterWeatherServiceServer(server, weatherServer)
+
+	return server
+}
+
+// Shutdown stops the server gracefully, letting the running RPCs finish, and
+// cancels the remaining ones, such as long-lived watches, when the context expires
+func Shutdown(ctx context.Context, server *grpc.Server) error {
+	stopped := make(chan struct{})
+	go func() {
+		server.GracefulStop()
+		close(stopped)
+	}()
+
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		server.Stop()
+		return nil
+	}
+}
+
+// MultiplexHandler routes gRPC requests to the gRPC server and everything else to the HTTP handler.
+// The HTTP server must accept HTTP/2, including unencrypted HTTP/2 when running without TLS.
+func MultiplexHandler(grpcServer *grpc.Server, httpHandler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor == 2 && strings.HasPrefix(r.Header.Get("Content-Type"), "application/grpc") {
+			grpcServer.ServeHTTP(w, r)
+			return
+		}
+
+		httpHandler.ServeHTTP(w, r)
+	})
+}
+
+// GetTemperature handles the GetTemperature RPC
+func (s *WeatherServer) GetTemperature(ctx context.Context, req *weatherv1.GetTemperatureRequest) (*weatherv1.GetTemperatureResponse, error) {
+	temperature, err := s.useCase.GetTemperatureByCEP(ctx, req.GetCep())
+	if err != nil {
+		return nil, toStatus(err).Err()
+	}
+
+	return &weatherv1.GetTemperatureResponse{
+		Cep:         req.GetCep(),
+		Temperature: toProtoTemperature(temperature),
+	}, nil
+}
+
+// BatchGetTemperature handles the BatchGetTemperature RPC
+func (s *WeatherServer) BatchGetTemperature(ctx context.Context, req *weatherv1.BatchGetTemperatureRequest) (*weatherv1.BatchGetTemperatureResponse, error) {
+	results, err := s.batchUseCase.GetTemperaturesByCEPs(ctx, req.GetCeps())
+	if err != nil {
+		return nil, toStatus(err).Err()
+	}
+
+	resp := &weatherv1.BatchGetTemperatureResponse{
+		Results: make([]*weatherv1.BatchGetTemperatureResult, 0, len(results)),
+	}
+	for _, result := range results {
+		item := &weatherv1.BatchGetTemperatureResult{Cep: result.CEP}
+		if result.Err != nil {
+			st := toStatus(result.Err)
+			item.Result = &weatherv1.BatchGetTemperatureResult_Error{
+				Error: &weatherv1.Error{Code: int32(st.Code()), Message: st.Message()},
+			}
+		} else {
+			item.Result = &weatherv1.BatchGetTemperatureResult_Temperature{
+				Temperature: toProtoTemperature(result.Temperature),
+			}
+		}
+		resp.Results = append(resp.Results, item)
+	}
+
+	return resp, nil
+}
+
+// WatchTemperature handles the WatchTemperature RPC, sending an update immediately and then on every interval
+func (s *WeatherServer) WatchTemperature(req *weatherv1.WatchTemperatureRequest, stream grpc.ServerStreamingServer[weatherv1.WatchTemperatureResponse]) error {
+	interval := defaultWatchInterval
+	if req.GetInterval() != nil {
+		interval = req.GetInterval().AsDuration()
+	}
+	if interval < minWatchInterval {
+		interval = minWatchInterval
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		temperature, err := s.useCase.GetTemperatureByCEP(stream.Context(), req.GetCep())
+		if err != nil {
+			return toStatus(err).Err()
+		}
+
+		err = stream.Send(&weatherv1.WatchTemperatureResponse{
+			Cep:         req.GetCep(),
+			Temperature: toProtoTemperature(temperature),
+			ObservedAt:  timestamppb.Now(),
+		})
+		if err != nil {
+			return err
+		}
+
+		select {
+		case <-stream.Context().Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// toStatus maps domain errors to gRPC status codes
+func toStatus(err error) *status.Status {
+	switch {
+	case errors.Is(err, domain.ErrInvalidCEPFormat):
+		return status.New(codes.InvalidArgument, "invalid zipcode")
+
+	case errors.Is(err, domain.ErrEmptyBatch), errors.Is(err, domain.ErrBatchTooLarge):
+		return status.New(codes.InvalidArgument, err.Error())
+
+	case errors.Is(err, domain.ErrCEPNotFound):
+		return status.New(codes.NotFound, "can not find zipcode")
+
+	case errors.Is(err, domain.ErrTemperatureNotFound):
+		return status.New(codes.Unavailable, "can not fetch temperature")
+
+	case errors.Is(err, domain.ErrUpstreamQuotaExceeded):
+		return status.New(codes.ResourceExhausted, "weather provider quota exceeded")
+
+	case errors.Is(err, domain.ErrUpstreamUnavailable):
+		return status.New(codes.Unavailable, "upstream service unavailable")
+
+	default:
+		slog.Error("gRPC internal error", "err", err)
+		return status.New(codes.Internal, "internal server error")
+	}
+}
+
+// toProtoTemperature converts the domain temperature to its protobuf message
+func toProtoTemperature(temperature *domain.Temperature) *weatherv1.Temperature {
+	return &weatherv1.Temperature{
+		Celsius:    temperature.Celsius,
+		Fahrenheit: temperature.Fahrenheit,
+		Kelvin:     temperature.Kelvin,
+	}
+}
@@ -0,0 +1,146 @@
+package grpcserver
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	weatherv1 "github.com/xavierpms/weather-by-city/api/proto/weather/v1"
+	"github.com/xavierpms/weather-by-city/internal/domain"
+	"github.com/xavierpms/weather-by-city/internal/usecase"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+)
+
+// MockTemperatureUseCase is a mock of the TemperatureUseCase for testing
+type MockTemperatureUseCase struct {
+	getTemperatureByCEPFunc func(cep string) (*domain.Temperature, error)
+}
+
+func (m *MockTemperatureUseCase) GetTemperatureByCEP(ctx context.Context, cep string) (*domain.Temperature, error) {
+	return m.getTemperatureByCEPFunc(cep)
+}
+
+// newTestClient starts the weather service on an in-memory listener and returns a client
+func newTestClient(t *testing.T, useCase domain.TemperatureUseCase, options ...grpc.ServerOption) weatherv1.WeatherServiceClient {
+	listener := bufconn.Listen(1024 * 1024)
+	server := NewServer(NewWeatherServer(useCase, usecase.NewGetTemperaturesByCEPs(useCase, 10, 2)), options...)
+	go func() { _ = server.Serve(listener) }()
+	t.Cleanup(server.Stop)
+
+	conn, err := grpc.NewClient(
+		"passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+	)
+	require.NoError(t, err)
+	t.Cleanup(func() { _ = conn.Close() })
+
+	return weatherv1.NewWeatherServiceClient(conn)
+}
+
+func temperatureOrError(cep string) (*domain.Temperature, error) {
+	switch cep {
+	case "3245000":
+		return nil, domain.ErrInvalidCEPFormat
+	case "99999999":
+		return nil, domain.ErrCEPNotFound
+	case "11111111":
+		return nil, domain.ErrTemperatureNotFound
+	default:
+		return &domain.Temperature{Celsius: 28.5, Fahrenheit: 83.3, Kelvin: 301.65}, nil
+	}
+}
+
+// TestGetTemperatureSuccess tests the success of the RPC
+func TestGetTemperatureSuccess(t *testing.T) {
+	// Arrange
+	client := newTestClient(t, &MockTemperatureUseCase{getTemperatureByCEPFunc: temperatureOrError})
+
+	// Act
+	resp, err := client.GetTemperature(context.Background(), &weatherv1.GetTemperatureRequest{Cep: "32450000"})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "32450000", resp.GetCep())
+	assert.Equal(t, 28.5, resp.GetTemperature().GetCelsius())
+	assert.Equal(t, 83.3, resp.GetTemperature().GetFahrenheit())
+	assert.Equal(t, 301.65, resp.GetTemperature().GetKelvin())
+}
+
+// TestGetTemperatureErrorCodes tests that domain errors are mapped to gRPC status codes
+func TestGetTemperatureErrorCodes(t *testing.T) {
+	// Arrange
+	client := newTestClient(t, &MockTemperatureUseCase{getTemperatureByCEPFunc: temperatureOrError})
+
+	testCases := map[string]codes.Code{
+		"3245000":  codes.InvalidArgument,
+		"99999999": codes.NotFound,
+		"11111111": codes.Unavailable,
+	}
+
+	for cep, code := range testCases {
+		// Act
+		_, err := client.GetTemperature(context.Background(), &weatherv1.GetTemperatureRequest{Cep: cep})
+
+		// Assert
+		assert.Equal(t, code, status.Code(err), "cep %s", cep)
+	}
+}
+
+// TestBatchGetTemperature tests that failures are reported per CEP and order is preserved
+func TestBatchGetTemperature(t *testing.T) {
+	// Arrange
+	client := newTestClient(t, &MockTemperatureUseCase{getTemperatureByCEPFunc: temperatureOrError})
+
+	// Act
+	resp, err := client.BatchGetTemperature(context.Background(), &weatherv1.BatchGetTemperatureRequest{
+		Ceps: []string{"32450000", "99999999", "32450000"},
+	})
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, resp.GetResults(), 3)
+	assert.Equal(t, 28.5, resp.GetResults()[0].GetTemperature().GetCelsius())
+	assert.Equal(t, int32(codes.NotFound), resp.GetResults()[1].GetError().GetCode())
+	assert.Equal(t, "32450000", resp.GetResults()[2].GetCep())
+}
+
+// TestBatchGetTemperatureTooLarge tests that oversized batches are rejected
+func TestBatchGetTemperatureTooLarge(t *testing.T) {
+	// Arrange
+	client := newTestClient(t, &MockTemperatureUseCase{getTemperatureByCEPFunc: temperatureOrError})
+
+	// Act
+	_, err := client.BatchGetTemperature(context.Background(), &weatherv1.BatchGetTemperatureRequest{
+		Ceps: make([]string, 11),
+	})
+
+	// Assert
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+// TestWatchTemperatureSendsInitialUpdate tests that the stream delivers the current temperature right away
+func TestWatchTemperatureSendsInitialUpdate(t *testing.T) {
+	// Arrange
+	client := newTestClient(t, &MockTemperatureUseCase{getTemperatureByCEPFunc: temperatureOrError})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// Act
+	stream, err := client.WatchTemperature(ctx, &weatherv1.WatchTemperatureRequest{Cep: "32450000"})
+	require.NoError(t, err)
+	update, err := stream.Recv()
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 28.5, update.GetTemperature().GetCelsius())
+	assert.NotNil(t, update.GetObservedAt())
+}
@@ -0,0 +1,147 @@
+package health
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Status values of checks and reports
+const (
+	StatusUp   = "up"
+	StatusDown = "down"
+	// StatusDegraded reports that only non-critical checks failed, so the instance keeps serving
+	StatusDegraded = "degraded"
+)
+
+// CheckFunc reports whether a dependency is usable
+type CheckFunc func(ctx context.Context) error
+
+// Result is the outcome of one check
+type Result struct {
+	Status    string    `json:"status"`
+	Error     string    `json:"error,omitempty"`
+	CheckedAt time.Time `json:"checked_at"`
+}
+
+// Report is the outcome of every check
+type Report struct {
+	Status string            `json:"status"`
+	Checks map[string]Result `json:"checks,omitempty"`
+}
+
+// Checker runs the readiness checks, caching each result so frequent probes do not hit the dependencies
+type Checker struct {
+	ttl     time.Duration
+	timeout time.Duration
+	now     func() time.Time
+
+	checks []*check
+}
+
+type check struct {
+	name     string
+	fn       CheckFunc
+	critical bool
+
+	mu     sync.Mutex
+	result Result
+}
+
+// NewChecker creates a checker that reuses results for ttl and gives each check up to timeout
+func NewChecker(ttl, timeout time.Duration) *Checker {
+	return &Checker{
+		ttl:     ttl,
+		timeout: timeout,
+		now:     time.Now,
+	}
+}
+
+// Add registers a check. Checks must be added before the checker is used.
+func (c *Checker) Add(name string, fn CheckFunc) {
+	c.checks = append(c.checks, &check{name: name, fn: fn, critical: true})
+}
+
+// AddNonCritical registers a check whose failure degrades the report without taking the instance down
+func (c *Checker) AddNonCritical(name string, fn CheckFunc) {
+	c.checks = append(c.checks, &check{name: name, fn: fn})
+}
+
+// Check runs the checks concurrently, reusing the fresh results, and reports down if any critical check
+// failed or degraded if only non-critical ones did
+func (c *Checker) Check(ctx context.Context) Report {
+	report := Report{Status: StatusUp, Checks: make(map[string]Result, len(c.checks))}
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	for _, chk := range c.checks {
+		wg.Add(1)
+		go func(chk *check) {
+			defer wg.Done()
+			result := c.run(ctx, chk)
+
+			mu.Lock()
+			defer mu.Unlock()
+			report.Checks[chk.name] = result
+			switch {
+			case result.Status == StatusUp:
+			case chk.critical:
+				report.Status = StatusDown
+			case report.Status == StatusUp:
+				report.Status = StatusDegraded
+			}
+		}(chk)
+	}
+	wg.Wait()
+
+	return report
+}
+
+// run returns the cached result of the check or runs it again when stale.
+// Concurrent probes wait for the same run instead of starting their own. The run is detached from
+// the cancellation of the probe, so a prober hanging up does not cache a failure for the whole TTL.
+func (c *Checker) run(ctx context.Context, chk *check) Result {
+	chk.mu.Lock()
+	defer chk.mu.Unlock()
+
+	if !chk.result.CheckedAt.IsZero() && c.now().Sub(chk.result.CheckedAt) < c.ttl {
+		return chk.result
+	}
+
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), c.timeout)
+	defer cancel()
+
+	result := Result{Status: StatusUp, CheckedAt: c.now()}
+	if err := chk.fn(ctx); err != nil {
+		result.Status = StatusDown
+		result.Error = err.Error()
+	}
+	chk.result = result
+
+	return result
+}
+
+// HTTPCheck reports a dependency as reachable when it answers the URL without a server error.
+// Client errors count as reachable so endpoints can be probed without credentials.
+func HTTPCheck(client *http.Client, url string) CheckFunc {
+	return func(ctx context.Context) error {
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+		if err != nil {
+			return err
+		}
+
+		resp, err := client.Do(req)
+		if err != nil {
+			return err
+		}
+		resp.Body.Close()
+
+		if resp.StatusCode >= http.StatusInternalServerError {
+			return fmt.Errorf("unexpected status %d", resp.StatusCode)
+		}
+
+		return nil
+	}
+}
@@ -0,0 +1,103 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestCheckerCachesResults tests that the checks only run again after the TTL
+func TestCheckerCachesResults(t *testing.T) {
+	// Arrange
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	checker := NewChecker(time.Minute, time.Second)
+	checker.now = func() time.Time { return now }
+
+	calls := 0
+	checker.Add("upstream", func(ctx context.Context) error {
+		calls++
+		if calls > 1 {
+			return errors.New("unreachable")
+		}
+		return nil
+	})
+	checker.Add("config", func(ctx context.Context) error { return nil })
+
+	// Act
+	first := checker.Check(context.Background())
+	cached := checker.Check(context.Background())
+	now = now.Add(time.Minute)
+	refreshed := checker.Check(context.Background())
+
+	// Assert
+	assert.Equal(t, StatusUp, first.Status)
+	assert.Equal(t, first, cached)
+	assert.Equal(t, 2, calls)
+
+	assert.Equal(t, StatusDown, refreshed.Status)
+	assert.Equal(t, StatusDown, refreshed.Checks["upstream"].Status)
+	assert.Equal(t, "unreachable", refreshed.Checks["upstream"].Error)
+	assert.Equal(t, StatusUp, refreshed.Checks["config"].Status)
+}
+
+// TestCheckerNonCriticalChecks tests that non-critical failures degrade the report without taking it down
+func TestCheckerNonCriticalChecks(t *testing.T) {
+	// Arrange
+	checker := NewChecker(time.Minute, time.Second)
+	checker.Add("config", func(ctx context.Context) error { return nil })
+	checker.AddNonCritical("breaker", func(ctx context.Context) error { return errors.New("open") })
+
+	// Act
+	report := checker.Check(context.Background())
+
+	// Assert
+	assert.Equal(t, StatusDegraded, report.Status)
+	assert.Equal(t, StatusDown, report.Checks["breaker"].Status)
+	assert.Equal(t, StatusUp, report.Checks["config"].Status)
+}
+
+// TestHTTPCheck tests that only server errors and network failures make a dependency unreachable
+func TestHTTPCheck(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/unauthorized":
+			w.WriteHeader(http.StatusUnauthorized)
+		case "/broken":
+			w.WriteHeader(http.StatusServiceUnavailable)
+		}
+	}))
+	defer server.Close()
+
+	// Act
+	okErr := HTTPCheck(server.Client(), server.URL+"/")(context.Background())
+	unauthorizedErr := HTTPCheck(server.Client(), server.URL+"/unauthorized")(context.Background())
+	brokenErr := HTTPCheck(server.Client(), server.URL+"/broken")(context.Background())
+
+	// Assert
+	assert.NoError(t, okErr)
+	assert.NoError(t, unauthorizedErr)
+	assert.EqualError(t, brokenErr, "unexpected status 503")
+}
+
+// TestCheckerIgnoresTheProbeCancellation tests that a prober hanging up does not cache a failed check
+func TestCheckerIgnoresTheProbeCancellation(t *testing.T) {
+	// Arrange
+	checker := NewChecker(time.Minute, time.Second)
+	checker.Add("upstream", func(ctx context.Context) error { return ctx.Err() })
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Act
+	disconnected := checker.Check(ctx)
+	next := checker.Check(context.Background())
+
+	// Assert
+	assert.Equal(t, StatusUp, disconnected.Status)
+	assert.Equal(t, StatusUp, next.Checks["upstream"].Status)
+}
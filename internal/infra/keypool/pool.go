@@ -0,0 +1,194 @@
+package keypool
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/xavierpms/weather-by-city/internal/domain"
+)
+
+// Strategy tells which active key serves the next call
+type Strategy string
+
+const (
+	// StrategyRoundRobin spreads the calls evenly over the active keys
+	StrategyRoundRobin Strategy = "round-robin"
+	// StrategyPriority uses the first active key, in the configured order
+	StrategyPriority Strategy = "priority"
+)
+
+// Key states reported by Health
+const (
+	StatusActive  = "active"
+	StatusRetired = "retired"
+)
+
+// KeyHealth describes a key without revealing its secret
+type KeyHealth struct {
+	ID            string     `json:"id"`
+	Fingerprint   string     `json:"fingerprint"`
+	Status        string     `json:"status"`
+	Calls         int64      `json:"calls"`
+	LastUsedAt    *time.Time `json:"last_used_at,omitempty"`
+	RetiredAt     *time.Time `json:"retired_at,omitempty"`
+	RetiredUntil  *time.Time `json:"retired_until,omitempty"`
+	RetiredCode   int        `json:"retired_code,omitempty"`
+	RetiredReason string     `json:"retired_reason,omitempty"`
+}
+
+type key struct {
+	id          string
+	secret      string
+	fingerprint string
+
+	calls         int64
+	lastUsedAt    time.Time
+	retiredAt     time.Time
+	retiredUntil  time.Time
+	retiredCode   int
+	retiredReason string
+}
+
+// Pool hands out the API keys of an upstream and retires the ones it refuses
+type Pool struct {
+	name string
+	now  func() time.Time
+
+	mu       sync.Mutex
+	strategy Strategy
+	keys     []*key
+	next     int
+}
+
+// New creates a pool of the secrets, identified as key-1, key-2... in the given order
+func New(name string, secrets []string, strategy Strategy) *Pool {
+	return &Pool{
+		name:     name,
+		strategy: strategy,
+		now:      time.Now,
+		keys:     newKeys(secrets, nil),
+	}
+}
+
+// SetKeys replaces the secrets of the pool. A secret that stays keeps its calls and retirement,
+// under the id of its new position.
+func (p *Pool) SetKeys(secrets []string) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.keys = newKeys(secrets, p.keys)
+	p.next = 0
+}
+
+// SetStrategy replaces the strategy, which applies from the next call
+func (p *Pool) SetStrategy(strategy Strategy) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	p.strategy = strategy
+}
+
+// newKeys creates the keys of the secrets, reusing the state of the previous keys with the same secret
+func newKeys(secrets []string, previous []*key) []*key {
+	bySecret := make(map[string]*key, len(previous))
+	for _, k := range previous {
+		bySecret[k.secret] = k
+	}
+
+	keys := make([]*key, 0, len(secrets))
+	for i, secret := range secrets {
+		k, ok := bySecret[secret]
+		if !ok {
+			sum := sha256.Sum256([]byte(secret))
+			k = &key{secret: secret, fingerprint: hex.EncodeToString(sum[:4])}
+		}
+		k.id = "key-" + strconv.Itoa(i+1)
+		keys = append(keys, k)
+	}
+
+	return keys
+}
+
+// Health reports the state of each key
+func (p *Pool) Health() []KeyHealth {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	health := make([]KeyHealth, 0, len(p.keys))
+	for _, k := range p.keys {
+		h := KeyHealth{
+			ID:          k.id,
+			Fingerprint: k.fingerprint,
+			Status:      StatusActive,
+			Calls:       k.calls,
+			LastUsedAt:  timePtr(k.lastUsedAt),
+		}
+		if k.retired(now) {
+			h.Status = StatusRetired
+			h.RetiredAt = timePtr(k.retiredAt)
+			h.RetiredUntil = timePtr(k.retiredUntil)
+			h.RetiredCode = k.retiredCode
+			h.RetiredReason = k.retiredReason
+		}
+		health = append(health, h)
+	}
+
+	return health
+}
+
+// acquire picks the key of the next call
+func (p *Pool) acquire() (*key, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now()
+	start := 0
+	if p.strategy == StrategyRoundRobin {
+		start = p.next
+	}
+	for i := range p.keys {
+		index := (start + i) % len(p.keys)
+		k := p.keys[index]
+		if k.retired(now) {
+			continue
+		}
+		p.next = index + 1
+		k.calls++
+		k.lastUsedAt = now
+		return k, nil
+	}
+
+	return nil, fmt.Errorf("%w: %w: every %s API key is retired",
+		domain.ErrUpstreamUnavailable, domain.ErrUpstreamQuotaExceeded, p.name)
+}
+
+// retire takes the key out of the pool until the start of the next UTC month, the billing window,
+// and returns its current id
+func (p *Pool) retire(k *key, code int, reason string) string {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+
+	now := p.now().UTC()
+	k.retiredAt = now
+	k.retiredUntil = time.Date(now.Year(), now.Month()+1, 1, 0, 0, 0, 0, time.UTC)
+	k.retiredCode = code
+	k.retiredReason = reason
+	return k.id
+}
+
+// retired reports whether the key is out of the pool at now
+func (k *key) retired(now time.Time) bool {
+	return now.Before(k.retiredUntil)
+}
+
+func timePtr(t time.Time) *time.Time {
+	if t.IsZero() {
+		return nil
+	}
+	return &t
+}
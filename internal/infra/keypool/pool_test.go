@@ -0,0 +1,181 @@
+package keypool
+
+import (
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xavierpms/weather-by-city/internal/domain"
+)
+
+// newWeatherAPI starts a fake WeatherAPI answering each key with the given error code, 0 meaning success
+func newWeatherAPI(t *testing.T, codes map[string]int, used *[]string) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := r.URL.Query().Get("key")
+		*used = append(*used, key)
+		if code := codes[key]; code != 0 {
+			w.WriteHeader(http.StatusForbidden)
+			json.NewEncoder(w).Encode(map[string]any{"error": map[string]any{"code": code, "message": "refused"}})
+			return
+		}
+		w.Write([]byte(`{"current":{"temp_c":21}}`))
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+func get(t *testing.T, client *http.Client, url string) (*http.Response, string, error) {
+	resp, err := client.Get(url + "?q=Sao+Paulo")
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	require.NoError(t, err)
+	return resp, string(body), nil
+}
+
+// TestPoolStrategies tests the order in which each strategy uses the keys
+func TestPoolStrategies(t *testing.T) {
+	for _, tc := range []struct {
+		strategy Strategy
+		expected []string
+	}{
+		{StrategyRoundRobin, []string{"a", "b", "c", "a"}},
+		{StrategyPriority, []string{"a", "a", "a", "a"}},
+	} {
+		t.Run(string(tc.strategy), func(t *testing.T) {
+			// Arrange
+			used := []string{}
+			server := newWeatherAPI(t, nil, &used)
+			client := &http.Client{Transport: New("weatherapi", []string{"a", "b", "c"}, tc.strategy).Transport(nil)}
+
+			// Act
+			for range 4 {
+				_, _, err := get(t, client, server.URL)
+				require.NoError(t, err)
+			}
+
+			// Assert
+			assert.Equal(t, tc.expected, used)
+		})
+	}
+}
+
+// TestPoolRetiresRefusedKeys tests that a refused key is retired until the next month and the call moves to the next key
+func TestPoolRetiresRefusedKeys(t *testing.T) {
+	// Arrange
+	used := []string{}
+	server := newWeatherAPI(t, map[string]int{"a": 2007}, &used)
+	pool := New("weatherapi", []string{"a", "b"}, StrategyPriority)
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	pool.now = func() time.Time { return now }
+	client := &http.Client{Transport: pool.Transport(nil)}
+
+	// Act
+	resp, body, err := get(t, client, server.URL)
+	require.NoError(t, err)
+	_, _, err = get(t, client, server.URL)
+	require.NoError(t, err)
+	retired := pool.Health()
+	now = time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC)
+	reactivated := pool.Health()
+
+	// Assert
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Contains(t, body, "temp_c")
+	assert.Equal(t, []string{"a", "b", "b"}, used)
+	assert.Equal(t, StatusRetired, retired[0].Status)
+	assert.Equal(t, 2007, retired[0].RetiredCode)
+	assert.Equal(t, time.Date(2026, 11, 1, 0, 0, 0, 0, time.UTC), *retired[0].RetiredUntil)
+	assert.Equal(t, StatusActive, retired[1].Status)
+	assert.Equal(t, int64(2), retired[1].Calls)
+	assert.Equal(t, StatusActive, reactivated[0].Status)
+}
+
+// TestPoolFailsWhenEveryKeyIsRetired tests that the exceeded quota is reported once no key is left
+func TestPoolFailsWhenEveryKeyIsRetired(t *testing.T) {
+	// Arrange
+	used := []string{}
+	server := newWeatherAPI(t, map[string]int{"a": 2006, "b": 2008}, &used)
+	client := &http.Client{Transport: New("weatherapi", []string{"a", "b"}, StrategyRoundRobin).Transport(nil)}
+
+	// Act
+	_, _, first := get(t, client, server.URL)
+	_, _, second := get(t, client, server.URL)
+
+	// Assert
+	assert.ErrorIs(t, first, domain.ErrUpstreamQuotaExceeded)
+	assert.ErrorIs(t, first, domain.ErrUpstreamUnavailable)
+	assert.ErrorIs(t, second, domain.ErrUpstreamQuotaExceeded)
+	assert.Equal(t, []string{"a", "b"}, used)
+}
+
+// TestPoolKeepsOtherClientErrors tests that client errors unrelated to the key are returned untouched
+func TestPoolKeepsOtherClientErrors(t *testing.T) {
+	// Arrange
+	used := []string{}
+	server := newWeatherAPI(t, map[string]int{"a": 1006}, &used)
+	pool := New("weatherapi", []string{"a", "b"}, StrategyPriority)
+	client := &http.Client{Transport: pool.Transport(nil)}
+
+	// Act
+	resp, body, err := get(t, client, server.URL)
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, http.StatusForbidden, resp.StatusCode)
+	assert.Contains(t, body, `"code":1006`)
+	assert.Equal(t, []string{"a"}, used)
+	assert.Equal(t, StatusActive, pool.Health()[0].Status)
+}
+
+// TestPoolHealthHidesSecrets tests that the health report never contains the secrets
+func TestPoolHealthHidesSecrets(t *testing.T) {
+	// Arrange
+	pool := New("weatherapi", []string{"secret-key-one", "secret-key-two"}, StrategyRoundRobin)
+
+	// Act
+	report, err := json.Marshal(pool.Health())
+
+	// Assert
+	require.NoError(t, err)
+	assert.NotContains(t, string(report), "secret-key")
+	assert.Equal(t, 2, strings.Count(string(report), `"fingerprint"`))
+	assert.Contains(t, string(report), `"id":"key-2"`)
+}
+
+// TestPoolSetKeysKeepsTheStateOfKeptKeys tests that replacing the keys keeps the retirement of the secrets that stay
+func TestPoolSetKeysKeepsTheStateOfKeptKeys(t *testing.T) {
+	// Arrange
+	used := []string{}
+	server := newWeatherAPI(t, map[string]int{"a": 2007}, &used)
+	pool := New("weatherapi", []string{"a", "b"}, StrategyPriority)
+	client := &http.Client{Transport: pool.Transport(nil)}
+	_, _, err := get(t, client, server.URL)
+	require.NoError(t, err)
+
+	// Act
+	pool.SetKeys([]string{"c", "a"})
+	pool.SetStrategy(StrategyRoundRobin)
+	for range 2 {
+		_, _, err = get(t, client, server.URL)
+		require.NoError(t, err)
+	}
+	health := pool.Health()
+
+	// Assert
+	assert.Equal(t, []string{"a", "b", "c", "c"}, used)
+	require.Len(t, health, 2)
+	assert.Equal(t, "key-1", health[0].ID)
+	assert.Equal(t, StatusActive, health[0].Status)
+	assert.Equal(t, "key-2", health[1].ID)
+	assert.Equal(t, StatusRetired, health[1].Status)
+	assert.Equal(t, int64(1), health[1].Calls)
+}
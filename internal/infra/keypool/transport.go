@@ -0,0 +1,82 @@
+package keypool
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+)
+
+// maxErrorBody bounds the error bodies read to find the WeatherAPI error code
+const maxErrorBody = 64 << 10
+
+// retiringCodes are the WeatherAPI error codes that disable a key for the billing window
+var retiringCodes = map[int]bool{
+	2006: true, // API key provided is invalid
+	2007: true, // API key has exceeded calls per month quota
+	2008: true, // API key has been disabled
+	2009: true, // API key does not have access to the resource
+}
+
+// weatherAPIError is the error body of WeatherAPI
+type weatherAPIError struct {
+	Error struct {
+		Code    int    `json:"code"`
+		Message string `json:"message"`
+	} `json:"error"`
+}
+
+type transport struct {
+	base http.RoundTripper
+	pool *Pool
+}
+
+// Transport wraps base so that each call carries a key of the pool in the key query parameter.
+// When WeatherAPI refuses a key, the key is retired and the call, a bodiless GET, is sent again with the next one.
+func (p *Pool) Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &transport{
+		base: base,
+		pool: p,
+	}
+}
+
+// RoundTrip sends the request with a key of the pool, moving to the next key while keys are refused
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	for {
+		k, err := t.pool.acquire()
+		if err != nil {
+			return nil, err
+		}
+
+		keyed := req.Clone(req.Context())
+		query := keyed.URL.Query()
+		query.Set("key", k.secret)
+		keyed.URL.RawQuery = query.Encode()
+
+		resp, err := t.base.RoundTrip(keyed)
+		if err != nil || resp.StatusCode < http.StatusBadRequest || resp.StatusCode >= http.StatusInternalServerError {
+			return resp, err
+		}
+
+		// Read the client error to find the WeatherAPI code, then hand the body back untouched
+		body, err := io.ReadAll(io.LimitReader(resp.Body, maxErrorBody))
+		resp.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		var apiErr weatherAPIError
+		if json.Unmarshal(body, &apiErr) != nil || !retiringCodes[apiErr.Error.Code] {
+			resp.Body = io.NopCloser(bytes.NewReader(body))
+			return resp, nil
+		}
+
+		id := t.pool.retire(k, apiErr.Error.Code, apiErr.Error.Message)
+		slog.WarnContext(req.Context(), "API key retired for the billing window",
+			"upstream", t.pool.name, "key", id, "fingerprint", k.fingerprint, "code", apiErr.Error.Code, "reason", apiErr.Error.Message)
+	}
+}
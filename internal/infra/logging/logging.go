@@ -0,0 +1,113 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"regexp"
+	"strings"
+
+	"github.com/go-chi/chi/middleware"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Supported log formats
+const (
+	FormatJSON = "json"
+	FormatText = "text"
+)
+
+// Redacted replaces secret values in the logs
+const Redacted = "REDACTED"
+
+// sensitiveKeys are attribute keys and query parameters whose values are never logged
+var sensitiveKeys = []string{"key", "api_key", "apikey", "access_token", "token", "secret", "password", "authorization"}
+
+// secretParamPattern matches the value of a sensitive query parameter inside any string, such as a URL or an error message
+var secretParamPattern = regexp.MustCompile(`(?i)([?&](?:` + strings.Join(sensitiveKeys, "|") + `)=)[^&\s"']*`)
+
+// New creates a logger writing records in the given format at or above the given level.
+// Records carry the request ID found in their context and secrets are redacted.
+func New(w io.Writer, level, format string) (*slog.Logger, error) {
+	lvl, err := ParseLevel(level)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewWithLevel(w, lvl, format)
+}
+
+// NewWithLevel creates a logger like New whose level may change while it runs, such as a *slog.LevelVar
+func NewWithLevel(w io.Writer, level slog.Leveler, format string) (*slog.Logger, error) {
+	options := &slog.HandlerOptions{Level: level, ReplaceAttr: redactAttr}
+
+	var handler slog.Handler
+	switch strings.ToLower(format) {
+	case FormatJSON:
+		handler = slog.NewJSONHandler(w, options)
+	case FormatText:
+		handler = slog.NewTextHandler(w, options)
+	default:
+		return nil, fmt.Errorf("invalid log format %q", format)
+	}
+
+	return slog.New(&contextHandler{Handler: handler}), nil
+}
+
+// ParseLevel reads a level name such as debug, info, warn or error
+func ParseLevel(level string) (slog.Level, error) {
+	var lvl slog.Level
+	if err := lvl.UnmarshalText([]byte(level)); err != nil {
+		return 0, fmt.Errorf("invalid log level %q", level)
+	}
+
+	return lvl, nil
+}
+
+// RedactURL hides the values of sensitive query parameters
+func RedactURL(rawURL string) string {
+	return secretParamPattern.ReplaceAllString(rawURL, "${1}"+Redacted)
+}
+
+// redactAttr hides sensitive attributes and secrets embedded in string and error values
+func redactAttr(groups []string, attr slog.Attr) slog.Attr {
+	for _, key := range sensitiveKeys {
+		if strings.EqualFold(attr.Key, key) {
+			return slog.String(attr.Key, Redacted)
+		}
+	}
+
+	switch value := attr.Value.Any().(type) {
+	case string:
+		return slog.String(attr.Key, RedactURL(value))
+	case error:
+		return slog.String(attr.Key, RedactURL(value.Error()))
+	}
+
+	return attr
+}
+
+// contextHandler adds the request ID and the trace of the record context to every record
+type contextHandler struct {
+	slog.Handler
+}
+
+func (h *contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if requestID := middleware.GetReqID(ctx); requestID != "" {
+		record.AddAttrs(slog.String("request_id", requestID))
+	}
+	if spanContext := trace.SpanContextFromContext(ctx); spanContext.IsValid() {
+		record.AddAttrs(slog.String("trace_id", spanContext.TraceID().String()), slog.String("span_id", spanContext.SpanID().String()))
+	}
+
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h *contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithAttrs(attrs)}
+}
+
+func (h *contextHandler) WithGroup(name string) slog.Handler {
+	return &contextHandler{Handler: h.Handler.WithGroup(name)}
+}
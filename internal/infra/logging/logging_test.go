@@ -0,0 +1,49 @@
+package logging
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+
+	"github.com/go-chi/chi/middleware"
+	"github.com/stretchr/testify/assert"
+)
+
+// TestLoggerAddsRequestIDAndRedactsSecrets tests the JSON output of the logger
+func TestLoggerAddsRequestIDAndRedactsSecrets(t *testing.T) {
+	// Arrange
+	var out bytes.Buffer
+	logger, err := New(&out, "info", FormatJSON)
+	assert.NoError(t, err)
+	ctx := context.WithValue(context.Background(), middleware.RequestIDKey, "host/abc-000001")
+
+	// Act
+	logger.InfoContext(ctx, "calling Weather API",
+		"url", "https://api.weatherapi.com/v1/current.json?q=Lavras&key=s3cr3t&lang=pt",
+		"err", errors.New(`Get "https://example.com/?token=abc": timeout`),
+		"secret", "hunter2",
+	)
+	logger.DebugContext(ctx, "hidden")
+
+	// Assert
+	var record map[string]interface{}
+	assert.NoError(t, json.Unmarshal(out.Bytes(), &record))
+	assert.Equal(t, "host/abc-000001", record["request_id"])
+	assert.Equal(t, "https://api.weatherapi.com/v1/current.json?q=Lavras&key=REDACTED&lang=pt", record["url"])
+	assert.Equal(t, `Get "https://example.com/?token=REDACTED": timeout`, record["err"])
+	assert.Equal(t, "REDACTED", record["secret"])
+	assert.NotContains(t, out.String(), "hidden")
+}
+
+// TestNewRejectsInvalidSettings tests the validation of the level and format
+func TestNewRejectsInvalidSettings(t *testing.T) {
+	// Act
+	_, levelErr := New(&bytes.Buffer{}, "verbose", FormatJSON)
+	_, formatErr := New(&bytes.Buffer{}, "debug", "xml")
+
+	// Assert
+	assert.Error(t, levelErr)
+	assert.Error(t, formatErr)
+}
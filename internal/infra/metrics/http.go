@@ -0,0 +1,84 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/go-chi/chi/middleware"
+)
+
+// Middleware counts the requests and measures their latency by route pattern, so that
+// every CEP is reported under the same /{cep} route
+func (m *Metrics) Middleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		start := time.Now()
+		m.httpRequestsInFlight.Inc()
+		defer m.httpRequestsInFlight.Dec()
+
+		ww := middleware.NewWrapResponseWriter(w, r.ProtoMajor)
+		next.ServeHTTP(ww, r)
+
+		status := ww.Status()
+		if status == 0 {
+			status = http.StatusOK
+		}
+
+		route := "unmatched"
+		if routeContext := chi.RouteContext(r.Context()); routeContext != nil && routeContext.RoutePattern() != "" {
+			route = routeContext.RoutePattern()
+		}
+
+		labels := []string{route, r.Method, strconv.Itoa(status)}
+		m.httpRequests.WithLabelValues(labels...).Inc()
+		m.httpRequestDuration.WithLabelValues(labels...).Observe(time.Since(start).Seconds())
+	})
+}
+
+// Transport instruments the calls to an upstream API made through the base round tripper
+func (m *Metrics) Transport(upstream string, base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &transport{metrics: m, upstream: upstream, base: base}
+}
+
+type transport struct {
+	metrics  *Metrics
+	upstream string
+	base     http.RoundTripper
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	start := time.Now()
+	resp, err := t.base.RoundTrip(req)
+
+	t.metrics.upstreamRequestDuration.WithLabelValues(t.upstream).Observe(time.Since(start).Seconds())
+	t.metrics.upstreamRequests.WithLabelValues(t.upstream, resultClass(resp, err)).Inc()
+
+	return resp, err
+}
+
+// resultClass classifies an upstream call as success, http_4xx, http_5xx, timeout, canceled or network_error
+func resultClass(resp *http.Response, err error) string {
+	var netErr net.Error
+	switch {
+	case errors.Is(err, context.Canceled):
+		return "canceled"
+	case errors.Is(err, context.DeadlineExceeded), errors.As(err, &netErr) && netErr.Timeout():
+		return "timeout"
+	case err != nil:
+		return "network_error"
+	case resp.StatusCode >= http.StatusInternalServerError:
+		return "http_5xx"
+	case resp.StatusCode >= http.StatusBadRequest:
+		return "http_4xx"
+	default:
+		return "success"
+	}
+}
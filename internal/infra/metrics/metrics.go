@@ -0,0 +1,226 @@
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"github.com/xavierpms/weather-by-city/internal/infra/breaker"
+	"github.com/xavierpms/weather-by-city/internal/infra/quota"
+)
+
+const namespace = "weather"
+
+// Upstream names used as the upstream label
+const (
+	UpstreamViaCEP     = "viacep"
+	UpstreamWeatherAPI = "weatherapi"
+	UpstreamOpenMeteo  = "openmeteo"
+)
+
+// Metrics holds the Prometheus collectors of the service
+type Metrics struct {
+	registry *prometheus.Registry
+
+	httpRequests         *prometheus.CounterVec
+	httpRequestDuration  *prometheus.HistogramVec
+	httpRequestsInFlight prometheus.Gauge
+
+	temperatureLookups        *prometheus.CounterVec
+	temperatureLookupDuration prometheus.Histogram
+
+	upstreamRequests        *prometheus.CounterVec
+	upstreamRequestDuration *prometheus.HistogramVec
+
+	cacheLookups *prometheus.CounterVec
+
+	breakerState       *prometheus.GaugeVec
+	breakerTransitions *prometheus.CounterVec
+
+	rateLimitDecisions *prometheus.CounterVec
+
+	quotaRejections *prometheus.CounterVec
+}
+
+// New creates the collectors and registers them, along with the Go runtime and process collectors
+func New() *Metrics {
+	m := &Metrics{
+		registry: prometheus.NewRegistry(),
+
+		httpRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "http_requests_total",
+			Help:      "HTTP requests by route pattern, method and status code.",
+		}, []string{"route", "method", "status"}),
+		httpRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "http_request_duration_seconds",
+			Help:      "HTTP request latency by route pattern, method and status code.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"route", "method", "status"}),
+		httpRequestsInFlight: prometheus.NewGauge(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "http_requests_in_flight",
+			Help:      "HTTP requests currently being served.",
+		}),
+
+		temperatureLookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "temperature_lookups_total",
+			Help:      "Temperature lookups by CEP, by outcome.",
+		}, []string{"outcome"}),
+		temperatureLookupDuration: prometheus.NewHistogram(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "temperature_lookup_duration_seconds",
+			Help:      "Latency of the temperature lookups by CEP, including the upstream calls.",
+			Buckets:   prometheus.DefBuckets,
+		}),
+
+		upstreamRequests: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "upstream_requests_total",
+			Help:      "Calls to the upstream APIs by upstream and result class.",
+		}, []string{"upstream", "result"}),
+		upstreamRequestDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Namespace: namespace,
+			Name:      "upstream_request_duration_seconds",
+			Help:      "Latency of the calls to the upstream APIs.",
+			Buckets:   prometheus.DefBuckets,
+		}, []string{"upstream"}),
+
+		cacheLookups: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "cache_lookups_total",
+			Help:      "Cache lookups by cache and result (hit or miss).",
+		}, []string{"cache", "result"}),
+
+		breakerState: prometheus.NewGaugeVec(prometheus.GaugeOpts{
+			Namespace: namespace,
+			Name:      "circuit_breaker_state",
+			Help:      "State of the circuit breaker of each upstream: 0 closed, 1 half-open, 2 open.",
+		}, []string{"upstream"}),
+		breakerTransitions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "circuit_breaker_transitions_total",
+			Help:      "Circuit breaker state transitions by upstream and new state.",
+		}, []string{"upstream", "state"}),
+
+		rateLimitDecisions: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "rate_limit_decisions_total",
+			Help:      "Rate limit decisions by route pattern and result (allowed or limited).",
+		}, []string{"route", "result"}),
+
+		quotaRejections: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Namespace: namespace,
+			Name:      "upstream_quota_rejections_total",
+			Help:      "Upstream calls refused before being sent, by upstream and reason (rate_limit or quota).",
+		}, []string{"upstream", "reason"}),
+	}
+
+	m.registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+		m.httpRequests,
+		m.httpRequestDuration,
+		m.httpRequestsInFlight,
+		m.temperatureLookups,
+		m.temperatureLookupDuration,
+		m.upstreamRequests,
+		m.upstreamRequestDuration,
+		m.cacheLookups,
+		m.breakerState,
+		m.breakerTransitions,
+		m.rateLimitDecisions,
+		m.quotaRejections,
+	)
+
+	return m
+}
+
+// Handler serves the metrics in the Prometheus exposition format
+func (m *Metrics) Handler() http.Handler {
+	return promhttp.HandlerFor(m.registry, promhttp.HandlerOpts{})
+}
+
+// ObserveCacheLookup counts a cache hit or miss
+func (m *Metrics) ObserveCacheLookup(cache string, hit bool) {
+	result := "miss"
+	if hit {
+		result = "hit"
+	}
+	m.cacheLookups.WithLabelValues(cache, result).Inc()
+}
+
+// ObserveBreakerState reports the initial state of a circuit breaker
+func (m *Metrics) ObserveBreakerState(upstream string, state breaker.State) {
+	m.breakerState.WithLabelValues(upstream).Set(float64(state))
+}
+
+// ObserveBreakerTransition records a circuit breaker state change, matching breaker.Settings.OnStateChange
+func (m *Metrics) ObserveBreakerTransition(upstream string, from, to breaker.State) {
+	m.breakerState.WithLabelValues(upstream).Set(float64(to))
+	m.breakerTransitions.WithLabelValues(upstream, to.String()).Inc()
+}
+
+// ObserveRateLimit counts a request allowed or limited by the rate limiter
+func (m *Metrics) ObserveRateLimit(route string, allowed bool) {
+	result := "limited"
+	if allowed {
+		result = "allowed"
+	}
+	m.rateLimitDecisions.WithLabelValues(route, result).Inc()
+}
+
+// ObserveQuotaRejection counts an upstream call refused by its rate limit or call budget
+func (m *Metrics) ObserveQuotaRejection(upstream, reason string) {
+	m.quotaRejections.WithLabelValues(upstream, reason).Inc()
+}
+
+// RegisterQuota exposes the calls counted by the budget, its limits and its level, read on each scrape
+func (m *Metrics) RegisterQuota(budget *quota.Budget) {
+	labels := func(extra ...string) prometheus.Labels {
+		l := prometheus.Labels{"upstream": budget.Name()}
+		for i := 0; i+1 < len(extra); i += 2 {
+			l[extra[i]] = extra[i+1]
+		}
+		return l
+	}
+	gauge := func(name, help string, constLabels prometheus.Labels, value func() float64) prometheus.Collector {
+		return prometheus.NewGaugeFunc(prometheus.GaugeOpts{
+			Namespace:   namespace,
+			Name:        name,
+			Help:        help,
+			ConstLabels: constLabels,
+		}, value)
+	}
+
+	limits := budget.Limits()
+	collectors := []prometheus.Collector{
+		gauge("upstream_quota_calls", "Upstream calls counted in the current UTC day or month, by upstream and window.",
+			labels("window", "daily"), func() float64 { return float64(budget.Usage().Daily) }),
+		gauge("upstream_quota_calls", "Upstream calls counted in the current UTC day or month, by upstream and window.",
+			labels("window", "monthly"), func() float64 { return float64(budget.Usage().Monthly) }),
+		gauge("upstream_quota_level", "Level of the upstream call budget: 0 normal, 1 soft limit reached, 2 hard limit reached.",
+			labels(), func() float64 { return float64(budget.Level()) }),
+	}
+	for _, limit := range []struct {
+		window, level string
+		value         int
+	}{
+		{"daily", "soft", limits.DailySoft},
+		{"daily", "hard", limits.DailyHard},
+		{"monthly", "soft", limits.MonthlySoft},
+		{"monthly", "hard", limits.MonthlyHard},
+	} {
+		if limit.value > 0 {
+			value := float64(limit.value)
+			collectors = append(collectors, gauge("upstream_quota_limit",
+				"Configured upstream call limits, by upstream, window and level.",
+				labels("window", limit.window, "level", limit.level), func() float64 { return value }))
+		}
+	}
+
+	m.registry.MustRegister(collectors...)
+}
@@ -0,0 +1,112 @@
+package metrics
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/xavierpms/weather-by-city/internal/domain"
+	"github.com/xavierpms/weather-by-city/internal/infra/breaker"
+)
+
+// MockTemperatureUseCase is a mock of the TemperatureUseCase for testing
+type MockTemperatureUseCase struct {
+	getTemperatureByCEPFunc func(ctx context.Context, cep string) (*domain.Temperature, error)
+}
+
+func (m *MockTemperatureUseCase) GetTemperatureByCEP(ctx context.Context, cep string) (*domain.Temperature, error) {
+	return m.getTemperatureByCEPFunc(ctx, cep)
+}
+
+// TestMiddlewareLabelsByRoutePattern tests that requests are grouped by route instead of by path
+func TestMiddlewareLabelsByRoutePattern(t *testing.T) {
+	// Arrange
+	m := New()
+	router := chi.NewRouter()
+	router.Use(m.Middleware)
+	router.Get("/{cep}", func(w http.ResponseWriter, r *http.Request) {
+		if chi.URLParam(r, "cep") == "99999999" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	})
+
+	// Act
+	for _, path := range []string{"/01001000", "/32450000", "/99999999"} {
+		router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", path, nil))
+	}
+
+	// Assert
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.httpRequests.WithLabelValues("/{cep}", "GET", "200")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.httpRequests.WithLabelValues("/{cep}", "GET", "404")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.httpRequestsInFlight))
+}
+
+// TestTransportClassifiesUpstreamResults tests the upstream result classes
+func TestTransportClassifiesUpstreamResults(t *testing.T) {
+	// Arrange
+	m := New()
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/fail" {
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer upstream.Close()
+	client := &http.Client{Transport: m.Transport(UpstreamViaCEP, nil)}
+
+	// Act
+	for _, path := range []string{"/ok", "/fail"} {
+		resp, err := client.Get(upstream.URL + path)
+		assert.NoError(t, err)
+		resp.Body.Close()
+	}
+	_, err := client.Get("http://127.0.0.1:1/unreachable")
+
+	// Assert
+	assert.Error(t, err)
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.upstreamRequests.WithLabelValues(UpstreamViaCEP, "success")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.upstreamRequests.WithLabelValues(UpstreamViaCEP, "http_5xx")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.upstreamRequests.WithLabelValues(UpstreamViaCEP, "network_error")))
+}
+
+// TestHandlerExposesUseCaseAndCacheMetrics tests the exposition of the use case and cache metrics
+func TestHandlerExposesUseCaseAndCacheMetrics(t *testing.T) {
+	// Arrange
+	m := New()
+	useCase := NewInstrumentedTemperatureUseCase(&MockTemperatureUseCase{
+		getTemperatureByCEPFunc: func(ctx context.Context, cep string) (*domain.Temperature, error) {
+			return nil, domain.ErrCEPNotFound
+		},
+	}, m)
+	m.ObserveCacheLookup("cep", true)
+	m.ObserveCacheLookup("cep", false)
+
+	// Act
+	_, _ = useCase.GetTemperatureByCEP(context.Background(), "99999999")
+	w := httptest.NewRecorder()
+	m.Handler().ServeHTTP(w, httptest.NewRequest("GET", "/metrics", nil))
+
+	// Assert
+	body := w.Body.String()
+	assert.Contains(t, body, `weather_temperature_lookups_total{outcome="zipcode_not_found"} 1`)
+	assert.Contains(t, body, `weather_cache_lookups_total{cache="cep",result="hit"} 1`)
+	assert.Contains(t, body, `weather_cache_lookups_total{cache="cep",result="miss"} 1`)
+	assert.Contains(t, body, "go_goroutines")
+}
+
+// TestObserveBreakerTransition tests the circuit breaker state gauge and transition counter
+func TestObserveBreakerTransition(t *testing.T) {
+	// Arrange
+	m := New()
+	m.ObserveBreakerState(UpstreamWeatherAPI, breaker.StateClosed)
+
+	// Act
+	m.ObserveBreakerTransition(UpstreamWeatherAPI, breaker.StateClosed, breaker.StateOpen)
+
+	// Assert
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.breakerState.WithLabelValues(UpstreamWeatherAPI)))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.breakerTransitions.WithLabelValues(UpstreamWeatherAPI, "open")))
+}
@@ -0,0 +1,53 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/xavierpms/weather-by-city/internal/domain"
+)
+
+// InstrumentedTemperatureUseCase decorates a domain.TemperatureUseCase with outcome and latency metrics
+type InstrumentedTemperatureUseCase struct {
+	next    domain.TemperatureUseCase
+	metrics *Metrics
+}
+
+// NewInstrumentedTemperatureUseCase creates a new instrumented temperature use case
+func NewInstrumentedTemperatureUseCase(next domain.TemperatureUseCase, metrics *Metrics) domain.TemperatureUseCase {
+	return &InstrumentedTemperatureUseCase{
+		next:    next,
+		metrics: metrics,
+	}
+}
+
+// GetTemperatureByCEP executes the decorated use case and records its outcome
+func (u *InstrumentedTemperatureUseCase) GetTemperatureByCEP(ctx context.Context, cep string) (*domain.Temperature, error) {
+	start := time.Now()
+	temperature, err := u.next.GetTemperatureByCEP(ctx, cep)
+
+	u.metrics.temperatureLookupDuration.Observe(time.Since(start).Seconds())
+	u.metrics.temperatureLookups.WithLabelValues(lookupOutcome(err)).Inc()
+
+	return temperature, err
+}
+
+func lookupOutcome(err error) string {
+	switch {
+	case err == nil:
+		return "success"
+	case errors.Is(err, domain.ErrInvalidCEPFormat):
+		return "invalid_zipcode"
+	case errors.Is(err, domain.ErrCEPNotFound):
+		return "zipcode_not_found"
+	case errors.Is(err, domain.ErrTemperatureNotFound):
+		return "temperature_unavailable"
+	case errors.Is(err, domain.ErrUpstreamQuotaExceeded):
+		return "upstream_quota_exceeded"
+	case errors.Is(err, domain.ErrUpstreamUnavailable):
+		return "upstream_unavailable"
+	default:
+		return "error"
+	}
+}
@@ -0,0 +1,199 @@
+package quota
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/xavierpms/weather-by-city/internal/domain"
+)
+
+// monthLayout formats the UTC month of the monthly window
+const monthLayout = "2006-01"
+
+// Level tells how much of the call budget is spent
+type Level int
+
+const (
+	LevelNormal Level = iota
+	// LevelSoft is reached at a soft limit, where cached data is preferred over new calls
+	LevelSoft
+	// LevelHard is reached at a hard limit, where calls are refused
+	LevelHard
+)
+
+// String returns the name of the level, as used in logs and metrics
+func (l Level) String() string {
+	switch l {
+	case LevelSoft:
+		return "soft"
+	case LevelHard:
+		return "hard"
+	default:
+		return "normal"
+	}
+}
+
+// Limits are the soft and hard call limits per UTC day and month. Zero means no limit.
+type Limits struct {
+	DailySoft   int
+	DailyHard   int
+	MonthlySoft int
+	MonthlyHard int
+}
+
+// Counts are the calls made in the current day and month
+type Counts struct {
+	Day     string `json:"day"`
+	Daily   int    `json:"daily"`
+	Month   string `json:"month"`
+	Monthly int    `json:"monthly"`
+}
+
+// Store persists the counts, so the budget survives restarts
+type Store interface {
+	Load() (Counts, error)
+	Save(counts Counts) error
+}
+
+// Budget counts the calls to an upstream against its daily and monthly limits
+type Budget struct {
+	name  string
+	store Store
+	now   func() time.Time
+
+	mu     sync.Mutex
+	limits Limits
+	counts Counts
+	dirty  bool
+}
+
+// NewBudget creates a budget, resuming the counts kept by the store
+func NewBudget(name string, limits Limits, store Store) (*Budget, error) {
+	counts, err := store.Load()
+	if err != nil {
+		return nil, fmt.Errorf("failed to load the %s call counts: %w", name, err)
+	}
+
+	return &Budget{
+		name:   name,
+		limits: limits,
+		store:  store,
+		now:    time.Now,
+		counts: counts,
+	}, nil
+}
+
+// Name returns the name of the upstream
+func (b *Budget) Name() string {
+	return b.name
+}
+
+// Limits returns the configured limits
+func (b *Budget) Limits() Limits {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.limits
+}
+
+// SetLimits replaces the limits, keeping the calls already counted
+func (b *Budget) SetLimits(limits Limits) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.limits = limits
+}
+
+// Usage returns the calls made in the current day and month
+func (b *Budget) Usage() Counts {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.roll()
+	return b.counts
+}
+
+// Level returns how much of the budget is spent
+func (b *Budget) Level() Level {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.roll()
+	return b.level()
+}
+
+// Reserve counts a call, unless a hard limit is reached
+func (b *Budget) Reserve() error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.roll()
+	if reached(b.counts.Daily, b.limits.DailyHard) {
+		return b.exceeded("daily", b.limits.DailyHard)
+	}
+	if reached(b.counts.Monthly, b.limits.MonthlyHard) {
+		return b.exceeded("monthly", b.limits.MonthlyHard)
+	}
+
+	b.counts.Daily++
+	b.counts.Monthly++
+	b.dirty = true
+	return nil
+}
+
+// Flush saves the counts when they changed since the last flush
+func (b *Budget) Flush(ctx context.Context) error {
+	b.mu.Lock()
+	if !b.dirty {
+		b.mu.Unlock()
+		return nil
+	}
+	counts := b.counts
+	b.dirty = false
+	b.mu.Unlock()
+
+	if err := b.store.Save(counts); err != nil {
+		b.mu.Lock()
+		b.dirty = true
+		b.mu.Unlock()
+		return fmt.Errorf("failed to save the %s call counts: %w", b.name, err)
+	}
+
+	return nil
+}
+
+// roll starts a new window when the UTC day or month changed
+func (b *Budget) roll() {
+	now := b.now().UTC()
+	if day := now.Format(time.DateOnly); b.counts.Day != day {
+		b.counts.Day, b.counts.Daily = day, 0
+		b.dirty = true
+	}
+	if month := now.Format(monthLayout); b.counts.Month != month {
+		b.counts.Month, b.counts.Monthly = month, 0
+		b.dirty = true
+	}
+}
+
+func (b *Budget) level() Level {
+	switch {
+	case reached(b.counts.Daily, b.limits.DailyHard), reached(b.counts.Monthly, b.limits.MonthlyHard):
+		return LevelHard
+	case reached(b.counts.Daily, b.limits.DailySoft), reached(b.counts.Monthly, b.limits.MonthlySoft):
+		return LevelSoft
+	default:
+		return LevelNormal
+	}
+}
+
+func (b *Budget) exceeded(window string, limit int) error {
+	return fmt.Errorf("%w: %w: the %s limit of %d %s calls is reached",
+		domain.ErrUpstreamUnavailable, domain.ErrUpstreamQuotaExceeded, window, limit, b.name)
+}
+
+// reached reports whether the count reached the limit, zero meaning no limit
+func reached(count, limit int) bool {
+	return limit > 0 && count >= limit
+}
@@ -0,0 +1,132 @@
+package quota
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xavierpms/weather-by-city/internal/domain"
+)
+
+// MockStore is a mock of the Store for testing
+type MockStore struct {
+	loadFunc func() (Counts, error)
+	saveFunc func(counts Counts) error
+}
+
+func (m *MockStore) Load() (Counts, error) {
+	if m.loadFunc == nil {
+		return Counts{}, nil
+	}
+	return m.loadFunc()
+}
+
+func (m *MockStore) Save(counts Counts) error {
+	if m.saveFunc == nil {
+		return nil
+	}
+	return m.saveFunc(counts)
+}
+
+// newTestBudget creates a budget whose clock is set by the returned function
+func newTestBudget(t *testing.T, limits Limits, store Store) (*Budget, func(time.Time)) {
+	budget, err := NewBudget("weatherapi", limits, store)
+	require.NoError(t, err)
+	now := time.Date(2026, 1, 31, 23, 0, 0, 0, time.UTC)
+	budget.now = func() time.Time { return now }
+	return budget, func(t time.Time) { now = t }
+}
+
+// TestBudgetLevels tests that the soft limit is reported and the hard limit refuses calls
+func TestBudgetLevels(t *testing.T) {
+	// Arrange
+	budget, _ := newTestBudget(t, Limits{DailySoft: 2, DailyHard: 3}, &MockStore{})
+	levels := []Level{}
+
+	// Act
+	for range 3 {
+		require.NoError(t, budget.Reserve())
+		levels = append(levels, budget.Level())
+	}
+	err := budget.Reserve()
+
+	// Assert
+	assert.Equal(t, []Level{LevelNormal, LevelSoft, LevelHard}, levels)
+	assert.ErrorIs(t, err, domain.ErrUpstreamQuotaExceeded)
+	assert.ErrorIs(t, err, domain.ErrUpstreamUnavailable)
+	assert.Equal(t, 3, budget.Usage().Daily)
+}
+
+// TestBudgetRollsOverWindows tests that the daily and monthly counts restart in a new UTC window
+func TestBudgetRollsOverWindows(t *testing.T) {
+	// Arrange
+	budget, setNow := newTestBudget(t, Limits{DailyHard: 1, MonthlyHard: 2}, &MockStore{})
+	require.NoError(t, budget.Reserve())
+
+	// Act
+	sameDay := budget.Reserve()
+	setNow(time.Date(2026, 2, 1, 0, 0, 0, 0, time.UTC))
+	nextMonth := budget.Reserve()
+	setNow(time.Date(2026, 2, 2, 0, 0, 0, 0, time.UTC))
+	nextDay := budget.Reserve()
+	monthSpent := func() error {
+		setNow(time.Date(2026, 2, 3, 0, 0, 0, 0, time.UTC))
+		return budget.Reserve()
+	}()
+
+	// Assert
+	assert.ErrorIs(t, sameDay, domain.ErrUpstreamQuotaExceeded)
+	assert.NoError(t, nextMonth)
+	assert.NoError(t, nextDay)
+	assert.ErrorIs(t, monthSpent, domain.ErrUpstreamQuotaExceeded)
+	assert.Equal(t, Counts{Day: "2026-02-03", Daily: 0, Month: "2026-02", Monthly: 2}, budget.Usage())
+}
+
+// TestBudgetResumesAndFlushesCounts tests that the counts are loaded from and saved to the store
+func TestBudgetResumesAndFlushesCounts(t *testing.T) {
+	// Arrange
+	saved := []Counts{}
+	failSave := true
+	store := &MockStore{
+		loadFunc: func() (Counts, error) {
+			return Counts{Day: "2026-01-31", Daily: 4, Month: "2026-01", Monthly: 40}, nil
+		},
+		saveFunc: func(counts Counts) error {
+			if failSave {
+				return errors.New("disk full")
+			}
+			saved = append(saved, counts)
+			return nil
+		},
+	}
+	budget, _ := newTestBudget(t, Limits{}, store)
+	require.NoError(t, budget.Reserve())
+
+	// Act
+	failed := budget.Flush(context.Background())
+	failSave = false
+	retried := budget.Flush(context.Background())
+	unchanged := budget.Flush(context.Background())
+
+	// Assert
+	assert.Error(t, failed)
+	assert.NoError(t, retried)
+	assert.NoError(t, unchanged)
+	assert.Equal(t, []Counts{{Day: "2026-01-31", Daily: 5, Month: "2026-01", Monthly: 41}}, saved)
+}
+
+// TestNewBudgetFailsWhenStoreCannotLoad tests that a broken store is reported at startup
+func TestNewBudgetFailsWhenStoreCannotLoad(t *testing.T) {
+	// Arrange
+	store := &MockStore{loadFunc: func() (Counts, error) { return Counts{}, errors.New("corrupt file") }}
+
+	// Act
+	budget, err := NewBudget("weatherapi", Limits{}, store)
+
+	// Assert
+	assert.Nil(t, budget)
+	assert.ErrorContains(t, err, "corrupt file")
+}
@@ -0,0 +1,104 @@
+package quota
+
+import (
+	"fmt"
+	"net/http"
+	"sync/atomic"
+	"time"
+
+	"github.com/xavierpms/weather-by-city/internal/domain"
+	"github.com/xavierpms/weather-by-city/internal/infra/ratelimit"
+)
+
+// Reasons reported to the Observer when a call is refused
+const (
+	ReasonRateLimit = "rate_limit"
+	ReasonQuota     = "quota"
+)
+
+// Observer is notified of the calls refused before reaching the upstream
+type Observer interface {
+	ObserveQuotaRejection(upstream, reason string)
+}
+
+// BudgetTransport spaces the calls to an upstream and counts them against its budget
+type BudgetTransport struct {
+	base     http.RoundTripper
+	budget   *Budget
+	rate     atomic.Pointer[ratelimit.Limit]
+	maxWait  time.Duration
+	bucket   *ratelimit.MemoryStore
+	observer Observer
+}
+
+// Transport wraps base so that calls are spaced by the rate token bucket and counted against the
+// budget. A call waits for a token unless the wait would outlast maxWait or the request deadline,
+// whichever comes first. A zero rate does not throttle. Each attempt is counted, so the transport
+// goes inside the retry transport.
+func Transport(base http.RoundTripper, budget *Budget, rate ratelimit.Limit, maxWait time.Duration, observer Observer) *BudgetTransport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	t := &BudgetTransport{
+		base:     base,
+		budget:   budget,
+		maxWait:  maxWait,
+		bucket:   ratelimit.NewMemoryStore(),
+		observer: observer,
+	}
+	t.rate.Store(&rate)
+	return t
+}
+
+// SetRate replaces the rate, which applies from the next call
+func (t *BudgetTransport) SetRate(rate ratelimit.Limit) {
+	t.rate.Store(&rate)
+}
+
+// RoundTrip waits for a token, reserves a call in the budget and sends the request
+func (t *BudgetTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if err := t.throttle(req); err != nil {
+		return nil, err
+	}
+
+	if err := t.budget.Reserve(); err != nil {
+		t.observer.ObserveQuotaRejection(t.budget.Name(), ReasonQuota)
+		return nil, err
+	}
+
+	return t.base.RoundTrip(req)
+}
+
+// throttle waits for a token of the rate token bucket, for at most maxWait
+func (t *BudgetTransport) throttle(req *http.Request) error {
+	rate := *t.rate.Load()
+	if rate.Requests == 0 {
+		return nil
+	}
+
+	ctx := req.Context()
+	giveUp := time.Now().Add(t.maxWait)
+	if deadline, ok := ctx.Deadline(); ok && deadline.Before(giveUp) {
+		giveUp = deadline
+	}
+	for {
+		decision, _ := t.bucket.Take(ctx, t.budget.Name(), rate)
+		if decision.Allowed {
+			return nil
+		}
+
+		if time.Until(giveUp) < decision.RetryAfter {
+			t.observer.ObserveQuotaRejection(t.budget.Name(), ReasonRateLimit)
+			return fmt.Errorf("%w: the %s outbound rate limit is reached", domain.ErrUpstreamUnavailable, t.budget.Name())
+		}
+
+		timer := time.NewTimer(decision.RetryAfter)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return ctx.Err()
+		case <-timer.C:
+		}
+	}
+}
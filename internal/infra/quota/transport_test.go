@@ -0,0 +1,108 @@
+package quota
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xavierpms/weather-by-city/internal/domain"
+	"github.com/xavierpms/weather-by-city/internal/infra/ratelimit"
+)
+
+// MockObserver is a mock of the Observer for testing
+type MockObserver struct {
+	rejections []string
+}
+
+func (m *MockObserver) ObserveQuotaRejection(upstream, reason string) {
+	m.rejections = append(m.rejections, upstream+":"+reason)
+}
+
+// TestTransportRefusesCallsOverHardLimit tests that calls over the hard limit never reach the upstream
+func TestTransportRefusesCallsOverHardLimit(t *testing.T) {
+	// Arrange
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer server.Close()
+	budget, _ := newTestBudget(t, Limits{DailyHard: 1}, &MockStore{})
+	observer := &MockObserver{}
+	client := &http.Client{Transport: Transport(nil, budget, ratelimit.Limit{}, time.Second, observer)}
+
+	// Act
+	resp, first := client.Get(server.URL)
+	_, second := client.Get(server.URL)
+
+	// Assert
+	require.NoError(t, first)
+	resp.Body.Close()
+	assert.ErrorIs(t, second, domain.ErrUpstreamQuotaExceeded)
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Equal(t, []string{"weatherapi:quota"}, observer.rejections)
+}
+
+// TestTransportThrottlesWithinDeadline tests that a call waits for a token only when the deadline allows it
+func TestTransportThrottlesWithinDeadline(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	budget, _ := newTestBudget(t, Limits{}, &MockStore{})
+	observer := &MockObserver{}
+	client := &http.Client{Transport: Transport(nil, budget, ratelimit.Limit{Requests: 1, Period: 50 * time.Millisecond}, time.Second, observer)}
+	get := func(timeout time.Duration) error {
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		defer cancel()
+		req, _ := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+		resp, err := client.Do(req)
+		if err == nil {
+			resp.Body.Close()
+		}
+		return err
+	}
+
+	// Act
+	first := get(time.Second)
+	tooShort := get(5 * time.Millisecond)
+	waited := get(time.Second)
+
+	// Assert
+	assert.NoError(t, first)
+	assert.ErrorIs(t, tooShort, domain.ErrUpstreamUnavailable)
+	assert.NotErrorIs(t, tooShort, domain.ErrUpstreamQuotaExceeded)
+	assert.NoError(t, waited)
+	assert.Equal(t, []string{"weatherapi:rate_limit"}, observer.rejections)
+	assert.Equal(t, 2, budget.Usage().Daily)
+}
+
+// TestTransportGivesUpAfterMaxWait tests that a call without a deadline waits for a token at most maxWait
+func TestTransportGivesUpAfterMaxWait(t *testing.T) {
+	// Arrange
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+	}))
+	defer server.Close()
+	budget, _ := newTestBudget(t, Limits{}, &MockStore{})
+	observer := &MockObserver{}
+	client := &http.Client{Transport: Transport(nil, budget, ratelimit.Limit{Requests: 1, Period: time.Hour}, 20*time.Millisecond, observer)}
+	resp, err := client.Get(server.URL)
+	require.NoError(t, err)
+	resp.Body.Close()
+
+	// Act
+	start := time.Now()
+	_, err = client.Get(server.URL)
+	elapsed := time.Since(start)
+
+	// Assert
+	assert.ErrorIs(t, err, domain.ErrUpstreamUnavailable)
+	assert.Less(t, elapsed, time.Second)
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Equal(t, []string{"weatherapi:rate_limit"}, observer.rejections)
+}
@@ -0,0 +1,35 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+
+	"github.com/xavierpms/weather-by-city/internal/infra/breaker"
+)
+
+// BreakerStore decorates a Store with a circuit breaker, so while the store is failing the
+// requests are let through at once instead of each waiting for it to time out
+type BreakerStore struct {
+	next    Store
+	breaker *breaker.Breaker
+}
+
+// NewBreakerStore creates a store protected by the breaker
+func NewBreakerStore(next Store, b *breaker.Breaker) *BreakerStore {
+	return &BreakerStore{
+		next:    next,
+		breaker: b,
+	}
+}
+
+// Take takes a token from the bucket of the key, failing fast while the breaker is open
+func (s *BreakerStore) Take(ctx context.Context, key string, limit Limit) (Decision, error) {
+	done, err := s.breaker.Allow()
+	if err != nil {
+		return Decision{}, err
+	}
+
+	decision, err := s.next.Take(ctx, key, limit)
+	done(err != nil && !errors.Is(err, context.Canceled))
+	return decision, err
+}
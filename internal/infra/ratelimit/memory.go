@@ -0,0 +1,74 @@
+package ratelimit
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// sweepInterval is how often the buckets that refilled completely are dropped
+const sweepInterval = time.Minute
+
+// MemoryStore keeps the buckets in memory, so each instance enforces its own limits
+type MemoryStore struct {
+	now func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+type bucket struct {
+	tokens float64
+	last   time.Time
+	// full is when the bucket will have refilled completely, so it can be dropped
+	full time.Time
+}
+
+// NewMemoryStore creates an empty in-memory store
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		now:     time.Now,
+		buckets: make(map[string]*bucket),
+	}
+}
+
+// Take takes a token from the bucket of the key, creating it full when missing
+func (s *MemoryStore) Take(ctx context.Context, key string, limit Limit) (Decision, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.now()
+	s.sweep(now)
+
+	b, ok := s.buckets[key]
+	if !ok {
+		b = &bucket{tokens: float64(limit.Requests), last: now}
+		s.buckets[key] = b
+	}
+
+	b.tokens = refill(limit, b.tokens, now.Sub(b.last))
+	b.last = now
+	allowed := b.tokens >= 1
+	if allowed {
+		b.tokens--
+	}
+
+	decision := decide(limit, b.tokens, allowed)
+	b.full = now.Add(decision.Reset)
+	return decision, nil
+}
+
+// sweep drops the buckets that are full again, which behave like missing ones, with the lock held
+func (s *MemoryStore) sweep(now time.Time) {
+	if now.Sub(s.lastSweep) < sweepInterval {
+		return
+	}
+	s.lastSweep = now
+
+	for key, b := range s.buckets {
+		if !now.Before(b.full) {
+			delete(s.buckets, key)
+		}
+	}
+}
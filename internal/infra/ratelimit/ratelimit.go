@@ -0,0 +1,105 @@
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Limit is a token bucket holding up to Requests tokens, refilled at Requests per Period
+type Limit struct {
+	Requests int
+	Period   time.Duration
+}
+
+// Decision is the outcome of taking a token from a bucket
+type Decision struct {
+	Allowed   bool
+	Limit     int
+	Remaining int
+	// Reset is the time until the bucket is full again
+	Reset time.Duration
+	// RetryAfter is the time until the next token is available, when the request was rejected
+	RetryAfter time.Duration
+}
+
+// Store keeps the token buckets
+type Store interface {
+	Take(ctx context.Context, key string, limit Limit) (Decision, error)
+}
+
+// ParseLimit parses a limit written as requests/period, such as 60/1m
+func ParseLimit(value string) (Limit, error) {
+	requests, period, ok := strings.Cut(strings.TrimSpace(value), "/")
+	if !ok {
+		return Limit{}, fmt.Errorf("rate limit %q must be written as requests/period", value)
+	}
+
+	n, err := strconv.Atoi(requests)
+	if err != nil || n < 1 {
+		return Limit{}, fmt.Errorf("rate limit %q must allow at least one request", value)
+	}
+	d, err := time.ParseDuration(period)
+	if err != nil || d <= 0 {
+		return Limit{}, fmt.Errorf("rate limit %q has an invalid period", value)
+	}
+
+	return Limit{Requests: n, Period: d}, nil
+}
+
+// ParseRouteLimits parses a comma-separated list of route=requests/period, such as /v1/graphql=30/1m
+func ParseRouteLimits(value string) (map[string]Limit, error) {
+	limits := make(map[string]Limit)
+	for _, entry := range strings.Split(value, ",") {
+		if strings.TrimSpace(entry) == "" {
+			continue
+		}
+
+		route, limitValue, ok := strings.Cut(entry, "=")
+		if !ok {
+			return nil, fmt.Errorf("route rate limit %q must be written as route=requests/period", entry)
+		}
+		limit, err := ParseLimit(limitValue)
+		if err != nil {
+			return nil, err
+		}
+		limits[strings.TrimSpace(route)] = limit
+	}
+
+	return limits, nil
+}
+
+// refill returns the tokens in the bucket after the elapsed time
+func refill(limit Limit, tokens float64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return tokens
+	}
+	return math.Min(float64(limit.Requests), tokens+elapsed.Seconds()*rate(limit))
+}
+
+// decide describes the bucket holding the given tokens after a take
+func decide(limit Limit, tokens float64, allowed bool) Decision {
+	decision := Decision{
+		Allowed:   allowed,
+		Limit:     limit.Requests,
+		Remaining: int(math.Floor(tokens)),
+		Reset:     seconds((float64(limit.Requests) - tokens) / rate(limit)),
+	}
+	if !allowed {
+		decision.RetryAfter = seconds((1 - tokens) / rate(limit))
+	}
+
+	return decision
+}
+
+// rate returns the tokens added per second
+func rate(limit Limit) float64 {
+	return float64(limit.Requests) / limit.Period.Seconds()
+}
+
+func seconds(s float64) time.Duration {
+	return time.Duration(s * float64(time.Second))
+}
@@ -0,0 +1,156 @@
+package ratelimit
+
+import (
+	"context"
+	"errors"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	"github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xavierpms/weather-by-city/internal/infra/breaker"
+)
+
+// TestParseRouteLimits tests the route limits syntax
+func TestParseRouteLimits(t *testing.T) {
+	// Act
+	limits, err := ParseRouteLimits("/{cep}=60/1m, /v1/temperatures/batch=10/1h")
+	_, invalidErr := ParseRouteLimits("/{cep}=60")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, map[string]Limit{
+		"/{cep}":                 {Requests: 60, Period: time.Minute},
+		"/v1/temperatures/batch": {Requests: 10, Period: time.Hour},
+	}, limits)
+	assert.Error(t, invalidErr)
+}
+
+// TestMemoryStoreRefillsTokens tests that the bucket rejects requests once empty and refills over time
+func TestMemoryStoreRefillsTokens(t *testing.T) {
+	// Arrange
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	store := NewMemoryStore()
+	store.now = func() time.Time { return now }
+	limit := Limit{Requests: 2, Period: 10 * time.Second}
+
+	// Act
+	first, _ := store.Take(context.Background(), "client", limit)
+	second, _ := store.Take(context.Background(), "client", limit)
+	rejected, _ := store.Take(context.Background(), "client", limit)
+	now = now.Add(5 * time.Second)
+	refilled, _ := store.Take(context.Background(), "client", limit)
+	other, _ := store.Take(context.Background(), "other", limit)
+
+	// Assert
+	assert.True(t, first.Allowed)
+	assert.Equal(t, 1, first.Remaining)
+	assert.True(t, second.Allowed)
+	assert.Equal(t, 0, second.Remaining)
+	assert.Equal(t, 10*time.Second, second.Reset)
+
+	assert.False(t, rejected.Allowed)
+	assert.Equal(t, 5*time.Second, rejected.RetryAfter)
+
+	assert.True(t, refilled.Allowed)
+	assert.True(t, other.Allowed)
+}
+
+// TestRedisStoreSharesBuckets tests the Redis store against an in-process server
+func TestRedisStoreSharesBuckets(t *testing.T) {
+	// Arrange
+	server := miniredis.RunT(t)
+	client := redis.NewClient(&redis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	// The buckets follow the server clock, whatever the clocks of the instances
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	server.SetTime(now)
+	first := NewRedisStore(client, "ratelimit:", time.Second)
+	second := NewRedisStore(client, "ratelimit:", time.Second)
+	limit := Limit{Requests: 2, Period: 10 * time.Second}
+
+	// Act
+	a, errA := first.Take(context.Background(), "client", limit)
+	b, errB := second.Take(context.Background(), "client", limit)
+	c, errC := first.Take(context.Background(), "client", limit)
+	server.SetTime(now.Add(5 * time.Second))
+	d, errD := second.Take(context.Background(), "client", limit)
+
+	// Assert
+	require.NoError(t, errA)
+	require.NoError(t, errB)
+	require.NoError(t, errC)
+	require.NoError(t, errD)
+	assert.True(t, a.Allowed)
+	assert.True(t, b.Allowed)
+	assert.Equal(t, 0, b.Remaining)
+	assert.False(t, c.Allowed)
+	assert.Equal(t, 5*time.Second, c.RetryAfter)
+	assert.True(t, d.Allowed)
+	assert.True(t, server.Exists("ratelimit:client"))
+}
+
+// TestRedisStoreGivesUpAfterTheTimeout tests that a server that does not answer delays a call by no more than the timeout
+func TestRedisStoreGivesUpAfterTheTimeout(t *testing.T) {
+	// Arrange
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			defer conn.Close()
+		}
+	}()
+	client := redis.NewClient(&redis.Options{Addr: listener.Addr().String(), ContextTimeoutEnabled: true, MaxRetries: -1})
+	defer client.Close()
+	store := NewRedisStore(client, "ratelimit:", 50*time.Millisecond)
+
+	// Act
+	start := time.Now()
+	_, err = store.Take(context.Background(), "client", Limit{Requests: 1, Period: time.Second})
+
+	// Assert
+	assert.Error(t, err)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+// MockStore is a mock of the Store for testing
+type MockStore struct {
+	takeFunc func(key string, limit Limit) (Decision, error)
+}
+
+func (m *MockStore) Take(ctx context.Context, key string, limit Limit) (Decision, error) {
+	return m.takeFunc(key, limit)
+}
+
+// TestBreakerStoreFailsFast tests that the store is no longer called once its failures opened the breaker
+func TestBreakerStoreFailsFast(t *testing.T) {
+	// Arrange
+	calls := 0
+	failing := &MockStore{takeFunc: func(key string, limit Limit) (Decision, error) {
+		calls++
+		return Decision{}, errors.New("connection refused")
+	}}
+	b := breaker.New("ratelimit_redis", breaker.Settings{FailureThreshold: 2, OpenDuration: time.Hour, HalfOpenProbes: 1})
+	store := NewBreakerStore(failing, b)
+	limit := Limit{Requests: 1, Period: time.Second}
+
+	// Act
+	_, first := store.Take(context.Background(), "client", limit)
+	_, second := store.Take(context.Background(), "client", limit)
+	_, rejected := store.Take(context.Background(), "client", limit)
+
+	// Assert
+	assert.Error(t, first)
+	assert.Error(t, second)
+	assert.ErrorIs(t, rejected, breaker.ErrOpen)
+	assert.Equal(t, 2, calls)
+}
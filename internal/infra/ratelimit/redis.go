@@ -0,0 +1,80 @@
+package ratelimit
+
+import (
+	"context"
+	"strconv"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// takeScript refills and takes a token atomically. The bucket is stored as a hash with the
+// remaining tokens and the time of the last update, and expires once it would be full again.
+// The time is read from the server, so instances with skewed clocks refill the buckets alike.
+var takeScript = redis.NewScript(`
+local capacity = tonumber(ARGV[1])
+local period_ms = tonumber(ARGV[2])
+local time = redis.call("TIME")
+local now_ms = tonumber(time[1]) * 1000 + math.floor(tonumber(time[2]) / 1000)
+
+local state = redis.call("HMGET", KEYS[1], "tokens", "ts")
+local tokens = tonumber(state[1])
+local ts = tonumber(state[2])
+if tokens == nil or ts == nil then
+	tokens = capacity
+	ts = now_ms
+end
+
+tokens = math.min(capacity, tokens + math.max(0, now_ms - ts) * capacity / period_ms)
+local allowed = 0
+if tokens >= 1 then
+	tokens = tokens - 1
+	allowed = 1
+end
+
+redis.call("HSET", KEYS[1], "tokens", tostring(tokens), "ts", now_ms)
+redis.call("PEXPIRE", KEYS[1], period_ms)
+return {allowed, tostring(tokens)}
+`)
+
+// RedisStore keeps the buckets in Redis, or any server speaking its protocol, so the limits
+// are shared by every instance
+type RedisStore struct {
+	client  redis.Scripter
+	prefix  string
+	timeout time.Duration
+}
+
+// NewRedisStore creates a store keeping the buckets under the key prefix. Each call gives up after
+// timeout, so an unreachable server delays the requests by no more than that; zero disables it.
+func NewRedisStore(client redis.Scripter, prefix string, timeout time.Duration) *RedisStore {
+	return &RedisStore{
+		client:  client,
+		prefix:  prefix,
+		timeout: timeout,
+	}
+}
+
+// Take takes a token from the bucket of the key, creating it full when missing
+func (s *RedisStore) Take(ctx context.Context, key string, limit Limit) (Decision, error) {
+	if s.timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, s.timeout)
+		defer cancel()
+	}
+
+	result, err := takeScript.Run(ctx, s.client, []string{s.prefix + key},
+		limit.Requests, limit.Period.Milliseconds()).Slice()
+	if err != nil {
+		return Decision{}, err
+	}
+
+	allowed, _ := result[0].(int64)
+	tokensValue, _ := result[1].(string)
+	tokens, err := strconv.ParseFloat(tokensValue, 64)
+	if err != nil {
+		return Decision{}, err
+	}
+
+	return decide(limit, tokens, allowed == 1), nil
+}
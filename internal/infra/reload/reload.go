@@ -0,0 +1,192 @@
+package reload
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"os"
+	"os/signal"
+	"slices"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/xavierpms/weather-by-city/internal/config"
+)
+
+// Status reports the config in use and the outcome of the last reload
+type Status struct {
+	LoadedAt       time.Time        `json:"loaded_at"`
+	LastReloadAt   *time.Time       `json:"last_reload_at,omitempty"`
+	LastError      string           `json:"last_error,omitempty"`
+	PendingRestart []string         `json:"pending_restart"`
+	Settings       []config.Setting `json:"settings"`
+}
+
+// Reloader loads the config again on demand, on SIGHUP or when the config file changes, and
+// applies the settings that can change while serving. The other settings keep the value read
+// at startup and are reported as pending a restart.
+type Reloader struct {
+	file       string
+	load       func() (*config.Config, error)
+	apply      func(cfg *config.Config) error
+	reloadable map[string]bool
+	now        func() time.Time
+
+	mu           sync.Mutex
+	startup      *config.Config
+	current      *config.Config
+	loadedAt     time.Time
+	lastReloadAt time.Time
+	lastError    string
+	pending      []string
+	fileState    fileInfo
+}
+
+// NewReloader creates a reloader of the config in use, read from file when it is set. load reads
+// the config again and apply swaps the reloadable settings, leaving everything untouched when it
+// returns an error.
+func NewReloader(current *config.Config, file string, load func() (*config.Config, error), apply func(cfg *config.Config) error, reloadable []string) *Reloader {
+	names := make(map[string]bool, len(reloadable))
+	for _, name := range reloadable {
+		names[name] = true
+	}
+
+	fileState, _ := stat(file)
+	return &Reloader{
+		file:       file,
+		load:       load,
+		apply:      apply,
+		reloadable: names,
+		now:        time.Now,
+		startup:    current,
+		current:    current,
+		loadedAt:   time.Now(),
+		pending:    []string{},
+		fileState:  fileState,
+	}
+}
+
+// Reload loads and validates the config and applies it. An invalid config is reported and the
+// config in use is kept.
+func (r *Reloader) Reload() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.lastReloadAt = r.now()
+	// The file is checked before it is read, so a change made while loading triggers another reload
+	r.fileState, _ = stat(r.file)
+	cfg, err := r.load()
+	if err == nil {
+		err = cfg.Validate()
+	}
+	if err == nil && len(r.current.Changed(cfg)) > 0 {
+		err = r.apply(cfg)
+	}
+	if err != nil {
+		r.lastError = err.Error()
+		slog.Error("Config reload failed, keeping the current config", "err", err)
+		return err
+	}
+
+	changed := r.current.Changed(cfg)
+	r.lastError = ""
+	if len(changed) == 0 {
+		slog.Info("Config reloaded without changes")
+		return nil
+	}
+
+	r.pending = slices.DeleteFunc(r.startup.Changed(cfg), func(name string) bool { return r.reloadable[name] })
+	r.current = cfg
+	r.loadedAt = r.lastReloadAt
+	slog.Info("Config reloaded", "changed", changed, "pending_restart", r.pending)
+	if len(r.pending) > 0 {
+		slog.Warn("Some changed settings only apply after a restart", "pending_restart", r.pending)
+	}
+
+	return nil
+}
+
+// Status reports the config in use, with the secrets redacted
+func (r *Reloader) Status() Status {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	status := Status{
+		LoadedAt:       r.loadedAt,
+		LastError:      r.lastError,
+		PendingRestart: slices.Clone(r.pending),
+		Settings:       r.current.Settings(),
+	}
+	if !r.lastReloadAt.IsZero() {
+		lastReloadAt := r.lastReloadAt
+		status.LastReloadAt = &lastReloadAt
+	}
+
+	return status
+}
+
+// Run reloads the config on SIGHUP and, when there is a config file, whenever its modification
+// time or size changes, checked at every interval. It returns when the context is cancelled.
+func (r *Reloader) Run(ctx context.Context, interval time.Duration) {
+	hangup := make(chan os.Signal, 1)
+	signal.Notify(hangup, syscall.SIGHUP)
+	defer signal.Stop(hangup)
+
+	var poll <-chan time.Time
+	if r.file != "" {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		poll = ticker.C
+		slog.Info("Watching the config file", "file", r.file, "interval", interval)
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hangup:
+			slog.Info("SIGHUP received, reloading the config")
+			r.Reload()
+		case <-poll:
+			if !r.fileChanged() {
+				continue
+			}
+			slog.Info("Config file changed, reloading the config", "file", r.file)
+			r.Reload()
+		}
+	}
+}
+
+// fileChanged reports whether the config file differs from the one last loaded. A missing file is
+// not a change, as it may disappear for a moment while an editor or a ConfigMap replaces it.
+func (r *Reloader) fileChanged() bool {
+	info, err := stat(r.file)
+	if err != nil {
+		slog.Debug("Config file unavailable", "file", r.file, "err", err)
+		return false
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return !info.modTime.Equal(r.fileState.modTime) || info.size != r.fileState.size
+}
+
+// fileInfo is what the watcher compares to detect a change of the config file
+type fileInfo struct {
+	modTime time.Time
+	size    int64
+}
+
+func stat(file string) (fileInfo, error) {
+	if file == "" {
+		return fileInfo{}, errors.New("no config file")
+	}
+	info, err := os.Stat(file)
+	if err != nil {
+		return fileInfo{}, err
+	}
+
+	return fileInfo{modTime: info.ModTime(), size: info.Size()}, nil
+}
@@ -0,0 +1,122 @@
+package reload
+
+import (
+	"context"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xavierpms/weather-by-city/internal/config"
+)
+
+// newTestReloader loads the config from a YAML file and records every config applied
+func newTestReloader(t *testing.T, content string) (*Reloader, string, *[]*config.Config) {
+	t.Setenv("CACHE_CEP_TTL", "")
+	t.Setenv("PORT", "")
+	t.Setenv("WEATHER_API_KEY", "test_key")
+	file := filepath.Join(t.TempDir(), "config.yaml")
+	writeFile(t, file, content)
+	load := func() (*config.Config, error) { return config.Load(config.Options{File: file}) }
+	current, err := load()
+	require.NoError(t, err)
+
+	applied := []*config.Config{}
+	reloader := NewReloader(current, file, load, func(cfg *config.Config) error {
+		applied = append(applied, cfg)
+		return nil
+	}, []string{"CACHE_CEP_TTL"})
+	return reloader, file, &applied
+}
+
+func writeFile(t *testing.T, file, content string) {
+	require.NoError(t, os.WriteFile(file, []byte(content), 0o644))
+}
+
+func settingValue(status Status, name string) string {
+	for _, setting := range status.Settings {
+		if setting.Name == name {
+			return setting.Value
+		}
+	}
+	return ""
+}
+
+// TestReloadAppliesChangedSettings tests that a valid config is applied and the settings needing a restart are reported
+func TestReloadAppliesChangedSettings(t *testing.T) {
+	// Arrange
+	reloader, file, applied := newTestReloader(t, "cache_cep_ttl: 1h\nport: 8080\n")
+	writeFile(t, file, "cache_cep_ttl: 2h\nport: 9090\n")
+
+	// Act
+	err := reloader.Reload()
+	status := reloader.Status()
+
+	// Assert
+	require.NoError(t, err)
+	require.Len(t, *applied, 1)
+	assert.Equal(t, 2*time.Hour, (*applied)[0].CacheCEPTTL)
+	assert.Equal(t, "2h", settingValue(status, "CACHE_CEP_TTL"))
+	assert.Equal(t, []string{"PORT"}, status.PendingRestart)
+	assert.Empty(t, status.LastError)
+	assert.NotNil(t, status.LastReloadAt)
+}
+
+// TestReloadKeepsTheConfigWhenInvalid tests that an invalid config is reported and not applied
+func TestReloadKeepsTheConfigWhenInvalid(t *testing.T) {
+	// Arrange
+	reloader, file, applied := newTestReloader(t, "cache_cep_ttl: 1h\n")
+	writeFile(t, file, "cache_cep_ttl: soon\n")
+
+	// Act
+	err := reloader.Reload()
+	status := reloader.Status()
+
+	// Assert
+	assert.ErrorContains(t, err, "CACHE_CEP_TTL must be a duration")
+	assert.Empty(t, *applied)
+	assert.Equal(t, "1h", settingValue(status, "CACHE_CEP_TTL"))
+	assert.Contains(t, status.LastError, "CACHE_CEP_TTL must be a duration")
+}
+
+// TestReloadKeepsTheConfigWhenApplyFails tests that a config refused by the components is not kept
+func TestReloadKeepsTheConfigWhenApplyFails(t *testing.T) {
+	// Arrange
+	reloader, file, _ := newTestReloader(t, "cache_cep_ttl: 1h\n")
+	reloader.apply = func(cfg *config.Config) error { return errors.New("invalid RATE_LIMIT_DEFAULT") }
+	writeFile(t, file, "cache_cep_ttl: 2h\n")
+
+	// Act
+	err := reloader.Reload()
+	status := reloader.Status()
+
+	// Assert
+	assert.EqualError(t, err, "invalid RATE_LIMIT_DEFAULT")
+	assert.Equal(t, "1h", settingValue(status, "CACHE_CEP_TTL"))
+	assert.Equal(t, "invalid RATE_LIMIT_DEFAULT", status.LastError)
+}
+
+// TestRunReloadsWhenTheFileChanges tests that the watcher applies a change of the config file
+func TestRunReloadsWhenTheFileChanges(t *testing.T) {
+	// Arrange
+	reloader, file, _ := newTestReloader(t, "cache_cep_ttl: 1h\n")
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		reloader.Run(ctx, 10*time.Millisecond)
+		close(done)
+	}()
+
+	// Act
+	writeFile(t, file, "cache_cep_ttl: 30m\n")
+
+	// Assert
+	assert.Eventually(t, func() bool {
+		return settingValue(reloader.Status(), "CACHE_CEP_TTL") == "30m"
+	}, time.Second, 10*time.Millisecond)
+	cancel()
+	<-done
+}
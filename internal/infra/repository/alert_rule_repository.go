@@ -0,0 +1,91 @@
+package repository
+
+import (
+	"sort"
+	"sync"
+
+	"github.com/xavierpms/weather-by-city/internal/domain"
+)
+
+// AlertRuleRepositoryImpl implements domain.AlertRuleRepository in memory
+type AlertRuleRepositoryImpl struct {
+	mu    sync.RWMutex
+	rules map[string]*domain.AlertRule
+}
+
+// NewAlertRuleRepository creates a new in-memory alert rule repository
+func NewAlertRuleRepository() domain.AlertRuleRepository {
+	return &AlertRuleRepositoryImpl{
+		rules: make(map[string]*domain.AlertRule),
+	}
+}
+
+// Create stores a new alert rule
+func (r *AlertRuleRepositoryImpl) Create(rule *domain.AlertRule) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *rule
+	r.rules[rule.ID] = &stored
+	return nil
+}
+
+// Get returns the alert rule with the given ID
+func (r *AlertRuleRepositoryImpl) Get(id string) (*domain.AlertRule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rule, ok := r.rules[id]
+	if !ok {
+		return nil, domain.ErrAlertRuleNotFound
+	}
+
+	found := *rule
+	return &found, nil
+}
+
+// List returns all alert rules ordered by creation time
+func (r *AlertRuleRepositoryImpl) List() ([]*domain.AlertRule, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	rules := make([]*domain.AlertRule, 0, len(r.rules))
+	for _, rule := range r.rules {
+		listed := *rule
+		rules = append(rules, &listed)
+	}
+
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].CreatedAt.Before(rules[j].CreatedAt)
+	})
+
+	return rules, nil
+}
+
+// CountByOwner returns the number of rules registered by the owner
+func (r *AlertRuleRepositoryImpl) CountByOwner(owner string) (int, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	count := 0
+	for _, rule := range r.rules {
+		if rule.Owner == owner {
+			count++
+		}
+	}
+
+	return count, nil
+}
+
+// Delete removes the alert rule with the given ID
+func (r *AlertRuleRepositoryImpl) Delete(id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.rules[id]; !ok {
+		return domain.ErrAlertRuleNotFound
+	}
+
+	delete(r.rules, id)
+	return nil
+}
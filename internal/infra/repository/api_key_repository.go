@@ -0,0 +1,169 @@
+package repository
+
+import (
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/xavierpms/weather-by-city/internal/domain"
+)
+
+// storedAPIKey is the file representation of an API key, including the hash left out of the API responses
+type storedAPIKey struct {
+	domain.APIKey
+	Hash string `json:"hash"`
+}
+
+// APIKeyRepositoryImpl implements domain.APIKeyRepository in memory, persisting every change to a JSON file
+type APIKeyRepositoryImpl struct {
+	path string
+
+	mu   sync.RWMutex
+	keys map[string]*domain.APIKey
+}
+
+// NewAPIKeyRepository loads the API keys from the file, which is created on the first change.
+// An empty path keeps the keys in memory only.
+func NewAPIKeyRepository(path string) (domain.APIKeyRepository, error) {
+	r := &APIKeyRepositoryImpl{
+		path: path,
+		keys: make(map[string]*domain.APIKey),
+	}
+
+	var stored []storedAPIKey
+	if err := readJSONFile(path, &stored); err != nil {
+		return nil, err
+	}
+	for _, s := range stored {
+		key := s.APIKey
+		key.Hash = s.Hash
+		r.keys[key.ID] = &key
+	}
+
+	return r, nil
+}
+
+// Create stores a new API key
+func (r *APIKeyRepositoryImpl) Create(key *domain.APIKey) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored := *key
+	r.keys[key.ID] = &stored
+	return r.save()
+}
+
+// GetByHash returns the API key whose secret has the given hash
+func (r *APIKeyRepositoryImpl) GetByHash(hash string) (*domain.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, key := range r.keys {
+		if key.Hash == hash {
+			found := *key
+			return &found, nil
+		}
+	}
+
+	return nil, domain.ErrAPIKeyNotFound
+}
+
+// List returns all API keys ordered by creation time
+func (r *APIKeyRepositoryImpl) List() ([]*domain.APIKey, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	keys := make([]*domain.APIKey, 0, len(r.keys))
+	for _, key := range r.keys {
+		listed := *key
+		keys = append(keys, &listed)
+	}
+
+	sort.Slice(keys, func(i, j int) bool {
+		return keys[i].CreatedAt.Before(keys[j].CreatedAt)
+	})
+
+	return keys, nil
+}
+
+// Revoke marks the API key as revoked, keeping it for the usage history
+func (r *APIKeyRepositoryImpl) Revoke(id string, at time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key, ok := r.keys[id]
+	if !ok {
+		return domain.ErrAPIKeyNotFound
+	}
+
+	if key.RevokedAt == nil {
+		key.RevokedAt = &at
+	}
+	return r.save()
+}
+
+// save writes the keys to the file, with the lock held
+func (r *APIKeyRepositoryImpl) save() error {
+	stored := make([]storedAPIKey, 0, len(r.keys))
+	for _, key := range r.keys {
+		stored = append(stored, storedAPIKey{APIKey: *key, Hash: key.Hash})
+	}
+	sort.Slice(stored, func(i, j int) bool {
+		return stored[i].CreatedAt.Before(stored[j].CreatedAt)
+	})
+
+	return writeJSONFile(r.path, stored)
+}
+
+// readJSONFile decodes the file into value, leaving it untouched when the path is empty or the file missing
+func readJSONFile(path string, value interface{}) error {
+	if path == "" {
+		return nil
+	}
+
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	return json.Unmarshal(data, value)
+}
+
+// writeJSONFile replaces the file atomically, readable by the owner only since it holds credentials
+// and consumption data. An empty path writes nothing.
+func writeJSONFile(path string, value interface{}) error {
+	if path == "" {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(value, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
@@ -0,0 +1,113 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/xavierpms/weather-by-city/internal/domain"
+	"github.com/xavierpms/weather-by-city/internal/infra/breaker"
+)
+
+// BreakerCEPRepository decorates a domain.CEPRepository with a circuit breaker
+type BreakerCEPRepository struct {
+	next    domain.CEPRepository
+	breaker *breaker.Breaker
+}
+
+// NewBreakerCEPRepository creates a new CEP repository protected by the breaker
+func NewBreakerCEPRepository(next domain.CEPRepository, b *breaker.Breaker) domain.CEPRepository {
+	return &BreakerCEPRepository{
+		next:    next,
+		breaker: b,
+	}
+}
+
+// GetCEPData fetches the CEP data, failing fast while the breaker is open
+func (r *BreakerCEPRepository) GetCEPData(ctx context.Context, cep string) (*domain.CEPData, error) {
+	done, err := r.breaker.Allow()
+	if err != nil {
+		return nil, unavailable(r.breaker)
+	}
+
+	cepData, err := r.next.GetCEPData(ctx, cep)
+	done(isUpstreamFailure(err))
+	return cepData, err
+}
+
+// BreakerTemperatureRepository decorates a domain.TemperatureRepository with a circuit breaker
+type BreakerTemperatureRepository struct {
+	next    domain.TemperatureRepository
+	breaker *breaker.Breaker
+}
+
+// NewBreakerTemperatureRepository creates a new temperature repository protected by the breaker
+func NewBreakerTemperatureRepository(next domain.TemperatureRepository, b *breaker.Breaker) domain.TemperatureRepository {
+	return &BreakerTemperatureRepository{
+		next:    next,
+		breaker: b,
+	}
+}
+
+// GetTemperatureByCityName fetches the temperature, failing fast while the breaker is open
+func (r *BreakerTemperatureRepository) GetTemperatureByCityName(ctx context.Context, cityName string) (*domain.Temperature, error) {
+	done, err := r.breaker.Allow()
+	if err != nil {
+		return nil, unavailable(r.breaker)
+	}
+
+	temperature, err := r.next.GetTemperatureByCityName(ctx, cityName)
+	done(isUpstreamFailure(err))
+	return temperature, err
+}
+
+// BreakerForecastRepository decorates a domain.ForecastRepository with a circuit breaker
+type BreakerForecastRepository struct {
+	next    domain.ForecastRepository
+	breaker *breaker.Breaker
+}
+
+// NewBreakerForecastRepository creates a new forecast repository protected by the breaker
+func NewBreakerForecastRepository(next domain.ForecastRepository, b *breaker.Breaker) domain.ForecastRepository {
+	return &BreakerForecastRepository{
+		next:    next,
+		breaker: b,
+	}
+}
+
+// GetForecastByCityName fetches the forecast, failing fast while the breaker is open
+func (r *BreakerForecastRepository) GetForecastByCityName(ctx context.Context, cityName string, days int) ([]domain.ForecastDay, error) {
+	done, err := r.breaker.Allow()
+	if err != nil {
+		return nil, unavailable(r.breaker)
+	}
+
+	forecast, err := r.next.GetForecastByCityName(ctx, cityName, days)
+	done(isUpstreamFailure(err))
+	return forecast, err
+}
+
+// unavailable returns the error reported while the breaker is open
+func unavailable(b *breaker.Breaker) error {
+	return fmt.Errorf("%w: %s circuit breaker is open", domain.ErrUpstreamUnavailable, b.Name())
+}
+
+// isUpstreamFailure reports whether the error means the upstream is unhealthy. Missing data, client
+// errors other than rate limiting, calls cancelled by the caller and calls refused locally, such as
+// by an exhausted call budget, do not count.
+func isUpstreamFailure(err error) bool {
+	if err == nil || errors.Is(err, domain.ErrCEPNotFound) || errors.Is(err, context.Canceled) {
+		return false
+	}
+
+	var statusErr *StatusError
+	if errors.As(err, &statusErr) {
+		return statusErr.StatusCode >= http.StatusInternalServerError || statusErr.StatusCode == http.StatusTooManyRequests
+	}
+	var requestErr *RequestError
+	if errors.As(err, &requestErr) {
+		return true
+	}
+	return !errors.Is(err, domain.ErrUpstreamUnavailable)
+}
@@ -0,0 +1,148 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/xavierpms/weather-by-city/internal/domain"
+	"github.com/xavierpms/weather-by-city/internal/infra/cache"
+)
+
+// Cache names reported to the CacheObserver
+const (
+	CacheTemperature = "temperature"
+	CacheCEP         = "cep"
+	CacheCEPNotFound = "cep_not_found"
+)
+
+// CacheObserver is notified of every cache lookup
+type CacheObserver interface {
+	ObserveCacheLookup(cache string, hit bool)
+}
+
+// CachedTemperatureRepository decorates a domain.TemperatureRepository with an in-memory cache
+type CachedTemperatureRepository struct {
+	next        domain.TemperatureRepository
+	cache       *cache.Cache[string, domain.Temperature]
+	observer    CacheObserver
+	staleFor    time.Duration
+	preferStale func() bool
+}
+
+// NewCachedTemperatureRepository creates a new cached temperature repository. Expired entries are
+// served for up to staleFor when the upstream is unavailable, or without calling it at all while
+// preferStale returns true, as when its call budget runs low; zero disables stale serving and a nil
+// preferStale never prefers stale entries.
+func NewCachedTemperatureRepository(next domain.TemperatureRepository, ttl, staleFor time.Duration, maxEntries int, observer CacheObserver, preferStale func() bool) *CachedTemperatureRepository {
+	return &CachedTemperatureRepository{
+		next:        next,
+		cache:       cache.New[string, domain.Temperature](ttl, maxEntries),
+		observer:    observer,
+		staleFor:    staleFor,
+		preferStale: preferStale,
+	}
+}
+
+// SetTTL changes the TTL of the temperatures cached from now on
+func (r *CachedTemperatureRepository) SetTTL(ttl time.Duration) {
+	r.cache.SetTTL(ttl)
+}
+
+// GetTemperatureByCityName returns the cached temperature, fetching it when missing or expired.
+// The returned temperature carries the expiration of the cached entry, which is in the past when stale.
+func (r *CachedTemperatureRepository) GetTemperatureByCityName(ctx context.Context, cityName string) (*domain.Temperature, error) {
+	key := strings.ToLower(strings.TrimSpace(cityName))
+	temperature, expiresAt, ok := r.cache.Get(key)
+	r.observer.ObserveCacheLookup(CacheTemperature, ok)
+	if ok {
+		temperature.ExpiresAt = expiresAt
+		return &temperature, nil
+	}
+	if r.preferStale != nil && r.staleFor > 0 && r.preferStale() {
+		if stale, expiresAt, ok := r.cache.GetStale(key, r.staleFor); ok {
+			stale.ExpiresAt = expiresAt
+			return &stale, nil
+		}
+	}
+
+	fetched, err := r.next.GetTemperatureByCityName(ctx, cityName)
+	if errors.Is(err, domain.ErrUpstreamUnavailable) && r.staleFor > 0 {
+		if stale, expiresAt, ok := r.cache.GetStale(key, r.staleFor); ok {
+			stale.ExpiresAt = expiresAt
+			return &stale, nil
+		}
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	cached := *fetched
+	cached.ExpiresAt = r.cache.Set(key, *fetched)
+	return &cached, nil
+}
+
+// CachedCEPRepository decorates a domain.CEPRepository with an in-memory cache. CEPs the upstream
+// does not know are kept in a separate cache, so they cannot evict the CEPs found.
+type CachedCEPRepository struct {
+	next     domain.CEPRepository
+	cache    *cache.Cache[string, domain.CEPData]
+	notFound *cache.Cache[string, struct{}]
+	observer CacheObserver
+	staleFor time.Duration
+}
+
+// NewCachedCEPRepository creates a new cached CEP repository. Expired entries are
+// served for up to staleFor when the upstream is unavailable; zero disables stale serving.
+// CEPs not found are cached for notFoundTTL, up to notFoundMaxEntries.
+func NewCachedCEPRepository(next domain.CEPRepository, ttl, notFoundTTL, staleFor time.Duration, maxEntries, notFoundMaxEntries int, observer CacheObserver) *CachedCEPRepository {
+	return &CachedCEPRepository{
+		next:     next,
+		cache:    cache.New[string, domain.CEPData](ttl, maxEntries),
+		notFound: cache.New[string, struct{}](notFoundTTL, notFoundMaxEntries),
+		observer: observer,
+		staleFor: staleFor,
+	}
+}
+
+// SetTTL changes the TTL of the CEP data cached from now on
+func (r *CachedCEPRepository) SetTTL(ttl time.Duration) {
+	r.cache.SetTTL(ttl)
+}
+
+// SetNotFoundTTL changes the TTL of the CEPs not found cached from now on
+func (r *CachedCEPRepository) SetNotFoundTTL(ttl time.Duration) {
+	r.notFound.SetTTL(ttl)
+}
+
+// GetCEPData returns the cached CEP data, fetching it when missing or expired. Only a CEP the
+// upstream reports as not found is cached as such, never a failure to reach it.
+func (r *CachedCEPRepository) GetCEPData(ctx context.Context, cep string) (*domain.CEPData, error) {
+	cached, _, ok := r.cache.Get(cep)
+	r.observer.ObserveCacheLookup(CacheCEP, ok)
+	if ok {
+		return &cached, nil
+	}
+	_, _, notFound := r.notFound.Get(cep)
+	r.observer.ObserveCacheLookup(CacheCEPNotFound, notFound)
+	if notFound {
+		return nil, domain.ErrCEPNotFound
+	}
+
+	cepData, err := r.next.GetCEPData(ctx, cep)
+	if errors.Is(err, domain.ErrUpstreamUnavailable) && r.staleFor > 0 {
+		if stale, _, ok := r.cache.GetStale(cep, r.staleFor); ok {
+			return &stale, nil
+		}
+	}
+	if errors.Is(err, domain.ErrCEPNotFound) {
+		r.notFound.Set(cep, struct{}{})
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	r.cache.Set(cep, *cepData)
+	return cepData, nil
+}
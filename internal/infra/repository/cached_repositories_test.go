@@ -0,0 +1,175 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xavierpms/weather-by-city/internal/domain"
+	"github.com/xavierpms/weather-by-city/internal/infra/breaker"
+)
+
+// MockCEPRepository is a mock of the CEPRepository for testing that counts its calls
+type MockCEPRepository struct {
+	calls          int
+	getCEPDataFunc func(cep string) (*domain.CEPData, error)
+}
+
+func (m *MockCEPRepository) GetCEPData(ctx context.Context, cep string) (*domain.CEPData, error) {
+	m.calls++
+	return m.getCEPDataFunc(cep)
+}
+
+// MockCacheObserver is a mock of the CacheObserver for testing
+type MockCacheObserver struct{}
+
+func (m *MockCacheObserver) ObserveCacheLookup(cache string, hit bool) {}
+
+// newTestCachedCEPRepository creates a cached CEP repository over next without stale serving
+func newTestCachedCEPRepository(next domain.CEPRepository) *CachedCEPRepository {
+	return NewCachedCEPRepository(next, time.Minute, time.Minute, 0, 10, 10, &MockCacheObserver{})
+}
+
+// TestCachedCEPRepositoryCachesNotFound tests that a CEP not found is answered from the cache without calling the upstream
+func TestCachedCEPRepositoryCachesNotFound(t *testing.T) {
+	// Arrange
+	next := &MockCEPRepository{getCEPDataFunc: func(cep string) (*domain.CEPData, error) {
+		return nil, domain.ErrCEPNotFound
+	}}
+	repo := newTestCachedCEPRepository(next)
+
+	// Act
+	_, first := repo.GetCEPData(context.Background(), "99999999")
+	_, second := repo.GetCEPData(context.Background(), "99999999")
+
+	// Assert
+	assert.ErrorIs(t, first, domain.ErrCEPNotFound)
+	assert.ErrorIs(t, second, domain.ErrCEPNotFound)
+	assert.Equal(t, 1, next.calls)
+}
+
+// TestCachedCEPRepositoryNeverCachesFailures tests that failures to reach the upstream are not cached as not found
+func TestCachedCEPRepositoryNeverCachesFailures(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+	}{
+		{"server error", &StatusError{Upstream: "viacep", StatusCode: http.StatusBadGateway}},
+		{"upstream unavailable", fmt.Errorf("%w: viacep timed out", domain.ErrUpstreamUnavailable)},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			failing := true
+			next := &MockCEPRepository{getCEPDataFunc: func(cep string) (*domain.CEPData, error) {
+				if failing {
+					return nil, tt.err
+				}
+				return &domain.CEPData{CEP: cep, City: "São Paulo"}, nil
+			}}
+			repo := newTestCachedCEPRepository(next)
+
+			// Act
+			_, first := repo.GetCEPData(context.Background(), "01001000")
+			failing = false
+			cepData, second := repo.GetCEPData(context.Background(), "01001000")
+
+			// Assert
+			assert.ErrorIs(t, first, tt.err)
+			assert.False(t, errors.Is(first, domain.ErrCEPNotFound))
+			require.NoError(t, second)
+			assert.Equal(t, "São Paulo", cepData.City)
+			assert.Equal(t, 2, next.calls)
+		})
+	}
+}
+
+// TestCachedCEPRepositoryNeverCachesOpenBreaker tests that a call refused by an open breaker is not cached as not found
+func TestCachedCEPRepositoryNeverCachesOpenBreaker(t *testing.T) {
+	// Arrange
+	failing := true
+	next := &MockCEPRepository{getCEPDataFunc: func(cep string) (*domain.CEPData, error) {
+		if failing {
+			return nil, &StatusError{Upstream: "viacep", StatusCode: http.StatusServiceUnavailable}
+		}
+		return &domain.CEPData{CEP: cep, City: "São Paulo"}, nil
+	}}
+	b := breaker.New("viacep", breaker.Settings{FailureThreshold: 1, OpenDuration: time.Hour, HalfOpenProbes: 1})
+	repo := newTestCachedCEPRepository(NewBreakerCEPRepository(next, b))
+	_, err := repo.GetCEPData(context.Background(), "01001000")
+	require.Error(t, err)
+	require.Equal(t, breaker.StateOpen, b.State())
+
+	// Act
+	_, refused := repo.GetCEPData(context.Background(), "01001000")
+	failing = false
+	_, stillRefused := repo.GetCEPData(context.Background(), "01001000")
+
+	// Assert
+	assert.ErrorIs(t, refused, domain.ErrUpstreamUnavailable)
+	assert.ErrorIs(t, stillRefused, domain.ErrUpstreamUnavailable)
+	assert.False(t, errors.Is(stillRefused, domain.ErrCEPNotFound))
+	_, _, cachedAsNotFound := repo.notFound.Get("01001000")
+	assert.False(t, cachedAsNotFound)
+	assert.Equal(t, 1, next.calls)
+}
+
+// TestCachedCEPRepositoryServesStaleOnServerErrors tests that expired data is served when ViaCEP answers with a server error, which still counts for the breaker
+func TestCachedCEPRepositoryServesStaleOnServerErrors(t *testing.T) {
+	// Arrange
+	var failing atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if failing.Load() {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.Write([]byte(`{"cep": "01001-000", "localidade": "São Paulo", "uf": "SP"}`))
+	}))
+	defer server.Close()
+	b := breaker.New("viacep", breaker.Settings{FailureThreshold: 1, OpenDuration: time.Hour, HalfOpenProbes: 1})
+	next := NewBreakerCEPRepository(NewCEPRepository(server.URL, server.Client()), b)
+	repo := NewCachedCEPRepository(next, time.Millisecond, time.Minute, time.Hour, 10, 10, &MockCacheObserver{})
+	_, err := repo.GetCEPData(context.Background(), "01001000")
+	require.NoError(t, err)
+	time.Sleep(5 * time.Millisecond)
+	failing.Store(true)
+
+	// Act
+	cepData, err := repo.GetCEPData(context.Background(), "01001000")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "São Paulo", cepData.City)
+	assert.Equal(t, breaker.StateOpen, b.State())
+}
+
+// TestUpstreamErrorsMatchUnavailable tests which upstream failures are reported as domain.ErrUpstreamUnavailable
+func TestUpstreamErrorsMatchUnavailable(t *testing.T) {
+	tests := []struct {
+		name        string
+		err         error
+		unavailable bool
+	}{
+		{"server error", &StatusError{Upstream: "viacep", StatusCode: http.StatusBadGateway}, true},
+		{"rate limited", &StatusError{Upstream: "viacep", StatusCode: http.StatusTooManyRequests}, true},
+		{"client error", &StatusError{Upstream: "viacep", StatusCode: http.StatusBadRequest}, false},
+		{"timeout", requestError("viacep", context.DeadlineExceeded), true},
+		{"cancelled", requestError("viacep", context.Canceled), false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Act
+			unavailable := errors.Is(tt.err, domain.ErrUpstreamUnavailable)
+
+			// Assert
+			assert.Equal(t, tt.unavailable, unavailable)
+		})
+	}
+}
@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"github.com/xavierpms/weather-by-city/internal/infra/quota"
+)
+
+// CallCountRepository implements quota.Store with a JSON file
+type CallCountRepository struct {
+	path string
+}
+
+// NewCallCountRepository creates the store of the upstream call counts. An empty path keeps the
+// counts in memory only, so they restart from zero with the service.
+func NewCallCountRepository(path string) *CallCountRepository {
+	return &CallCountRepository{path: path}
+}
+
+// Load returns the saved counts, zero when the file does not exist yet
+func (r *CallCountRepository) Load() (quota.Counts, error) {
+	var counts quota.Counts
+	err := readJSONFile(r.path, &counts)
+	return counts, err
+}
+
+// Save replaces the saved counts
+func (r *CallCountRepository) Save(counts quota.Counts) error {
+	return writeJSONFile(r.path, counts)
+}
@@ -1,81 +1,124 @@
 package repository
 
 import (
+	"context"
 	"encoding/json"
-	"errors"
+	"fmt"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
+	"strconv"
 
 	"github.com/xavierpms/weather-by-city/internal/domain"
 )
 
 // ViaCEPResponse represents the response from the ViaCEP API
 type ViaCEPResponse struct {
-	CEP         string `json:"cep"`
-	Logradouro  string `json:"logradouro"`
-	Complemento string `json:"complemento"`
-	Bairro      string `json:"bairro"`
-	Localidade  string `json:"localidade"`
-	UF          string `json:"uf"`
-	IBGE        string `json:"ibge"`
-	GIA         string `json:"gia"`
-	DDD         string `json:"ddd"`
-	SIAFI       string `json:"siafi"`
-	Erro        bool   `json:"erro"`
+	CEP         string     `json:"cep"`
+	Logradouro  string     `json:"logradouro"`
+	Complemento string     `json:"complemento"`
+	Bairro      string     `json:"bairro"`
+	Localidade  string     `json:"localidade"`
+	UF          string     `json:"uf"`
+	IBGE        string     `json:"ibge"`
+	GIA         string     `json:"gia"`
+	DDD         string     `json:"ddd"`
+	SIAFI       string     `json:"siafi"`
+	Erro        viaCEPFlag `json:"erro"`
+}
+
+// viaCEPFlag decodes the erro flag of ViaCEP, sent as a boolean or, by some of its versions, as
+// the string "true"
+type viaCEPFlag bool
+
+func (f *viaCEPFlag) UnmarshalJSON(data []byte) error {
+	var value any
+	if err := json.Unmarshal(data, &value); err != nil {
+		return err
+	}
+
+	switch v := value.(type) {
+	case bool:
+		*f = viaCEPFlag(v)
+	case string:
+		flag, err := strconv.ParseBool(v)
+		if err != nil {
+			return fmt.Errorf("invalid erro flag %q", v)
+		}
+		*f = viaCEPFlag(flag)
+	case nil:
+		*f = false
+	default:
+		return fmt.Errorf("invalid erro flag %s", data)
+	}
+	return nil
 }
 
 // CEPRepositoryImpl implement domain.CEPRepository
 type CEPRepositoryImpl struct {
 	apiURL string
+	client *http.Client
 }
 
 // NewCEPRepository creates a new CEP repository
-func NewCEPRepository(apiURL string) domain.CEPRepository {
+func NewCEPRepository(apiURL string, client *http.Client) domain.CEPRepository {
 	return &CEPRepositoryImpl{
 		apiURL: apiURL,
+		client: client,
 	}
 }
 
 // GetCEPData fetches the data for a given CEP
-func (r *CEPRepositoryImpl) GetCEPData(cep string) (*domain.CEPData, error) {
+func (r *CEPRepositoryImpl) GetCEPData(ctx context.Context, cep string) (*domain.CEPData, error) {
 	// Build the URL
 	requestURL := r.apiURL + "/" + cep + "/json/"
-	log.Printf("calling ViaCEP API: url=%s cep=%s", requestURL, cep)
+	slog.InfoContext(ctx, "calling ViaCEP API", "url", requestURL, "cep", cep)
 
 	// Make the request
-	resp, err := http.Get(requestURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
 	if err != nil {
-		log.Printf("ViaCEP API request error: cep=%s err=%v", cep, err)
 		return nil, err
 	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		slog.ErrorContext(ctx, "ViaCEP API request error", "cep", cep, "err", err)
+		return nil, requestError("ViaCEP API", err)
+	}
 	defer resp.Body.Close()
-	log.Printf("ViaCEP API response: cep=%s status=%d", cep, resp.StatusCode)
+	slog.InfoContext(ctx, "ViaCEP API response", "cep", cep, "status", resp.StatusCode)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{Upstream: "ViaCEP API", StatusCode: resp.StatusCode}
+	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		log.Printf("ViaCEP API read response error: cep=%s err=%v", cep, err)
-		return nil, err
+		slog.ErrorContext(ctx, "ViaCEP API read response error", "cep", cep, "err", err)
+		return nil, requestError("ViaCEP API", err)
 	}
 
 	// Unmarshal the response
 	var viaCepData ViaCEPResponse
 	err = json.Unmarshal(body, &viaCepData)
 	if err != nil {
-		log.Printf("ViaCEP API parse response error: cep=%s err=%v", cep, err)
+		slog.ErrorContext(ctx, "ViaCEP API parse response error", "cep", cep, "err", err)
 		return nil, err
 	}
 
 	// Validate if the CEP was found
 	if viaCepData.Erro {
-		log.Printf("ViaCEP API returned not found: cep=%s", cep)
-		return nil, errors.New("CEP not found in ViaCEP")
+		slog.InfoContext(ctx, "ViaCEP API returned not found", "cep", cep)
+		return nil, fmt.Errorf("%w in ViaCEP", domain.ErrCEPNotFound)
 	}
-	log.Printf("ViaCEP API request succeeded: cep=%s city=%s", cep, viaCepData.Localidade)
+	slog.InfoContext(ctx, "ViaCEP API request succeeded", "cep", cep, "city", viaCepData.Localidade)
 
 	return &domain.CEPData{
-		CEP:    viaCepData.CEP,
-		City:   viaCepData.Localidade,
-		Region: viaCepData.UF,
+		CEP:          viaCepData.CEP,
+		Street:       viaCepData.Logradouro,
+		Neighborhood: viaCepData.Bairro,
+		City:         viaCepData.Localidade,
+		Region:       viaCepData.UF,
+		IBGE:         viaCepData.IBGE,
+		DDD:          viaCepData.DDD,
 	}, nil
 }
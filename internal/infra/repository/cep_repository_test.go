@@ -0,0 +1,65 @@
+package repository
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xavierpms/weather-by-city/internal/domain"
+)
+
+// TestCEPRepositoryReadsTheNotFoundFlag tests that both shapes of the ViaCEP erro flag are understood
+func TestCEPRepositoryReadsTheNotFoundFlag(t *testing.T) {
+	tests := []struct {
+		name     string
+		body     string
+		notFound bool
+	}{
+		{"boolean flag", `{"erro": true}`, true},
+		{"string flag", `{"erro": "true"}`, true},
+		{"false string flag", `{"cep": "01001-000", "localidade": "São Paulo", "uf": "SP", "erro": "false"}`, false},
+		{"no flag", `{"cep": "01001-000", "localidade": "São Paulo", "uf": "SP"}`, false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// Arrange
+			server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				w.Write([]byte(tt.body))
+			}))
+			defer server.Close()
+			repo := NewCEPRepository(server.URL, server.Client())
+
+			// Act
+			cepData, err := repo.GetCEPData(context.Background(), "01001000")
+
+			// Assert
+			if tt.notFound {
+				assert.ErrorIs(t, err, domain.ErrCEPNotFound)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, "São Paulo", cepData.City)
+		})
+	}
+}
+
+// TestCEPRepositoryRejectsAnUnknownFlag tests that an erro flag of another shape is not taken as a found CEP
+func TestCEPRepositoryRejectsAnUnknownFlag(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Write([]byte(`{"erro": "sim"}`))
+	}))
+	defer server.Close()
+	repo := NewCEPRepository(server.URL, server.Client())
+
+	// Act
+	_, err := repo.GetCEPData(context.Background(), "01001000")
+
+	// Assert
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, domain.ErrCEPNotFound)
+}
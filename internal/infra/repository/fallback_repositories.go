@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"sync/atomic"
+
+	"github.com/xavierpms/weather-by-city/internal/domain"
+)
+
+// FallbackTemperatureRepository decorates a domain.TemperatureRepository with a second provider,
+// used once the call budget of the first one is spent
+type FallbackTemperatureRepository struct {
+	next     domain.TemperatureRepository
+	fallback domain.TemperatureRepository
+	enabled  atomic.Bool
+}
+
+// NewFallbackTemperatureRepository creates a new temperature repository falling back to the fallback
+// repository when next reports domain.ErrUpstreamQuotaExceeded, as long as the fallback is enabled
+func NewFallbackTemperatureRepository(next, fallback domain.TemperatureRepository, enabled bool) *FallbackTemperatureRepository {
+	r := &FallbackTemperatureRepository{
+		next:     next,
+		fallback: fallback,
+	}
+	r.enabled.Store(enabled)
+	return r
+}
+
+// SetEnabled turns the fallback on or off
+func (r *FallbackTemperatureRepository) SetEnabled(enabled bool) {
+	r.enabled.Store(enabled)
+}
+
+// GetTemperatureByCityName fetches the temperature from the first provider, or from the fallback
+// when the quota of the first one is exceeded
+func (r *FallbackTemperatureRepository) GetTemperatureByCityName(ctx context.Context, cityName string) (*domain.Temperature, error) {
+	temperature, err := r.next.GetTemperatureByCityName(ctx, cityName)
+	if !errors.Is(err, domain.ErrUpstreamQuotaExceeded) || !r.enabled.Load() {
+		return temperature, err
+	}
+
+	slog.WarnContext(ctx, "Using the fallback temperature provider", "city", cityName, "err", err)
+	return r.fallback.GetTemperatureByCityName(ctx, cityName)
+}
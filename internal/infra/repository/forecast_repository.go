@@ -0,0 +1,107 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+
+	"github.com/xavierpms/weather-by-city/internal/domain"
+	"github.com/xavierpms/weather-by-city/internal/infra/logging"
+)
+
+// WeatherAPIForecastResponse represents the response from the WeatherAPI forecast endpoint
+type WeatherAPIForecastResponse struct {
+	Forecast struct {
+		ForecastDay []struct {
+			Date string `json:"date"`
+			Day  struct {
+				MaxTempC  float64 `json:"maxtemp_c"`
+				MaxTempF  float64 `json:"maxtemp_f"`
+				MinTempC  float64 `json:"mintemp_c"`
+				MinTempF  float64 `json:"mintemp_f"`
+				AvgTempC  float64 `json:"avgtemp_c"`
+				AvgTempF  float64 `json:"avgtemp_f"`
+				Condition struct {
+					Text string `json:"text"`
+				} `json:"condition"`
+			} `json:"day"`
+		} `json:"forecastday"`
+	} `json:"forecast"`
+}
+
+// ForecastRepository implements domain.ForecastRepository
+type ForecastRepository struct {
+	apiURL string
+	client *http.Client
+}
+
+// NewForecastRepository creates a new forecast repository. The client adds the API key.
+func NewForecastRepository(apiURL string, client *http.Client) domain.ForecastRepository {
+	return &ForecastRepository{
+		apiURL: apiURL,
+		client: client,
+	}
+}
+
+// GetForecastByCityName fetches the daily forecast for a given city
+func (r *ForecastRepository) GetForecastByCityName(ctx context.Context, cityName string, days int) ([]domain.ForecastDay, error) {
+	// Build the URL with parameters
+	params := url.Values{}
+	params.Set("q", cityName)
+	params.Set("days", strconv.Itoa(days))
+	params.Set("lang", "pt")
+	requestURL := r.apiURL + "?" + params.Encode()
+	slog.InfoContext(ctx, "calling Weather API forecast", "url", logging.RedactURL(requestURL), "city", cityName, "days", days)
+
+	// Make the request
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		slog.ErrorContext(ctx, "Weather API forecast request error", "city", cityName, "err", err)
+		return nil, requestError("Weather API forecast", err)
+	}
+	defer resp.Body.Close()
+	slog.InfoContext(ctx, "Weather API forecast response", "city", cityName, "status", resp.StatusCode)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{Upstream: "Weather API forecast", StatusCode: resp.StatusCode}
+	}
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		slog.ErrorContext(ctx, "Weather API forecast read response error", "city", cityName, "err", err)
+		return nil, requestError("Weather API forecast", err)
+	}
+
+	// Unmarshal the response
+	var forecastResp WeatherAPIForecastResponse
+	err = json.Unmarshal(body, &forecastResp)
+	if err != nil {
+		slog.ErrorContext(ctx, "Weather API forecast parse response error", "city", cityName, "err", err)
+		return nil, err
+	}
+
+	forecast := make([]domain.ForecastDay, 0, len(forecastResp.Forecast.ForecastDay))
+	for _, day := range forecastResp.Forecast.ForecastDay {
+		forecast = append(forecast, domain.ForecastDay{
+			Date:          day.Date,
+			MinCelsius:    day.Day.MinTempC,
+			MaxCelsius:    day.Day.MaxTempC,
+			AvgCelsius:    day.Day.AvgTempC,
+			MinFahrenheit: day.Day.MinTempF,
+			MaxFahrenheit: day.Day.MaxTempF,
+			AvgFahrenheit: day.Day.AvgTempF,
+			Condition:     day.Day.Condition.Text,
+		})
+	}
+	slog.InfoContext(ctx, "Weather API forecast request succeeded", "city", cityName, "days", len(forecast))
+
+	return forecast, nil
+}
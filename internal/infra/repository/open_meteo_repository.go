@@ -0,0 +1,134 @@
+package repository
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/xavierpms/weather-by-city/internal/domain"
+)
+
+// OpenMeteoGeocodingResponse represents the response from the Open-Meteo geocoding API
+type OpenMeteoGeocodingResponse struct {
+	Results []struct {
+		Latitude  float64 `json:"latitude"`
+		Longitude float64 `json:"longitude"`
+	} `json:"results"`
+}
+
+// OpenMeteoForecastResponse represents the response from the Open-Meteo forecast API
+type OpenMeteoForecastResponse struct {
+	Current struct {
+		Temperature2M *float64 `json:"temperature_2m"`
+	} `json:"current"`
+}
+
+type coordinates struct {
+	latitude, longitude float64
+}
+
+// OpenMeteoTemperatureRepository implements domain.TemperatureRepository with Open-Meteo, which needs
+// no API key. It serves as the fallback when the WeatherAPI budget is spent.
+type OpenMeteoTemperatureRepository struct {
+	geocodingURL string
+	forecastURL  string
+	client       *http.Client
+
+	// cities caches the coordinates of the cities, which do not change
+	mu     sync.Mutex
+	cities map[string]coordinates
+}
+
+// NewOpenMeteoTemperatureRepository creates a new Open-Meteo temperature repository
+func NewOpenMeteoTemperatureRepository(geocodingURL, forecastURL string, client *http.Client) domain.TemperatureRepository {
+	return &OpenMeteoTemperatureRepository{
+		geocodingURL: geocodingURL,
+		forecastURL:  forecastURL,
+		client:       client,
+		cities:       make(map[string]coordinates),
+	}
+}
+
+// GetTemperatureByCityName geocodes the Brazilian city and fetches its current temperature
+func (r *OpenMeteoTemperatureRepository) GetTemperatureByCityName(ctx context.Context, cityName string) (*domain.Temperature, error) {
+	coords, err := r.geocode(ctx, cityName)
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{}
+	params.Set("latitude", strconv.FormatFloat(coords.latitude, 'f', 4, 64))
+	params.Set("longitude", strconv.FormatFloat(coords.longitude, 'f', 4, 64))
+	params.Set("current", "temperature_2m")
+	var forecast OpenMeteoForecastResponse
+	if err := r.getJSON(ctx, r.forecastURL+"?"+params.Encode(), &forecast); err != nil {
+		return nil, err
+	}
+	if forecast.Current.Temperature2M == nil {
+		return nil, domain.ErrTemperatureNotFound
+	}
+
+	celsius := *forecast.Current.Temperature2M
+	slog.InfoContext(ctx, "Open-Meteo request succeeded", "city", cityName, "temp_c", celsius)
+
+	return &domain.Temperature{
+		Celsius:    celsius,
+		Fahrenheit: celsius*1.8 + 32,
+		Kelvin:     celsius + kelvinOffset,
+	}, nil
+}
+
+// geocode returns the coordinates of the city, looking them up once
+func (r *OpenMeteoTemperatureRepository) geocode(ctx context.Context, cityName string) (coordinates, error) {
+	key := strings.ToLower(strings.TrimSpace(cityName))
+	r.mu.Lock()
+	coords, ok := r.cities[key]
+	r.mu.Unlock()
+	if ok {
+		return coords, nil
+	}
+
+	params := url.Values{}
+	params.Set("name", cityName)
+	params.Set("count", "1")
+	params.Set("language", "pt")
+	params.Set("countryCode", "BR")
+	var geocoding OpenMeteoGeocodingResponse
+	if err := r.getJSON(ctx, r.geocodingURL+"?"+params.Encode(), &geocoding); err != nil {
+		return coordinates{}, err
+	}
+	if len(geocoding.Results) == 0 {
+		return coordinates{}, fmt.Errorf("%w: Open-Meteo cannot geocode %q", domain.ErrTemperatureNotFound, cityName)
+	}
+
+	coords = coordinates{latitude: geocoding.Results[0].Latitude, longitude: geocoding.Results[0].Longitude}
+	r.mu.Lock()
+	r.cities[key] = coords
+	r.mu.Unlock()
+	return coords, nil
+}
+
+func (r *OpenMeteoTemperatureRepository) getJSON(ctx context.Context, requestURL string, value any) error {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
+	if err != nil {
+		return err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		slog.ErrorContext(ctx, "Open-Meteo request error", "err", err)
+		return requestError("Open-Meteo", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &StatusError{Upstream: "Open-Meteo", StatusCode: resp.StatusCode}
+	}
+
+	return json.NewDecoder(resp.Body).Decode(value)
+}
@@ -0,0 +1,52 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+
+	"github.com/xavierpms/weather-by-city/internal/domain"
+)
+
+// StatusError is returned when an upstream API answers with an unexpected status code. Server
+// errors and rate limiting match domain.ErrUpstreamUnavailable.
+type StatusError struct {
+	Upstream   string
+	StatusCode int
+}
+
+func (e *StatusError) Error() string {
+	return fmt.Sprintf("%s returned status %d", e.Upstream, e.StatusCode)
+}
+
+func (e *StatusError) Unwrap() error {
+	if e.StatusCode >= http.StatusInternalServerError || e.StatusCode == http.StatusTooManyRequests {
+		return domain.ErrUpstreamUnavailable
+	}
+	return nil
+}
+
+// RequestError is returned when an upstream API cannot be reached or its answer cannot be read, as
+// on timeouts and network errors. It matches domain.ErrUpstreamUnavailable.
+type RequestError struct {
+	Upstream string
+	Err      error
+}
+
+func (e *RequestError) Error() string {
+	return fmt.Sprintf("%s request failed: %v", e.Upstream, e.Err)
+}
+
+func (e *RequestError) Unwrap() []error {
+	return []error{domain.ErrUpstreamUnavailable, e.Err}
+}
+
+// requestError wraps a failed request in a RequestError, unless the caller cancelled it or a
+// transport refused it locally, such as for an exhausted call budget
+func requestError(upstream string, err error) error {
+	if errors.Is(err, context.Canceled) || errors.Is(err, domain.ErrUpstreamUnavailable) {
+		return err
+	}
+	return &RequestError{Upstream: upstream, Err: err}
+}
@@ -1,15 +1,20 @@
 package repository
 
 import (
+	"context"
 	"encoding/json"
 	"io"
-	"log"
+	"log/slog"
 	"net/http"
 	"net/url"
 
 	"github.com/xavierpms/weather-by-city/internal/domain"
+	"github.com/xavierpms/weather-by-city/internal/infra/logging"
 )
 
+// kelvinOffset converts a temperature in Celsius to Kelvin
+const kelvinOffset = 273.15
+
 // WeatherAPIResponse represents the response from the WeatherAPI
 type WeatherAPIResponse struct {
 	Current struct {
@@ -21,52 +26,61 @@ type WeatherAPIResponse struct {
 // TemperatureRepository implements domain.TemperatureRepository
 type TemperatureRepository struct {
 	apiURL string
-	apiKey string
+	client *http.Client
 }
 
-// NewTemperatureRepository creates a new temperature repository
-func NewTemperatureRepository(apiURL, apiKey string) domain.TemperatureRepository {
+// NewTemperatureRepository creates a new temperature repository. The client adds the API key.
+func NewTemperatureRepository(apiURL string, client *http.Client) domain.TemperatureRepository {
 	return &TemperatureRepository{
 		apiURL: apiURL,
-		apiKey: apiKey,
+		client: client,
 	}
 }
 
 // GetTemperatureByCityName fetches the temperature for a given city
-func (r *TemperatureRepository) GetTemperatureByCityName(cityName string) (*domain.Temperature, error) {
-	// Encode the city name for URL
-	encodedCityName := url.QueryEscape(cityName)
-
+func (r *TemperatureRepository) GetTemperatureByCityName(ctx context.Context, cityName string) (*domain.Temperature, error) {
 	// Build the URL with parameters
-	requestURL := r.apiURL + "?q=" + encodedCityName + "&lang=pt&country=Brazil&key=" + r.apiKey
-	log.Printf("calling Weather API: base_url=%s city=%s", requestURL, cityName)
+	params := url.Values{}
+	params.Set("q", cityName)
+	params.Set("lang", "pt")
+	params.Set("country", "Brazil")
+	requestURL := r.apiURL + "?" + params.Encode()
+	slog.InfoContext(ctx, "calling Weather API", "url", logging.RedactURL(requestURL), "city", cityName)
 
 	// Make the request
-	resp, err := http.Get(requestURL)
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, requestURL, nil)
 	if err != nil {
-		log.Printf("Weather API request error: city=%s err=%v", cityName, err)
 		return nil, err
 	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		slog.ErrorContext(ctx, "Weather API request error", "city", cityName, "err", err)
+		return nil, requestError("Weather API", err)
+	}
 	defer resp.Body.Close()
-	log.Printf("Weather API response: city=%s status=%d", cityName, resp.StatusCode)
+	slog.InfoContext(ctx, "Weather API response", "city", cityName, "status", resp.StatusCode)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &StatusError{Upstream: "Weather API", StatusCode: resp.StatusCode}
+	}
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
-		log.Printf("Weather API read response error: city=%s err=%v", cityName, err)
-		return nil, err
+		slog.ErrorContext(ctx, "Weather API read response error", "city", cityName, "err", err)
+		return nil, requestError("Weather API", err)
 	}
 
 	// Unmarshal the response
 	var weatherResp WeatherAPIResponse
 	err = json.Unmarshal(body, &weatherResp)
 	if err != nil {
-		log.Printf("Weather API parse response error: city=%s err=%v", cityName, err)
+		slog.ErrorContext(ctx, "Weather API parse response error", "city", cityName, "err", err)
 		return nil, err
 	}
 
 	// Calculate the temperature in Kelvin
-	kelvin := weatherResp.Current.TempC + 273.0
-	log.Printf("Weather API request succeeded: city=%s temp_c=%.2f", cityName, weatherResp.Current.TempC)
+	kelvin := weatherResp.Current.TempC + kelvinOffset
+	slog.InfoContext(ctx, "Weather API request succeeded", "city", cityName, "temp_c", weatherResp.Current.TempC)
 
 	return &domain.Temperature{
 		Celsius:    weatherResp.Current.TempC,
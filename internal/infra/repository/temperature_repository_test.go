@@ -0,0 +1,56 @@
+package repository
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTemperatureRepositoryConvertsToKelvin tests that WeatherAPI temperatures are converted with 273.15
+func TestTemperatureRepositoryConvertsToKelvin(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.Write([]byte(`{"current": {"temp_c": 28.5, "temp_f": 83.3}}`))
+	}))
+	defer server.Close()
+	repo := NewTemperatureRepository(server.URL, server.Client())
+
+	// Act
+	temperature, err := repo.GetTemperatureByCityName(context.Background(), "São Paulo")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 28.5, temperature.Celsius)
+	assert.Equal(t, 83.3, temperature.Fahrenheit)
+	assert.InDelta(t, 301.65, temperature.Kelvin, 1e-9)
+}
+
+// TestOpenMeteoTemperatureRepositoryConvertsToKelvin tests that Open-Meteo temperatures are converted with 273.15
+func TestOpenMeteoTemperatureRepositoryConvertsToKelvin(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasPrefix(r.URL.Path, "/geocoding") {
+			w.Write([]byte(`{"results": [{"latitude": -23.55, "longitude": -46.63}]}`))
+			return
+		}
+		w.Write([]byte(`{"current": {"temperature_2m": 28.5}}`))
+	}))
+	defer server.Close()
+	repo := NewOpenMeteoTemperatureRepository(server.URL+"/geocoding", server.URL+"/forecast", server.Client())
+
+	// Act
+	temperature, err := repo.GetTemperatureByCityName(context.Background(), "São Paulo")
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, 28.5, temperature.Celsius)
+	assert.InDelta(t, 83.3, temperature.Fahrenheit, 1e-9)
+	assert.InDelta(t, 301.65, temperature.Kelvin, 1e-9)
+}
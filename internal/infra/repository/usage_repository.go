@@ -0,0 +1,126 @@
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/xavierpms/weather-by-city/internal/domain"
+)
+
+// usageRetentionDays is the number of days kept per API key
+const usageRetentionDays = 31
+
+// UsageRepositoryImpl implements domain.UsageRepository in memory. Flush persists the counters to a
+// JSON file, from which they are loaded on start.
+type UsageRepositoryImpl struct {
+	path string
+
+	mu sync.Mutex
+	// usage maps key ID, day and route to the number of requests
+	usage map[string]map[string]map[string]int
+	dirty bool
+}
+
+// NewUsageRepository loads the usage from the file. An empty path keeps the usage in memory only.
+func NewUsageRepository(path string) (*UsageRepositoryImpl, error) {
+	r := &UsageRepositoryImpl{
+		path:  path,
+		usage: make(map[string]map[string]map[string]int),
+	}
+
+	if err := readJSONFile(path, &r.usage); err != nil {
+		return nil, err
+	}
+
+	return r, nil
+}
+
+// Increment counts a request of the key on the day and route
+func (r *UsageRepositoryImpl) Increment(keyID, day, route string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	days, ok := r.usage[keyID]
+	if !ok {
+		days = make(map[string]map[string]int)
+		r.usage[keyID] = days
+	}
+	routes, ok := days[day]
+	if !ok {
+		routes = make(map[string]int)
+		days[day] = routes
+		prune(days)
+	}
+
+	routes[route]++
+	r.dirty = true
+	return nil
+}
+
+// Total returns the requests of the key on the day
+func (r *UsageRepositoryImpl) Total(keyID, day string) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	total := 0
+	for _, requests := range r.usage[keyID][day] {
+		total += requests
+	}
+	return total, nil
+}
+
+// List returns the usage of the key, most recent day first
+func (r *UsageRepositoryImpl) List(keyID string) ([]domain.DailyUsage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	days := make([]domain.DailyUsage, 0, len(r.usage[keyID]))
+	for day, routes := range r.usage[keyID] {
+		usage := domain.DailyUsage{Day: day, Routes: make(map[string]int, len(routes))}
+		for route, requests := range routes {
+			usage.Routes[route] = requests
+			usage.Total += requests
+		}
+		days = append(days, usage)
+	}
+
+	sort.Slice(days, func(i, j int) bool {
+		return days[i].Day > days[j].Day
+	})
+
+	return days, nil
+}
+
+// Flush writes the counters to the file when they changed since the last flush
+func (r *UsageRepositoryImpl) Flush(ctx context.Context) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if !r.dirty {
+		return nil
+	}
+	if err := writeJSONFile(r.path, r.usage); err != nil {
+		return err
+	}
+
+	r.dirty = false
+	return nil
+}
+
+// prune drops the oldest days beyond the retention. Days are formatted as 2006-01-02, so they sort as strings.
+func prune(days map[string]map[string]int) {
+	if len(days) <= usageRetentionDays {
+		return
+	}
+
+	sorted := make([]string, 0, len(days))
+	for day := range days {
+		sorted = append(sorted, day)
+	}
+	sort.Strings(sorted)
+
+	for _, day := range sorted[:len(sorted)-usageRetentionDays] {
+		delete(days, day)
+	}
+}
@@ -0,0 +1,215 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"math/rand/v2"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/xavierpms/weather-by-city/internal/domain"
+	"github.com/xavierpms/weather-by-city/internal/infra/logging"
+)
+
+// maxDrainBytes bounds how much of a discarded response is read so its connection can be reused
+const maxDrainBytes = 64 << 10
+
+// Policy configures the retries of the idempotent upstream requests
+type Policy struct {
+	// MaxAttempts is the total number of attempts, including the first one
+	MaxAttempts int
+	// BaseDelay is the backoff ceiling before the first retry, doubled on every attempt
+	BaseDelay time.Duration
+	// MaxDelay caps the backoff. A Retry-After longer than MaxDelay is not waited for.
+	MaxDelay time.Duration
+	// Timeout bounds the whole request, attempts and waits included; zero leaves it to the caller
+	Timeout time.Duration
+	// AttemptTimeout bounds each attempt, so a hung upstream leaves time for a retry; zero disables it
+	AttemptTimeout time.Duration
+}
+
+// Transport wraps base so that idempotent requests failing with a retryable error are retried
+// with capped exponential backoff and full jitter, never waiting past the request deadline. The
+// deadline is the earlier of the caller's and the policy Timeout.
+func Transport(base http.RoundTripper, policy Policy) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &transport{
+		base:   base,
+		policy: policy,
+		jitter: func(d time.Duration) time.Duration { return rand.N(d + 1) },
+		now:    time.Now,
+	}
+}
+
+type transport struct {
+	base   http.RoundTripper
+	policy Policy
+	jitter func(time.Duration) time.Duration
+	now    func() time.Time
+}
+
+func (t *transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if t.policy.Timeout <= 0 {
+		return t.roundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.policy.Timeout)
+	resp, err := t.roundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+func (t *transport) roundTrip(req *http.Request) (*http.Response, error) {
+	if !replayable(req) {
+		return t.attempt(req)
+	}
+
+	for attempt := 1; ; attempt++ {
+		resp, err := t.attempt(req)
+		if attempt >= t.policy.MaxAttempts || !retryable(req.Context(), resp, err) {
+			return resp, err
+		}
+
+		delay, ok := t.delay(req.Context(), attempt, resp)
+		if !ok {
+			return resp, err
+		}
+
+		ctx := req.Context()
+		if err != nil {
+			slog.WarnContext(ctx, "retrying upstream request", "url", logging.RedactURL(req.URL.String()), "attempt", attempt, "delay_ms", delay.Milliseconds(), "err", err)
+		} else {
+			slog.WarnContext(ctx, "retrying upstream request", "url", logging.RedactURL(req.URL.String()), "attempt", attempt, "delay_ms", delay.Milliseconds(), "status", resp.StatusCode)
+			io.CopyN(io.Discard, resp.Body, maxDrainBytes)
+			resp.Body.Close()
+		}
+
+		if err := sleep(ctx, delay); err != nil {
+			return nil, err
+		}
+		if req.Body != nil && req.Body != http.NoBody {
+			body, err := req.GetBody()
+			if err != nil {
+				return nil, err
+			}
+			req = req.Clone(ctx)
+			req.Body = body
+		}
+	}
+}
+
+// attempt sends the request once, bounded by the policy AttemptTimeout
+func (t *transport) attempt(req *http.Request) (*http.Response, error) {
+	if t.policy.AttemptTimeout <= 0 {
+		return t.base.RoundTrip(req)
+	}
+
+	ctx, cancel := context.WithTimeout(req.Context(), t.policy.AttemptTimeout)
+	resp, err := t.base.RoundTrip(req.WithContext(ctx))
+	if err != nil {
+		cancel()
+		return nil, err
+	}
+	resp.Body = &cancelOnClose{ReadCloser: resp.Body, cancel: cancel}
+	return resp, nil
+}
+
+// cancelOnClose releases the context of a response once its body is closed
+type cancelOnClose struct {
+	io.ReadCloser
+	cancel context.CancelFunc
+}
+
+func (b *cancelOnClose) Close() error {
+	err := b.ReadCloser.Close()
+	b.cancel()
+	return err
+}
+
+// delay returns how long to wait before the next attempt, or false when the wait would exceed
+// the policy or the deadline of the request
+func (t *transport) delay(ctx context.Context, attempt int, resp *http.Response) (time.Duration, bool) {
+	ceiling := t.policy.MaxDelay
+	if backoff := t.policy.BaseDelay << (attempt - 1); backoff > 0 && backoff < ceiling {
+		ceiling = backoff
+	}
+	delay := t.jitter(ceiling)
+
+	if resp != nil {
+		if retryAfter, ok := parseRetryAfter(resp.Header.Get("Retry-After"), t.now()); ok {
+			if retryAfter > t.policy.MaxDelay {
+				return 0, false
+			}
+			delay = retryAfter
+		}
+	}
+
+	if deadline, ok := ctx.Deadline(); ok && !t.now().Add(delay).Before(deadline) {
+		return 0, false
+	}
+
+	return delay, true
+}
+
+// replayable reports whether the request is idempotent and can be sent again
+func replayable(req *http.Request) bool {
+	if req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return false
+	}
+	return req.Body == nil || req.Body == http.NoBody || req.GetBody != nil
+}
+
+// retryable reports whether the failure is transient: a network error or an attempt timing out
+// while the caller is still waiting, a server error other than 501, or rate limiting. Inner
+// transports refusing the call because the upstream is known to be unavailable, such as an
+// exhausted call budget, are not retried.
+func retryable(ctx context.Context, resp *http.Response, err error) bool {
+	if err != nil {
+		return ctx.Err() == nil && !errors.Is(err, domain.ErrUpstreamUnavailable)
+	}
+
+	switch resp.StatusCode {
+	case http.StatusTooManyRequests, http.StatusInternalServerError, http.StatusBadGateway,
+		http.StatusServiceUnavailable, http.StatusGatewayTimeout:
+		return true
+	default:
+		return false
+	}
+}
+
+// parseRetryAfter parses a Retry-After header given either in seconds or as an HTTP date
+func parseRetryAfter(value string, now time.Time) (time.Duration, bool) {
+	if value == "" {
+		return 0, false
+	}
+	if seconds, err := strconv.Atoi(value); err == nil && seconds >= 0 {
+		return time.Duration(seconds) * time.Second, true
+	}
+	if date, err := http.ParseTime(value); err == nil {
+		return max(date.Sub(now), 0), true
+	}
+	return 0, false
+}
+
+// sleep waits for the delay, returning early with the context error when the caller gives up
+func sleep(ctx context.Context, delay time.Duration) error {
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
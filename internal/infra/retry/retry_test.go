@@ -0,0 +1,181 @@
+package retry
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// newTestTransport creates a transport without jitter, so the delays are the backoff ceilings
+func newTestTransport(policy Policy) *transport {
+	t := Transport(nil, policy).(*transport)
+	t.jitter = func(d time.Duration) time.Duration { return d }
+	return t
+}
+
+// TestTransportRetriesServerErrors tests that transient server errors are retried until success
+func TestTransportRetriesServerErrors(t *testing.T) {
+	// Arrange
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) < 3 {
+			w.WriteHeader(http.StatusBadGateway)
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: newTestTransport(Policy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond})}
+
+	// Act
+	resp, err := client.Get(server.URL)
+
+	// Assert
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+// TestTransportDoesNotRetryClientErrors tests that client errors and non-idempotent requests are sent once
+func TestTransportDoesNotRetryClientErrors(t *testing.T) {
+	// Arrange
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		if r.Method == http.MethodPost {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: newTestTransport(Policy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond})}
+
+	// Act
+	getResp, getErr := client.Get(server.URL)
+	postResp, postErr := client.Post(server.URL, "text/plain", nil)
+
+	// Assert
+	require.NoError(t, getErr)
+	require.NoError(t, postErr)
+	getResp.Body.Close()
+	postResp.Body.Close()
+	assert.Equal(t, http.StatusNotFound, getResp.StatusCode)
+	assert.Equal(t, http.StatusServiceUnavailable, postResp.StatusCode)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+// TestTransportHonorsRetryAfter tests that Retry-After replaces the backoff and is not waited for when too long
+func TestTransportHonorsRetryAfter(t *testing.T) {
+	// Arrange
+	tr := newTestTransport(Policy{MaxAttempts: 3, BaseDelay: 100 * time.Millisecond, MaxDelay: 5 * time.Second})
+	short := &http.Response{Header: http.Header{"Retry-After": []string{"2"}}}
+	long := &http.Response{Header: http.Header{"Retry-After": []string{"10"}}}
+
+	// Act
+	shortDelay, shortOK := tr.delay(context.Background(), 1, short)
+	_, longOK := tr.delay(context.Background(), 1, long)
+	backoff, _ := tr.delay(context.Background(), 3, &http.Response{Header: http.Header{}})
+
+	// Assert
+	assert.True(t, shortOK)
+	assert.Equal(t, 2*time.Second, shortDelay)
+	assert.False(t, longOK)
+	assert.Equal(t, 400*time.Millisecond, backoff)
+}
+
+// TestTransportRespectsDeadline tests that no retry is attempted when the wait would exceed the deadline
+func TestTransportRespectsDeadline(t *testing.T) {
+	// Arrange
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: newTestTransport(Policy{MaxAttempts: 5, BaseDelay: time.Second, MaxDelay: time.Second})}
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL, nil)
+	require.NoError(t, err)
+
+	// Act
+	resp, err := client.Do(req)
+
+	// Assert
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+// TestTransportRetriesHungAttempts tests that an attempt past AttemptTimeout is abandoned and retried
+func TestTransportRetriesHungAttempts(t *testing.T) {
+	// Arrange
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			<-r.Context().Done()
+			return
+		}
+		w.Write([]byte("ok"))
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: newTestTransport(Policy{MaxAttempts: 3, BaseDelay: time.Millisecond, MaxDelay: 10 * time.Millisecond,
+		Timeout: time.Second, AttemptTimeout: 50 * time.Millisecond})}
+
+	// Act
+	resp, err := client.Get(server.URL)
+
+	// Assert
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+	assert.Equal(t, int32(2), calls.Load())
+}
+
+// TestTransportStopsRetryingAtTheTimeout tests that a hung upstream is given up at Timeout, without a caller deadline
+func TestTransportStopsRetryingAtTheTimeout(t *testing.T) {
+	// Arrange
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+	client := &http.Client{Transport: newTestTransport(Policy{MaxAttempts: 100, BaseDelay: time.Millisecond, MaxDelay: time.Millisecond,
+		Timeout: 200 * time.Millisecond, AttemptTimeout: 50 * time.Millisecond})}
+	start := time.Now()
+
+	// Act
+	_, err := client.Get(server.URL)
+
+	// Assert
+	assert.ErrorIs(t, err, context.DeadlineExceeded)
+	assert.Less(t, time.Since(start), time.Second)
+	assert.GreaterOrEqual(t, calls.Load(), int32(2))
+	assert.LessOrEqual(t, calls.Load(), int32(4))
+}
+
+// TestRetryable tests the classification of the failures
+func TestRetryable(t *testing.T) {
+	// Arrange
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// Act & Assert
+	assert.True(t, retryable(context.Background(), nil, errors.New("connection reset by peer")))
+	assert.False(t, retryable(canceled, nil, context.Canceled))
+	assert.True(t, retryable(context.Background(), nil, context.DeadlineExceeded))
+	assert.True(t, retryable(context.Background(), &http.Response{StatusCode: http.StatusTooManyRequests}, nil))
+	assert.False(t, retryable(context.Background(), &http.Response{StatusCode: http.StatusNotImplemented}, nil))
+	assert.False(t, retryable(context.Background(), &http.Response{StatusCode: http.StatusBadRequest}, nil))
+}
@@ -0,0 +1,44 @@
+package scheduler
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// Scheduler runs a job at a fixed interval
+type Scheduler struct {
+	name     string
+	interval time.Duration
+	job      func(ctx context.Context) error
+}
+
+// NewScheduler creates a new scheduler
+func NewScheduler(name string, interval time.Duration, job func(ctx context.Context) error) *Scheduler {
+	return &Scheduler{
+		name:     name,
+		interval: interval,
+		job:      job,
+	}
+}
+
+// Run executes the job on every tick until the context is cancelled. The jobs run one at a time,
+// ticks missed while a job runs are dropped. A job in progress gets the cancellation through its
+// context, and Run returns once it has stopped.
+func (s *Scheduler) Run(ctx context.Context) {
+	ticker := time.NewTicker(s.interval)
+	defer ticker.Stop()
+
+	slog.Info("scheduler started", "name", s.name, "interval", s.interval)
+	for {
+		select {
+		case <-ctx.Done():
+			slog.Info("scheduler stopped", "name", s.name)
+			return
+		case <-ticker.C:
+			if err := s.job(ctx); err != nil {
+				slog.ErrorContext(ctx, "scheduler job error", "name", s.name, "err", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package scheduler
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// runInBackground starts the scheduler and returns a channel closed when Run returns
+func runInBackground(ctx context.Context, s *Scheduler) <-chan struct{} {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		s.Run(ctx)
+	}()
+	return done
+}
+
+// TestRunExecutesTheJobEveryInterval tests that the job runs on every tick, not right away
+func TestRunExecutesTheJobEveryInterval(t *testing.T) {
+	// Arrange
+	var runs atomic.Int32
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := NewScheduler("test", 20*time.Millisecond, func(ctx context.Context) error {
+		runs.Add(1)
+		return nil
+	})
+
+	// Act
+	done := runInBackground(ctx, s)
+
+	// Assert
+	assert.Equal(t, int32(0), runs.Load())
+	assert.Eventually(t, func() bool { return runs.Load() >= 3 }, time.Second, time.Millisecond)
+	cancel()
+	<-done
+}
+
+// TestRunDoesNotOverlapJobs tests that a job slower than the interval is not started again while it runs
+func TestRunDoesNotOverlapJobs(t *testing.T) {
+	// Arrange
+	var running, maxRunning, runs atomic.Int32
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := NewScheduler("test", time.Millisecond, func(ctx context.Context) error {
+		current := running.Add(1)
+		defer running.Add(-1)
+		if current > maxRunning.Load() {
+			maxRunning.Store(current)
+		}
+		runs.Add(1)
+		time.Sleep(10 * time.Millisecond)
+		return nil
+	})
+
+	// Act
+	done := runInBackground(ctx, s)
+	require.Eventually(t, func() bool { return runs.Load() >= 3 }, time.Second, time.Millisecond)
+	cancel()
+	<-done
+
+	// Assert
+	assert.Equal(t, int32(1), maxRunning.Load())
+}
+
+// TestRunCancelsTheJobOnShutdown tests that a job in progress is cancelled with the scheduler, which waits for it
+func TestRunCancelsTheJobOnShutdown(t *testing.T) {
+	// Arrange
+	started := make(chan struct{})
+	var jobErr atomic.Value
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	s := NewScheduler("test", time.Millisecond, func(ctx context.Context) error {
+		close(started)
+		<-ctx.Done()
+		jobErr.Store(ctx.Err())
+		return ctx.Err()
+	})
+	done := runInBackground(ctx, s)
+	<-started
+
+	// Act
+	cancel()
+
+	// Assert
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Run did not return after the cancellation")
+	}
+	assert.ErrorIs(t, jobErr.Load().(error), context.Canceled)
+}
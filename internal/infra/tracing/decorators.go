@@ -0,0 +1,89 @@
+package tracing
+
+import (
+	"context"
+
+	"github.com/xavierpms/weather-by-city/internal/domain"
+)
+
+// TracedTemperatureUseCase decorates a domain.TemperatureUseCase with a span
+type TracedTemperatureUseCase struct {
+	next domain.TemperatureUseCase
+}
+
+// NewTracedTemperatureUseCase creates a new traced temperature use case
+func NewTracedTemperatureUseCase(next domain.TemperatureUseCase) domain.TemperatureUseCase {
+	return &TracedTemperatureUseCase{next: next}
+}
+
+// GetTemperatureByCEP executes the decorated use case inside a span
+func (u *TracedTemperatureUseCase) GetTemperatureByCEP(ctx context.Context, cep string) (*domain.Temperature, error) {
+	ctx, span := start(ctx, "GetTemperatureByCEP", AttrCEPPrefix.String(CEPPrefix(cep)))
+	temperature, err := u.next.GetTemperatureByCEP(ctx, cep)
+	end(span, err)
+
+	return temperature, err
+}
+
+// TracedCEPRepository decorates a domain.CEPRepository with a span
+type TracedCEPRepository struct {
+	next     domain.CEPRepository
+	provider string
+}
+
+// NewTracedCEPRepository creates a new traced CEP repository
+func NewTracedCEPRepository(next domain.CEPRepository, provider string) domain.CEPRepository {
+	return &TracedCEPRepository{next: next, provider: provider}
+}
+
+// GetCEPData fetches the CEP data inside a span
+func (r *TracedCEPRepository) GetCEPData(ctx context.Context, cep string) (*domain.CEPData, error) {
+	ctx, span := start(ctx, "GetCEPData", AttrCEPPrefix.String(CEPPrefix(cep)), AttrProvider.String(r.provider))
+	cepData, err := r.next.GetCEPData(ctx, cep)
+	if err == nil {
+		span.SetAttributes(AttrCity.String(cepData.City))
+	}
+	end(span, err)
+
+	return cepData, err
+}
+
+// TracedTemperatureRepository decorates a domain.TemperatureRepository with a span
+type TracedTemperatureRepository struct {
+	next     domain.TemperatureRepository
+	provider string
+}
+
+// NewTracedTemperatureRepository creates a new traced temperature repository
+func NewTracedTemperatureRepository(next domain.TemperatureRepository, provider string) domain.TemperatureRepository {
+	return &TracedTemperatureRepository{next: next, provider: provider}
+}
+
+// GetTemperatureByCityName fetches the temperature inside a span
+func (r *TracedTemperatureRepository) GetTemperatureByCityName(ctx context.Context, cityName string) (*domain.Temperature, error) {
+	ctx, span := start(ctx, "GetTemperatureByCityName", AttrCity.String(cityName), AttrProvider.String(r.provider))
+	temperature, err := r.next.GetTemperatureByCityName(ctx, cityName)
+	end(span, err)
+
+	return temperature, err
+}
+
+// TracedForecastRepository decorates a domain.ForecastRepository with a span
+type TracedForecastRepository struct {
+	next     domain.ForecastRepository
+	provider string
+}
+
+// NewTracedForecastRepository creates a new traced forecast repository
+func NewTracedForecastRepository(next domain.ForecastRepository, provider string) domain.ForecastRepository {
+	return &TracedForecastRepository{next: next, provider: provider}
+}
+
+// GetForecastByCityName fetches the forecast inside a span
+func (r *TracedForecastRepository) GetForecastByCityName(ctx context.Context, cityName string, days int) ([]domain.ForecastDay, error) {
+	ctx, span := start(ctx, "GetForecastByCityName", AttrCity.String(cityName), AttrProvider.String(r.provider), AttrDays.Int(days))
+	forecast, err := r.next.GetForecastByCityName(ctx, cityName, days)
+	end(span, err)
+
+	return forecast, err
+}
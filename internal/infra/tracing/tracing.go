@@ -0,0 +1,193 @@
+package tracing
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os"
+
+	"github.com/go-chi/chi"
+	"github.com/xavierpms/weather-by-city/internal/infra/logging"
+	"go.opentelemetry.io/contrib/instrumentation/net/http/otelhttp"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// Supported exporters
+const (
+	ExporterNone   = "none"
+	ExporterStdout = "stdout"
+	ExporterOTLP   = "otlp"
+)
+
+const (
+	serviceName = "weather-by-city"
+	tracerName  = "github.com/xavierpms/weather-by-city"
+)
+
+// Span attribute keys
+const (
+	AttrCEPPrefix = attribute.Key("cep.prefix")
+	AttrCity      = attribute.Key("city")
+	AttrProvider  = attribute.Key("provider")
+	AttrDays      = attribute.Key("forecast.days")
+)
+
+// Options configure the tracer provider
+type Options struct {
+	Exporter     string
+	OTLPEndpoint string
+	OTLPInsecure bool
+	SampleRatio  float64
+}
+
+// Setup installs the global tracer provider and the W3C trace context propagator.
+// The returned function flushes the pending spans and must be called on shutdown.
+func Setup(ctx context.Context, options Options) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch options.Exporter {
+	case ExporterNone, "":
+		return func(context.Context) error { return nil }, nil
+
+	case ExporterStdout:
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+
+	case ExporterOTLP:
+		clientOptions := []otlptracegrpc.Option{}
+		if options.OTLPEndpoint != "" {
+			clientOptions = append(clientOptions, otlptracegrpc.WithEndpoint(options.OTLPEndpoint))
+		}
+		if options.OTLPInsecure {
+			clientOptions = append(clientOptions, otlptracegrpc.WithInsecure())
+		}
+		exporter, err = otlptracegrpc.New(ctx, clientOptions...)
+
+	default:
+		return nil, fmt.Errorf("invalid tracing exporter %q", options.Exporter)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	res, err := resource.Merge(resource.Default(), resource.NewSchemaless(semconv.ServiceName(serviceName)))
+	if err != nil {
+		return nil, err
+	}
+
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(options.SampleRatio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return provider.Shutdown, nil
+}
+
+// Middleware starts a server span for every request, continuing the trace of the caller
+func Middleware(next http.Handler) http.Handler {
+	return otelhttp.NewHandler(routeNamer(next), "http.server")
+}
+
+// routeNamer names the server span after the chi route pattern once the request has been routed
+func routeNamer(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		next.ServeHTTP(w, r)
+
+		routeContext := chi.RouteContext(r.Context())
+		if routeContext == nil || routeContext.RoutePattern() == "" {
+			return
+		}
+		span := trace.SpanFromContext(r.Context())
+		span.SetName(r.Method + " " + routeContext.RoutePattern())
+		span.SetAttributes(semconv.HTTPRoute(routeContext.RoutePattern()))
+	})
+}
+
+// originalURLKey carries the URL to send while the client span sees its redacted copy
+type originalURLKey struct{}
+
+// Transport propagates the trace context in the outgoing requests and records a client span for
+// each of them. The span sees the URL with its secret query parameters, such as API keys, redacted.
+func Transport(base http.RoundTripper) http.RoundTripper {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+
+	return &redactingTransport{traced: otelhttp.NewTransport(&restoringTransport{base: base})}
+}
+
+// redactingTransport hands the traced transport a copy of the request with a redacted URL
+type redactingTransport struct {
+	traced http.RoundTripper
+}
+
+func (t *redactingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	redacted := logging.RedactURL(req.URL.String())
+	if redacted == req.URL.String() {
+		return t.traced.RoundTrip(req)
+	}
+	redactedURL, err := url.Parse(redacted)
+	if err != nil {
+		return nil, err
+	}
+
+	masked := req.Clone(context.WithValue(req.Context(), originalURLKey{}, req.URL))
+	masked.URL = redactedURL
+	return t.traced.RoundTrip(masked)
+}
+
+// restoringTransport sends the request, once traced, to the URL it had before being redacted
+type restoringTransport struct {
+	base http.RoundTripper
+}
+
+func (t *restoringTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	original, ok := req.Context().Value(originalURLKey{}).(*url.URL)
+	if !ok {
+		return t.base.RoundTrip(req)
+	}
+
+	unmasked := req.Clone(req.Context())
+	unmasked.URL = original
+	return t.base.RoundTrip(unmasked)
+}
+
+// CEPPrefix returns the first five digits of the CEP, which identify the region without pinpointing the address
+func CEPPrefix(cep string) string {
+	if len(cep) > 5 {
+		return cep[:5]
+	}
+	return cep
+}
+
+// start starts an internal span with the global tracer
+func start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	return otel.Tracer(tracerName).Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// end records the error, if any, and ends the span. The error message is redacted, since the
+// errors of the HTTP client carry the URL called.
+func end(span trace.Span, err error) {
+	if err != nil {
+		message := logging.RedactURL(err.Error())
+		span.AddEvent(semconv.ExceptionEventName, trace.WithAttributes(
+			semconv.ExceptionType(fmt.Sprintf("%T", err)),
+			semconv.ExceptionMessage(message),
+		))
+		span.SetStatus(codes.Error, message)
+	}
+	span.End()
+}
@@ -0,0 +1,123 @@
+package tracing
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/xavierpms/weather-by-city/internal/domain"
+	"github.com/xavierpms/weather-by-city/internal/infra/keypool"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/propagation"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+)
+
+// stubCEPRepository calls the upstream server with the given client
+type stubCEPRepository struct {
+	client *http.Client
+	url    string
+}
+
+func (r *stubCEPRepository) GetCEPData(ctx context.Context, cep string) (*domain.CEPData, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, r.url, nil)
+	if err != nil {
+		return nil, err
+	}
+	resp, err := r.client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	resp.Body.Close()
+
+	return &domain.CEPData{CEP: cep, City: "Lavras"}, nil
+}
+
+// TestSpansArePropagatedToUpstream tests the span hierarchy and the traceparent header sent upstream
+func TestSpansArePropagatedToUpstream(t *testing.T) {
+	// Arrange
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	var traceparent string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer upstream.Close()
+
+	repo := NewTracedCEPRepository(&stubCEPRepository{client: &http.Client{Transport: Transport(nil)}, url: upstream.URL}, "viacep")
+	router := chi.NewRouter()
+	router.Use(Middleware)
+	router.Get("/{cep}", func(w http.ResponseWriter, r *http.Request) {
+		_, _ = repo.GetCEPData(r.Context(), chi.URLParam(r, "cep"))
+	})
+
+	// Act
+	router.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest("GET", "/37200000", nil))
+
+	// Assert
+	spans := recorder.Ended()
+	byName := make(map[string]sdktrace.ReadOnlySpan)
+	for _, span := range spans {
+		byName[span.Name()] = span
+	}
+
+	server, ok := byName["GET /{cep}"]
+	assert.True(t, ok)
+	repository, ok := byName["GetCEPData"]
+	assert.True(t, ok)
+	assert.Equal(t, server.SpanContext().SpanID(), repository.Parent().SpanID())
+	assert.Contains(t, repository.Attributes(), AttrCEPPrefix.String("37200"))
+	assert.Contains(t, repository.Attributes(), AttrCity.String("Lavras"))
+	assert.Contains(t, repository.Attributes(), attribute.String("provider", "viacep"))
+
+	assert.Contains(t, traceparent, server.SpanContext().TraceID().String())
+}
+
+// TestSpansNeverCarryTheUpstreamKey tests that the key added by the key pool is redacted from the client spans and errors
+func TestSpansNeverCarryTheUpstreamKey(t *testing.T) {
+	// Arrange
+	recorder := tracetest.NewSpanRecorder()
+	otel.SetTracerProvider(sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder)))
+
+	const secret = "live-weather-api-key"
+	var sentKey string
+	upstream := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sentKey = r.URL.Query().Get("key")
+	}))
+	defer upstream.Close()
+	down := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	down.Close()
+
+	keys := keypool.New("weatherapi", []string{secret}, keypool.StrategyRoundRobin)
+	client := &http.Client{Transport: keys.Transport(Transport(nil))}
+
+	// Act
+	_, ok := NewTracedCEPRepository(&stubCEPRepository{client: client, url: upstream.URL + "/current.json?q=Lavras"}, "weatherapi").
+		GetCEPData(context.Background(), "37200000")
+	_, failed := NewTracedCEPRepository(&stubCEPRepository{client: client, url: down.URL + "/current.json?q=Lavras"}, "weatherapi").
+		GetCEPData(context.Background(), "37200000")
+
+	// Assert
+	assert.NoError(t, ok)
+	assert.Error(t, failed)
+	assert.Equal(t, secret, sentKey)
+	spans := recorder.Ended()
+	assert.Len(t, spans, 4)
+	for _, span := range spans {
+		attrs := span.Attributes()
+		for _, event := range span.Events() {
+			attrs = append(attrs, event.Attributes...)
+		}
+		for _, attr := range attrs {
+			assert.NotContains(t, attr.Value.Emit(), secret, "span %q attribute %s", span.Name(), attr.Key)
+		}
+		assert.False(t, strings.Contains(span.Status().Description, secret), "span %q status", span.Name())
+	}
+}
@@ -0,0 +1,87 @@
+package validator
+
+import (
+	_ "embed"
+	"encoding/csv"
+	"fmt"
+	"io"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+//go:embed cep_ranges.csv
+var cepRangesCSV string
+
+// cepRange is a range of CEPs assigned to a UF, bounds included
+type cepRange struct {
+	uf          string
+	first, last int
+}
+
+// cepRanges are the Correios ranges, sorted by their first CEP
+var cepRanges = mustParseCEPRanges(cepRangesCSV)
+
+// mustParseCEPRanges reads the embedded table, which is checked by the tests
+func mustParseCEPRanges(table string) []cepRange {
+	ranges, err := parseCEPRanges(table)
+	if err != nil {
+		panic(err)
+	}
+	return ranges
+}
+
+// parseCEPRanges reads the uf,first,last lines of the table, refusing overlapping ranges
+func parseCEPRanges(table string) ([]cepRange, error) {
+	reader := csv.NewReader(strings.NewReader(table))
+	reader.Comment = '#'
+	reader.FieldsPerRecord = 3
+
+	var ranges []cepRange
+	for {
+		record, err := reader.Read()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid CEP range table: %w", err)
+		}
+
+		first, firstErr := strconv.Atoi(record[1])
+		last, lastErr := strconv.Atoi(record[2])
+		if firstErr != nil || lastErr != nil || len(record[1]) != cepLength || len(record[2]) != cepLength || first > last {
+			return nil, fmt.Errorf("invalid CEP range %s: %s-%s", record[0], record[1], record[2])
+		}
+		ranges = append(ranges, cepRange{uf: record[0], first: first, last: last})
+	}
+
+	slices.SortFunc(ranges, func(a, b cepRange) int { return a.first - b.first })
+	for i := 1; i < len(ranges); i++ {
+		if ranges[i].first <= ranges[i-1].last {
+			return nil, fmt.Errorf("CEP ranges of %s and %s overlap", ranges[i-1].uf, ranges[i].uf)
+		}
+	}
+
+	return ranges, nil
+}
+
+// ufOf returns the UF of the range holding the CEP, given as its 8 digits
+func ufOf(cep string) (string, bool) {
+	number, err := strconv.Atoi(cep)
+	if err != nil {
+		return "", false
+	}
+
+	// The first range starting after the CEP follows the only range that may hold it
+	i, _ := slices.BinarySearchFunc(cepRanges, number, func(r cepRange, n int) int {
+		if r.first > n {
+			return 1
+		}
+		return -1
+	})
+	if i == 0 || cepRanges[i-1].last < number {
+		return "", false
+	}
+
+	return cepRanges[i-1].uf, true
+}
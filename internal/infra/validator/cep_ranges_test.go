@@ -0,0 +1,88 @@
+package validator
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestInferUF(t *testing.T) {
+	// Arrange
+	validator := NewCEPValidator()
+
+	testCases := []struct {
+		cep      string
+		expected string
+	}{
+		{"01000000", "SP"}, // First CEP of the table
+		{"01001000", "SP"},
+		{"20040002", "RJ"},
+		{"32450000", "MG"},
+		{"69301000", "RR"},
+		{"69400000", "AM"}, // Second range of AM, after RR
+		{"70040010", "DF"},
+		{"72800000", "GO"}, // Between the two ranges of DF
+		{"73700000", "GO"},
+		{"78900000", "RO"},
+		{"99999999", "RS"}, // Last CEP of the table
+	}
+
+	for _, tc := range testCases {
+		// Act
+		uf, ok := validator.InferUF(tc.cep)
+
+		// Assert
+		assert.True(t, ok, tc.cep)
+		assert.Equal(t, tc.expected, uf, tc.cep)
+	}
+}
+
+func TestInferUFOutsideTheRanges(t *testing.T) {
+	// Arrange
+	validator := NewCEPValidator()
+
+	for _, cep := range []string{"00000000", "00999999", "0100000", "01001-000", "abcdefgh"} {
+		// Act
+		uf, ok := validator.InferUF(cep)
+
+		// Assert
+		assert.False(t, ok, cep)
+		assert.Empty(t, uf, cep)
+	}
+}
+
+func TestEmbeddedCEPRangesCoverEveryUF(t *testing.T) {
+	// Arrange
+	ufs := map[string]bool{}
+
+	// Act
+	for _, r := range cepRanges {
+		ufs[r.uf] = true
+	}
+
+	// Assert
+	assert.Len(t, ufs, 27)
+}
+
+func TestParseCEPRangesRejectsInvalidTables(t *testing.T) {
+	testCases := map[string]string{
+		"overlap":      "SP,01000000,19999999\nRJ,19000000,28999999\n",
+		"reversed":     "SP,19999999,01000000\n",
+		"short bound":  "SP,1000000,19999999\n",
+		"not a number": "SP,0100000a,19999999\n",
+		"missing last": "SP,01000000\n",
+	}
+
+	for name, table := range testCases {
+		// Act
+		_, err := parseCEPRanges(table)
+
+		// Assert
+		assert.Error(t, err, name)
+	}
+
+	ranges, err := parseCEPRanges("# uf,first,last\nRJ,20000000,28999999\nSP,01000000,19999999\n")
+	require.NoError(t, err)
+	assert.Equal(t, []cepRange{{"SP", 1000000, 19999999}, {"RJ", 20000000, 28999999}}, ranges)
+}
@@ -1,6 +1,14 @@
 package validator
 
-import "strconv"
+import (
+	"strings"
+	"unicode"
+
+	"github.com/xavierpms/weather-by-city/internal/domain"
+)
+
+// cepLength is the number of digits of a CEP
+const cepLength = 8
 
 // CEPValidatorImpl implements domain.CEPValidator
 type CEPValidatorImpl struct{}
@@ -10,14 +18,50 @@ func NewCEPValidator() *CEPValidatorImpl {
 	return &CEPValidatorImpl{}
 }
 
-// ValidateCEPFormat validates the format of the CEP (must have 8 digits)
+// ValidateCEPFormat validates the format of the CEP (must have 8 digits within a Correios range)
 func (v *CEPValidatorImpl) ValidateCEPFormat(cep string) bool {
 	// Check if it has exactly 8 characters
 	if len(cep) != 8 {
 		return false
 	}
 
-	// Check if all are numbers
-	_, err := strconv.Atoi(cep)
-	return err == nil
+	// Check if all are numbers and belong to a UF
+	_, ok := ufOf(cep)
+	return ok
+}
+
+// InferUF returns the UF of the Correios range holding the CEP, given as 8 digits
+func (v *CEPValidatorImpl) InferUF(cep string) (string, bool) {
+	if len(cep) != cepLength {
+		return "", false
+	}
+	return ufOf(cep)
+}
+
+// NormalizeCEP accepts the CEP as people and spreadsheets write it, such as 01001-000,
+// 01.001-000 or " 01001 000 ", and returns its 8 digits. Dots, hyphens and whitespace are
+// dropped; any other character is refused, and so is a CEP outside the Correios ranges.
+func (v *CEPValidatorImpl) NormalizeCEP(cep string) (string, error) {
+	var digits strings.Builder
+	for _, c := range cep {
+		switch {
+		case c >= '0' && c <= '9':
+			digits.WriteRune(c)
+		case c == '.' || c == '-' || unicode.IsSpace(c):
+		default:
+			return "", &domain.CEPFormatError{Reason: domain.CEPFormatInvalidCharacter, Character: c}
+		}
+	}
+
+	switch digits.Len() {
+	case 0:
+		return "", &domain.CEPFormatError{Reason: domain.CEPFormatEmpty}
+	case cepLength:
+		if _, ok := ufOf(digits.String()); !ok {
+			return "", &domain.CEPFormatError{Reason: domain.CEPFormatOutOfRange}
+		}
+		return digits.String(), nil
+	default:
+		return "", &domain.CEPFormatError{Reason: domain.CEPFormatWrongLength, Digits: digits.Len()}
+	}
 }
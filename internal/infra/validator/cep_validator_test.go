@@ -4,6 +4,7 @@ import (
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/xavierpms/weather-by-city/internal/domain"
 )
 
 func TestValidateCEPFormatValid(t *testing.T) {
@@ -13,7 +14,6 @@ func TestValidateCEPFormatValid(t *testing.T) {
 	// Act & Assert
 	assert.True(t, validator.ValidateCEPFormat("32450000"))
 	assert.True(t, validator.ValidateCEPFormat("01021200"))
-	assert.True(t, validator.ValidateCEPFormat("00000000"))
 }
 
 func TestValidateCEPFormatInvalid(t *testing.T) {
@@ -28,9 +28,65 @@ func TestValidateCEPFormatInvalid(t *testing.T) {
 		"",          // Empty
 		"   ",       // Only spaces
 		"abcdefgh",  // All letters
+		"00000000",  // Outside the Correios ranges
 	}
 
 	for _, cep := range testCases {
 		assert.False(t, validator.ValidateCEPFormat(cep))
 	}
 }
+
+func TestNormalizeCEPAcceptsCommonWritings(t *testing.T) {
+	// Arrange
+	validator := NewCEPValidator()
+
+	testCases := []string{
+		"01001000",
+		"01001-000",
+		"01.001-000",
+		" 01001000 ",
+		"01001 000",
+		"\t01001-000\n",
+		"01001\u00a0000", // Non-breaking space, as pasted from spreadsheets
+	}
+
+	for _, cep := range testCases {
+		// Act
+		normalized, err := validator.NormalizeCEP(cep)
+
+		// Assert
+		assert.NoError(t, err, cep)
+		assert.Equal(t, "01001000", normalized, cep)
+	}
+}
+
+func TestNormalizeCEPReportsReasons(t *testing.T) {
+	// Arrange
+	validator := NewCEPValidator()
+
+	testCases := []struct {
+		cep      string
+		expected domain.CEPFormatError
+	}{
+		{"", domain.CEPFormatError{Reason: domain.CEPFormatEmpty}},
+		{" .- ", domain.CEPFormatError{Reason: domain.CEPFormatEmpty}},
+		{"3245000", domain.CEPFormatError{Reason: domain.CEPFormatWrongLength, Digits: 7}},
+		{"32450-0000", domain.CEPFormatError{Reason: domain.CEPFormatWrongLength, Digits: 9}},
+		{"3245000a", domain.CEPFormatError{Reason: domain.CEPFormatInvalidCharacter, Character: 'a'}},
+		{"32450/000", domain.CEPFormatError{Reason: domain.CEPFormatInvalidCharacter, Character: '/'}},
+		{"00000-000", domain.CEPFormatError{Reason: domain.CEPFormatOutOfRange}},
+		{"00999999", domain.CEPFormatError{Reason: domain.CEPFormatOutOfRange}},
+	}
+
+	for _, tc := range testCases {
+		// Act
+		_, err := validator.NormalizeCEP(tc.cep)
+
+		// Assert
+		var formatErr *domain.CEPFormatError
+		assert.ErrorIs(t, err, domain.ErrInvalidCEPFormat, tc.cep)
+		if assert.ErrorAs(t, err, &formatErr, tc.cep) {
+			assert.Equal(t, tc.expected, *formatErr, tc.cep)
+		}
+	}
+}
@@ -0,0 +1,319 @@
+package webhook
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/netip"
+	"strconv"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/xavierpms/weather-by-city/internal/domain"
+)
+
+const (
+	// SignatureHeader carries the HMAC-SHA256 of "<timestamp>.<body>" signed with the webhook secret
+	SignatureHeader = "X-Webhook-Signature"
+	// TimestampHeader carries the unix timestamp used in the signature
+	TimestampHeader = "X-Webhook-Timestamp"
+	// DeliveryIDHeader carries the delivery ID, stable across retries and replays
+	DeliveryIDHeader = "X-Webhook-Delivery"
+
+	maxBackoff = 5 * time.Minute
+)
+
+// errForbiddenAddress is returned when a webhook host resolves to an address of the internal network
+var errForbiddenAddress = errors.New("webhook address is not public")
+
+// Dispatcher implements domain.WebhookDispatcher with signed, retried deliveries
+// and a bounded in-memory dead-letter list
+type Dispatcher struct {
+	client         *http.Client
+	secret         []byte
+	maxAttempts    int
+	backoff        time.Duration
+	maxDeadLetters int
+	// allowAddr tells whether the dialer may connect to an address
+	allowAddr func(netip.Addr) bool
+
+	mu          sync.Mutex
+	deadLetters []*domain.WebhookDelivery
+	replaying   map[string]bool
+	inFlight    sync.WaitGroup
+
+	stopping chan struct{}
+	stopOnce sync.Once
+}
+
+// NewDispatcher creates a new webhook dispatcher keeping up to maxDeadLetters failed deliveries.
+// Webhooks are only delivered to public addresses, checked after the host name is resolved so a
+// DNS answer cannot point a rule to the internal network.
+func NewDispatcher(secret string, maxAttempts int, backoff time.Duration, maxDeadLetters int) *Dispatcher {
+	if maxAttempts < 1 {
+		maxAttempts = 1
+	}
+
+	d := &Dispatcher{
+		secret:         []byte(secret),
+		maxAttempts:    maxAttempts,
+		backoff:        backoff,
+		maxDeadLetters: max(maxDeadLetters, 1),
+		allowAddr:      domain.IsPublicWebhookAddr,
+		replaying:      make(map[string]bool),
+		stopping:       make(chan struct{}),
+	}
+
+	dialer := &net.Dialer{Timeout: 5 * time.Second, Control: d.controlDial}
+	d.client = &http.Client{
+		Timeout: 10 * time.Second,
+		// No proxy, whose address would be checked instead of the webhook's
+		Transport: &http.Transport{
+			DialContext:         dialer.DialContext,
+			TLSHandshakeTimeout: 5 * time.Second,
+			MaxIdleConns:        10,
+			IdleConnTimeout:     90 * time.Second,
+		},
+	}
+
+	return d
+}
+
+// Dispatch delivers the event in the background, retrying with exponential backoff
+func (d *Dispatcher) Dispatch(rule *domain.AlertRule, event *domain.AlertEvent) error {
+	delivery := &domain.WebhookDelivery{
+		ID:     newDeliveryID(),
+		RuleID: rule.ID,
+		URL:    rule.WebhookURL,
+		Event:  *event,
+	}
+
+	d.inFlight.Add(1)
+	go func() {
+		defer d.inFlight.Done()
+		d.deliver(delivery)
+	}()
+
+	return nil
+}
+
+// DeadLetters returns the deliveries that exhausted their attempts
+func (d *Dispatcher) DeadLetters() []*domain.WebhookDelivery {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	deadLetters := make([]*domain.WebhookDelivery, 0, len(d.deadLetters))
+	for _, delivery := range d.deadLetters {
+		listed := *delivery
+		deadLetters = append(deadLetters, &listed)
+	}
+
+	return deadLetters
+}
+
+// Replay makes one more delivery attempt for a dead letter, removing it from the list on success.
+// A dead letter is replayed by one caller at a time, so concurrent replays cannot deliver it twice.
+func (d *Dispatcher) Replay(id string) error {
+	d.mu.Lock()
+	var delivery *domain.WebhookDelivery
+	for _, deadLetter := range d.deadLetters {
+		if deadLetter.ID == id {
+			delivery = deadLetter
+			break
+		}
+	}
+	if delivery == nil {
+		d.mu.Unlock()
+		return domain.ErrDeadLetterNotFound
+	}
+	if d.replaying[id] {
+		d.mu.Unlock()
+		return domain.ErrDeadLetterReplaying
+	}
+	d.replaying[id] = true
+	d.mu.Unlock()
+
+	_, err := d.attempt(delivery)
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	delete(d.replaying, id)
+
+	now := time.Now().UTC()
+	delivery.Attempts++
+	delivery.ReplayedAt = &now
+
+	if err != nil {
+		delivery.LastError = err.Error()
+		slog.Warn("webhook replay failed", "delivery", delivery.ID, "err", err)
+		return fmt.Errorf("%w: %v", domain.ErrWebhookDeliveryFail, err)
+	}
+
+	for i, deadLetter := range d.deadLetters {
+		if deadLetter.ID == id {
+			d.deadLetters = append(d.deadLetters[:i], d.deadLetters[i+1:]...)
+			break
+		}
+	}
+	slog.Info("webhook replay succeeded", "delivery", delivery.ID)
+
+	return nil
+}
+
+// Wait blocks until all background deliveries have finished
+func (d *Dispatcher) Wait() {
+	d.inFlight.Wait()
+}
+
+// Shutdown cancels the pending retries and waits for the attempts in progress,
+// up to the context deadline. Deliveries that could not be retried are dead-lettered.
+func (d *Dispatcher) Shutdown(ctx context.Context) error {
+	d.stopOnce.Do(func() { close(d.stopping) })
+
+	done := make(chan struct{})
+	go func() {
+		d.inFlight.Wait()
+		close(done)
+	}()
+
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// deliver attempts the delivery until it succeeds, fails permanently or runs out of attempts
+func (d *Dispatcher) deliver(delivery *domain.WebhookDelivery) {
+	for attempt := 1; attempt <= d.maxAttempts; attempt++ {
+		retryable, err := d.attempt(delivery)
+		delivery.Attempts = attempt
+		if err == nil {
+			slog.Info("webhook delivered", "delivery", delivery.ID, "rule", delivery.RuleID, "attempts", attempt)
+			return
+		}
+
+		delivery.LastError = err.Error()
+		slog.Warn("webhook attempt failed", "delivery", delivery.ID, "attempt", attempt, "err", err)
+
+		if !retryable || attempt == d.maxAttempts || !d.sleep(d.backoffFor(attempt)) {
+			break
+		}
+	}
+
+	delivery.FailedAt = time.Now().UTC()
+
+	d.mu.Lock()
+	if len(d.deadLetters) >= d.maxDeadLetters {
+		// Drop the oldest dead letter that is not being replayed
+		for i, deadLetter := range d.deadLetters {
+			if !d.replaying[deadLetter.ID] {
+				slog.Warn("webhook dead letter dropped", "delivery", deadLetter.ID, "rule", deadLetter.RuleID)
+				d.deadLetters = append(d.deadLetters[:i], d.deadLetters[i+1:]...)
+				break
+			}
+		}
+	}
+	d.deadLetters = append(d.deadLetters, delivery)
+	d.mu.Unlock()
+	slog.Error("webhook dead-lettered", "delivery", delivery.ID, "rule", delivery.RuleID)
+}
+
+// controlDial refuses the connections to addresses the dispatcher may not deliver to
+func (d *Dispatcher) controlDial(network, address string, _ syscall.RawConn) error {
+	addrPort, err := netip.ParseAddrPort(address)
+	if err != nil {
+		return err
+	}
+	if !d.allowAddr(addrPort.Addr()) {
+		return fmt.Errorf("%w: %s", errForbiddenAddress, addrPort.Addr())
+	}
+	return nil
+}
+
+// sleep waits for the backoff, returning false if the dispatcher is shutting down
+func (d *Dispatcher) sleep(backoff time.Duration) bool {
+	timer := time.NewTimer(backoff)
+	defer timer.Stop()
+
+	select {
+	case <-timer.C:
+		return true
+	case <-d.stopping:
+		return false
+	}
+}
+
+// attempt performs a single signed POST and reports whether a failure is worth retrying
+func (d *Dispatcher) attempt(delivery *domain.WebhookDelivery) (bool, error) {
+	body, err := json.Marshal(delivery.Event)
+	if err != nil {
+		return false, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, delivery.URL, bytes.NewReader(body))
+	if err != nil {
+		return false, err
+	}
+
+	timestamp := strconv.FormatInt(time.Now().Unix(), 10)
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(DeliveryIDHeader, delivery.ID)
+	req.Header.Set(TimestampHeader, timestamp)
+	req.Header.Set(SignatureHeader, "sha256="+Sign(d.secret, timestamp, body))
+
+	resp, err := d.client.Do(req)
+	if err != nil {
+		return !errors.Is(err, errForbiddenAddress), err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		return false, nil
+	}
+
+	// Client errors will not succeed on retry, except for timeouts and throttling
+	retryable := resp.StatusCode >= 500 ||
+		resp.StatusCode == http.StatusRequestTimeout ||
+		resp.StatusCode == http.StatusTooManyRequests
+
+	return retryable, fmt.Errorf("unexpected status %d", resp.StatusCode)
+}
+
+// backoffFor returns the wait before the next attempt, doubling each time
+func (d *Dispatcher) backoffFor(attempt int) time.Duration {
+	wait := d.backoff << (attempt - 1)
+	if wait <= 0 || wait > maxBackoff {
+		return maxBackoff
+	}
+
+	return wait
+}
+
+// Sign computes the hex HMAC-SHA256 of "<timestamp>.<body>"
+func Sign(secret []byte, timestamp string, body []byte) string {
+	mac := hmac.New(sha256.New, secret)
+	mac.Write([]byte(timestamp))
+	mac.Write([]byte("."))
+	mac.Write(body)
+
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// newDeliveryID generates a random delivery identifier
+func newDeliveryID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
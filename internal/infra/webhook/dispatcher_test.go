@@ -0,0 +1,231 @@
+package webhook
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/netip"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xavierpms/weather-by-city/internal/domain"
+)
+
+// newTestDispatcher creates a dispatcher allowed to deliver to the loopback test servers
+func newTestDispatcher(secret string, maxAttempts int, backoff time.Duration, maxDeadLetters int) *Dispatcher {
+	dispatcher := NewDispatcher(secret, maxAttempts, backoff, maxDeadLetters)
+	dispatcher.allowAddr = func(addr netip.Addr) bool { return addr.IsLoopback() }
+	return dispatcher
+}
+
+// TestDispatchSignsPayload tests that the receiver can verify the HMAC signature
+func TestDispatchSignsPayload(t *testing.T) {
+	// Arrange
+	var verified atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		expected := "sha256=" + Sign([]byte("secret"), r.Header.Get(TimestampHeader), body)
+		verified.Store(r.Header.Get(SignatureHeader) == expected)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	dispatcher := newTestDispatcher("secret", 3, time.Millisecond, 10)
+	rule := &domain.AlertRule{ID: "rule-1", WebhookURL: server.URL}
+
+	// Act
+	err := dispatcher.Dispatch(rule, &domain.AlertEvent{RuleID: rule.ID, State: domain.AlertStateAbove})
+	dispatcher.Wait()
+
+	// Assert
+	assert.NoError(t, err)
+	assert.True(t, verified.Load())
+	assert.Empty(t, dispatcher.DeadLetters())
+}
+
+// TestDispatchRetriesThenDeadLetters tests that failing deliveries are retried and parked
+func TestDispatchRetriesThenDeadLetters(t *testing.T) {
+	// Arrange
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	dispatcher := newTestDispatcher("secret", 3, time.Millisecond, 10)
+	rule := &domain.AlertRule{ID: "rule-1", WebhookURL: server.URL}
+
+	// Act
+	_ = dispatcher.Dispatch(rule, &domain.AlertEvent{RuleID: rule.ID})
+	dispatcher.Wait()
+
+	// Assert
+	assert.Equal(t, int32(3), calls.Load())
+	deadLetters := dispatcher.DeadLetters()
+	assert.Len(t, deadLetters, 1)
+	assert.Equal(t, 3, deadLetters[0].Attempts)
+	assert.Equal(t, "unexpected status 503", deadLetters[0].LastError)
+}
+
+// TestDispatchDoesNotRetryClientErrors tests that permanent failures go straight to the dead-letter list
+func TestDispatchDoesNotRetryClientErrors(t *testing.T) {
+	// Arrange
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer server.Close()
+
+	dispatcher := newTestDispatcher("secret", 5, time.Millisecond, 10)
+
+	// Act
+	_ = dispatcher.Dispatch(&domain.AlertRule{ID: "rule-1", WebhookURL: server.URL}, &domain.AlertEvent{})
+	dispatcher.Wait()
+
+	// Assert
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Len(t, dispatcher.DeadLetters(), 1)
+}
+
+// TestReplayDeadLetter tests that a replayed dead letter is removed once delivered
+func TestReplayDeadLetter(t *testing.T) {
+	// Arrange
+	var healthy atomic.Bool
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !healthy.Load() {
+			w.WriteHeader(http.StatusInternalServerError)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer server.Close()
+
+	dispatcher := newTestDispatcher("secret", 1, time.Millisecond, 10)
+	_ = dispatcher.Dispatch(&domain.AlertRule{ID: "rule-1", WebhookURL: server.URL}, &domain.AlertEvent{})
+	dispatcher.Wait()
+	id := dispatcher.DeadLetters()[0].ID
+
+	// Act & Assert
+	assert.ErrorIs(t, dispatcher.Replay(id), domain.ErrWebhookDeliveryFail)
+	assert.Len(t, dispatcher.DeadLetters(), 1)
+	assert.Equal(t, 2, dispatcher.DeadLetters()[0].Attempts)
+
+	healthy.Store(true)
+	assert.NoError(t, dispatcher.Replay(id))
+	assert.Empty(t, dispatcher.DeadLetters())
+	assert.ErrorIs(t, dispatcher.Replay(id), domain.ErrDeadLetterNotFound)
+}
+
+// TestShutdownCancelsPendingRetries tests that shutdown does not wait for the backoff
+func TestShutdownCancelsPendingRetries(t *testing.T) {
+	// Arrange
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer server.Close()
+
+	dispatcher := newTestDispatcher("secret", 5, time.Hour, 10)
+	_ = dispatcher.Dispatch(&domain.AlertRule{ID: "rule-1", WebhookURL: server.URL}, &domain.AlertEvent{})
+	assert.Eventually(t, func() bool { return calls.Load() == 1 }, time.Second, time.Millisecond)
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+
+	// Act
+	err := dispatcher.Shutdown(ctx)
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Equal(t, int32(1), calls.Load())
+	assert.Len(t, dispatcher.DeadLetters(), 1)
+}
+
+// TestDispatchRefusesInternalAddresses tests that webhooks are not delivered to the internal network, nor retried
+func TestDispatchRefusesInternalAddresses(t *testing.T) {
+	// Arrange
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	dispatcher := NewDispatcher("secret", 3, time.Millisecond, 10)
+
+	// Act
+	_ = dispatcher.Dispatch(&domain.AlertRule{ID: "rule-1", WebhookURL: server.URL}, &domain.AlertEvent{})
+	dispatcher.Wait()
+
+	// Assert
+	assert.Equal(t, int32(0), calls.Load())
+	deadLetters := dispatcher.DeadLetters()
+	require.Len(t, deadLetters, 1)
+	assert.Equal(t, 1, deadLetters[0].Attempts)
+	assert.Contains(t, deadLetters[0].LastError, "webhook address is not public")
+}
+
+// TestDeadLettersAreBounded tests that the oldest dead letters are dropped once the list is full
+func TestDeadLettersAreBounded(t *testing.T) {
+	// Arrange
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusGone)
+	}))
+	defer server.Close()
+
+	dispatcher := newTestDispatcher("secret", 1, time.Millisecond, 2)
+
+	// Act
+	for _, id := range []string{"rule-1", "rule-2", "rule-3"} {
+		_ = dispatcher.Dispatch(&domain.AlertRule{ID: id, WebhookURL: server.URL}, &domain.AlertEvent{})
+		dispatcher.Wait()
+	}
+
+	// Assert
+	deadLetters := dispatcher.DeadLetters()
+	require.Len(t, deadLetters, 2)
+	assert.Equal(t, "rule-2", deadLetters[0].RuleID)
+	assert.Equal(t, "rule-3", deadLetters[1].RuleID)
+}
+
+// TestReplayIsNotConcurrent tests that a dead letter being replayed cannot be replayed again until the attempt ends
+func TestReplayIsNotConcurrent(t *testing.T) {
+	// Arrange
+	var calls atomic.Int32
+	release := make(chan struct{})
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if calls.Add(1) == 1 {
+			w.WriteHeader(http.StatusGone)
+			return
+		}
+		<-release
+		w.WriteHeader(http.StatusNoContent)
+	}))
+	defer server.Close()
+
+	dispatcher := newTestDispatcher("secret", 1, time.Millisecond, 10)
+	_ = dispatcher.Dispatch(&domain.AlertRule{ID: "rule-1", WebhookURL: server.URL}, &domain.AlertEvent{})
+	dispatcher.Wait()
+	id := dispatcher.DeadLetters()[0].ID
+
+	firstReplay := make(chan error, 1)
+	go func() { firstReplay <- dispatcher.Replay(id) }()
+	require.Eventually(t, func() bool { return calls.Load() == 2 }, time.Second, time.Millisecond)
+
+	// Act
+	concurrent := dispatcher.Replay(id)
+	close(release)
+
+	// Assert
+	assert.ErrorIs(t, concurrent, domain.ErrDeadLetterReplaying)
+	assert.NoError(t, <-firstReplay)
+	assert.Equal(t, int32(2), calls.Load())
+	assert.Empty(t, dispatcher.DeadLetters())
+}
@@ -0,0 +1,140 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/xavierpms/weather-by-city/internal/domain"
+	"github.com/xavierpms/weather-by-city/internal/infra/auth"
+	"github.com/xavierpms/weather-by-city/internal/infra/webserver/problem"
+)
+
+// AlertHandler handle the requests related to alert rules and webhook deliveries
+type AlertHandler struct {
+	useCase    domain.AlertRuleUseCase
+	dispatcher domain.WebhookDispatcher
+	problems   *problem.Writer
+}
+
+// NewAlertHandler creates a new alert handler
+func NewAlertHandler(useCase domain.AlertRuleUseCase, dispatcher domain.WebhookDispatcher, problems *problem.Writer) *AlertHandler {
+	return &AlertHandler{
+		useCase:    useCase,
+		dispatcher: dispatcher,
+		problems:   problems,
+	}
+}
+
+// CreateRule handles the POST /v1/alerts/rules request
+func (h *AlertHandler) CreateRule(w http.ResponseWriter, r *http.Request) {
+	var rule domain.AlertRule
+	if err := json.NewDecoder(r.Body).Decode(&rule); err != nil {
+		h.handleError(w, r, domain.ErrInvalidAlertRule)
+		return
+	}
+	rule.Owner = ruleOwner(r)
+
+	created, err := h.useCase.CreateRule(&rule)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusCreated, created)
+}
+
+// ListRules handles the GET /v1/alerts/rules request
+func (h *AlertHandler) ListRules(w http.ResponseWriter, r *http.Request) {
+	rules, err := h.useCase.ListRules()
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, rules)
+}
+
+// DeleteRule handles the DELETE /v1/alerts/rules/{id} request
+func (h *AlertHandler) DeleteRule(w http.ResponseWriter, r *http.Request) {
+	if err := h.useCase.DeleteRule(chi.URLParam(r, "id")); err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListDeadLetters handles the GET /v1/alerts/dead-letters request
+func (h *AlertHandler) ListDeadLetters(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, h.dispatcher.DeadLetters())
+}
+
+// ReplayDeadLetter handles the POST /v1/alerts/dead-letters/{id}/replay request
+func (h *AlertHandler) ReplayDeadLetter(w http.ResponseWriter, r *http.Request) {
+	if err := h.dispatcher.Replay(chi.URLParam(r, "id")); err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// handleError handles errors and returns the appropriate response
+func (h *AlertHandler) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, domain.ErrInvalidCEPFormat):
+		h.problems.WriteProblem(w, r, problem.Problem{Status: http.StatusUnprocessableEntity, Code: problem.CodeInvalidZipcode,
+			Title: "Invalid zipcode", Detail: invalidCEPDetail(err), Reason: problemReason(err)})
+
+	case errors.Is(err, domain.ErrInvalidAlertRule):
+		h.problems.Write(w, r, http.StatusUnprocessableEntity, problem.CodeInvalidAlertRule,
+			"Invalid alert rule", "A rule needs an above or below threshold, below lower than above, a non-negative hysteresis and an http(s) webhook URL to a public address")
+
+	case errors.Is(err, domain.ErrAlertRuleLimit):
+		h.problems.Write(w, r, http.StatusConflict, problem.CodeAlertRuleLimitReached,
+			"Alert rule limit reached", "Delete a rule before registering another one")
+
+	case errors.Is(err, domain.ErrAlertRuleNotFound):
+		h.problems.Write(w, r, http.StatusNotFound, problem.CodeAlertRuleNotFound,
+			"Cannot find alert rule", "")
+
+	case errors.Is(err, domain.ErrDeadLetterNotFound):
+		h.problems.Write(w, r, http.StatusNotFound, problem.CodeDeadLetterNotFound,
+			"Cannot find dead letter", "")
+
+	case errors.Is(err, domain.ErrDeadLetterReplaying):
+		h.problems.Write(w, r, http.StatusConflict, problem.CodeDeadLetterReplaying,
+			"Dead letter replay in progress", "")
+
+	case errors.Is(err, domain.ErrWebhookDeliveryFail):
+		h.problems.Write(w, r, http.StatusBadGateway, problem.CodeWebhookDeliveryFailed,
+			"Webhook delivery failed", err.Error())
+
+	default:
+		slog.ErrorContext(r.Context(), "Internal error", "err", err)
+		h.problems.Write(w, r, http.StatusInternalServerError, problem.CodeInternalError,
+			"Internal server error", "")
+	}
+}
+
+// ruleOwner identifies who registers a rule: the API key, else the token subject. Anonymous
+// callers share the empty owner.
+func ruleOwner(r *http.Request) string {
+	if key, ok := auth.APIKeyFromContext(r.Context()); ok {
+		return "key:" + key.ID
+	}
+	if claims, ok := auth.ClaimsFromContext(r.Context()); ok && claims.Subject != "" {
+		return "sub:" + claims.Subject
+	}
+	return ""
+}
+
+// writeJSON writes the value as a JSON response with the given status
+func writeJSON(w http.ResponseWriter, status int, value interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(value)
+}
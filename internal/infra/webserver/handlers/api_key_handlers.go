@@ -0,0 +1,104 @@
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+
+	"github.com/go-chi/chi"
+	"github.com/xavierpms/weather-by-city/internal/domain"
+	"github.com/xavierpms/weather-by-city/internal/infra/auth"
+	"github.com/xavierpms/weather-by-city/internal/infra/webserver/problem"
+)
+
+// APIKeyHandler handle the requests related to API keys and their usage
+type APIKeyHandler struct {
+	useCase  domain.APIKeyUseCase
+	problems *problem.Writer
+}
+
+// NewAPIKeyHandler creates a new API key handler
+func NewAPIKeyHandler(useCase domain.APIKeyUseCase, problems *problem.Writer) *APIKeyHandler {
+	return &APIKeyHandler{
+		useCase:  useCase,
+		problems: problems,
+	}
+}
+
+// CreateKey handles the POST /admin/api-keys request. The secret is only shown in this response.
+func (h *APIKeyHandler) CreateKey(w http.ResponseWriter, r *http.Request) {
+	var key domain.APIKey
+	if err := json.NewDecoder(r.Body).Decode(&key); err != nil {
+		h.handleError(w, r, domain.ErrInvalidAPIKeySpec)
+		return
+	}
+
+	created, err := h.useCase.CreateKey(&key)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusCreated, created)
+}
+
+// ListKeys handles the GET /admin/api-keys request
+func (h *APIKeyHandler) ListKeys(w http.ResponseWriter, r *http.Request) {
+	keys, err := h.useCase.ListKeys()
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	writeJSON(w, http.StatusOK, keys)
+}
+
+// RevokeKey handles the DELETE /admin/api-keys/{id} request
+func (h *APIKeyHandler) RevokeKey(w http.ResponseWriter, r *http.Request) {
+	if err := h.useCase.RevokeKey(chi.URLParam(r, "id")); err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetUsage handles the GET /v1/usage request, reporting the consumption of the calling key
+func (h *APIKeyHandler) GetUsage(w http.ResponseWriter, r *http.Request) {
+	key, ok := auth.APIKeyFromContext(r.Context())
+	if !ok {
+		w.Header().Set("WWW-Authenticate", `Bearer realm="weather-by-city"`)
+		h.problems.Write(w, r, http.StatusUnauthorized, problem.CodeUnauthorized,
+			"Unauthorized", "The usage report needs an API key, in the X-API-Key header or as a bearer token")
+		return
+	}
+
+	report, err := h.useCase.Usage(key)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, report)
+}
+
+// handleError handles errors and returns the appropriate response
+func (h *APIKeyHandler) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	switch {
+	case errors.Is(err, domain.ErrInvalidAPIKeySpec):
+		h.problems.Write(w, r, http.StatusUnprocessableEntity, problem.CodeInvalidAPIKey,
+			"Invalid API key", "A key needs a name and a non-negative daily quota")
+
+	case errors.Is(err, domain.ErrAPIKeyNotFound):
+		h.problems.Write(w, r, http.StatusNotFound, problem.CodeAPIKeyNotFound,
+			"Cannot find API key", "")
+
+	default:
+		slog.ErrorContext(r.Context(), "Internal error", "err", err)
+		h.problems.Write(w, r, http.StatusInternalServerError, problem.CodeInternalError,
+			"Internal server error", "")
+	}
+}
@@ -0,0 +1,48 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/xavierpms/weather-by-city/internal/infra/reload"
+	"github.com/xavierpms/weather-by-city/internal/infra/webserver/problem"
+)
+
+// ConfigReloader reloads the config and reports the config in use
+type ConfigReloader interface {
+	Reload() error
+	Status() reload.Status
+}
+
+// ConfigHandler serves the config in use and reloads it on demand
+type ConfigHandler struct {
+	reloader ConfigReloader
+	problems *problem.Writer
+}
+
+// NewConfigHandler creates a new config handler
+func NewConfigHandler(reloader ConfigReloader, problems *problem.Writer) *ConfigHandler {
+	return &ConfigHandler{
+		reloader: reloader,
+		problems: problems,
+	}
+}
+
+// GetConfig handles the GET /admin/config request, reporting the settings with the secrets
+// redacted, the outcome of the last reload and the settings waiting for a restart
+func (h *ConfigHandler) GetConfig(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, h.reloader.Status())
+}
+
+// ReloadConfig handles the POST /admin/config/reload request. An invalid config is refused and
+// the config in use is kept.
+func (h *ConfigHandler) ReloadConfig(w http.ResponseWriter, r *http.Request) {
+	if err := h.reloader.Reload(); err != nil {
+		h.problems.Write(w, r, http.StatusUnprocessableEntity, problem.CodeInvalidConfig,
+			"Invalid config", err.Error())
+		return
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, h.reloader.Status())
+}
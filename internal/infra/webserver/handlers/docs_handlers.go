@@ -0,0 +1,31 @@
+package handlers
+
+import "net/http"
+
+// DocsHandler serves the API description and its documentation page
+type DocsHandler struct {
+	spec []byte
+	page []byte
+}
+
+// NewDocsHandler creates a new docs handler
+func NewDocsHandler(spec, page []byte) *DocsHandler {
+	return &DocsHandler{
+		spec: spec,
+		page: page,
+	}
+}
+
+// GetOpenAPI handles the GET /openapi.json request
+func (h *DocsHandler) GetOpenAPI(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	w.Write(h.spec)
+}
+
+// GetDocs handles the GET /docs request
+func (h *DocsHandler) GetDocs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusOK)
+	w.Write(h.page)
+}
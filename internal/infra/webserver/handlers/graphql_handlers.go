@@ -0,0 +1,64 @@
+package handlers
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+
+	"github.com/graphql-go/graphql"
+	"github.com/xavierpms/weather-by-city/internal/infra/webserver/problem"
+)
+
+// GraphQLExecutor defines the contract for executing GraphQL queries
+type GraphQLExecutor interface {
+	Execute(ctx context.Context, query string, variables map[string]interface{}, operationName string) *graphql.Result
+}
+
+// GraphQLHandler handle the GraphQL requests
+type GraphQLHandler struct {
+	executor GraphQLExecutor
+	problems *problem.Writer
+}
+
+// GraphQLRequest represents a GraphQL request body
+type GraphQLRequest struct {
+	Query         string                 `json:"query"`
+	Variables     map[string]interface{} `json:"variables"`
+	OperationName string                 `json:"operationName"`
+}
+
+// NewGraphQLHandler creates a new GraphQL handler
+func NewGraphQLHandler(executor GraphQLExecutor, problems *problem.Writer) *GraphQLHandler {
+	return &GraphQLHandler{
+		executor: executor,
+		problems: problems,
+	}
+}
+
+// Query handles the GET and POST /v1/graphql requests
+func (h *GraphQLHandler) Query(w http.ResponseWriter, r *http.Request) {
+	var req GraphQLRequest
+
+	if r.Method == http.MethodGet {
+		req.Query = r.URL.Query().Get("query")
+		req.OperationName = r.URL.Query().Get("operationName")
+		if variables := r.URL.Query().Get("variables"); variables != "" {
+			if err := json.Unmarshal([]byte(variables), &req.Variables); err != nil {
+				h.problems.Write(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid variables", "The variables parameter must be a JSON object")
+				return
+			}
+		}
+	} else if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.problems.Write(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Invalid GraphQL request", err.Error())
+		return
+	}
+
+	if req.Query == "" {
+		h.problems.Write(w, r, http.StatusBadRequest, problem.CodeInvalidRequest, "Missing query", "")
+		return
+	}
+
+	// Field errors are reported in the result body, following the GraphQL over HTTP convention
+	result := h.executor.Execute(r.Context(), req.Query, req.Variables, req.OperationName)
+	writeJSON(w, http.StatusOK, result)
+}
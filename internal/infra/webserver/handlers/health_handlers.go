@@ -0,0 +1,44 @@
+package handlers
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/xavierpms/weather-by-city/internal/infra/health"
+)
+
+// ReadinessChecker reports the state of the dependencies
+type ReadinessChecker interface {
+	Check(ctx context.Context) health.Report
+}
+
+// HealthHandler serves the liveness and readiness probes
+type HealthHandler struct {
+	checker ReadinessChecker
+}
+
+// NewHealthHandler creates a new health handler
+func NewHealthHandler(checker ReadinessChecker) *HealthHandler {
+	return &HealthHandler{
+		checker: checker,
+	}
+}
+
+// GetLiveness handles the GET /healthz request, answering as long as the process serves requests
+func (h *HealthHandler) GetLiveness(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, health.Report{Status: health.StatusUp})
+}
+
+// GetReadiness handles the GET /readyz request, answering 503 while a critical dependency is down
+func (h *HealthHandler) GetReadiness(w http.ResponseWriter, r *http.Request) {
+	report := h.checker.Check(r.Context())
+
+	status := http.StatusOK
+	if report.Status == health.StatusDown {
+		status = http.StatusServiceUnavailable
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, status, report)
+}
@@ -0,0 +1,46 @@
+package handlers
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xavierpms/weather-by-city/internal/infra/health"
+)
+
+// TestGetReadiness tests the readiness breakdown and status code
+func TestGetReadiness(t *testing.T) {
+	// Arrange
+	checker := health.NewChecker(time.Minute, time.Second)
+	checker.Add("config", func(ctx context.Context) error { return nil })
+	checker.Add("weatherapi", func(ctx context.Context) error { return errors.New("unexpected status 503") })
+	handler := NewHealthHandler(checker)
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.GetReadiness(w, httptest.NewRequest("GET", "/readyz", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+	assert.Contains(t, w.Body.String(), `"status":"down"`)
+	assert.Contains(t, w.Body.String(), `"config":{"status":"up"`)
+	assert.Contains(t, w.Body.String(), `"weatherapi":{"status":"down","error":"unexpected status 503"`)
+}
+
+// TestGetLiveness tests that liveness does not depend on the checks
+func TestGetLiveness(t *testing.T) {
+	// Arrange
+	handler := NewHealthHandler(health.NewChecker(time.Minute, time.Second))
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.GetLiveness(w, httptest.NewRequest("GET", "/healthz", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"status":"up"}`, w.Body.String())
+}
@@ -0,0 +1,59 @@
+package handlers
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/xavierpms/weather-by-city/internal/infra/auth"
+)
+
+// strongETag computes a strong entity tag from the encoded payload
+func strongETag(body []byte) string {
+	sum := sha256.Sum256(body)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// matchesETag reports whether an If-None-Match header matches the entity tag, using the
+// weak comparison required for conditional GET
+func matchesETag(ifNoneMatch, etag string) bool {
+	for _, candidate := range strings.Split(ifNoneMatch, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+
+	return false
+}
+
+// cacheControl derives the freshness of the response from the expiration of the cached upstream data.
+// Private responses, served to an authenticated client, must not be stored by shared caches, which
+// would serve them to clients without a key and bypass the quotas and the usage accounting.
+func cacheControl(expiresAt, now time.Time, private bool) string {
+	if expiresAt.IsZero() {
+		return "no-cache"
+	}
+
+	maxAge := int(expiresAt.Sub(now).Seconds())
+	if maxAge < 0 {
+		maxAge = 0
+	}
+
+	if private {
+		return "private, max-age=" + strconv.Itoa(maxAge)
+	}
+	return "public, max-age=" + strconv.Itoa(maxAge)
+}
+
+// authenticated reports whether the request was authenticated by an API key or a JWT
+func authenticated(r *http.Request) bool {
+	if _, ok := auth.APIKeyFromContext(r.Context()); ok {
+		return true
+	}
+	_, ok := auth.ClaimsFromContext(r.Context())
+	return ok
+}
@@ -1,74 +1,203 @@
 package handlers
 
 import (
+	"bytes"
 	"encoding/json"
-	"log"
+	"errors"
+	"fmt"
+	"log/slog"
 	"net/http"
+	"strings"
+	"time"
 
 	"github.com/go-chi/chi"
 	"github.com/xavierpms/weather-by-city/internal/domain"
+	"github.com/xavierpms/weather-by-city/internal/infra/webserver/problem"
+	"github.com/xavierpms/weather-by-city/internal/infra/webserver/render"
 )
 
 // TemperatureHandler handle the requests related to temperature
 type TemperatureHandler struct {
-	useCase domain.TemperatureUseCase
-}
-
-// ErrorResponse represents an error response
-type ErrorResponse struct {
-	Message string `json:"message"`
+	useCase      domain.TemperatureUseCase
+	batchUseCase domain.BatchTemperatureUseCase
+	problems     *problem.Writer
+	encoders     *render.Registry
 }
 
 // NewTemperatureHandler creates a new temperature handler
-func NewTemperatureHandler(useCase domain.TemperatureUseCase) *TemperatureHandler {
+func NewTemperatureHandler(
+	useCase domain.TemperatureUseCase,
+	batchUseCase domain.BatchTemperatureUseCase,
+	problems *problem.Writer,
+	encoders *render.Registry,
+) *TemperatureHandler {
 	return &TemperatureHandler{
-		useCase: useCase,
+		useCase:      useCase,
+		batchUseCase: batchUseCase,
+		problems:     problems,
+		encoders:     encoders,
 	}
 }
 
 // GetTemperatureByCEP handles the GET /{cep} request
 func (h *TemperatureHandler) GetTemperatureByCEP(w http.ResponseWriter, r *http.Request) {
+	// The representation depends on the Accept header, and whether it may be shared on the
+	// credentials, so shared caches must key on them
+	w.Header().Add("Vary", "Accept, Authorization, X-API-Key")
+
+	// Pick the response format before doing any upstream call
+	encoder, ok := h.negotiate(w, r)
+	if !ok {
+		return
+	}
+
 	// Extract the CEP from the URL
 	cep := chi.URLParam(r, "cep")
 
 	// Execute the use case
-	temperature, err := h.useCase.GetTemperatureByCEP(cep)
+	temperature, err := h.useCase.GetTemperatureByCEP(r.Context(), cep)
 
 	// Handle errors
 	if err != nil {
-		h.handleError(w, err)
+		h.handleError(w, r, err)
 		return
 	}
 
-	// Return success
-	w.Header().Set("Content-Type", "application/json")
+	// Return success, or 304 when the client already has this representation
+	h.writeCacheable(w, r, encoder, newTemperatureView(temperature), temperature.ExpiresAt)
+}
+
+// GetTemperaturesByCEPs handles the POST /v1/temperatures/batch request
+func (h *TemperatureHandler) GetTemperaturesByCEPs(w http.ResponseWriter, r *http.Request) {
+	encoder, ok := h.negotiate(w, r)
+	if !ok {
+		return
+	}
+
+	var req BatchRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		h.problems.Write(w, r, http.StatusBadRequest, problem.CodeInvalidRequest,
+			"Invalid batch request", `The body must be a JSON object like {"ceps": ["01001000"]}`)
+		return
+	}
+
+	results, err := h.batchUseCase.GetTemperaturesByCEPs(r.Context(), req.CEPs)
+	if err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	h.write(w, encoder, newBatchView(results))
+}
+
+// negotiate selects the encoder for the request, answering 406 when none is acceptable
+func (h *TemperatureHandler) negotiate(w http.ResponseWriter, r *http.Request) (render.Encoder, bool) {
+	encoder, ok := h.encoders.Negotiate(r)
+	if !ok {
+		h.problems.Write(w, r, http.StatusNotAcceptable, problem.CodeNotAcceptable,
+			"Not acceptable", "Supported media types: "+strings.Join(h.encoders.MediaTypes(), ", "))
+	}
+
+	return encoder, ok
+}
+
+// write sends the successful response with the negotiated encoder
+func (h *TemperatureHandler) write(w http.ResponseWriter, encoder render.Encoder, value interface{}) {
+	if err := render.Write(w, http.StatusOK, encoder, value); err != nil {
+		slog.Error("Response encoding error", "content_type", encoder.ContentType(), "err", err)
+	}
+}
+
+// writeCacheable sends the response with an ETag and a max-age bounded by the upstream cache expiration
+func (h *TemperatureHandler) writeCacheable(w http.ResponseWriter, r *http.Request, encoder render.Encoder, value interface{}, expiresAt time.Time) {
+	var body bytes.Buffer
+	if err := encoder.Encode(&body, value); err != nil {
+		h.handleError(w, r, err)
+		return
+	}
+
+	etag := strongETag(body.Bytes())
+	w.Header().Set("ETag", etag)
+	w.Header().Set("Cache-Control", cacheControl(expiresAt, time.Now(), authenticated(r)))
+
+	if matchesETag(r.Header.Get("If-None-Match"), etag) {
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+
+	w.Header().Set("Content-Type", encoder.ContentType())
 	w.WriteHeader(http.StatusOK)
-	json.NewEncoder(w).Encode(temperature)
+	w.Write(body.Bytes())
 }
 
 // handleError handles errors and returns the appropriate response
-func (h *TemperatureHandler) handleError(w http.ResponseWriter, err error) {
-	w.Header().Set("Content-Type", "application/json")
+func (h *TemperatureHandler) handleError(w http.ResponseWriter, r *http.Request, err error) {
+	status, code, title, detail := temperatureProblem(err)
+	if status >= http.StatusInternalServerError {
+		slog.ErrorContext(r.Context(), title, "err", err)
+	} else {
+		slog.InfoContext(r.Context(), title, "err", err)
+	}
+	h.problems.WriteProblem(w, r, problem.Problem{Status: status, Code: code, Title: title, Detail: detail, Reason: problemReason(err)})
+}
+
+// temperatureProblem maps the temperature use case errors to problem details
+func temperatureProblem(err error) (int, problem.Code, string, string) {
+	switch {
+	case errors.Is(err, domain.ErrInvalidCEPFormat):
+		return http.StatusUnprocessableEntity, problem.CodeInvalidZipcode,
+			"Invalid zipcode", invalidCEPDetail(err)
 
-	switch err {
-	case domain.ErrInvalidCEPFormat:
-		log.Printf("Invalid zipcode: %v", err)
-		w.WriteHeader(http.StatusUnprocessableEntity)
-		json.NewEncoder(w).Encode(ErrorResponse{Message: "Invalid zipcode"})
+	case errors.Is(err, domain.ErrCEPNotFound):
+		return http.StatusNotFound, problem.CodeZipcodeNotFound,
+			"Cannot find zipcode", "No address is registered for the zipcode"
 
-	case domain.ErrCEPNotFound:
-		log.Printf("CEP not found: %v", err)
-		w.WriteHeader(http.StatusNotFound)
-		json.NewEncoder(w).Encode(ErrorResponse{Message: "Cannot find zipcode"})
+	case errors.Is(err, domain.ErrTemperatureNotFound):
+		return http.StatusInternalServerError, problem.CodeTemperatureUnavailable,
+			"Cannot fetch temperature", "The weather provider did not return the temperature for the zipcode"
 
-	case domain.ErrTemperatureNotFound:
-		log.Printf("Temperature not found: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(ErrorResponse{Message: "Cannot fetch temperature"})
+	case errors.Is(err, domain.ErrUpstreamQuotaExceeded):
+		return http.StatusServiceUnavailable, problem.CodeUpstreamQuotaExceeded,
+			"Upstream quota exceeded", "The call quota of the weather provider is spent and no cached temperature is available"
 
+	case errors.Is(err, domain.ErrUpstreamUnavailable):
+		return http.StatusServiceUnavailable, problem.CodeUpstreamUnavailable,
+			"Upstream unavailable", "An upstream provider is unavailable, try again later"
+
+	case errors.Is(err, domain.ErrEmptyBatch), errors.Is(err, domain.ErrBatchTooLarge):
+		return http.StatusUnprocessableEntity, problem.CodeInvalidBatch,
+			"Invalid batch", err.Error()
+
+	default:
+		return http.StatusInternalServerError, problem.CodeInternalError,
+			"Internal server error", ""
+	}
+}
+
+// invalidCEPDetail explains why the CEP was refused
+func invalidCEPDetail(err error) string {
+	var formatErr *domain.CEPFormatError
+	if !errors.As(err, &formatErr) {
+		return "The zipcode must have exactly 8 digits"
+	}
+
+	switch formatErr.Reason {
+	case domain.CEPFormatEmpty:
+		return "The zipcode is empty"
+	case domain.CEPFormatInvalidCharacter:
+		return fmt.Sprintf("The zipcode has the invalid character %q, only digits, dots, hyphens and spaces are accepted", formatErr.Character)
+	case domain.CEPFormatOutOfRange:
+		return "The zipcode is outside the CEP ranges of the Correios"
 	default:
-		log.Printf("Internal error: %v", err)
-		w.WriteHeader(http.StatusInternalServerError)
-		json.NewEncoder(w).Encode(ErrorResponse{Message: "Internal server error"})
+		return fmt.Sprintf("The zipcode must have exactly 8 digits, not %d", formatErr.Digits)
+	}
+}
+
+// problemReason returns the reason of a refused input, empty for the other errors
+func problemReason(err error) string {
+	var formatErr *domain.CEPFormatError
+	if errors.As(err, &formatErr) {
+		return string(formatErr.Reason)
 	}
-}
\ No newline at end of file
+	return ""
+}
@@ -1,13 +1,22 @@
 package handlers
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
+	"github.com/vmihailenco/msgpack/v5"
 	"github.com/xavierpms/weather-by-city/internal/domain"
+	"github.com/xavierpms/weather-by-city/internal/infra/auth"
+	"github.com/xavierpms/weather-by-city/internal/infra/webserver/problem"
+	"github.com/xavierpms/weather-by-city/internal/infra/webserver/render"
+	"github.com/xavierpms/weather-by-city/internal/usecase"
 )
 
 // MockTemperatureUseCase is a mock of the TemperatureUseCase for testing
@@ -15,10 +24,20 @@ type MockTemperatureUseCase struct {
 	getTemperatureByCEPFunc func(cep string) (*domain.Temperature, error)
 }
 
-func (m *MockTemperatureUseCase) GetTemperatureByCEP(cep string) (*domain.Temperature, error) {
+func (m *MockTemperatureUseCase) GetTemperatureByCEP(ctx context.Context, cep string) (*domain.Temperature, error) {
 	return m.getTemperatureByCEPFunc(cep)
 }
 
+// newTestTemperatureHandler creates a handler with every encoder registered
+func newTestTemperatureHandler(useCase domain.TemperatureUseCase, compat bool) *TemperatureHandler {
+	return NewTemperatureHandler(
+		useCase,
+		usecase.NewGetTemperaturesByCEPs(useCase, 10, 2),
+		problem.NewWriter(compat),
+		render.NewDefaultRegistry(),
+	)
+}
+
 // TestGetTemperatureByCEPSuccess tests the success of the request
 func TestGetTemperatureByCEPSuccess(t *testing.T) {
 	// Arrange
@@ -32,7 +51,7 @@ func TestGetTemperatureByCEPSuccess(t *testing.T) {
 		},
 	}
 
-	handler := NewTemperatureHandler(mockUseCase)
+	handler := newTestTemperatureHandler(mockUseCase, true)
 	req := httptest.NewRequest("GET", "/32450000", nil)
 	w := httptest.NewRecorder()
 
@@ -59,7 +78,7 @@ func TestGetTemperatureByCEPInvalidFormat(t *testing.T) {
 		},
 	}
 
-	handler := NewTemperatureHandler(mockUseCase)
+	handler := newTestTemperatureHandler(mockUseCase, true)
 	req := httptest.NewRequest("GET", "/3245000000", nil)
 	w := httptest.NewRecorder()
 
@@ -69,10 +88,40 @@ func TestGetTemperatureByCEPInvalidFormat(t *testing.T) {
 	// Assert
 	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
 
-	var errResponse ErrorResponse
+	var errResponse problem.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &errResponse)
 	assert.NoError(t, err)
 	assert.Equal(t, "Invalid zipcode", errResponse.Message)
+	assert.Equal(t, problem.CodeInvalidZipcode, errResponse.Code)
+	assert.Equal(t, http.StatusUnprocessableEntity, errResponse.Status)
+	assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+}
+
+// TestGetTemperatureByCEPInvalidFormatReason tests that the problem tells why the CEP was refused
+func TestGetTemperatureByCEPInvalidFormatReason(t *testing.T) {
+	// Arrange
+	mockUseCase := &MockTemperatureUseCase{
+		getTemperatureByCEPFunc: func(cep string) (*domain.Temperature, error) {
+			return nil, &domain.CEPFormatError{Reason: domain.CEPFormatWrongLength, Digits: 10}
+		},
+	}
+
+	handler := newTestTemperatureHandler(mockUseCase, true)
+	req := httptest.NewRequest("GET", "/3245000000", nil)
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.GetTemperatureByCEP(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusUnprocessableEntity, w.Code)
+
+	var errResponse problem.Problem
+	err := json.Unmarshal(w.Body.Bytes(), &errResponse)
+	assert.NoError(t, err)
+	assert.Equal(t, problem.CodeInvalidZipcode, errResponse.Code)
+	assert.Equal(t, "wrong_length", errResponse.Reason)
+	assert.Equal(t, "The zipcode must have exactly 8 digits, not 10", errResponse.Detail)
 }
 
 // TestGetTemperatureByCEPNotFound tests the case when the CEP is not found
@@ -84,7 +133,7 @@ func TestGetTemperatureByCEPNotFound(t *testing.T) {
 		},
 	}
 
-	handler := NewTemperatureHandler(mockUseCase)
+	handler := newTestTemperatureHandler(mockUseCase, true)
 	req := httptest.NewRequest("GET", "/00000000", nil)
 	w := httptest.NewRecorder()
 
@@ -94,10 +143,11 @@ func TestGetTemperatureByCEPNotFound(t *testing.T) {
 	// Assert
 	assert.Equal(t, http.StatusNotFound, w.Code)
 
-	var errResponse ErrorResponse
+	var errResponse problem.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &errResponse)
 	assert.NoError(t, err)
 	assert.Equal(t, "Cannot find zipcode", errResponse.Message)
+	assert.Equal(t, problem.CodeZipcodeNotFound, errResponse.Code)
 }
 
 // TestGetTemperatureByCEPTemperatureNotFound tests the case when the temperature is not found
@@ -109,7 +159,7 @@ func TestGetTemperatureByCEPTemperatureNotFound(t *testing.T) {
 		},
 	}
 
-	handler := NewTemperatureHandler(mockUseCase)
+	handler := newTestTemperatureHandler(mockUseCase, true)
 	req := httptest.NewRequest("GET", "/32450000", nil)
 	w := httptest.NewRecorder()
 
@@ -119,8 +169,329 @@ func TestGetTemperatureByCEPTemperatureNotFound(t *testing.T) {
 	// Assert
 	assert.Equal(t, http.StatusInternalServerError, w.Code)
 
-	var errResponse ErrorResponse
+	var errResponse problem.Problem
 	err := json.Unmarshal(w.Body.Bytes(), &errResponse)
 	assert.NoError(t, err)
 	assert.Equal(t, "Cannot fetch temperature", errResponse.Message)
+	assert.Equal(t, problem.CodeTemperatureUnavailable, errResponse.Code)
+}
+
+// TestGetTemperatureByCEPUpstreamUnavailable tests the case when a circuit breaker is open
+func TestGetTemperatureByCEPUpstreamUnavailable(t *testing.T) {
+	// Arrange
+	mockUseCase := &MockTemperatureUseCase{
+		getTemperatureByCEPFunc: func(cep string) (*domain.Temperature, error) {
+			return nil, fmt.Errorf("%w: weatherapi circuit breaker is open", domain.ErrUpstreamUnavailable)
+		},
+	}
+
+	handler := newTestTemperatureHandler(mockUseCase, true)
+	req := httptest.NewRequest("GET", "/32450000", nil)
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.GetTemperatureByCEP(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	var errResponse problem.Problem
+	err := json.Unmarshal(w.Body.Bytes(), &errResponse)
+	assert.NoError(t, err)
+	assert.Equal(t, problem.CodeUpstreamUnavailable, errResponse.Code)
+}
+
+// TestGetTemperatureByCEPUpstreamQuotaExceeded tests the case when the WeatherAPI call budget is spent
+func TestGetTemperatureByCEPUpstreamQuotaExceeded(t *testing.T) {
+	// Arrange
+	mockUseCase := &MockTemperatureUseCase{
+		getTemperatureByCEPFunc: func(cep string) (*domain.Temperature, error) {
+			return nil, fmt.Errorf("%w: %w: the daily limit of 1000 weatherapi calls is reached", domain.ErrUpstreamUnavailable, domain.ErrUpstreamQuotaExceeded)
+		},
+	}
+
+	handler := newTestTemperatureHandler(mockUseCase, true)
+	req := httptest.NewRequest("GET", "/32450000", nil)
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.GetTemperatureByCEP(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusServiceUnavailable, w.Code)
+
+	var errResponse problem.Problem
+	err := json.Unmarshal(w.Body.Bytes(), &errResponse)
+	assert.NoError(t, err)
+	assert.Equal(t, "Upstream quota exceeded", errResponse.Message)
+	assert.Equal(t, problem.CodeUpstreamQuotaExceeded, errResponse.Code)
+}
+
+// TestGetTemperatureByCEPProblemWithoutCompatMode tests the problem body without the legacy message field
+func TestGetTemperatureByCEPProblemWithoutCompatMode(t *testing.T) {
+	// Arrange
+	mockUseCase := &MockTemperatureUseCase{
+		getTemperatureByCEPFunc: func(cep string) (*domain.Temperature, error) {
+			return nil, domain.ErrCEPNotFound
+		},
+	}
+
+	handler := newTestTemperatureHandler(mockUseCase, false)
+	req := httptest.NewRequest("GET", "/00000000", nil)
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.GetTemperatureByCEP(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusNotFound, w.Code)
+
+	var body map[string]interface{}
+	err := json.Unmarshal(w.Body.Bytes(), &body)
+	assert.NoError(t, err)
+	assert.NotContains(t, body, "message")
+	assert.Equal(t, "urn:weather-by-city:problem:zipcode_not_found", body["type"])
+	assert.Equal(t, "Cannot find zipcode", body["title"])
+	assert.Equal(t, "zipcode_not_found", body["code"])
+	assert.Equal(t, "/00000000", body["instance"])
+}
+
+// fixedTemperature returns the same temperature for every valid CEP
+func fixedTemperature(cep string) (*domain.Temperature, error) {
+	if cep == "99999999" {
+		return nil, domain.ErrCEPNotFound
+	}
+	return &domain.Temperature{Celsius: 28.5, Fahrenheit: 83.3, Kelvin: 301.65}, nil
+}
+
+// TestGetTemperatureByCEPContentNegotiation tests the formats selected by the Accept header and the format parameter
+func TestGetTemperatureByCEPContentNegotiation(t *testing.T) {
+	// Arrange
+	handler := newTestTemperatureHandler(&MockTemperatureUseCase{getTemperatureByCEPFunc: fixedTemperature}, true)
+
+	testCases := []struct {
+		target      string
+		accept      string
+		contentType string
+		body        string
+	}{
+		{"/32450000", "", "application/json", `{"temp_C":28.5,"temp_F":83.3,"temp_K":301.65}` + "\n"},
+		{"/32450000", "application/xml", "application/xml; charset=utf-8",
+			`<?xml version="1.0" encoding="UTF-8"?>` + "\n" + `<temperature><temp_C>28.5</temp_C><temp_F>83.3</temp_F><temp_K>301.65</temp_K></temperature>`},
+		{"/32450000", "text/csv", "text/csv; charset=utf-8", "temp_C,temp_F,temp_K\n28.5,83.3,301.65\n"},
+		{"/32450000", "text/plain", "text/plain; charset=utf-8", "28.5°C / 83.3°F / 301.65K\n"},
+		{"/32450000?format=text", "application/json", "text/plain; charset=utf-8", "28.5°C / 83.3°F / 301.65K\n"},
+		{"/32450000", "text/html;q=0.9, text/csv;q=0.5, */*;q=0.1", "text/csv; charset=utf-8", "temp_C,temp_F,temp_K\n28.5,83.3,301.65\n"},
+	}
+
+	for _, tc := range testCases {
+		req := httptest.NewRequest("GET", tc.target, nil)
+		req.Header.Set("Accept", tc.accept)
+		w := httptest.NewRecorder()
+
+		// Act
+		handler.GetTemperatureByCEP(w, req)
+
+		// Assert
+		assert.Equal(t, http.StatusOK, w.Code, "accept %q", tc.accept)
+		assert.Equal(t, tc.contentType, w.Header().Get("Content-Type"))
+		assert.Equal(t, tc.body, w.Body.String())
+	}
+}
+
+// TestGetTemperatureByCEPMessagePack tests the MessagePack encoding
+func TestGetTemperatureByCEPMessagePack(t *testing.T) {
+	// Arrange
+	handler := newTestTemperatureHandler(&MockTemperatureUseCase{getTemperatureByCEPFunc: fixedTemperature}, true)
+	req := httptest.NewRequest("GET", "/32450000?format=msgpack", nil)
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.GetTemperatureByCEP(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.Equal(t, "application/msgpack", w.Header().Get("Content-Type"))
+
+	var decoded map[string]float64
+	assert.NoError(t, msgpack.Unmarshal(w.Body.Bytes(), &decoded))
+	assert.Equal(t, map[string]float64{"temp_C": 28.5, "temp_F": 83.3, "temp_K": 301.65}, decoded)
+}
+
+// TestGetTemperatureByCEPNotAcceptable tests that unsupported formats are refused before calling the use case
+func TestGetTemperatureByCEPNotAcceptable(t *testing.T) {
+	// Arrange
+	called := false
+	mockUseCase := &MockTemperatureUseCase{
+		getTemperatureByCEPFunc: func(cep string) (*domain.Temperature, error) {
+			called = true
+			return fixedTemperature(cep)
+		},
+	}
+	handler := newTestTemperatureHandler(mockUseCase, true)
+
+	for _, target := range []string{"/32450000?format=yaml", "/32450000"} {
+		req := httptest.NewRequest("GET", target, nil)
+		req.Header.Set("Accept", "application/yaml")
+		w := httptest.NewRecorder()
+
+		// Act
+		handler.GetTemperatureByCEP(w, req)
+
+		// Assert
+		assert.Equal(t, http.StatusNotAcceptable, w.Code)
+		assert.Contains(t, w.Body.String(), `"code":"not_acceptable"`)
+	}
+	assert.False(t, called)
+}
+
+// TestGetTemperaturesByCEPs tests the batch endpoint in JSON and CSV
+func TestGetTemperaturesByCEPs(t *testing.T) {
+	// Arrange
+	handler := newTestTemperatureHandler(&MockTemperatureUseCase{getTemperatureByCEPFunc: fixedTemperature}, true)
+	body := `{"ceps":["32450000","99999999"]}`
+
+	// Act
+	jsonReq := httptest.NewRequest("POST", "/v1/temperatures/batch", strings.NewReader(body))
+	jsonResp := httptest.NewRecorder()
+	handler.GetTemperaturesByCEPs(jsonResp, jsonReq)
+
+	csvReq := httptest.NewRequest("POST", "/v1/temperatures/batch?format=csv", strings.NewReader(body))
+	csvResp := httptest.NewRecorder()
+	handler.GetTemperaturesByCEPs(csvResp, csvReq)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, jsonResp.Code)
+	assert.JSONEq(t, `{"results":[
+		{"cep":"32450000","temperature":{"temp_C":28.5,"temp_F":83.3,"temp_K":301.65}},
+		{"cep":"99999999","error":{"code":"zipcode_not_found","title":"Cannot find zipcode"}}
+	]}`, jsonResp.Body.String())
+
+	assert.Equal(t, http.StatusOK, csvResp.Code)
+	assert.Equal(t, "cep,temp_C,temp_F,temp_K,error\n32450000,28.5,83.3,301.65,\n99999999,,,,zipcode_not_found\n", csvResp.Body.String())
+}
+
+// TestGetTemperaturesByCEPsInvalidCEPReason tests that a refused CEP of a batch carries its reason
+func TestGetTemperaturesByCEPsInvalidCEPReason(t *testing.T) {
+	// Arrange
+	handler := newTestTemperatureHandler(&MockTemperatureUseCase{
+		getTemperatureByCEPFunc: func(cep string) (*domain.Temperature, error) {
+			return nil, &domain.CEPFormatError{Reason: domain.CEPFormatInvalidCharacter, Character: 'x'}
+		},
+	}, true)
+	req := httptest.NewRequest("POST", "/v1/temperatures/batch", strings.NewReader(`{"ceps":["0100x000"]}`))
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.GetTemperaturesByCEPs(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.JSONEq(t, `{"results":[
+		{"cep":"0100x000","error":{"code":"invalid_zipcode","title":"Invalid zipcode","reason":"invalid_character"}}
+	]}`, w.Body.String())
+}
+
+// TestGetTemperaturesByCEPsInvalidBatch tests the rejection of empty and oversized batches
+func TestGetTemperaturesByCEPsInvalidBatch(t *testing.T) {
+	// Arrange
+	handler := newTestTemperatureHandler(&MockTemperatureUseCase{getTemperatureByCEPFunc: fixedTemperature}, true)
+
+	testCases := map[string]int{
+		`{"ceps":[]}`: http.StatusUnprocessableEntity,
+		`{"ceps":["1","2","3","4","5","6","7","8","9","10","11"]}`: http.StatusUnprocessableEntity,
+		`not json`: http.StatusBadRequest,
+	}
+
+	for body, status := range testCases {
+		req := httptest.NewRequest("POST", "/v1/temperatures/batch", strings.NewReader(body))
+		w := httptest.NewRecorder()
+
+		// Act
+		handler.GetTemperaturesByCEPs(w, req)
+
+		// Assert
+		assert.Equal(t, status, w.Code, body)
+	}
+}
+
+// TestGetTemperatureByCEPConditionalGet tests the ETag, Cache-Control and If-None-Match handling
+func TestGetTemperatureByCEPConditionalGet(t *testing.T) {
+	// Arrange
+	mockUseCase := &MockTemperatureUseCase{
+		getTemperatureByCEPFunc: func(cep string) (*domain.Temperature, error) {
+			temperature, err := fixedTemperature(cep)
+			temperature.ExpiresAt = time.Now().Add(90 * time.Second)
+			return temperature, err
+		},
+	}
+	handler := newTestTemperatureHandler(mockUseCase, true)
+
+	first := httptest.NewRecorder()
+	handler.GetTemperatureByCEP(first, httptest.NewRequest("GET", "/32450000", nil))
+	etag := first.Header().Get("ETag")
+
+	req := httptest.NewRequest("GET", "/32450000", nil)
+	req.Header.Set("If-None-Match", `"other", W/`+etag)
+	w := httptest.NewRecorder()
+
+	csvReq := httptest.NewRequest("GET", "/32450000?format=csv", nil)
+	csvReq.Header.Set("If-None-Match", etag)
+	csvResp := httptest.NewRecorder()
+
+	// Act
+	handler.GetTemperatureByCEP(w, req)
+	handler.GetTemperatureByCEP(csvResp, csvReq)
+
+	// Assert
+	assert.Equal(t, http.StatusOK, first.Code)
+	assert.Regexp(t, `^"[0-9a-f]{32}"$`, etag)
+	assert.Regexp(t, `^public, max-age=(89|90)$`, first.Header().Get("Cache-Control"))
+	assert.Equal(t, "Accept, Authorization, X-API-Key", first.Header().Get("Vary"))
+
+	assert.Equal(t, http.StatusNotModified, w.Code)
+	assert.Empty(t, w.Body.String())
+	assert.Equal(t, etag, w.Header().Get("ETag"))
+	assert.NotEmpty(t, w.Header().Get("Cache-Control"))
+
+	assert.Equal(t, http.StatusOK, csvResp.Code)
+	assert.NotEqual(t, etag, csvResp.Header().Get("ETag"))
+}
+
+// TestGetTemperatureByCEPWithoutExpiration tests that uncached temperatures must be revalidated
+func TestGetTemperatureByCEPWithoutExpiration(t *testing.T) {
+	// Arrange
+	handler := newTestTemperatureHandler(&MockTemperatureUseCase{getTemperatureByCEPFunc: fixedTemperature}, true)
+	w := httptest.NewRecorder()
+
+	// Act
+	handler.GetTemperatureByCEP(w, httptest.NewRequest("GET", "/32450000", nil))
+
+	// Assert
+	assert.Equal(t, "no-cache", w.Header().Get("Cache-Control"))
+}
+
+// TestGetTemperatureByCEPAuthenticatedIsPrivate tests that shared caches may not store a response to an authenticated client
+func TestGetTemperatureByCEPAuthenticatedIsPrivate(t *testing.T) {
+	// Arrange
+	mockUseCase := &MockTemperatureUseCase{
+		getTemperatureByCEPFunc: func(cep string) (*domain.Temperature, error) {
+			return &domain.Temperature{Celsius: 28.5, ExpiresAt: time.Now().Add(90 * time.Second)}, nil
+		},
+	}
+	handler := newTestTemperatureHandler(mockUseCase, true)
+	withKey := httptest.NewRequest("GET", "/32450000", nil)
+	withKey = withKey.WithContext(auth.WithAPIKey(withKey.Context(), &domain.APIKey{ID: "key-1"}))
+	withToken := httptest.NewRequest("GET", "/32450000", nil)
+	withToken = withToken.WithContext(auth.WithClaims(withToken.Context(), &auth.Claims{}))
+	keyResp := httptest.NewRecorder()
+	tokenResp := httptest.NewRecorder()
+
+	// Act
+	handler.GetTemperatureByCEP(keyResp, withKey)
+	handler.GetTemperatureByCEP(tokenResp, withToken)
+
+	// Assert
+	assert.Regexp(t, `^private, max-age=(89|90)$`, keyResp.Header().Get("Cache-Control"))
+	assert.Regexp(t, `^private, max-age=(89|90)$`, tokenResp.Header().Get("Cache-Control"))
 }
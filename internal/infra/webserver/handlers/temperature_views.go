@@ -0,0 +1,116 @@
+package handlers
+
+import (
+	"encoding/xml"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/xavierpms/weather-by-city/internal/domain"
+	"github.com/xavierpms/weather-by-city/internal/infra/webserver/problem"
+)
+
+// TemperatureView represents a temperature in every negotiable format
+type TemperatureView struct {
+	XMLName    xml.Name `json:"-" msgpack:"-" xml:"temperature"`
+	Celsius    float64  `json:"temp_C" msgpack:"temp_C" xml:"temp_C"`
+	Fahrenheit float64  `json:"temp_F" msgpack:"temp_F" xml:"temp_F"`
+	Kelvin     float64  `json:"temp_K" msgpack:"temp_K" xml:"temp_K"`
+}
+
+// BatchItemError represents the failure of one CEP in a batch
+type BatchItemError struct {
+	Code   problem.Code `json:"code" msgpack:"code" xml:"code"`
+	Title  string       `json:"title" msgpack:"title" xml:"title"`
+	Reason string       `json:"reason,omitempty" msgpack:"reason,omitempty" xml:"reason,omitempty"`
+}
+
+// BatchItemView represents the result for one CEP in a batch
+type BatchItemView struct {
+	CEP         string           `json:"cep" msgpack:"cep" xml:"cep,attr"`
+	Temperature *TemperatureView `json:"temperature,omitempty" msgpack:"temperature,omitempty" xml:"temperature,omitempty"`
+	Error       *BatchItemError  `json:"error,omitempty" msgpack:"error,omitempty" xml:"error,omitempty"`
+}
+
+// BatchView represents the results of a batch request
+type BatchView struct {
+	XMLName xml.Name        `json:"-" msgpack:"-" xml:"temperatures"`
+	Results []BatchItemView `json:"results" msgpack:"results" xml:"result"`
+}
+
+// BatchRequest represents the body of a batch request
+type BatchRequest struct {
+	CEPs []string `json:"ceps"`
+}
+
+func newTemperatureView(temperature *domain.Temperature) *TemperatureView {
+	return &TemperatureView{
+		Celsius:    temperature.Celsius,
+		Fahrenheit: temperature.Fahrenheit,
+		Kelvin:     temperature.Kelvin,
+	}
+}
+
+func newBatchView(results []domain.TemperatureResult) *BatchView {
+	view := &BatchView{Results: make([]BatchItemView, 0, len(results))}
+	for _, result := range results {
+		item := BatchItemView{CEP: result.CEP}
+		if result.Err != nil {
+			_, code, title, _ := temperatureProblem(result.Err)
+			item.Error = &BatchItemError{Code: code, Title: title, Reason: problemReason(result.Err)}
+		} else {
+			item.Temperature = newTemperatureView(result.Temperature)
+		}
+		view.Results = append(view.Results, item)
+	}
+
+	return view
+}
+
+// Text returns the temperature as "28.5°C / 83.3°F / 301.65K"
+func (v *TemperatureView) Text() string {
+	return fmt.Sprintf("%s°C / %s°F / %sK", formatFloat(v.Celsius), formatFloat(v.Fahrenheit), formatFloat(v.Kelvin))
+}
+
+func (v *TemperatureView) Header() []string {
+	return []string{"temp_C", "temp_F", "temp_K"}
+}
+
+func (v *TemperatureView) Rows() [][]string {
+	return [][]string{{formatFloat(v.Celsius), formatFloat(v.Fahrenheit), formatFloat(v.Kelvin)}}
+}
+
+// Text returns one "<cep>: <temperature>" line per CEP
+func (v *BatchView) Text() string {
+	lines := make([]string, 0, len(v.Results))
+	for _, item := range v.Results {
+		if item.Error != nil {
+			lines = append(lines, item.CEP+": error "+string(item.Error.Code))
+			continue
+		}
+		lines = append(lines, item.CEP+": "+item.Temperature.Text())
+	}
+
+	return strings.Join(lines, "\n")
+}
+
+func (v *BatchView) Header() []string {
+	return []string{"cep", "temp_C", "temp_F", "temp_K", "error"}
+}
+
+func (v *BatchView) Rows() [][]string {
+	rows := make([][]string, 0, len(v.Results))
+	for _, item := range v.Results {
+		if item.Error != nil {
+			rows = append(rows, []string{item.CEP, "", "", "", string(item.Error.Code)})
+			continue
+		}
+		rows = append(rows, append([]string{item.CEP}, append(item.Temperature.Rows()[0], "")...))
+	}
+
+	return rows
+}
+
+func formatFloat(value float64) string {
+	return strconv.FormatFloat(value, 'f', -1, 64)
+}
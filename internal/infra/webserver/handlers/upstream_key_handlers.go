@@ -0,0 +1,31 @@
+package handlers
+
+import (
+	"net/http"
+
+	"github.com/xavierpms/weather-by-city/internal/infra/keypool"
+)
+
+// KeyHealthReporter reports the state of the API keys of an upstream
+type KeyHealthReporter interface {
+	Health() []keypool.KeyHealth
+}
+
+// UpstreamKeyHandler serves the health of the keys used to call the upstream APIs
+type UpstreamKeyHandler struct {
+	weatherAPIKeys KeyHealthReporter
+}
+
+// NewUpstreamKeyHandler creates a new upstream key handler
+func NewUpstreamKeyHandler(weatherAPIKeys KeyHealthReporter) *UpstreamKeyHandler {
+	return &UpstreamKeyHandler{
+		weatherAPIKeys: weatherAPIKeys,
+	}
+}
+
+// ListWeatherAPIKeys handles the GET /admin/weatherapi-keys request. The keys are identified by
+// their position and fingerprint, never by their secret.
+func (h *UpstreamKeyHandler) ListWeatherAPIKeys(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Cache-Control", "no-store")
+	writeJSON(w, http.StatusOK, h.weatherAPIKeys.Health())
+}
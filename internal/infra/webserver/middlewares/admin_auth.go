@@ -0,0 +1,27 @@
+package middlewares
+
+import (
+	"crypto/subtle"
+	"net/http"
+	"strings"
+
+	"github.com/xavierpms/weather-by-city/internal/infra/webserver/problem"
+)
+
+// AdminToken only lets through the requests carrying the admin token as a bearer token.
+// An empty token rejects every request.
+func AdminToken(token string, problems *problem.Writer) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			scheme, sent, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+			if token == "" || !ok || !strings.EqualFold(scheme, "Bearer") || subtle.ConstantTimeCompare([]byte(sent), []byte(token)) != 1 {
+				w.Header().Set("WWW-Authenticate", `Bearer realm="weather-by-city-admin"`)
+				problems.Write(w, r, http.StatusUnauthorized, problem.CodeUnauthorized,
+					"Unauthorized", "The admin token is missing or invalid")
+				return
+			}
+
+			next.ServeHTTP(w, r)
+		})
+	}
+}
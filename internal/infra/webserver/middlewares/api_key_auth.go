@@ -0,0 +1,107 @@
+package middlewares
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/xavierpms/weather-by-city/internal/domain"
+	"github.com/xavierpms/weather-by-city/internal/infra/auth"
+	"github.com/xavierpms/weather-by-city/internal/infra/webserver/problem"
+)
+
+// apiKeyBearerPrefix marks the bearer tokens that are API keys rather than other credentials
+const apiKeyBearerPrefix = "wk_"
+
+// APIKeyAuth authenticates the consumers by API key and enforces their routes and daily quotas
+type APIKeyAuth struct {
+	useCase  domain.APIKeyUseCase
+	required bool
+	problems *problem.Writer
+}
+
+// NewAPIKeyAuth creates the API key middlewares. When required is false, requests without a key
+// are served anonymously, but a key that is sent must still be valid.
+func NewAPIKeyAuth(useCase domain.APIKeyUseCase, required bool, problems *problem.Writer) *APIKeyAuth {
+	return &APIKeyAuth{
+		useCase:  useCase,
+		required: required,
+		problems: problems,
+	}
+}
+
+// Authenticate resolves the API key sent in X-API-Key or as a bearer token and stores it in the context
+func (a *APIKeyAuth) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		secret := apiKeySecret(r)
+		if secret == "" {
+			if a.required {
+				a.unauthorized(w, r, "An API key is required, in the X-API-Key header or as a bearer token")
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		key, err := a.useCase.Authenticate(secret)
+		if err != nil {
+			slog.InfoContext(r.Context(), "API key rejected", "err", err)
+			a.unauthorized(w, r, "The API key is unknown or revoked")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.WithAPIKey(r.Context(), key)))
+	})
+}
+
+// Authorize checks the route and the daily quota of the authenticated key and counts the request.
+// It must run after Authenticate and after routing, in a chi group, so the route pattern is known.
+func (a *APIKeyAuth) Authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key, ok := auth.APIKeyFromContext(r.Context())
+		if !ok {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		err := a.useCase.Authorize(key, chi.RouteContext(r.Context()).RoutePattern())
+		switch {
+		case err == nil:
+			next.ServeHTTP(w, r)
+
+		case errors.Is(err, domain.ErrRouteNotAllowed):
+			a.problems.Write(w, r, http.StatusForbidden, problem.CodeForbidden,
+				"Forbidden", "The API key is not allowed to call this route")
+
+		case errors.Is(err, domain.ErrQuotaExceeded):
+			a.problems.Write(w, r, http.StatusTooManyRequests, problem.CodeQuotaExceeded,
+				"Daily quota exceeded", "The daily quota of the API key is exhausted, it resets at midnight UTC")
+
+		default:
+			slog.ErrorContext(r.Context(), "API key authorization error", "err", err)
+			a.problems.Write(w, r, http.StatusInternalServerError, problem.CodeInternalError,
+				"Internal server error", "")
+		}
+	})
+}
+
+func (a *APIKeyAuth) unauthorized(w http.ResponseWriter, r *http.Request, detail string) {
+	w.Header().Set("WWW-Authenticate", `Bearer realm="weather-by-city"`)
+	a.problems.Write(w, r, http.StatusUnauthorized, problem.CodeUnauthorized, "Unauthorized", detail)
+}
+
+// apiKeySecret returns the API key of the request, from X-API-Key or from an Authorization bearer
+// token with the API key prefix
+func apiKeySecret(r *http.Request) string {
+	if secret := strings.TrimSpace(r.Header.Get(APIKeyHeader)); secret != "" {
+		return secret
+	}
+
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	if ok && strings.EqualFold(scheme, "Bearer") && strings.HasPrefix(token, apiKeyBearerPrefix) {
+		return strings.TrimSpace(token)
+	}
+	return ""
+}
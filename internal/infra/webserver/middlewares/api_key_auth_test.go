@@ -0,0 +1,187 @@
+package middlewares
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xavierpms/weather-by-city/api/openapi"
+	"github.com/xavierpms/weather-by-city/internal/domain"
+	"github.com/xavierpms/weather-by-city/internal/infra/repository"
+	"github.com/xavierpms/weather-by-city/internal/infra/validator"
+	"github.com/xavierpms/weather-by-city/internal/infra/webhook"
+	"github.com/xavierpms/weather-by-city/internal/infra/webserver/handlers"
+	"github.com/xavierpms/weather-by-city/internal/infra/webserver/problem"
+	"github.com/xavierpms/weather-by-city/internal/infra/webserver/render"
+	"github.com/xavierpms/weather-by-city/internal/usecase"
+)
+
+// newAPIKeyRouter mounts the temperature, usage and admin handlers behind the validator and the API key middlewares
+func newAPIKeyRouter(t *testing.T, required bool) (http.Handler, domain.APIKeyUseCase, *[]string) {
+	problems := problem.NewWriter(true)
+	v, err := NewOpenAPIValidator(openapi.Spec, true, problems)
+	require.NoError(t, err)
+	mismatches := []string{}
+	v.onResponseError = func(r *http.Request, status int, err error) {
+		mismatches = append(mismatches, r.Method+" "+r.URL.Path+": "+err.Error())
+	}
+
+	keyRepo, err := repository.NewAPIKeyRepository("")
+	require.NoError(t, err)
+	usageRepo, err := repository.NewUsageRepository("")
+	require.NoError(t, err)
+	manageAPIKeys := usecase.NewManageAPIKeys(keyRepo, usageRepo, 0)
+	apiKeyAuth := NewAPIKeyAuth(manageAPIKeys, required, problems)
+	apiKeyHandler := handlers.NewAPIKeyHandler(manageAPIKeys, problems)
+	temperatureHandler := handlers.NewTemperatureHandler(
+		&MockTemperatureUseCase{},
+		usecase.NewGetTemperaturesByCEPs(&MockTemperatureUseCase{}, 10, 2),
+		problems,
+		render.NewDefaultRegistry(),
+	)
+	alertHandler := handlers.NewAlertHandler(
+		usecase.NewManageAlertRules(repository.NewAlertRuleRepository(), validator.NewCEPValidator(), 10),
+		webhook.NewDispatcher("", 1, time.Millisecond, 10),
+		problems,
+	)
+
+	router := chi.NewRouter()
+	router.Use(v.Handler)
+	router.Route("/admin", func(r chi.Router) {
+		r.Use(AdminToken("admin-secret", problems))
+		r.Post("/api-keys", apiKeyHandler.CreateKey)
+		r.Get("/api-keys", apiKeyHandler.ListKeys)
+	})
+	router.Group(func(r chi.Router) {
+		r.Use(apiKeyAuth.Authenticate)
+		r.Get("/v1/usage", apiKeyHandler.GetUsage)
+		r.Group(func(r chi.Router) {
+			r.Use(apiKeyAuth.Authorize)
+			r.Post("/v1/temperatures/batch", temperatureHandler.GetTemperaturesByCEPs)
+			r.Get("/v1/alerts/rules", alertHandler.ListRules)
+			r.Get("/{cep}", temperatureHandler.GetTemperatureByCEP)
+		})
+	})
+
+	return router, manageAPIKeys, &mismatches
+}
+
+func serve(router http.Handler, req *http.Request) *httptest.ResponseRecorder {
+	w := httptest.NewRecorder()
+	router.ServeHTTP(w, req)
+	return w
+}
+
+// TestAPIKeyAuthQuotaAndUsage tests a key created through the admin endpoint, its quota and its usage report
+func TestAPIKeyAuthQuotaAndUsage(t *testing.T) {
+	// Arrange
+	router, _, mismatches := newAPIKeyRouter(t, false)
+	req := httptest.NewRequest(http.MethodPost, "/admin/api-keys", strings.NewReader(`{"name":"partner","daily_quota":2}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("Authorization", "Bearer admin-secret")
+	createResponse := serve(router, req)
+	require.Equal(t, http.StatusCreated, createResponse.Code)
+	var created domain.CreatedAPIKey
+	require.NoError(t, json.Unmarshal(createResponse.Body.Bytes(), &created))
+
+	// Act
+	var statuses []int
+	for i := range 3 {
+		req := httptest.NewRequest(http.MethodGet, "/01001000", nil)
+		if i%2 == 0 {
+			req.Header.Set(APIKeyHeader, created.Secret)
+		} else {
+			req.Header.Set("Authorization", "Bearer "+created.Secret)
+		}
+		statuses = append(statuses, serve(router, req).Code)
+	}
+	usageRequest := httptest.NewRequest(http.MethodGet, "/v1/usage", nil)
+	usageRequest.Header.Set(APIKeyHeader, created.Secret)
+	usageResponse := serve(router, usageRequest)
+
+	// Assert
+	assert.Equal(t, []int{200, 200, 429}, statuses)
+	assert.Equal(t, http.StatusOK, usageResponse.Code)
+	var report domain.UsageReport
+	require.NoError(t, json.Unmarshal(usageResponse.Body.Bytes(), &report))
+	require.Len(t, report.Days, 1)
+	assert.Equal(t, map[string]int{"/{cep}": 2}, report.Days[0].Routes)
+	assert.NotContains(t, createResponse.Body.String(), "hash")
+	assert.Empty(t, *mismatches)
+}
+
+// TestAPIKeyAuthRejections tests the responses to missing, unknown and restricted keys
+func TestAPIKeyAuthRejections(t *testing.T) {
+	// Arrange
+	router, useCase, mismatches := newAPIKeyRouter(t, true)
+	restricted, err := useCase.CreateKey(&domain.APIKey{Name: "batch-only", AllowedRoutes: []string{"/v1/temperatures/batch"}})
+	require.NoError(t, err)
+	request := func(path, secret string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		if secret != "" {
+			req.Header.Set(APIKeyHeader, secret)
+		}
+		return serve(router, req)
+	}
+
+	// Act
+	missing := request("/01001000", "")
+	unknown := request("/01001000", "wk_unknown")
+	forbidden := request("/01001000", restricted.Secret)
+	adminWithoutToken := request("/admin/api-keys", "")
+
+	// Assert
+	assert.Equal(t, http.StatusUnauthorized, missing.Code)
+	assert.NotEmpty(t, missing.Header().Get("WWW-Authenticate"))
+	assert.Contains(t, missing.Body.String(), `"code":"unauthorized"`)
+	assert.Equal(t, http.StatusUnauthorized, unknown.Code)
+	assert.Equal(t, http.StatusForbidden, forbidden.Code)
+	assert.Contains(t, forbidden.Body.String(), `"code":"forbidden"`)
+	assert.Equal(t, http.StatusUnauthorized, adminWithoutToken.Code)
+	assert.Empty(t, *mismatches)
+}
+
+// TestAPIKeyAuthOptional tests that anonymous requests are served when keys are optional
+func TestAPIKeyAuthOptional(t *testing.T) {
+	// Arrange
+	router, _, _ := newAPIKeyRouter(t, false)
+
+	// Act
+	anonymous := serve(router, httptest.NewRequest(http.MethodGet, "/01001000", nil))
+	anonymousUsage := serve(router, httptest.NewRequest(http.MethodGet, "/v1/usage", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusOK, anonymous.Code)
+	assert.Equal(t, http.StatusUnauthorized, anonymousUsage.Code)
+}
+
+// TestAPIKeyAuthAllowsAlertRoutes tests that an alert route listed in the allowed routes is served and counted under its pattern
+func TestAPIKeyAuthAllowsAlertRoutes(t *testing.T) {
+	// Arrange
+	router, useCase, mismatches := newAPIKeyRouter(t, true)
+	key, err := useCase.CreateKey(&domain.APIKey{Name: "alerts-only", AllowedRoutes: []string{"/v1/alerts/rules"}})
+	require.NoError(t, err)
+	request := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set(APIKeyHeader, key.Secret)
+		return serve(router, req)
+	}
+
+	// Act
+	alerts := request("/v1/alerts/rules")
+	temperature := request("/01001000")
+	usage := request("/v1/usage")
+
+	// Assert
+	assert.Equal(t, http.StatusOK, alerts.Code)
+	assert.Equal(t, http.StatusForbidden, temperature.Code)
+	require.Equal(t, http.StatusOK, usage.Code)
+	assert.Contains(t, usage.Body.String(), `"/v1/alerts/rules":1`)
+	assert.Empty(t, *mismatches)
+}
@@ -0,0 +1,112 @@
+package middlewares
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/go-chi/chi"
+	"github.com/xavierpms/weather-by-city/internal/infra/auth"
+	"github.com/xavierpms/weather-by-city/internal/infra/webserver/problem"
+)
+
+// JWTAuth authenticates bearer JWTs and enforces the scopes required by each route
+type JWTAuth struct {
+	verifier    *auth.Verifier
+	routeScopes map[string][]string
+	problems    *problem.Writer
+}
+
+// NewJWTAuth creates the JWT middlewares. routeScopes maps route patterns to the scopes they require;
+// routes without an entry are served without a token.
+func NewJWTAuth(verifier *auth.Verifier, routeScopes map[string][]string, problems *problem.Writer) *JWTAuth {
+	return &JWTAuth{
+		verifier:    verifier,
+		routeScopes: routeScopes,
+		problems:    problems,
+	}
+}
+
+// Authenticate verifies the bearer JWT, if any, and stores its claims in the context.
+// Bearer tokens with the API key prefix are left to the API key middleware.
+func (a *JWTAuth) Authenticate(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		token := bearerJWT(r)
+		if token == "" {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		claims, err := a.verifier.Verify(r.Context(), token)
+		if err != nil {
+			slog.InfoContext(r.Context(), "JWT rejected", "err", err)
+			w.Header().Set("WWW-Authenticate", `Bearer realm="weather-by-city", error="invalid_token"`)
+			a.problems.Write(w, r, http.StatusUnauthorized, problem.CodeUnauthorized,
+				"Unauthorized", "The token is invalid or expired")
+			return
+		}
+
+		next.ServeHTTP(w, r.WithContext(auth.WithClaims(r.Context(), claims)))
+	})
+}
+
+// Authorize enforces the scopes configured for the route. It must run after Authenticate and after
+// routing, in a chi group, so the route pattern is known.
+func (a *JWTAuth) Authorize(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		scopes := a.routeScopes[chi.RouteContext(r.Context()).RoutePattern()]
+		if a.allowed(w, r, scopes) {
+			next.ServeHTTP(w, r)
+		}
+	})
+}
+
+// RequireScope only lets through the requests whose token grants the scope
+func (a *JWTAuth) RequireScope(scope string) func(http.Handler) http.Handler {
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			if a.allowed(w, r, []string{scope}) {
+				next.ServeHTTP(w, r)
+			}
+		})
+	}
+}
+
+// allowed checks that the token grants every scope, writing the error response when it does not
+func (a *JWTAuth) allowed(w http.ResponseWriter, r *http.Request, scopes []string) bool {
+	if len(scopes) == 0 {
+		return true
+	}
+
+	required := strings.Join(scopes, " ")
+	claims, ok := auth.ClaimsFromContext(r.Context())
+	if !ok {
+		w.Header().Set("WWW-Authenticate", fmt.Sprintf(`Bearer realm="weather-by-city", scope=%q`, required))
+		a.problems.Write(w, r, http.StatusUnauthorized, problem.CodeUnauthorized,
+			"Unauthorized", "A bearer token with the scope "+required+" is required")
+		return false
+	}
+
+	for _, scope := range scopes {
+		if !claims.HasScope(scope) {
+			w.Header().Set("WWW-Authenticate",
+				fmt.Sprintf(`Bearer realm="weather-by-city", error="insufficient_scope", scope=%q`, required))
+			a.problems.Write(w, r, http.StatusForbidden, problem.CodeForbidden,
+				"Forbidden", "The token lacks the scope "+required)
+			return false
+		}
+	}
+
+	return true
+}
+
+// bearerJWT returns the bearer token of the request unless it is an API key
+func bearerJWT(r *http.Request) string {
+	scheme, token, ok := strings.Cut(r.Header.Get("Authorization"), " ")
+	token = strings.TrimSpace(token)
+	if !ok || !strings.EqualFold(scheme, "Bearer") || strings.HasPrefix(token, apiKeyBearerPrefix) {
+		return ""
+	}
+	return token
+}
@@ -0,0 +1,168 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xavierpms/weather-by-city/api/openapi"
+	"github.com/xavierpms/weather-by-city/internal/infra/auth"
+	"github.com/xavierpms/weather-by-city/internal/infra/repository"
+	"github.com/xavierpms/weather-by-city/internal/infra/validator"
+	"github.com/xavierpms/weather-by-city/internal/infra/webhook"
+	"github.com/xavierpms/weather-by-city/internal/infra/webserver/handlers"
+	"github.com/xavierpms/weather-by-city/internal/infra/webserver/problem"
+	"github.com/xavierpms/weather-by-city/internal/infra/webserver/render"
+	"github.com/xavierpms/weather-by-city/internal/usecase"
+)
+
+var jwtTestSecret = []byte("0123456789abcdef0123456789abcdef")
+
+// newJWTRouter mounts the temperature and admin handlers behind the validator and the JWT middlewares
+func newJWTRouter(t *testing.T) (http.Handler, *[]string) {
+	problems := problem.NewWriter(true)
+	v, err := NewOpenAPIValidator(openapi.Spec, true, problems)
+	require.NoError(t, err)
+	mismatches := []string{}
+	v.onResponseError = func(r *http.Request, status int, err error) {
+		mismatches = append(mismatches, r.Method+" "+r.URL.Path+": "+err.Error())
+	}
+
+	verifier, err := auth.NewVerifier(auth.VerifierOptions{HS256Secret: jwtTestSecret})
+	require.NoError(t, err)
+	jwtAuth := NewJWTAuth(verifier, map[string][]string{
+		"/v1/temperatures/batch": {auth.ScopeBatchWrite},
+		"/v1/alerts/rules":       {auth.ScopeAdmin},
+	}, problems)
+
+	keyRepo, err := repository.NewAPIKeyRepository("")
+	require.NoError(t, err)
+	usageRepo, err := repository.NewUsageRepository("")
+	require.NoError(t, err)
+	apiKeyHandler := handlers.NewAPIKeyHandler(usecase.NewManageAPIKeys(keyRepo, usageRepo, 0), problems)
+	temperatureHandler := handlers.NewTemperatureHandler(
+		&MockTemperatureUseCase{},
+		usecase.NewGetTemperaturesByCEPs(&MockTemperatureUseCase{}, 10, 2),
+		problems,
+		render.NewDefaultRegistry(),
+	)
+	alertHandler := handlers.NewAlertHandler(
+		usecase.NewManageAlertRules(repository.NewAlertRuleRepository(), validator.NewCEPValidator(), 10),
+		webhook.NewDispatcher("", 1, time.Millisecond, 10),
+		problems,
+	)
+
+	router := chi.NewRouter()
+	router.Use(v.Handler)
+	router.Route("/admin", func(r chi.Router) {
+		r.Use(jwtAuth.Authenticate, jwtAuth.RequireScope(auth.ScopeAdmin))
+		r.Get("/api-keys", apiKeyHandler.ListKeys)
+	})
+	router.Group(func(r chi.Router) {
+		r.Use(jwtAuth.Authenticate)
+		r.Group(func(r chi.Router) {
+			r.Use(jwtAuth.Authorize)
+			r.Post("/v1/temperatures/batch", temperatureHandler.GetTemperaturesByCEPs)
+			r.Get("/v1/alerts/rules", alertHandler.ListRules)
+			r.Get("/{cep}", temperatureHandler.GetTemperatureByCEP)
+		})
+	})
+
+	return router, &mismatches
+}
+
+// bearer mints an HS256 token granting the scopes
+func bearer(t *testing.T, scope string, expiresIn time.Duration) string {
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub":   "client-1",
+		"scope": scope,
+		"exp":   time.Now().Add(expiresIn).Unix(),
+	}).SignedString(jwtTestSecret)
+	require.NoError(t, err)
+	return "Bearer " + token
+}
+
+// TestJWTAuthEnforcesRouteScopes tests the responses of the scoped routes to each kind of token
+func TestJWTAuthEnforcesRouteScopes(t *testing.T) {
+	// Arrange
+	router, mismatches := newJWTRouter(t)
+	batch := func(authorization string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/v1/temperatures/batch", strings.NewReader(`{"ceps":["01001000"]}`))
+		req.Header.Set("Content-Type", "application/json")
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		return serve(router, req)
+	}
+
+	// Act
+	anonymous := batch("")
+	expired := batch(bearer(t, "batch:write", -time.Minute))
+	readOnly := batch(bearer(t, "weather:read", time.Hour))
+	allowed := batch(bearer(t, "weather:read batch:write", time.Hour))
+	unscopedRoute := serve(router, httptest.NewRequest(http.MethodGet, "/01001000", nil))
+
+	// Assert
+	assert.Equal(t, http.StatusUnauthorized, anonymous.Code)
+	assert.Equal(t, `Bearer realm="weather-by-city", scope="batch:write"`, anonymous.Header().Get("WWW-Authenticate"))
+	assert.Equal(t, http.StatusUnauthorized, expired.Code)
+	assert.Contains(t, expired.Header().Get("WWW-Authenticate"), `error="invalid_token"`)
+	assert.Equal(t, http.StatusForbidden, readOnly.Code)
+	assert.Contains(t, readOnly.Header().Get("WWW-Authenticate"), `error="insufficient_scope"`)
+	assert.Contains(t, readOnly.Body.String(), `"code":"forbidden"`)
+	assert.Equal(t, http.StatusOK, allowed.Code)
+	assert.Equal(t, http.StatusOK, unscopedRoute.Code)
+	assert.Empty(t, *mismatches)
+}
+
+// TestJWTAuthRequiresAdminScope tests that the admin routes need a token with the admin scope
+func TestJWTAuthRequiresAdminScope(t *testing.T) {
+	// Arrange
+	router, mismatches := newJWTRouter(t)
+	listKeys := func(authorization string) int {
+		req := httptest.NewRequest(http.MethodGet, "/admin/api-keys", nil)
+		req.Header.Set("Authorization", authorization)
+		return serve(router, req).Code
+	}
+
+	// Act
+	withBatchScope := listKeys(bearer(t, "batch:write", time.Hour))
+	withAdminScope := listKeys(bearer(t, "admin", time.Hour))
+	withAPIKey := listKeys("Bearer wk_not-a-jwt")
+
+	// Assert
+	assert.Equal(t, http.StatusForbidden, withBatchScope)
+	assert.Equal(t, http.StatusOK, withAdminScope)
+	assert.Equal(t, http.StatusUnauthorized, withAPIKey)
+	assert.Empty(t, *mismatches)
+}
+
+// TestJWTAuthEnforcesAlertRouteScopes tests that a scope set for an alert route applies to it
+func TestJWTAuthEnforcesAlertRouteScopes(t *testing.T) {
+	// Arrange
+	router, mismatches := newJWTRouter(t)
+	listRules := func(authorization string) int {
+		req := httptest.NewRequest(http.MethodGet, "/v1/alerts/rules", nil)
+		if authorization != "" {
+			req.Header.Set("Authorization", authorization)
+		}
+		return serve(router, req).Code
+	}
+
+	// Act
+	anonymous := listRules("")
+	readOnly := listRules(bearer(t, "weather:read", time.Hour))
+	admin := listRules(bearer(t, "admin", time.Hour))
+
+	// Assert
+	assert.Equal(t, http.StatusUnauthorized, anonymous)
+	assert.Equal(t, http.StatusForbidden, readOnly)
+	assert.Equal(t, http.StatusOK, admin)
+	assert.Empty(t, *mismatches)
+}
@@ -0,0 +1,135 @@
+package middlewares
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"log/slog"
+	"net/http"
+	"strings"
+
+	"github.com/getkin/kin-openapi/openapi3"
+	"github.com/getkin/kin-openapi/openapi3filter"
+	"github.com/getkin/kin-openapi/routers"
+	"github.com/getkin/kin-openapi/routers/legacy"
+	"github.com/xavierpms/weather-by-city/internal/infra/webserver/problem"
+)
+
+func init() {
+	// The documentation page is described as a plain string
+	openapi3filter.RegisterBodyDecoder("text/html", openapi3filter.PlainBodyDecoder)
+}
+
+// OpenAPIValidator validates requests, and optionally responses, against an OpenAPI 3 document
+type OpenAPIValidator struct {
+	router            routers.Router
+	validateResponses bool
+	problems          *problem.Writer
+	onResponseError   func(r *http.Request, status int, err error)
+}
+
+// NewOpenAPIValidator loads and validates the document and creates the validator
+func NewOpenAPIValidator(spec []byte, validateResponses bool, problems *problem.Writer) (*OpenAPIValidator, error) {
+	doc, err := openapi3.NewLoader().LoadFromData(spec)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := doc.Validate(context.Background()); err != nil {
+		return nil, err
+	}
+
+	router, err := legacy.NewRouter(doc)
+	if err != nil {
+		return nil, err
+	}
+
+	return &OpenAPIValidator{
+		router:            router,
+		validateResponses: validateResponses,
+		problems:          problems,
+		onResponseError:   logResponseError,
+	}, nil
+}
+
+// Handler rejects requests that do not match the document with 400. Response mismatches
+// are logged and the original response is still sent, so a stale document never breaks clients.
+func (v *OpenAPIValidator) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route, pathParams, err := v.router.FindRoute(r)
+		if err != nil {
+			// Routes missing from the document are not validated
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		requestInput := &openapi3filter.RequestValidationInput{
+			Request:    r,
+			PathParams: pathParams,
+			Route:      route,
+			Options: &openapi3filter.Options{
+				AuthenticationFunc: openapi3filter.NoopAuthenticationFunc,
+			},
+		}
+		if err := openapi3filter.ValidateRequest(r.Context(), requestInput); err != nil {
+			slog.InfoContext(r.Context(), "OpenAPI request validation failed", "method", r.Method, "path", r.URL.Path, "err", err)
+			v.problems.Write(w, r, http.StatusBadRequest, problem.CodeInvalidRequest,
+				"Request does not match the API specification", err.Error())
+			return
+		}
+
+		if !v.validateResponses {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		recorder := &responseRecorder{ResponseWriter: w, status: http.StatusOK}
+		next.ServeHTTP(recorder, r)
+
+		responseInput := &openapi3filter.ResponseValidationInput{
+			RequestValidationInput: requestInput,
+			Status:                 recorder.status,
+			Header:                 w.Header(),
+			Body:                   io.NopCloser(bytes.NewReader(recorder.body.Bytes())),
+			Options: &openapi3filter.Options{
+				IncludeResponseStatus: true,
+				// Only JSON bodies are checked against the schemas, other negotiated formats are checked by media type
+				ExcludeResponseBody: !isJSON(w.Header().Get("Content-Type")),
+			},
+		}
+		if err := openapi3filter.ValidateResponse(r.Context(), responseInput); err != nil {
+			v.onResponseError(r, recorder.status, err)
+		}
+
+		w.WriteHeader(recorder.status)
+		w.Write(recorder.body.Bytes())
+	})
+}
+
+// isJSON reports whether the content type is JSON or a +json media type
+func isJSON(contentType string) bool {
+	mediaType, _, _ := strings.Cut(contentType, ";")
+	mediaType = strings.TrimSpace(mediaType)
+
+	return mediaType == "application/json" || strings.HasSuffix(mediaType, "+json")
+}
+
+// logResponseError reports a response that does not match the document
+func logResponseError(r *http.Request, status int, err error) {
+	slog.ErrorContext(r.Context(), "OpenAPI response validation failed", "method", r.Method, "path", r.URL.Path, "status", status, "err", err)
+}
+
+// responseRecorder buffers the response so it can be validated before being sent
+type responseRecorder struct {
+	http.ResponseWriter
+	status int
+	body   bytes.Buffer
+}
+
+func (r *responseRecorder) WriteHeader(status int) {
+	r.status = status
+}
+
+func (r *responseRecorder) Write(b []byte) (int, error) {
+	return r.body.Write(b)
+}
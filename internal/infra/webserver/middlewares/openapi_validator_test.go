@@ -0,0 +1,168 @@
+package middlewares
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xavierpms/weather-by-city/api/openapi"
+	"github.com/xavierpms/weather-by-city/internal/config"
+	"github.com/xavierpms/weather-by-city/internal/domain"
+	"github.com/xavierpms/weather-by-city/internal/infra/keypool"
+	"github.com/xavierpms/weather-by-city/internal/infra/reload"
+	"github.com/xavierpms/weather-by-city/internal/infra/repository"
+	"github.com/xavierpms/weather-by-city/internal/infra/validator"
+	"github.com/xavierpms/weather-by-city/internal/infra/webhook"
+	"github.com/xavierpms/weather-by-city/internal/infra/webserver/handlers"
+	"github.com/xavierpms/weather-by-city/internal/infra/webserver/problem"
+	"github.com/xavierpms/weather-by-city/internal/infra/webserver/render"
+	"github.com/xavierpms/weather-by-city/internal/usecase"
+)
+
+// MockTemperatureUseCase is a mock of the TemperatureUseCase for testing
+type MockTemperatureUseCase struct{}
+
+func (m *MockTemperatureUseCase) GetTemperatureByCEP(ctx context.Context, cep string) (*domain.Temperature, error) {
+	switch cep {
+	case "01001000":
+		return &domain.Temperature{Celsius: 28.5, Fahrenheit: 83.3, Kelvin: 301.65}, nil
+	case "99999999":
+		return nil, domain.ErrCEPNotFound
+	default:
+		return nil, domain.ErrInvalidCEPFormat
+	}
+}
+
+// MockConfigReloader is a mock of the ConfigReloader for testing
+type MockConfigReloader struct {
+	reloadFunc func() error
+}
+
+func (m *MockConfigReloader) Reload() error {
+	return m.reloadFunc()
+}
+
+func (m *MockConfigReloader) Status() reload.Status {
+	return reload.Status{
+		LoadedAt:       time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC),
+		PendingRestart: []string{},
+		Settings:       []config.Setting{{Name: "ADMIN_TOKEN", Value: "REDACTED", Source: config.SourceSecret, Secret: true}},
+	}
+}
+
+// newContractRouter mounts the real handlers behind the validator and collects response mismatches
+func newContractRouter(t *testing.T) (http.Handler, *[]string) {
+	problems := problem.NewWriter(true)
+	v, err := NewOpenAPIValidator(openapi.Spec, true, problems)
+	require.NoError(t, err)
+
+	mismatches := []string{}
+	v.onResponseError = func(r *http.Request, status int, err error) {
+		mismatches = append(mismatches, r.Method+" "+r.URL.Path+": "+err.Error())
+	}
+
+	temperatureHandler := handlers.NewTemperatureHandler(
+		&MockTemperatureUseCase{},
+		usecase.NewGetTemperaturesByCEPs(&MockTemperatureUseCase{}, 10, 2),
+		problems,
+		render.NewDefaultRegistry(),
+	)
+	alertHandler := handlers.NewAlertHandler(
+		usecase.NewManageAlertRules(repository.NewAlertRuleRepository(), validator.NewCEPValidator(), 10),
+		webhook.NewDispatcher("secret", 1, 0, 10),
+		problems,
+	)
+	docsHandler := handlers.NewDocsHandler(openapi.Spec, openapi.DocsPage)
+	upstreamKeyHandler := handlers.NewUpstreamKeyHandler(keypool.New("weatherapi", []string{"first", "second"}, keypool.StrategyRoundRobin))
+	configHandler := handlers.NewConfigHandler(&MockConfigReloader{
+		reloadFunc: func() error { return errors.New("CACHE_CEP_TTL must be positive: \"0s\"") },
+	}, problems)
+
+	router := chi.NewRouter()
+	router.Use(v.Handler)
+	router.Get("/openapi.json", docsHandler.GetOpenAPI)
+	router.Get("/docs", docsHandler.GetDocs)
+	router.Get("/admin/weatherapi-keys", upstreamKeyHandler.ListWeatherAPIKeys)
+	router.Get("/admin/config", configHandler.GetConfig)
+	router.Post("/admin/config/reload", configHandler.ReloadConfig)
+	router.Post("/v1/alerts/rules", alertHandler.CreateRule)
+	router.Get("/v1/alerts/rules", alertHandler.ListRules)
+	router.Delete("/v1/alerts/rules/{id}", alertHandler.DeleteRule)
+	router.Get("/v1/alerts/dead-letters", alertHandler.ListDeadLetters)
+	router.Post("/v1/temperatures/batch", temperatureHandler.GetTemperaturesByCEPs)
+	router.Get("/{cep}", temperatureHandler.GetTemperatureByCEP)
+
+	return router, &mismatches
+}
+
+// TestHandlersMatchOpenAPIDocument tests that the handler responses follow the document
+func TestHandlersMatchOpenAPIDocument(t *testing.T) {
+	// Arrange
+	router, mismatches := newContractRouter(t)
+
+	testCases := []struct {
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{http.MethodGet, "/01001000", "", http.StatusOK},
+		{http.MethodGet, "/99999999", "", http.StatusNotFound},
+		{http.MethodGet, "/3245000", "", http.StatusUnprocessableEntity},
+		{http.MethodGet, "/01001000?format=xml", "", http.StatusOK},
+		{http.MethodGet, "/01001000?format=csv", "", http.StatusOK},
+		{http.MethodGet, "/01001000?format=text", "", http.StatusOK},
+		{http.MethodGet, "/01001000?format=msgpack", "", http.StatusOK},
+		{http.MethodPost, "/v1/temperatures/batch", `{"ceps":["01001000","99999999","3245000"]}`, http.StatusOK},
+		{http.MethodPost, "/v1/temperatures/batch", `{"ceps":[]}`, http.StatusUnprocessableEntity},
+		{http.MethodPost, "/v1/alerts/rules", `{"cep":"01001000","above":35,"webhook_url":"https://example.com/hook"}`, http.StatusCreated},
+		{http.MethodPost, "/v1/alerts/rules", `{"cep":"01001000","webhook_url":"https://example.com/hook"}`, http.StatusUnprocessableEntity},
+		{http.MethodGet, "/v1/alerts/rules", "", http.StatusOK},
+		{http.MethodDelete, "/v1/alerts/rules/unknown", "", http.StatusNotFound},
+		{http.MethodGet, "/v1/alerts/dead-letters", "", http.StatusOK},
+		{http.MethodGet, "/openapi.json", "", http.StatusOK},
+		{http.MethodGet, "/docs", "", http.StatusOK},
+		{http.MethodGet, "/admin/weatherapi-keys", "", http.StatusOK},
+		{http.MethodGet, "/admin/config", "", http.StatusOK},
+		{http.MethodPost, "/admin/config/reload", "", http.StatusUnprocessableEntity},
+	}
+
+	for _, tc := range testCases {
+		req := httptest.NewRequest(tc.method, tc.path, strings.NewReader(tc.body))
+		if tc.body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		w := httptest.NewRecorder()
+
+		// Act
+		router.ServeHTTP(w, req)
+
+		// Assert
+		assert.Equal(t, tc.status, w.Code, "%s %s", tc.method, tc.path)
+	}
+	assert.Empty(t, *mismatches)
+}
+
+// TestOpenAPIValidatorRejectsInvalidRequests tests that requests outside the document get 400
+func TestOpenAPIValidatorRejectsInvalidRequests(t *testing.T) {
+	// Arrange
+	router, _ := newContractRouter(t)
+	req := httptest.NewRequest(http.MethodPost, "/v1/alerts/rules", strings.NewReader(`{"above":35}`))
+	req.Header.Set("Content-Type", "application/json")
+	w := httptest.NewRecorder()
+
+	// Act
+	router.ServeHTTP(w, req)
+
+	// Assert
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Equal(t, problem.ContentType, w.Header().Get("Content-Type"))
+	assert.Contains(t, w.Body.String(), `"code":"invalid_request"`)
+}
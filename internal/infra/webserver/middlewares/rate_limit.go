@@ -0,0 +1,169 @@
+package middlewares
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"log/slog"
+	"math"
+	"net"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync/atomic"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/xavierpms/weather-by-city/internal/infra/auth"
+	"github.com/xavierpms/weather-by-city/internal/infra/ratelimit"
+	"github.com/xavierpms/weather-by-city/internal/infra/webserver/problem"
+)
+
+// APIKeyHeader is the header carrying the API key of the client
+const APIKeyHeader = "X-API-Key"
+
+// RateLimitObserver is notified of every rate limit decision
+type RateLimitObserver interface {
+	ObserveRateLimit(route string, allowed bool)
+}
+
+// RateLimitOptions configure the rate limiter
+type RateLimitOptions struct {
+	// Default applies to the routes without their own limit
+	Default ratelimit.Limit
+	// Routes maps chi route patterns, such as /{cep}, to their limits
+	Routes map[string]ratelimit.Limit
+	// TrustedHops is the number of proxies in front of the service appending to X-Forwarded-For
+	TrustedHops int
+}
+
+// RateLimiter limits the requests of each client IP, and of each API key, per route. It must
+// run after the API key authentication, when enabled.
+type RateLimiter struct {
+	store    ratelimit.Store
+	options  atomic.Pointer[RateLimitOptions]
+	problems *problem.Writer
+	observer RateLimitObserver
+}
+
+// NewRateLimiter creates a rate limiter keeping its buckets in the store
+func NewRateLimiter(store ratelimit.Store, options RateLimitOptions, problems *problem.Writer, observer RateLimitObserver) *RateLimiter {
+	l := &RateLimiter{
+		store:    store,
+		problems: problems,
+		observer: observer,
+	}
+	l.options.Store(&options)
+	return l
+}
+
+// SetOptions replaces the limits, which apply from the next request. The buckets are kept, so a
+// client that spent its tokens does not get them back.
+func (l *RateLimiter) SetOptions(options RateLimitOptions) {
+	l.options.Store(&options)
+}
+
+// Allow takes a token of the route for a client. Authenticated consumers, with the API key in the
+// context, are limited per key. Otherwise the client is limited by its IP, read from remoteAddr and
+// the X-Forwarded-For values, and by apiKey, the key it sent, if any.
+func (l *RateLimiter) Allow(ctx context.Context, route, remoteAddr string, forwardedFor []string, apiKey string) (ratelimit.Decision, ratelimit.Limit, error) {
+	options := l.options.Load()
+	limit, ok := options.Routes[route]
+	if !ok {
+		limit = options.Default
+	}
+
+	// Authenticated consumers are limited per key, wherever they call from. Otherwise the IP
+	// bucket always applies, so clients cannot escape it by sending made-up keys.
+	var keys []string
+	if key, ok := auth.APIKeyFromContext(ctx); ok {
+		keys = []string{route + "|key:" + key.ID}
+	} else {
+		keys = []string{route + "|ip:" + clientIP(remoteAddr, forwardedFor, options.TrustedHops)}
+		if apiKey != "" {
+			sum := sha256.Sum256([]byte(apiKey))
+			keys = append(keys, route+"|key:"+hex.EncodeToString(sum[:8]))
+		}
+	}
+
+	var decision ratelimit.Decision
+	for i, key := range keys {
+		d, err := l.store.Take(ctx, key, limit)
+		if err != nil {
+			return ratelimit.Decision{}, limit, err
+		}
+		if i == 0 || !d.Allowed || d.Remaining < decision.Remaining {
+			decision = d
+		}
+		if !d.Allowed {
+			break
+		}
+	}
+	l.observer.ObserveRateLimit(route, decision.Allowed)
+
+	return decision, limit, nil
+}
+
+// Handler rejects the requests over the limit with 429. It must run after routing, in a chi
+// group, so the route pattern is known. Store errors let the request through.
+func (l *RateLimiter) Handler(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		route := chi.RouteContext(r.Context()).RoutePattern()
+		decision, limit, err := l.Allow(r.Context(), route, r.RemoteAddr, r.Header.Values("X-Forwarded-For"), r.Header.Get(APIKeyHeader))
+		if err != nil {
+			slog.WarnContext(r.Context(), "rate limit store error, letting the request through", "err", err)
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		w.Header().Set("RateLimit-Limit", strconv.Itoa(decision.Limit))
+		w.Header().Set("RateLimit-Remaining", strconv.Itoa(decision.Remaining))
+		w.Header().Set("RateLimit-Reset", strconv.Itoa(ceilSeconds(decision.Reset)))
+		w.Header().Set("RateLimit-Policy", strconv.Itoa(limit.Requests)+";w="+strconv.Itoa(ceilSeconds(limit.Period)))
+
+		if !decision.Allowed {
+			w.Header().Set("Retry-After", strconv.Itoa(max(ceilSeconds(decision.RetryAfter), 1)))
+			l.problems.Write(w, r, http.StatusTooManyRequests, problem.CodeRateLimited,
+				"Too many requests", "The rate limit of the route was exceeded, retry after the Retry-After delay")
+			return
+		}
+
+		next.ServeHTTP(w, r)
+	})
+}
+
+// clientIP returns the address of the client, skipping the trusted proxies that appended
+// themselves to X-Forwarded-For. Entries added before them can be forged and are ignored. A header
+// with fewer entries than trusted hops did not come through every proxy, and the peer is used.
+func clientIP(remoteAddr string, forwardedFor []string, trustedHops int) string {
+	remoteIP, _, err := net.SplitHostPort(remoteAddr)
+	if err != nil {
+		remoteIP = remoteAddr
+	}
+	if trustedHops <= 0 {
+		return remoteIP
+	}
+
+	var hops []string
+	for _, header := range forwardedFor {
+		for _, hop := range strings.Split(header, ",") {
+			if hop = strings.TrimSpace(hop); hop != "" {
+				hops = append(hops, hop)
+			}
+		}
+	}
+
+	// The last trusted proxy is the peer, so the client is trustedHops entries from the end
+	index := len(hops) - trustedHops
+	if index < 0 || index >= len(hops) {
+		return remoteIP
+	}
+	if ip := net.ParseIP(hops[index]); ip != nil {
+		return ip.String()
+	}
+	return remoteIP
+}
+
+func ceilSeconds(d time.Duration) int {
+	return int(math.Ceil(d.Seconds()))
+}
@@ -0,0 +1,239 @@
+package middlewares
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/go-chi/chi"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xavierpms/weather-by-city/api/openapi"
+	"github.com/xavierpms/weather-by-city/internal/infra/ratelimit"
+	"github.com/xavierpms/weather-by-city/internal/infra/repository"
+	"github.com/xavierpms/weather-by-city/internal/infra/validator"
+	"github.com/xavierpms/weather-by-city/internal/infra/webhook"
+	"github.com/xavierpms/weather-by-city/internal/infra/webserver/handlers"
+	"github.com/xavierpms/weather-by-city/internal/infra/webserver/problem"
+	"github.com/xavierpms/weather-by-city/internal/infra/webserver/render"
+	"github.com/xavierpms/weather-by-city/internal/usecase"
+)
+
+// MockRateLimitObserver is a mock of the RateLimitObserver for testing
+type MockRateLimitObserver struct {
+	limited []string
+}
+
+func (m *MockRateLimitObserver) ObserveRateLimit(route string, allowed bool) {
+	if !allowed {
+		m.limited = append(m.limited, route)
+	}
+}
+
+// newRateLimitedRouter mounts the temperature handler behind the validator and a rate limiter
+func newRateLimitedRouter(t *testing.T, options RateLimitOptions) (http.Handler, *MockRateLimitObserver, *[]string) {
+	problems := problem.NewWriter(true)
+	v, err := NewOpenAPIValidator(openapi.Spec, true, problems)
+	require.NoError(t, err)
+	mismatches := []string{}
+	v.onResponseError = func(r *http.Request, status int, err error) {
+		mismatches = append(mismatches, r.Method+" "+r.URL.Path+": "+err.Error())
+	}
+
+	observer := &MockRateLimitObserver{}
+	limiter := NewRateLimiter(ratelimit.NewMemoryStore(), options, problems, observer)
+	temperatureHandler := handlers.NewTemperatureHandler(
+		&MockTemperatureUseCase{},
+		usecase.NewGetTemperaturesByCEPs(&MockTemperatureUseCase{}, 10, 2),
+		problems,
+		render.NewDefaultRegistry(),
+	)
+	alertHandler := handlers.NewAlertHandler(
+		usecase.NewManageAlertRules(repository.NewAlertRuleRepository(), validator.NewCEPValidator(), 10),
+		webhook.NewDispatcher("", 1, time.Millisecond, 10),
+		problems,
+	)
+
+	router := chi.NewRouter()
+	router.Use(v.Handler)
+	router.Group(func(r chi.Router) {
+		r.Use(limiter.Handler)
+		r.Post("/v1/temperatures/batch", temperatureHandler.GetTemperaturesByCEPs)
+		r.Get("/v1/alerts/rules", alertHandler.ListRules)
+		r.Get("/{cep}", temperatureHandler.GetTemperatureByCEP)
+	})
+
+	return router, observer, &mismatches
+}
+
+// TestRateLimiterRejectsOverLimit tests the 429 response and the rate limit headers
+func TestRateLimiterRejectsOverLimit(t *testing.T) {
+	// Arrange
+	router, observer, mismatches := newRateLimitedRouter(t, RateLimitOptions{
+		Default: ratelimit.Limit{Requests: 2, Period: time.Minute},
+	})
+
+	// Act
+	responses := make([]*httptest.ResponseRecorder, 3)
+	for i := range responses {
+		responses[i] = httptest.NewRecorder()
+		router.ServeHTTP(responses[i], httptest.NewRequest(http.MethodGet, "/01001000", nil))
+	}
+
+	// Assert
+	assert.Equal(t, http.StatusOK, responses[0].Code)
+	assert.Equal(t, "2", responses[0].Header().Get("RateLimit-Limit"))
+	assert.Equal(t, "1", responses[0].Header().Get("RateLimit-Remaining"))
+	assert.Equal(t, "2;w=60", responses[0].Header().Get("RateLimit-Policy"))
+
+	limited := responses[2]
+	assert.Equal(t, http.StatusTooManyRequests, limited.Code)
+	assert.Equal(t, "30", limited.Header().Get("Retry-After"))
+	assert.Equal(t, "0", limited.Header().Get("RateLimit-Remaining"))
+	assert.Contains(t, limited.Body.String(), `"code":"rate_limited"`)
+	assert.Equal(t, []string{"/{cep}"}, observer.limited)
+	assert.Empty(t, *mismatches)
+}
+
+// TestRateLimiterAppliesRouteLimits tests that routes and clients have separate buckets
+func TestRateLimiterAppliesRouteLimits(t *testing.T) {
+	// Arrange
+	router, _, _ := newRateLimitedRouter(t, RateLimitOptions{
+		Default: ratelimit.Limit{Requests: 1, Period: time.Minute},
+		Routes:  map[string]ratelimit.Limit{"/{cep}": {Requests: 5, Period: time.Minute}},
+	})
+	request := func(remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodGet, "/01001000", nil)
+		req.RemoteAddr = remoteAddr
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Act
+	var statuses []int
+	for range 6 {
+		statuses = append(statuses, request("10.0.0.1:1234"))
+	}
+	otherClient := request("10.0.0.2:1234")
+
+	// Assert
+	assert.Equal(t, []int{200, 200, 200, 200, 200, 429}, statuses)
+	assert.Equal(t, http.StatusOK, otherClient)
+}
+
+// TestRateLimiterAppliesAlertRouteLimits tests that a limit set for an alert route applies to it
+func TestRateLimiterAppliesAlertRouteLimits(t *testing.T) {
+	// Arrange
+	router, observer, mismatches := newRateLimitedRouter(t, RateLimitOptions{
+		Default: ratelimit.Limit{Requests: 5, Period: time.Minute},
+		Routes:  map[string]ratelimit.Limit{"/v1/alerts/rules": {Requests: 1, Period: time.Minute}},
+	})
+	request := func(path string) int {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Code
+	}
+
+	// Act
+	first := request("/v1/alerts/rules")
+	second := request("/v1/alerts/rules")
+	temperature := request("/01001000")
+
+	// Assert
+	assert.Equal(t, http.StatusOK, first)
+	assert.Equal(t, http.StatusTooManyRequests, second)
+	assert.Equal(t, http.StatusOK, temperature)
+	assert.Equal(t, []string{"/v1/alerts/rules"}, observer.limited)
+	assert.Empty(t, *mismatches)
+}
+
+// TestRateLimiterSetOptions tests that new limits apply to the next requests of the existing buckets
+func TestRateLimiterSetOptions(t *testing.T) {
+	// Arrange
+	limiter := NewRateLimiter(ratelimit.NewMemoryStore(), RateLimitOptions{
+		Default: ratelimit.Limit{Requests: 10, Period: time.Minute},
+	}, problem.NewWriter(true), &MockRateLimitObserver{})
+	router := chi.NewRouter()
+	router.Group(func(r chi.Router) {
+		r.Use(limiter.Handler)
+		r.Get("/{cep}", func(w http.ResponseWriter, r *http.Request) {})
+	})
+	request := func() *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/01001000", nil))
+		return w
+	}
+
+	// Act
+	before := request()
+	limiter.SetOptions(RateLimitOptions{
+		Routes: map[string]ratelimit.Limit{"/{cep}": {Requests: 1, Period: time.Minute}},
+	})
+	lowered := request()
+	limited := request()
+
+	// Assert
+	assert.Equal(t, "10", before.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, http.StatusOK, lowered.Code)
+	assert.Equal(t, "1", lowered.Header().Get("RateLimit-Limit"))
+	assert.Equal(t, http.StatusTooManyRequests, limited.Code)
+}
+
+// TestRateLimiterLimitsAPIKeys tests that an API key has its own bucket on top of the IP bucket
+func TestRateLimiterLimitsAPIKeys(t *testing.T) {
+	// Arrange
+	router, _, _ := newRateLimitedRouter(t, RateLimitOptions{
+		Default: ratelimit.Limit{Requests: 1, Period: time.Minute},
+	})
+	request := func(remoteAddr string) int {
+		req := httptest.NewRequest(http.MethodGet, "/01001000", nil)
+		req.RemoteAddr = remoteAddr
+		req.Header.Set(APIKeyHeader, "partner-key")
+		w := httptest.NewRecorder()
+		router.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	// Act
+	first := request("10.0.0.1:1234")
+	fromOtherIP := request("10.0.0.2:1234")
+
+	// Assert
+	assert.Equal(t, http.StatusOK, first)
+	assert.Equal(t, http.StatusTooManyRequests, fromOtherIP)
+}
+
+// TestClientIP tests that only the trusted X-Forwarded-For hops are honored
+func TestClientIP(t *testing.T) {
+	testCases := []struct {
+		name         string
+		forwardedFor string
+		trustedHops  int
+		expectedIP   string
+	}{
+		{"untrusted header", "203.0.113.7", 0, "10.0.0.1"},
+		{"one trusted hop", "203.0.113.7", 1, "203.0.113.7"},
+		{"forged entry", "198.51.100.1, 203.0.113.7", 1, "203.0.113.7"},
+		{"two trusted hops", "203.0.113.7, 10.1.1.1", 2, "203.0.113.7"},
+		{"fewer entries than trusted hops", "203.0.113.7", 2, "10.0.0.1"},
+		{"missing header", "", 1, "10.0.0.1"},
+		{"invalid entry", "not-an-ip", 1, "10.0.0.1"},
+	}
+
+	for _, tc := range testCases {
+		// Arrange
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.RemoteAddr = "10.0.0.1:1234"
+		if tc.forwardedFor != "" {
+			req.Header.Set("X-Forwarded-For", tc.forwardedFor)
+		}
+
+		// Act
+		ip := clientIP(req.RemoteAddr, req.Header.Values("X-Forwarded-For"), tc.trustedHops)
+
+		// Assert
+		assert.Equal(t, tc.expectedIP, ip, tc.name)
+	}
+}
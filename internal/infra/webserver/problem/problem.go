@@ -0,0 +1,90 @@
+package problem
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/go-chi/chi/middleware"
+)
+
+// ContentType is the media type of RFC 7807 error responses
+const ContentType = "application/problem+json"
+
+// typePrefix builds the problem type URI from the stable code
+const typePrefix = "urn:weather-by-city:problem:"
+
+// Code is a stable, machine-readable error code
+type Code string
+
+const (
+	CodeInvalidRequest         Code = "invalid_request"
+	CodeNotAcceptable          Code = "not_acceptable"
+	CodeInvalidZipcode         Code = "invalid_zipcode"
+	CodeZipcodeNotFound        Code = "zipcode_not_found"
+	CodeTemperatureUnavailable Code = "temperature_unavailable"
+	CodeUpstreamUnavailable    Code = "upstream_unavailable"
+	CodeUpstreamQuotaExceeded  Code = "upstream_quota_exceeded"
+	CodeRateLimited            Code = "rate_limited"
+	CodeUnauthorized           Code = "unauthorized"
+	CodeForbidden              Code = "forbidden"
+	CodeQuotaExceeded          Code = "quota_exceeded"
+	CodeInvalidAPIKey          Code = "invalid_api_key"
+	CodeAPIKeyNotFound         Code = "api_key_not_found"
+	CodeInvalidBatch           Code = "invalid_batch"
+	CodeInvalidAlertRule       Code = "invalid_alert_rule"
+	CodeAlertRuleNotFound      Code = "alert_rule_not_found"
+	CodeAlertRuleLimitReached  Code = "alert_rule_limit_reached"
+	CodeDeadLetterNotFound     Code = "dead_letter_not_found"
+	CodeDeadLetterReplaying    Code = "dead_letter_replay_in_progress"
+	CodeWebhookDeliveryFailed  Code = "webhook_delivery_failed"
+	CodeInvalidConfig          Code = "invalid_config"
+	CodeInternalError          Code = "internal_error"
+)
+
+// Problem represents an RFC 7807 problem details object
+type Problem struct {
+	Type      string `json:"type"`
+	Title     string `json:"title"`
+	Status    int    `json:"status"`
+	Detail    string `json:"detail,omitempty"`
+	Instance  string `json:"instance,omitempty"`
+	Code      Code   `json:"code"`
+	RequestID string `json:"request_id,omitempty"`
+	// Reason refines the code with why the input was refused, such as wrong_length for invalid_zipcode
+	Reason string `json:"reason,omitempty"`
+
+	// Message repeats the title for consumers of the former {message} error body
+	Message string `json:"message,omitempty"`
+}
+
+// Writer writes problem responses
+type Writer struct {
+	compat bool
+}
+
+// NewWriter creates a new problem writer. In compatibility mode the responses
+// also carry the legacy message field.
+func NewWriter(compat bool) *Writer {
+	return &Writer{
+		compat: compat,
+	}
+}
+
+// Write sends a problem response for the request
+func (pw *Writer) Write(w http.ResponseWriter, r *http.Request, status int, code Code, title, detail string) {
+	pw.WriteProblem(w, r, Problem{Status: status, Code: code, Title: title, Detail: detail})
+}
+
+// WriteProblem sends the problem, filling in its type, instance, request ID and legacy message
+func (pw *Writer) WriteProblem(w http.ResponseWriter, r *http.Request, p Problem) {
+	p.Type = typePrefix + string(p.Code)
+	p.Instance = r.URL.RequestURI()
+	p.RequestID = middleware.GetReqID(r.Context())
+	if pw.compat {
+		p.Message = p.Title
+	}
+
+	w.Header().Set("Content-Type", ContentType)
+	w.WriteHeader(p.Status)
+	json.NewEncoder(w).Encode(p)
+}
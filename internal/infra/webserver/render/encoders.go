@@ -0,0 +1,112 @@
+package render
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"encoding/xml"
+	"io"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+// Encoder writes a value in one representation
+type Encoder interface {
+	// Formats are the values accepted by the format query parameter
+	Formats() []string
+	// MediaTypes are the media types matched against the Accept header, the first one is canonical
+	MediaTypes() []string
+	// ContentType is the Content-Type header of the response
+	ContentType() string
+	Encode(w io.Writer, value interface{}) error
+}
+
+// Tabular is implemented by values that can be written as rows
+type Tabular interface {
+	Header() []string
+	Rows() [][]string
+}
+
+// Texter is implemented by values that have a compact human-readable form
+type Texter interface {
+	Text() string
+}
+
+// JSONEncoder encodes values as JSON
+type JSONEncoder struct{}
+
+func (JSONEncoder) Formats() []string    { return []string{"json"} }
+func (JSONEncoder) MediaTypes() []string { return []string{"application/json"} }
+func (JSONEncoder) ContentType() string  { return "application/json" }
+
+func (JSONEncoder) Encode(w io.Writer, value interface{}) error {
+	return json.NewEncoder(w).Encode(value)
+}
+
+// XMLEncoder encodes values as XML
+type XMLEncoder struct{}
+
+func (XMLEncoder) Formats() []string    { return []string{"xml"} }
+func (XMLEncoder) MediaTypes() []string { return []string{"application/xml", "text/xml"} }
+func (XMLEncoder) ContentType() string  { return "application/xml; charset=utf-8" }
+
+func (XMLEncoder) Encode(w io.Writer, value interface{}) error {
+	if _, err := io.WriteString(w, xml.Header); err != nil {
+		return err
+	}
+
+	return xml.NewEncoder(w).Encode(value)
+}
+
+// CSVEncoder encodes tabular values as CSV with a header row
+type CSVEncoder struct{}
+
+func (CSVEncoder) Formats() []string    { return []string{"csv"} }
+func (CSVEncoder) MediaTypes() []string { return []string{"text/csv"} }
+func (CSVEncoder) ContentType() string  { return "text/csv; charset=utf-8" }
+
+func (CSVEncoder) Encode(w io.Writer, value interface{}) error {
+	tabular, ok := value.(Tabular)
+	if !ok {
+		return ErrUnsupportedPayload
+	}
+
+	writer := csv.NewWriter(w)
+	if err := writer.Write(tabular.Header()); err != nil {
+		return err
+	}
+	if err := writer.WriteAll(tabular.Rows()); err != nil {
+		return err
+	}
+
+	return writer.Error()
+}
+
+// TextEncoder encodes values in their one-line text form
+type TextEncoder struct{}
+
+func (TextEncoder) Formats() []string    { return []string{"text", "txt"} }
+func (TextEncoder) MediaTypes() []string { return []string{"text/plain"} }
+func (TextEncoder) ContentType() string  { return "text/plain; charset=utf-8" }
+
+func (TextEncoder) Encode(w io.Writer, value interface{}) error {
+	texter, ok := value.(Texter)
+	if !ok {
+		return ErrUnsupportedPayload
+	}
+
+	_, err := io.WriteString(w, texter.Text()+"\n")
+	return err
+}
+
+// MessagePackEncoder encodes values as MessagePack
+type MessagePackEncoder struct{}
+
+func (MessagePackEncoder) Formats() []string { return []string{"msgpack"} }
+func (MessagePackEncoder) MediaTypes() []string {
+	return []string{"application/msgpack", "application/x-msgpack", "application/vnd.msgpack"}
+}
+func (MessagePackEncoder) ContentType() string { return "application/msgpack" }
+
+func (MessagePackEncoder) Encode(w io.Writer, value interface{}) error {
+	return msgpack.NewEncoder(w).Encode(value)
+}
@@ -0,0 +1,138 @@
+package render
+
+import (
+	"errors"
+	"mime"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// ErrUnsupportedPayload is returned when an encoder cannot represent a value
+var ErrUnsupportedPayload = errors.New("payload not supported by the encoder")
+
+// Registry selects an encoder from the format query parameter or the Accept header
+type Registry struct {
+	encoders []Encoder
+	byFormat map[string]Encoder
+	byType   map[string]Encoder
+}
+
+// NewRegistry creates a registry. The first encoder is used when the client has no preference.
+func NewRegistry(encoders ...Encoder) *Registry {
+	r := &Registry{
+		encoders: encoders,
+		byFormat: make(map[string]Encoder),
+		byType:   make(map[string]Encoder),
+	}
+
+	for _, encoder := range encoders {
+		for _, format := range encoder.Formats() {
+			r.byFormat[format] = encoder
+		}
+		for _, mediaType := range encoder.MediaTypes() {
+			r.byType[mediaType] = encoder
+		}
+	}
+
+	return r
+}
+
+// NewDefaultRegistry creates a registry with JSON, XML, CSV, plain text and MessagePack encoders
+func NewDefaultRegistry() *Registry {
+	return NewRegistry(JSONEncoder{}, XMLEncoder{}, CSVEncoder{}, TextEncoder{}, MessagePackEncoder{})
+}
+
+// Negotiate returns the encoder for the request, or false when none is acceptable
+func (r *Registry) Negotiate(req *http.Request) (Encoder, bool) {
+	if format := strings.ToLower(req.URL.Query().Get("format")); format != "" {
+		encoder, ok := r.byFormat[format]
+		return encoder, ok
+	}
+
+	accept := req.Header.Get("Accept")
+	if strings.TrimSpace(accept) == "" {
+		return r.encoders[0], true
+	}
+
+	for _, mediaRange := range parseAccept(accept) {
+		switch {
+		case mediaRange == "*/*":
+			return r.encoders[0], true
+
+		case strings.HasSuffix(mediaRange, "/*"):
+			prefix := strings.TrimSuffix(mediaRange, "*")
+			for _, encoder := range r.encoders {
+				if strings.HasPrefix(encoder.MediaTypes()[0], prefix) {
+					return encoder, true
+				}
+			}
+
+		default:
+			if encoder, ok := r.byType[mediaRange]; ok {
+				return encoder, true
+			}
+		}
+	}
+
+	return nil, false
+}
+
+// MediaTypes returns the media types that can be negotiated, in preference order
+func (r *Registry) MediaTypes() []string {
+	mediaTypes := make([]string, 0, len(r.encoders))
+	for _, encoder := range r.encoders {
+		mediaTypes = append(mediaTypes, encoder.MediaTypes()[0])
+	}
+
+	return mediaTypes
+}
+
+// Write encodes the value with the encoder and sends it with the given status
+func Write(w http.ResponseWriter, status int, encoder Encoder, value interface{}) error {
+	w.Header().Set("Content-Type", encoder.ContentType())
+	w.WriteHeader(status)
+
+	return encoder.Encode(w, value)
+}
+
+// parseAccept returns the media ranges of an Accept header ordered by quality,
+// dropping the ranges the client refuses with q=0
+func parseAccept(header string) []string {
+	type mediaRange struct {
+		value   string
+		quality float64
+	}
+
+	ranges := []mediaRange{}
+	for _, part := range strings.Split(header, ",") {
+		mediaType, params, err := mime.ParseMediaType(strings.TrimSpace(part))
+		if err != nil {
+			continue
+		}
+
+		quality := 1.0
+		if q, ok := params["q"]; ok {
+			if parsed, err := strconv.ParseFloat(q, 64); err == nil {
+				quality = parsed
+			}
+		}
+		if quality <= 0 {
+			continue
+		}
+
+		ranges = append(ranges, mediaRange{value: mediaType, quality: quality})
+	}
+
+	sort.SliceStable(ranges, func(i, j int) bool {
+		return ranges[i].quality > ranges[j].quality
+	})
+
+	values := make([]string, 0, len(ranges))
+	for _, r := range ranges {
+		values = append(values, r.value)
+	}
+
+	return values
+}
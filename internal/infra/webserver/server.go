@@ -0,0 +1,106 @@
+package webserver
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Timeouts bound the phases of an HTTP request. Zero disables the timeout.
+type Timeouts struct {
+	ReadHeader time.Duration
+	Read       time.Duration
+	Write      time.Duration
+	Idle       time.Duration
+}
+
+// ShutdownHook stops a background component, giving up when the context expires
+type ShutdownHook func(ctx context.Context) error
+
+// Shutdown orders the stop of the components around the HTTP server
+type Shutdown struct {
+	// Timeout bounds the drain of the requests along with the Stop hooks, then the Drain hooks
+	Timeout time.Duration
+	// Stop hooks run concurrently with the drain of the requests, for servers whose long-lived
+	// streams would hold the drain until the timeout, such as gRPC served on the same port
+	Stop []ShutdownHook
+	// Drain hooks run in order once the requests are drained, such as the background workers
+	Drain []ShutdownHook
+	// FlushTimeout bounds the Flush hooks, apart from Timeout so a slow drain cannot starve them
+	FlushTimeout time.Duration
+	// Flush hooks run in order last, such as the exporters of the telemetry and usage counts
+	Flush []ShutdownHook
+}
+
+// NewServer creates an HTTP server with the given timeouts
+func NewServer(addr string, handler http.Handler, protocols *http.Protocols, timeouts Timeouts) *http.Server {
+	return &http.Server{
+		Addr:              addr,
+		Handler:           handler,
+		Protocols:         protocols,
+		ReadHeaderTimeout: timeouts.ReadHeader,
+		ReadTimeout:       timeouts.Read,
+		WriteTimeout:      timeouts.Write,
+		IdleTimeout:       timeouts.Idle,
+	}
+}
+
+// Serve serves on the listener until ctx is cancelled. It then stops accepting connections and
+// waits for the in-flight requests while running the Stop hooks, runs the Drain hooks, all within
+// the shutdown timeout, and finally the Flush hooks within the flush timeout.
+func Serve(ctx context.Context, server *http.Server, listener net.Listener, shutdown Shutdown) error {
+	serveErr := make(chan error, 1)
+	go func() {
+		serveErr <- server.Serve(listener)
+	}()
+
+	select {
+	case err := <-serveErr:
+		return err
+	case <-ctx.Done():
+	}
+	slog.Info("Shutting down", "timeout", shutdown.Timeout, "flush_timeout", shutdown.FlushTimeout)
+
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdown.Timeout)
+	defer cancel()
+
+	var stopping sync.WaitGroup
+	stopErrs := make([]error, len(shutdown.Stop))
+	for i, hook := range shutdown.Stop {
+		stopping.Go(func() { stopErrs[i] = hook(shutdownCtx) })
+	}
+
+	var errs []error
+	if err := server.Shutdown(shutdownCtx); err != nil {
+		// The deadline expired with requests still running, drop their connections
+		errs = append(errs, err, server.Close())
+	}
+	if err := <-serveErr; !errors.Is(err, http.ErrServerClosed) {
+		errs = append(errs, err)
+	}
+	stopping.Wait()
+	errs = append(errs, stopErrs...)
+
+	errs = append(errs, runHooks(shutdownCtx, shutdown.Drain)...)
+
+	flushCtx, cancelFlush := context.WithTimeout(context.Background(), shutdown.FlushTimeout)
+	defer cancelFlush()
+	errs = append(errs, runHooks(flushCtx, shutdown.Flush)...)
+
+	return errors.Join(errs...)
+}
+
+// runHooks runs the hooks in order and returns their errors
+func runHooks(ctx context.Context, hooks []ShutdownHook) []error {
+	var errs []error
+	for _, hook := range hooks {
+		if err := hook(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
@@ -0,0 +1,179 @@
+package webserver
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// TestServeDrainsInFlightRequests tests that a request in progress when shutdown starts still completes
+func TestServeDrainsInFlightRequests(t *testing.T) {
+	// Arrange
+	started := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		time.Sleep(200 * time.Millisecond)
+		io.WriteString(w, "done")
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	server := NewServer(listener.Addr().String(), handler, nil, Timeouts{ReadHeader: time.Second})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var hookCalled bool
+	served := make(chan error, 1)
+	go func() {
+		served <- Serve(ctx, server, listener, Shutdown{
+			Timeout: 5 * time.Second,
+			Drain: []ShutdownHook{func(ctx context.Context) error {
+				hookCalled = true
+				return nil
+			}},
+			FlushTimeout: time.Second,
+		})
+	}()
+
+	type response struct {
+		body string
+		err  error
+	}
+	responses := make(chan response, 1)
+	go func() {
+		resp, err := http.Get("http://" + listener.Addr().String())
+		if err != nil {
+			responses <- response{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		responses <- response{body: string(body), err: err}
+	}()
+	<-started
+
+	// Act
+	cancel()
+
+	// Assert
+	inFlight := <-responses
+	assert.NoError(t, inFlight.err)
+	assert.Equal(t, "done", inFlight.body)
+
+	assert.NoError(t, <-served)
+	assert.True(t, hookCalled)
+
+	_, err = http.Get("http://" + listener.Addr().String())
+	assert.Error(t, err)
+}
+
+// TestServeAbortsAfterShutdownTimeout tests that shutdown gives up on requests that outlive the deadline
+func TestServeAbortsAfterShutdownTimeout(t *testing.T) {
+	// Arrange
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	server := NewServer(listener.Addr().String(), handler, nil, Timeouts{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- Serve(ctx, server, listener, Shutdown{Timeout: 50 * time.Millisecond, FlushTimeout: time.Second})
+	}()
+	go http.Get("http://" + listener.Addr().String())
+	<-started
+
+	// Act
+	cancel()
+
+	// Assert
+	assert.ErrorIs(t, <-served, context.DeadlineExceeded)
+}
+
+// TestServeRunsTheStopHooksDuringTheDrain tests that the stop hooks end the streams the drain would otherwise wait for
+func TestServeRunsTheStopHooksDuringTheDrain(t *testing.T) {
+	// Arrange
+	started := make(chan struct{})
+	streamsStopped := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-streamsStopped
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	server := NewServer(listener.Addr().String(), handler, nil, Timeouts{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	served := make(chan error, 1)
+	go func() {
+		served <- Serve(ctx, server, listener, Shutdown{
+			Timeout: 5 * time.Second,
+			Stop: []ShutdownHook{func(ctx context.Context) error {
+				close(streamsStopped)
+				return nil
+			}},
+			FlushTimeout: time.Second,
+		})
+	}()
+	go http.Get("http://" + listener.Addr().String())
+	<-started
+
+	// Act
+	start := time.Now()
+	cancel()
+
+	// Assert
+	assert.NoError(t, <-served)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+// TestServeGivesTheFlushHooksTheirOwnBudget tests that the flush hooks still run with time left after the drain timed out
+func TestServeGivesTheFlushHooksTheirOwnBudget(t *testing.T) {
+	// Arrange
+	started := make(chan struct{})
+	release := make(chan struct{})
+	defer close(release)
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		close(started)
+		<-release
+	})
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	server := NewServer(listener.Addr().String(), handler, nil, Timeouts{})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	var flushErr error
+	served := make(chan error, 1)
+	go func() {
+		served <- Serve(ctx, server, listener, Shutdown{
+			Timeout:      50 * time.Millisecond,
+			FlushTimeout: time.Second,
+			Flush: []ShutdownHook{func(ctx context.Context) error {
+				flushErr = ctx.Err()
+				return nil
+			}},
+		})
+	}()
+	go http.Get("http://" + listener.Addr().String())
+	<-started
+
+	// Act
+	cancel()
+
+	// Assert
+	assert.ErrorIs(t, <-served, context.DeadlineExceeded)
+	assert.NoError(t, flushErr)
+}
@@ -0,0 +1,137 @@
+package usecase
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/xavierpms/weather-by-city/internal/domain"
+)
+
+// EvaluateAlertRules represents the use case for checking alert rules against the current temperature
+type EvaluateAlertRules struct {
+	ruleRepository     domain.AlertRuleRepository
+	temperatureUseCase domain.TemperatureUseCase
+	dispatcher         domain.WebhookDispatcher
+
+	mu     sync.Mutex
+	states map[string]domain.AlertState
+}
+
+// NewEvaluateAlertRules creates a new instance of the use case
+func NewEvaluateAlertRules(
+	ruleRepo domain.AlertRuleRepository,
+	temperatureUseCase domain.TemperatureUseCase,
+	dispatcher domain.WebhookDispatcher,
+) *EvaluateAlertRules {
+	return &EvaluateAlertRules{
+		ruleRepository:     ruleRepo,
+		temperatureUseCase: temperatureUseCase,
+		dispatcher:         dispatcher,
+		states:             make(map[string]domain.AlertState),
+	}
+}
+
+// Evaluate checks every rule and dispatches an event for each threshold crossing
+func (u *EvaluateAlertRules) Evaluate(ctx context.Context) error {
+	rules, err := u.ruleRepository.List()
+	if err != nil {
+		return err
+	}
+
+	// Fetch the temperature once per CEP, even if several rules watch it
+	temperatures := make(map[string]*domain.Temperature)
+	for _, rule := range rules {
+		if _, fetched := temperatures[rule.CEP]; fetched {
+			continue
+		}
+
+		temperature, err := u.temperatureUseCase.GetTemperatureByCEP(ctx, rule.CEP)
+		if err != nil {
+			slog.WarnContext(ctx, "alert evaluation skipped", "cep", rule.CEP, "err", err)
+		}
+		temperatures[rule.CEP] = temperature
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	active := make(map[string]bool, len(rules))
+	for _, rule := range rules {
+		active[rule.ID] = true
+
+		temperature := temperatures[rule.CEP]
+		if temperature == nil {
+			continue
+		}
+
+		previous, known := u.states[rule.ID]
+		state := nextAlertState(rule, previous, temperature.Celsius)
+		u.states[rule.ID] = state
+
+		// The first evaluation only notifies when the temperature is already out of bounds
+		if state == previous || (!known && state == domain.AlertStateNormal) {
+			continue
+		}
+		if !known {
+			previous = domain.AlertStateNormal
+		}
+
+		event := &domain.AlertEvent{
+			RuleID:      rule.ID,
+			CEP:         rule.CEP,
+			State:       state,
+			Previous:    previous,
+			Threshold:   alertThreshold(rule, state, previous),
+			Temperature: *temperature,
+			OccurredAt:  time.Now().UTC(),
+		}
+		if err := u.dispatcher.Dispatch(rule, event); err != nil {
+			slog.ErrorContext(ctx, "alert dispatch error", "rule", rule.ID, "err", err)
+		}
+	}
+
+	// Forget the state of rules that were deleted
+	for id := range u.states {
+		if !active[id] {
+			delete(u.states, id)
+		}
+	}
+
+	return nil
+}
+
+// nextAlertState computes the state of a rule, keeping a crossed state until the
+// temperature moves back past the threshold by more than the hysteresis margin
+func nextAlertState(rule *domain.AlertRule, previous domain.AlertState, celsius float64) domain.AlertState {
+	if rule.Above != nil {
+		if celsius > *rule.Above || (previous == domain.AlertStateAbove && celsius > *rule.Above-rule.Hysteresis) {
+			return domain.AlertStateAbove
+		}
+	}
+
+	if rule.Below != nil {
+		if celsius < *rule.Below || (previous == domain.AlertStateBelow && celsius < *rule.Below+rule.Hysteresis) {
+			return domain.AlertStateBelow
+		}
+	}
+
+	return domain.AlertStateNormal
+}
+
+// alertThreshold returns the threshold involved in a transition
+func alertThreshold(rule *domain.AlertRule, state, previous domain.AlertState) *float64 {
+	if state == domain.AlertStateNormal {
+		state = previous
+	}
+
+	switch state {
+	case domain.AlertStateAbove:
+		return rule.Above
+	case domain.AlertStateBelow:
+		return rule.Below
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,129 @@
+package usecase
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/xavierpms/weather-by-city/internal/domain"
+	"github.com/xavierpms/weather-by-city/internal/infra/repository"
+)
+
+// MockTemperatureUseCase is a mock of the TemperatureUseCase for testing
+type MockTemperatureUseCase struct {
+	getTemperatureByCEPFunc func(cep string) (*domain.Temperature, error)
+}
+
+func (m *MockTemperatureUseCase) GetTemperatureByCEP(ctx context.Context, cep string) (*domain.Temperature, error) {
+	return m.getTemperatureByCEPFunc(cep)
+}
+
+// MockWebhookDispatcher records the dispatched events
+type MockWebhookDispatcher struct {
+	events []*domain.AlertEvent
+}
+
+func (m *MockWebhookDispatcher) Dispatch(rule *domain.AlertRule, event *domain.AlertEvent) error {
+	m.events = append(m.events, event)
+	return nil
+}
+
+func (m *MockWebhookDispatcher) DeadLetters() []*domain.WebhookDelivery {
+	return nil
+}
+
+func (m *MockWebhookDispatcher) Replay(id string) error {
+	return domain.ErrDeadLetterNotFound
+}
+
+func float(v float64) *float64 {
+	return &v
+}
+
+// TestEvaluateAlertRulesHysteresis tests that crossings are notified once and recover only past the margin
+func TestEvaluateAlertRulesHysteresis(t *testing.T) {
+	// Arrange
+	ruleRepo := repository.NewAlertRuleRepository()
+	_ = ruleRepo.Create(&domain.AlertRule{ID: "rule-1", CEP: "01001000", Above: float(35), Below: float(5), Hysteresis: 1})
+
+	celsius := 30.0
+	mockUseCase := &MockTemperatureUseCase{
+		getTemperatureByCEPFunc: func(cep string) (*domain.Temperature, error) {
+			return &domain.Temperature{Celsius: celsius}, nil
+		},
+	}
+	dispatcher := &MockWebhookDispatcher{}
+	evaluator := NewEvaluateAlertRules(ruleRepo, mockUseCase, dispatcher)
+
+	// Act & Assert
+	steps := []struct {
+		celsius float64
+		events  int
+		state   domain.AlertState
+	}{
+		{30, 0, ""},                       // First reading within bounds is not notified
+		{35.5, 1, domain.AlertStateAbove}, // Crossed above
+		{34.5, 1, ""},                     // Still within the hysteresis margin
+		{36, 1, ""},                       // Flapping around the threshold is not notified
+		{33.9, 2, domain.AlertStateNormal},
+		{4, 3, domain.AlertStateBelow},
+		{5.5, 3, ""},
+		{6.1, 4, domain.AlertStateNormal},
+	}
+
+	for _, step := range steps {
+		celsius = step.celsius
+		assert.NoError(t, evaluator.Evaluate(context.Background()))
+		assert.Len(t, dispatcher.events, step.events, "at %.1f°C", step.celsius)
+		if step.state != "" {
+			assert.Equal(t, step.state, dispatcher.events[len(dispatcher.events)-1].State)
+		}
+	}
+}
+
+// TestEvaluateAlertRulesFirstReadingOutOfBounds tests that a rule already out of bounds is notified
+func TestEvaluateAlertRulesFirstReadingOutOfBounds(t *testing.T) {
+	// Arrange
+	ruleRepo := repository.NewAlertRuleRepository()
+	_ = ruleRepo.Create(&domain.AlertRule{ID: "rule-1", CEP: "01001000", Below: float(5)})
+
+	mockUseCase := &MockTemperatureUseCase{
+		getTemperatureByCEPFunc: func(cep string) (*domain.Temperature, error) {
+			return &domain.Temperature{Celsius: 2}, nil
+		},
+	}
+	dispatcher := &MockWebhookDispatcher{}
+	evaluator := NewEvaluateAlertRules(ruleRepo, mockUseCase, dispatcher)
+
+	// Act
+	err := evaluator.Evaluate(context.Background())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Len(t, dispatcher.events, 1)
+	assert.Equal(t, domain.AlertStateBelow, dispatcher.events[0].State)
+	assert.Equal(t, domain.AlertStateNormal, dispatcher.events[0].Previous)
+	assert.Equal(t, 5.0, *dispatcher.events[0].Threshold)
+}
+
+// TestEvaluateAlertRulesSkipsFailedFetches tests that a failing CEP does not produce events
+func TestEvaluateAlertRulesSkipsFailedFetches(t *testing.T) {
+	// Arrange
+	ruleRepo := repository.NewAlertRuleRepository()
+	_ = ruleRepo.Create(&domain.AlertRule{ID: "rule-1", CEP: "99999999", Above: float(35)})
+
+	mockUseCase := &MockTemperatureUseCase{
+		getTemperatureByCEPFunc: func(cep string) (*domain.Temperature, error) {
+			return nil, domain.ErrCEPNotFound
+		},
+	}
+	dispatcher := &MockWebhookDispatcher{}
+	evaluator := NewEvaluateAlertRules(ruleRepo, mockUseCase, dispatcher)
+
+	// Act
+	err := evaluator.Evaluate(context.Background())
+
+	// Assert
+	assert.NoError(t, err)
+	assert.Empty(t, dispatcher.events)
+}
@@ -1,6 +1,11 @@
 package usecase
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+
 	"github.com/xavierpms/weather-by-city/internal/domain"
 )
 
@@ -25,20 +30,36 @@ func NewGetTemperatureByCEP(
 }
 
 // GetTemperatureByCEP executes the business logic
-func (u *GetTemperatureByCEP) GetTemperatureByCEP(cep string) (*domain.Temperature, error) {
-	// Validate the CEP format
-	if !u.cepValidator.ValidateCEPFormat(cep) {
-		return nil, domain.ErrInvalidCEPFormat
+func (u *GetTemperatureByCEP) GetTemperatureByCEP(ctx context.Context, cep string) (*domain.Temperature, error) {
+	// Normalize the CEP to its 8 digits, refusing the invalid formats
+	cep, err := u.cepValidator.NormalizeCEP(cep)
+	if err != nil {
+		return nil, err
 	}
 
-	// Fetch the CEP data
-	cepData, err := u.cepRepository.GetCEPData(cep)
-	if err != nil {
+	// Fetch the CEP data. Only the provider saying the CEP does not exist is a miss, any other
+	// failure, such as a 5xx, a timeout or an unreadable answer, leaves the CEP unknown.
+	cepData, err := u.cepRepository.GetCEPData(ctx, cep)
+	switch {
+	case err == nil:
+	case errors.Is(err, domain.ErrCEPNotFound):
 		return nil, domain.ErrCEPNotFound
+	case errors.Is(err, domain.ErrUpstreamUnavailable):
+		return nil, err
+	default:
+		return nil, fmt.Errorf("%w: %w", domain.ErrUpstreamUnavailable, err)
+	}
+
+	// The UF of the Correios range should match the one of the provider
+	if uf, ok := u.cepValidator.InferUF(cep); ok && cepData.Region != "" && cepData.Region != uf {
+		slog.WarnContext(ctx, "CEP provider returned a UF outside the Correios range", "cep", cep, "inferred_uf", uf, "provider_uf", cepData.Region)
 	}
 
 	// Fetch the temperature for the city
-	temperature, err := u.temperatureRepository.GetTemperatureByCityName(cepData.City)
+	temperature, err := u.temperatureRepository.GetTemperatureByCityName(ctx, cepData.City)
+	if errors.Is(err, domain.ErrUpstreamUnavailable) {
+		return nil, err
+	}
 	if err != nil {
 		return nil, domain.ErrTemperatureNotFound
 	}
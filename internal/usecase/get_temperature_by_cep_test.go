@@ -0,0 +1,136 @@
+package usecase
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xavierpms/weather-by-city/internal/domain"
+	"github.com/xavierpms/weather-by-city/internal/infra/validator"
+)
+
+// MockCEPRepository is a mock of the CEPRepository for testing
+type MockCEPRepository struct {
+	getCEPDataFunc func(cep string) (*domain.CEPData, error)
+}
+
+func (m *MockCEPRepository) GetCEPData(ctx context.Context, cep string) (*domain.CEPData, error) {
+	return m.getCEPDataFunc(cep)
+}
+
+// MockTemperatureRepository is a mock of the TemperatureRepository for testing
+type MockTemperatureRepository struct {
+	getTemperatureByCityNameFunc func(cityName string) (*domain.Temperature, error)
+}
+
+func (m *MockTemperatureRepository) GetTemperatureByCityName(ctx context.Context, cityName string) (*domain.Temperature, error) {
+	return m.getTemperatureByCityNameFunc(cityName)
+}
+
+// TestGetTemperatureByCEPNormalizesTheCEP tests that the CEP is looked up by its 8 digits
+func TestGetTemperatureByCEPNormalizesTheCEP(t *testing.T) {
+	// Arrange
+	lookedUp := []string{}
+	useCase := NewGetTemperatureByCEP(
+		&MockCEPRepository{getCEPDataFunc: func(cep string) (*domain.CEPData, error) {
+			lookedUp = append(lookedUp, cep)
+			return &domain.CEPData{City: "São Paulo"}, nil
+		}},
+		&MockTemperatureRepository{getTemperatureByCityNameFunc: func(cityName string) (*domain.Temperature, error) {
+			return &domain.Temperature{Celsius: 21}, nil
+		}},
+		validator.NewCEPValidator(),
+	)
+
+	// Act
+	for _, cep := range []string{"01001-000", " 01.001-000 "} {
+		_, err := useCase.GetTemperatureByCEP(context.Background(), cep)
+		require.NoError(t, err)
+	}
+
+	// Assert
+	assert.Equal(t, []string{"01001000", "01001000"}, lookedUp)
+}
+
+// TestGetTemperatureByCEPReportsTheFormatReason tests that a refused CEP is not looked up and carries its reason
+func TestGetTemperatureByCEPReportsTheFormatReason(t *testing.T) {
+	// Arrange
+	useCase := NewGetTemperatureByCEP(
+		&MockCEPRepository{getCEPDataFunc: func(cep string) (*domain.CEPData, error) {
+			t.Fatalf("unexpected lookup of %q", cep)
+			return nil, nil
+		}},
+		&MockTemperatureRepository{},
+		validator.NewCEPValidator(),
+	)
+
+	// Act
+	_, err := useCase.GetTemperatureByCEP(context.Background(), "01001-00")
+
+	// Assert
+	var formatErr *domain.CEPFormatError
+	assert.ErrorIs(t, err, domain.ErrInvalidCEPFormat)
+	require.ErrorAs(t, err, &formatErr)
+	assert.Equal(t, domain.CEPFormatWrongLength, formatErr.Reason)
+	assert.Equal(t, 7, formatErr.Digits)
+}
+
+// TestGetTemperatureByCEPRefusesCEPsOutsideTheRanges tests that an impossible CEP is refused without a ViaCEP round trip
+func TestGetTemperatureByCEPRefusesCEPsOutsideTheRanges(t *testing.T) {
+	// Arrange
+	useCase := NewGetTemperatureByCEP(
+		&MockCEPRepository{getCEPDataFunc: func(cep string) (*domain.CEPData, error) {
+			t.Fatalf("unexpected lookup of %q", cep)
+			return nil, nil
+		}},
+		&MockTemperatureRepository{},
+		validator.NewCEPValidator(),
+	)
+
+	// Act
+	_, err := useCase.GetTemperatureByCEP(context.Background(), "00000-000")
+
+	// Assert
+	var formatErr *domain.CEPFormatError
+	require.ErrorAs(t, err, &formatErr)
+	assert.Equal(t, domain.CEPFormatOutOfRange, formatErr.Reason)
+}
+
+// TestGetTemperatureByCEPOnlyReportsMissesAsNotFound tests that failures to reach the provider are not reported as unknown CEPs
+func TestGetTemperatureByCEPOnlyReportsMissesAsNotFound(t *testing.T) {
+	testCases := []struct {
+		name        string
+		err         error
+		expected    error
+		notExpected error
+	}{
+		{"not found", fmt.Errorf("%w in ViaCEP", domain.ErrCEPNotFound), domain.ErrCEPNotFound, domain.ErrUpstreamUnavailable},
+		{"server error", errors.New("ViaCEP API returned status 502"), domain.ErrUpstreamUnavailable, domain.ErrCEPNotFound},
+		{"timeout", context.DeadlineExceeded, domain.ErrUpstreamUnavailable, domain.ErrCEPNotFound},
+		{"unreadable answer", errors.New("invalid character '<' looking for beginning of value"), domain.ErrUpstreamUnavailable, domain.ErrCEPNotFound},
+		{"open breaker", fmt.Errorf("%w: viacep circuit breaker is open", domain.ErrUpstreamUnavailable), domain.ErrUpstreamUnavailable, domain.ErrCEPNotFound},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			useCase := NewGetTemperatureByCEP(
+				&MockCEPRepository{getCEPDataFunc: func(cep string) (*domain.CEPData, error) {
+					return nil, tc.err
+				}},
+				&MockTemperatureRepository{},
+				validator.NewCEPValidator(),
+			)
+
+			// Act
+			_, err := useCase.GetTemperatureByCEP(context.Background(), "01001000")
+
+			// Assert
+			assert.ErrorIs(t, err, tc.expected)
+			assert.NotErrorIs(t, err, tc.notExpected)
+		})
+	}
+}
@@ -0,0 +1,69 @@
+package usecase
+
+import (
+	"context"
+	"sync"
+
+	"github.com/xavierpms/weather-by-city/internal/domain"
+)
+
+// GetTemperaturesByCEPs represents the use case for fetching the temperature of several CEPs
+type GetTemperaturesByCEPs struct {
+	temperatureUseCase domain.TemperatureUseCase
+	maxBatchSize       int
+	concurrency        int
+}
+
+// NewGetTemperaturesByCEPs creates a new instance of the use case
+func NewGetTemperaturesByCEPs(temperatureUseCase domain.TemperatureUseCase, maxBatchSize, concurrency int) domain.BatchTemperatureUseCase {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	return &GetTemperaturesByCEPs{
+		temperatureUseCase: temperatureUseCase,
+		maxBatchSize:       maxBatchSize,
+		concurrency:        concurrency,
+	}
+}
+
+// GetTemperaturesByCEPs fetches each distinct CEP once and returns one result per requested CEP, in order
+func (u *GetTemperaturesByCEPs) GetTemperaturesByCEPs(ctx context.Context, ceps []string) ([]domain.TemperatureResult, error) {
+	if len(ceps) == 0 {
+		return nil, domain.ErrEmptyBatch
+	}
+
+	if u.maxBatchSize > 0 && len(ceps) > u.maxBatchSize {
+		return nil, domain.ErrBatchTooLarge
+	}
+
+	// Deduplicate the CEPs so repeated entries cost a single lookup
+	unique := make(map[string]*domain.TemperatureResult)
+	for _, cep := range ceps {
+		if _, ok := unique[cep]; !ok {
+			unique[cep] = &domain.TemperatureResult{CEP: cep}
+		}
+	}
+
+	var wg sync.WaitGroup
+	slots := make(chan struct{}, u.concurrency)
+	for _, result := range unique {
+		wg.Add(1)
+		slots <- struct{}{}
+
+		go func(result *domain.TemperatureResult) {
+			defer wg.Done()
+			defer func() { <-slots }()
+
+			result.Temperature, result.Err = u.temperatureUseCase.GetTemperatureByCEP(ctx, result.CEP)
+		}(result)
+	}
+	wg.Wait()
+
+	results := make([]domain.TemperatureResult, 0, len(ceps))
+	for _, cep := range ceps {
+		results = append(results, *unique[cep])
+	}
+
+	return results, nil
+}
@@ -0,0 +1,117 @@
+package usecase
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"net/netip"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xavierpms/weather-by-city/internal/domain"
+)
+
+// ManageAlertRules represents the use case for registering and removing alert rules
+type ManageAlertRules struct {
+	ruleRepository   domain.AlertRuleRepository
+	cepValidator     domain.CEPValidator
+	maxRulesPerOwner int
+
+	// mu serializes the creations, so concurrent requests cannot exceed the limit of an owner
+	mu sync.Mutex
+}
+
+// NewManageAlertRules creates a new instance of the use case. Each owner may register up to
+// maxRulesPerOwner rules.
+func NewManageAlertRules(ruleRepo domain.AlertRuleRepository, validator domain.CEPValidator, maxRulesPerOwner int) domain.AlertRuleUseCase {
+	return &ManageAlertRules{
+		ruleRepository:   ruleRepo,
+		cepValidator:     validator,
+		maxRulesPerOwner: maxRulesPerOwner,
+	}
+}
+
+// CreateRule validates and stores a new alert rule for its owner
+func (u *ManageAlertRules) CreateRule(rule *domain.AlertRule) (*domain.AlertRule, error) {
+	cep, err := u.cepValidator.NormalizeCEP(rule.CEP)
+	if err != nil {
+		return nil, err
+	}
+
+	if !isValidAlertRule(rule) {
+		return nil, domain.ErrInvalidAlertRule
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	count, err := u.ruleRepository.CountByOwner(rule.Owner)
+	if err != nil {
+		return nil, err
+	}
+	if count >= u.maxRulesPerOwner {
+		return nil, domain.ErrAlertRuleLimit
+	}
+
+	created := *rule
+	created.CEP = cep
+	created.ID = newID()
+	created.CreatedAt = time.Now().UTC()
+
+	if err := u.ruleRepository.Create(&created); err != nil {
+		return nil, err
+	}
+
+	return &created, nil
+}
+
+// ListRules returns all registered alert rules
+func (u *ManageAlertRules) ListRules() ([]*domain.AlertRule, error) {
+	return u.ruleRepository.List()
+}
+
+// DeleteRule removes an alert rule
+func (u *ManageAlertRules) DeleteRule(id string) error {
+	return u.ruleRepository.Delete(id)
+}
+
+// isValidAlertRule checks the thresholds, hysteresis and webhook URL of a rule. Webhooks to
+// localhost and to IP addresses of the internal network are refused; the dispatcher checks the
+// addresses host names resolve to when delivering.
+func isValidAlertRule(rule *domain.AlertRule) bool {
+	// At least one threshold is required
+	if rule.Above == nil && rule.Below == nil {
+		return false
+	}
+
+	if rule.Above != nil && rule.Below != nil && *rule.Below >= *rule.Above {
+		return false
+	}
+
+	if rule.Hysteresis < 0 {
+		return false
+	}
+
+	webhookURL, err := url.Parse(rule.WebhookURL)
+	if err != nil || webhookURL.Hostname() == "" {
+		return false
+	}
+
+	host := strings.ToLower(strings.TrimSuffix(webhookURL.Hostname(), "."))
+	if host == "localhost" || strings.HasSuffix(host, ".localhost") {
+		return false
+	}
+	if addr, err := netip.ParseAddr(host); err == nil && !domain.IsPublicWebhookAddr(addr) {
+		return false
+	}
+
+	return webhookURL.Scheme == "http" || webhookURL.Scheme == "https"
+}
+
+// newID generates a random identifier
+func newID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
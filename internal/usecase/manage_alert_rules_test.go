@@ -0,0 +1,77 @@
+package usecase
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xavierpms/weather-by-city/internal/domain"
+	"github.com/xavierpms/weather-by-city/internal/infra/repository"
+	"github.com/xavierpms/weather-by-city/internal/infra/validator"
+)
+
+// TestCreateRuleRefusesInternalWebhooks tests that webhooks to localhost and the internal network are refused
+func TestCreateRuleRefusesInternalWebhooks(t *testing.T) {
+	above := 35.0
+	testCases := []struct {
+		name       string
+		webhookURL string
+		valid      bool
+	}{
+		{"public host", "https://example.com/hooks/weather", true},
+		{"public address", "http://203.0.113.10:8080/hook", true},
+		{"localhost", "http://localhost:8080/hook", false},
+		{"localhost subdomain", "http://api.localhost/hook", false},
+		{"loopback", "http://127.0.0.1/hook", false},
+		{"loopback IPv6", "http://[::1]/hook", false},
+		{"private network", "http://10.0.0.5/hook", false},
+		{"private network 192.168", "http://192.168.1.1/hook", false},
+		{"cloud metadata", "http://169.254.169.254/latest/meta-data/", false},
+		{"IPv4-mapped IPv6", "http://[::ffff:10.0.0.5]/hook", false},
+		{"shared address space", "http://100.64.0.1/hook", false},
+		{"unspecified", "http://0.0.0.0/hook", false},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			// Arrange
+			useCase := NewManageAlertRules(repository.NewAlertRuleRepository(), validator.NewCEPValidator(), 10)
+
+			// Act
+			_, err := useCase.CreateRule(&domain.AlertRule{CEP: "01001000", Above: &above, WebhookURL: tc.webhookURL})
+
+			// Assert
+			if tc.valid {
+				assert.NoError(t, err)
+			} else {
+				assert.ErrorIs(t, err, domain.ErrInvalidAlertRule)
+			}
+		})
+	}
+}
+
+// TestCreateRuleLimitsTheRulesOfEachOwner tests that an owner cannot register more rules than the limit
+func TestCreateRuleLimitsTheRulesOfEachOwner(t *testing.T) {
+	// Arrange
+	above := 35.0
+	ruleRepo := repository.NewAlertRuleRepository()
+	useCase := NewManageAlertRules(ruleRepo, validator.NewCEPValidator(), 2)
+	newRule := func(owner string) *domain.AlertRule {
+		return &domain.AlertRule{CEP: "01001000", Above: &above, WebhookURL: "https://example.com/hook", Owner: owner}
+	}
+	for range 2 {
+		_, err := useCase.CreateRule(newRule("key:1"))
+		require.NoError(t, err)
+	}
+
+	// Act
+	_, overLimit := useCase.CreateRule(newRule("key:1"))
+	_, otherOwner := useCase.CreateRule(newRule("key:2"))
+
+	// Assert
+	assert.ErrorIs(t, overLimit, domain.ErrAlertRuleLimit)
+	assert.NoError(t, otherOwner)
+	count, err := ruleRepo.CountByOwner("key:1")
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
@@ -0,0 +1,133 @@
+package usecase
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/xavierpms/weather-by-city/internal/domain"
+)
+
+// apiKeyPrefix identifies the secrets issued by the service, so leaked keys are easy to search for
+const apiKeyPrefix = "wk_"
+
+// ManageAPIKeys represents the use case for issuing API keys and enforcing their routes and quotas
+type ManageAPIKeys struct {
+	keyRepository     domain.APIKeyRepository
+	usageRepository   domain.UsageRepository
+	defaultDailyQuota int
+	now               func() time.Time
+
+	// mu makes the quota check and the usage increment atomic
+	mu sync.Mutex
+}
+
+// NewManageAPIKeys creates a new instance of the use case. Keys created without a quota get
+// defaultDailyQuota; zero means unlimited.
+func NewManageAPIKeys(keyRepo domain.APIKeyRepository, usageRepo domain.UsageRepository, defaultDailyQuota int) domain.APIKeyUseCase {
+	return &ManageAPIKeys{
+		keyRepository:     keyRepo,
+		usageRepository:   usageRepo,
+		defaultDailyQuota: defaultDailyQuota,
+		now:               time.Now,
+	}
+}
+
+// CreateKey issues a new secret and stores its hash. The secret is only returned here.
+func (u *ManageAPIKeys) CreateKey(key *domain.APIKey) (*domain.CreatedAPIKey, error) {
+	if strings.TrimSpace(key.Name) == "" || key.DailyQuota < 0 {
+		return nil, domain.ErrInvalidAPIKeySpec
+	}
+
+	b := make([]byte, 32)
+	_, _ = rand.Read(b)
+	secret := apiKeyPrefix + base64.RawURLEncoding.EncodeToString(b)
+
+	created := domain.APIKey{
+		ID:            newID(),
+		Name:          strings.TrimSpace(key.Name),
+		Prefix:        secret[:len(apiKeyPrefix)+6],
+		Hash:          hashAPIKey(secret),
+		DailyQuota:    key.DailyQuota,
+		AllowedRoutes: key.AllowedRoutes,
+		CreatedAt:     u.now().UTC(),
+	}
+	if created.DailyQuota == 0 {
+		created.DailyQuota = u.defaultDailyQuota
+	}
+
+	if err := u.keyRepository.Create(&created); err != nil {
+		return nil, err
+	}
+
+	return &domain.CreatedAPIKey{APIKey: created, Secret: secret}, nil
+}
+
+// ListKeys returns all API keys, including the revoked ones
+func (u *ManageAPIKeys) ListKeys() ([]*domain.APIKey, error) {
+	return u.keyRepository.List()
+}
+
+// RevokeKey revokes an API key immediately
+func (u *ManageAPIKeys) RevokeKey(id string) error {
+	return u.keyRepository.Revoke(id, u.now().UTC())
+}
+
+// Authenticate returns the active API key matching the secret
+func (u *ManageAPIKeys) Authenticate(secret string) (*domain.APIKey, error) {
+	key, err := u.keyRepository.GetByHash(hashAPIKey(secret))
+	if err != nil || key.RevokedAt != nil {
+		return nil, domain.ErrInvalidAPIKey
+	}
+
+	return key, nil
+}
+
+// Authorize checks that the key may call the route and has quota left for today, and counts the request
+func (u *ManageAPIKeys) Authorize(key *domain.APIKey, route string) error {
+	if len(key.AllowedRoutes) > 0 && !slices.Contains(key.AllowedRoutes, route) {
+		return domain.ErrRouteNotAllowed
+	}
+
+	u.mu.Lock()
+	defer u.mu.Unlock()
+
+	day := u.now().UTC().Format(time.DateOnly)
+	if key.DailyQuota > 0 {
+		total, err := u.usageRepository.Total(key.ID, day)
+		if err != nil {
+			return err
+		}
+		if total >= key.DailyQuota {
+			return domain.ErrQuotaExceeded
+		}
+	}
+
+	return u.usageRepository.Increment(key.ID, day, route)
+}
+
+// Usage returns the consumption of the key by day and route
+func (u *ManageAPIKeys) Usage(key *domain.APIKey) (*domain.UsageReport, error) {
+	days, err := u.usageRepository.List(key.ID)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.UsageReport{
+		KeyID:      key.ID,
+		Name:       key.Name,
+		DailyQuota: key.DailyQuota,
+		Days:       days,
+	}, nil
+}
+
+// hashAPIKey hashes the secret. Secrets are random, so a fast unsalted hash is enough.
+func hashAPIKey(secret string) string {
+	sum := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(sum[:])
+}
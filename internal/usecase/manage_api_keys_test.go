@@ -0,0 +1,108 @@
+package usecase
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"github.com/xavierpms/weather-by-city/internal/domain"
+	"github.com/xavierpms/weather-by-city/internal/infra/repository"
+)
+
+// newManageAPIKeys creates the use case over in-memory repositories, with a settable clock
+func newManageAPIKeys(t *testing.T, now *time.Time) *ManageAPIKeys {
+	keyRepo, err := repository.NewAPIKeyRepository("")
+	require.NoError(t, err)
+	usageRepo, err := repository.NewUsageRepository("")
+	require.NoError(t, err)
+
+	u := NewManageAPIKeys(keyRepo, usageRepo, 100).(*ManageAPIKeys)
+	u.now = func() time.Time { return *now }
+	return u
+}
+
+// TestManageAPIKeysCreateAndAuthenticate tests that only the issued secret authenticates, until revoked
+func TestManageAPIKeysCreateAndAuthenticate(t *testing.T) {
+	// Arrange
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	u := newManageAPIKeys(t, &now)
+
+	// Act
+	created, err := u.CreateKey(&domain.APIKey{Name: " mobile-app "})
+
+	// Assert
+	require.NoError(t, err)
+	assert.Equal(t, "mobile-app", created.Name)
+	assert.Equal(t, 100, created.DailyQuota)
+	assert.Contains(t, created.Secret, "wk_")
+	assert.Equal(t, created.Secret[:len(created.Prefix)], created.Prefix)
+	assert.NotContains(t, created.Hash, created.Secret)
+
+	key, err := u.Authenticate(created.Secret)
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, key.ID)
+
+	_, err = u.Authenticate(created.Secret + "x")
+	assert.ErrorIs(t, err, domain.ErrInvalidAPIKey)
+
+	require.NoError(t, u.RevokeKey(created.ID))
+	_, err = u.Authenticate(created.Secret)
+	assert.ErrorIs(t, err, domain.ErrInvalidAPIKey)
+	assert.ErrorIs(t, u.RevokeKey("missing"), domain.ErrAPIKeyNotFound)
+}
+
+// TestManageAPIKeysInvalidSpec tests that keys need a name and a non-negative quota
+func TestManageAPIKeysInvalidSpec(t *testing.T) {
+	// Arrange
+	now := time.Now()
+	u := newManageAPIKeys(t, &now)
+
+	// Act & Assert
+	_, err := u.CreateKey(&domain.APIKey{Name: "  "})
+	assert.ErrorIs(t, err, domain.ErrInvalidAPIKeySpec)
+	_, err = u.CreateKey(&domain.APIKey{Name: "app", DailyQuota: -1})
+	assert.ErrorIs(t, err, domain.ErrInvalidAPIKeySpec)
+}
+
+// TestManageAPIKeysAuthorize tests the allowed routes, the daily quota and its reset at midnight UTC
+func TestManageAPIKeysAuthorize(t *testing.T) {
+	// Arrange
+	now := time.Date(2026, 10, 19, 23, 59, 0, 0, time.UTC)
+	u := newManageAPIKeys(t, &now)
+	created, err := u.CreateKey(&domain.APIKey{Name: "app", DailyQuota: 2, AllowedRoutes: []string{"/{cep}"}})
+	require.NoError(t, err)
+	key := &created.APIKey
+
+	// Act & Assert
+	assert.ErrorIs(t, u.Authorize(key, "/v1/temperatures/batch"), domain.ErrRouteNotAllowed)
+	assert.NoError(t, u.Authorize(key, "/{cep}"))
+	assert.NoError(t, u.Authorize(key, "/{cep}"))
+	assert.ErrorIs(t, u.Authorize(key, "/{cep}"), domain.ErrQuotaExceeded)
+
+	now = now.Add(2 * time.Minute)
+	assert.NoError(t, u.Authorize(key, "/{cep}"))
+
+	report, err := u.Usage(key)
+	require.NoError(t, err)
+	assert.Equal(t, created.ID, report.KeyID)
+	assert.Equal(t, 2, report.DailyQuota)
+	require.Len(t, report.Days, 2)
+	assert.Equal(t, domain.DailyUsage{Day: "2026-10-20", Total: 1, Routes: map[string]int{"/{cep}": 1}}, report.Days[0])
+	assert.Equal(t, domain.DailyUsage{Day: "2026-10-19", Total: 2, Routes: map[string]int{"/{cep}": 2}}, report.Days[1])
+}
+
+// TestManageAPIKeysUnlimited tests that a zero quota does not limit the key
+func TestManageAPIKeysUnlimited(t *testing.T) {
+	// Arrange
+	keyRepo, _ := repository.NewAPIKeyRepository("")
+	usageRepo, _ := repository.NewUsageRepository("")
+	u := NewManageAPIKeys(keyRepo, usageRepo, 0)
+	created, err := u.CreateKey(&domain.APIKey{Name: "internal"})
+	require.NoError(t, err)
+
+	// Act & Assert
+	for range 5 {
+		assert.NoError(t, u.Authorize(&created.APIKey, "/{cep}"))
+	}
+}